@@ -1,23 +1,28 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/ansrivas/fiberprometheus/v2"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/placement-portal-kec/admin-service/internal/database"
-	"github.com/placement-portal-kec/admin-service/internal/middleware"
+	"github.com/placement-portal-kec/admin-service/internal/handlers"
 	"github.com/placement-portal-kec/admin-service/internal/routes"
 	"github.com/placement-portal-kec/admin-service/internal/services"
+	"github.com/placement-portal-kec/admin-service/internal/utils"
 )
 
 func main() {
-	// Initialize JWT Secret
-	middleware.SecretKey = []byte(os.Getenv("JWT_SECRET"))
+	// Load auth-service public keys for JWT verification
+	utils.InitJWKS()
 
 	// Connect to Database
 	pool, err := database.Connect()
@@ -48,12 +53,35 @@ func main() {
 	}))
 
 	// Setup Routes
-	routes.SetupRoutes(app)
+	background := routes.SetupRoutes(app)
+
+	// Background jobs stop when the service is asked to shut down
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Send queued email, WhatsApp and push notifications
+	go background.NotificationWorker.Run(ctx)
+	// Scheduled broadcasts and drive-day reminders
+	go background.Broadcasts.RunScheduler(ctx)
+	// Weekly (or daily) digest emails, unless DIGEST_DAY=off
+	if schedule, ok := handlers.DigestScheduleFromEnv(); ok {
+		go background.Digests.RunDigests(ctx, schedule)
+	}
+
+	go func() {
+		<-ctx.Done()
+		log.Println("Admin Service shutting down")
+		if err := app.ShutdownWithTimeout(10 * time.Second); err != nil {
+			log.Printf("Shutdown: %v", err)
+		}
+	}()
 
 	// Start Server
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8085"
 	}
-	log.Fatal(app.Listen(":" + port))
+	if err := app.Listen(":" + port); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,39 @@
+// Command fake-meta serves the fake WhatsApp Cloud API in internal/fakemeta,
+// for trying the WhatsApp provider and webhook without a Meta account.
+//
+// Run it next to admin-service with the same app secret:
+//
+//	WHATSAPP_APP_SECRET=dev-secret go run ./cmd/fake-meta
+//	WHATSAPP_APP_SECRET=dev-secret WHATSAPP_API_URL=http://localhost:8090/v17.0 \
+//	  WHATSAPP_PHONE_NUMBER_ID=1 WHATSAPP_ACCESS_TOKEN=x go run ./cmd/api
+//
+// Then send a message through the portal, or deliver one to the webhook:
+//
+//	curl -d '{"from": "919876543210", "name": "Asha", "text": "STOP"}' localhost:8090/inbound
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/placement-portal-kec/admin-service/internal/fakemeta"
+)
+
+func main() {
+	addr := flag.String("addr", ":8090", "listen address")
+	webhook := flag.String("webhook", "http://localhost:8085/api/v1/webhooks/whatsapp", "admin-service webhook URL")
+	delay := flag.Duration("delay", time.Second, "pause between the statuses of a sent message")
+	flag.Parse()
+
+	secret := os.Getenv("WHATSAPP_APP_SECRET")
+	if secret == "" {
+		log.Fatal("WHATSAPP_APP_SECRET must be set to the secret admin-service verifies webhooks with")
+	}
+
+	f := &fakemeta.Server{WebhookURL: *webhook, AppSecret: secret, Delay: *delay, Client: &http.Client{Timeout: 10 * time.Second}}
+	log.Printf("fake Meta API listening on %s, calling webhook %s", *addr, *webhook)
+	log.Fatal(http.ListenAndServe(*addr, f.Handler()))
+}
@@ -0,0 +1,250 @@
+// Package emails renders the portal's emails from the templates embedded
+// under templates/. Every email shares the branded layout (layout.html and
+// its plain-text twin layout.txt); each template has one file per language,
+// <name>.<lang>.tmpl, defining its "subject", "html" and "text" parts.
+package emails
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// DefaultLanguage is used when a template has no variant in the requested one
+const DefaultLanguage = "en"
+
+// Languages lists the languages the layout is translated into
+var Languages = []string{"en", "ta"}
+
+// ErrUnknownTemplate is returned when rendering a template that does not exist
+var ErrUnknownTemplate = errors.New("unknown email template")
+
+// Brand is the college branding shown in the layout of every email
+type Brand struct {
+	PortalName  string
+	CollegeName string
+	LogoURL     string // empty to show the name only
+	Year        int
+}
+
+// Message is a rendered email with its HTML and plain-text alternatives
+type Message struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// Definition describes a template. Sample holds variable values for previews.
+type Definition struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Variables   []string          `json:"variables"`
+	Sample      map[string]string `json:"sample"`
+	Languages   []string          `json:"languages"`
+}
+
+var definitions = []Definition{
+	{
+		Name:        "welcome",
+		Description: "Account set-up email sent to students created by an admin",
+		Variables:   []string{"name", "otp"},
+		Sample:      map[string]string{"name": "Priya S", "otp": "482913"},
+	},
+	{
+		Name:        "password_reset",
+		Description: "One-time password for resetting a forgotten password",
+		Variables:   []string{"otp"},
+		Sample:      map[string]string{"otp": "730164"},
+	},
+	{
+		Name:        "notification",
+		Description: "Branded wrapper for broadcasts and other free-text messages",
+		Variables:   []string{"title", "body"},
+		Sample: map[string]string{
+			"title": "Infosys drive tomorrow",
+			"body":  "The Infosys drive starts at 9:00 AM in the Main Auditorium.\nBring two copies of your resume.",
+		},
+	},
+	{
+		Name:        "student_digest",
+		Description: "Weekly or daily summary of a student's new drives, deadlines and application updates",
+		Variables:   []string{"name", "frequency", "new_drives", "new_drives_more", "deadlines", "deadlines_more", "status_changes", "status_changes_more"},
+		Sample: map[string]string{
+			"name":           "Priya S",
+			"frequency":      "weekly",
+			"new_drives":     "Infosys\tSystems Engineer\tThu, 23 Oct\nZoho\tMember Technical Staff, QA Engineer\tSat, 25 Oct",
+			"deadlines":      "TCS\tTue, 21 Oct 5:00 PM",
+			"status_changes": "Accenture\tShortlisted\nWipro\tNot selected",
+		},
+	},
+	{
+		Name:        "coordinator_digest",
+		Description: "Weekly or daily department summary for coordinators: new drives, pending change requests and incomplete profiles",
+		Variables: []string{"name", "department", "frequency", "new_drives", "new_drives_more",
+			"change_requests", "change_requests_total", "incomplete_profiles", "incomplete_profiles_total"},
+		Sample: map[string]string{
+			"name":                      "Dr. K Ramesh",
+			"department":                "CSE",
+			"frequency":                 "weekly",
+			"new_drives":                "Infosys\tThu, 23 Oct\nZoho\tSat, 25 Oct",
+			"change_requests":           "Priya S\t21CS101\tMobile Number\nArun K\t21CS117\tUG CGPA",
+			"change_requests_total":     "2",
+			"incomplete_profiles":       "Divya M\t21CS109\nKarthik R\t21CS142",
+			"incomplete_profiles_total": "2",
+		},
+	},
+}
+
+// layoutText are the layout's own strings, per language
+var layoutText = map[string]map[string]string{
+	"en": {
+		"automated": "This is an automated message from",
+		"rights":    "All rights reserved.",
+	},
+	"ta": {
+		"automated": "இது தானியங்கி முறையில் அனுப்பப்பட்ட செய்தி:",
+		"rights":    "அனைத்து உரிமைகளும் பாதுகாக்கப்பட்டவை.",
+	},
+}
+
+var funcs = map[string]any{
+	// lines splits free text into lines so templates can keep line breaks
+	"lines": func(s string) []string { return strings.Split(strings.TrimSpace(s), "\n") },
+	// rows splits a list variable, one row per line with tab-separated cells
+	"rows": func(s string) [][]string {
+		var rows [][]string
+		for _, line := range strings.Split(s, "\n") {
+			if strings.TrimSpace(line) != "" {
+				rows = append(rows, strings.Split(line, "\t"))
+			}
+		}
+		return rows
+	},
+}
+
+type variant struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// view is what templates see: {{.Vars.otp}}, {{.Brand.CollegeName}}, {{.T.rights}}
+type view struct {
+	Brand   Brand
+	Lang    string
+	Subject string
+	Vars    map[string]string
+	T       map[string]string
+}
+
+var registry = mustLoad()
+
+func mustLoad() map[string]map[string]variant {
+	r, err := load()
+	if err != nil {
+		panic(fmt.Sprintf("emails: %v", err))
+	}
+	return r
+}
+
+func load() (map[string]map[string]variant, error) {
+	files, err := fs.Glob(templateFS, "templates/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	r := map[string]map[string]variant{}
+	for _, file := range files {
+		name, lang, ok := strings.Cut(strings.TrimSuffix(path.Base(file), ".tmpl"), ".")
+		if !ok {
+			return nil, fmt.Errorf("%s: want <name>.<lang>.tmpl", file)
+		}
+		html, err := htmltemplate.New("layout.html").Funcs(funcs).Option("missingkey=zero").ParseFS(templateFS, "templates/layout.html", file)
+		if err != nil {
+			return nil, err
+		}
+		text, err := texttemplate.New("layout.txt").Funcs(funcs).Option("missingkey=zero").ParseFS(templateFS, "templates/layout.txt", file)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range []string{"subject", "html", "text"} {
+			if text.Lookup(part) == nil {
+				return nil, fmt.Errorf("%s: missing {{define %q}}", file, part)
+			}
+		}
+		if r[name] == nil {
+			r[name] = map[string]variant{}
+		}
+		r[name][lang] = variant{html: html, text: text}
+	}
+	for _, def := range definitions {
+		if _, ok := r[def.Name][DefaultLanguage]; !ok {
+			return nil, fmt.Errorf("%s: no %s variant", def.Name, DefaultLanguage)
+		}
+	}
+	return r, nil
+}
+
+// Definitions lists the templates with the languages each is available in
+func Definitions() []Definition {
+	defs := make([]Definition, len(definitions))
+	for i, def := range definitions {
+		for lang := range registry[def.Name] {
+			def.Languages = append(def.Languages, lang)
+		}
+		slices.Sort(def.Languages)
+		defs[i] = def
+	}
+	return defs
+}
+
+// Lookup returns the named template's definition
+func Lookup(name string) (Definition, bool) {
+	for _, def := range Definitions() {
+		if def.Name == name {
+			return def, true
+		}
+	}
+	return Definition{}, false
+}
+
+// Render renders the named template in lang, falling back to the English
+// variant when there is no translation (the layout is still localised).
+func Render(name, lang string, brand Brand, vars map[string]string) (Message, error) {
+	variants, ok := registry[name]
+	if !ok {
+		return Message{}, fmt.Errorf("%w: %q", ErrUnknownTemplate, name)
+	}
+	if !slices.Contains(Languages, lang) {
+		lang = DefaultLanguage
+	}
+	v, ok := variants[lang]
+	if !ok {
+		v = variants[DefaultLanguage]
+	}
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	data := view{Brand: brand, Lang: lang, Vars: vars, T: layoutText[lang]}
+
+	var subject, html, text bytes.Buffer
+	if err := v.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	data.Subject = strings.Join(strings.Fields(subject.String()), " ")
+	if err := v.html.ExecuteTemplate(&html, "layout.html", data); err != nil {
+		return Message{}, err
+	}
+	if err := v.text.ExecuteTemplate(&text, "layout.txt", data); err != nil {
+		return Message{}, err
+	}
+	return Message{Subject: data.Subject, HTML: html.String(), Text: strings.TrimSpace(text.String()) + "\n"}, nil
+}
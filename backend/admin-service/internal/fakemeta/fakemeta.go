@@ -0,0 +1,178 @@
+// Package fakemeta is a local stand-in for the WhatsApp Cloud API, for
+// testing the WhatsApp provider and webhook without a Meta account.
+//
+// It accepts sends on POST /{version}/{phone-number-id}/messages, answers
+// with a message ID like the Graph API, then calls the admin-service webhook
+// with signed "sent", "delivered" and "read" statuses for it. Numbers ending
+// in 0000 get a "failed" status instead; numbers ending in 9999 are rejected
+// outright, as an invalid recipient would be.
+//
+// POST /inbound {"from": "919876543210", "name": "Asha", "text": "STOP"}
+// delivers a signed incoming message to the webhook, to try opt-out
+// keywords and support inbox messages.
+package fakemeta
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/placement-portal-kec/admin-service/internal/services"
+)
+
+// Server is the fake Cloud API. It calls WebhookURL with payloads signed
+// with AppSecret, waiting Delay between the statuses of a sent message.
+type Server struct {
+	WebhookURL string
+	AppSecret  string
+	Delay      time.Duration
+	Client     *http.Client
+}
+
+// Handler serves the send and inbound endpoints
+func (f *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /{version}/{phone}/messages", f.handleSend)
+	mux.HandleFunc("POST /inbound", f.handleInbound)
+	return mux
+}
+
+type sendRequest struct {
+	To   string `json:"to"`
+	Type string `json:"type"`
+	Text *struct {
+		Body string `json:"body"`
+	} `json:"text"`
+	Template *struct {
+		Name string `json:"name"`
+	} `json:"template"`
+}
+
+func (f *Server) handleSend(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		writeError(w, http.StatusUnauthorized, 190, "Invalid OAuth access token")
+		return
+	}
+	var req sendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.To == "" {
+		writeError(w, http.StatusBadRequest, 100, "Invalid parameter")
+		return
+	}
+	if strings.HasSuffix(req.To, "9999") {
+		writeError(w, http.StatusBadRequest, 131030, "Recipient phone number not in allowed list")
+		return
+	}
+
+	id := "wamid.fake." + randomHex(12)
+	switch {
+	case req.Template != nil:
+		log.Printf("send %s to %s: template %s", id, req.To, req.Template.Name)
+	case req.Text != nil:
+		log.Printf("send %s to %s: %q", id, req.To, req.Text.Body)
+	default:
+		log.Printf("send %s to %s: %s message", id, req.To, req.Type)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"messaging_product": "whatsapp",
+		"contacts":          []map[string]string{{"input": req.To, "wa_id": req.To}},
+		"messages":          []map[string]string{{"id": id}},
+	})
+	go f.reportStatuses(id, req.To)
+}
+
+// reportStatuses walks a sent message through its statuses, as Meta would
+func (f *Server) reportStatuses(id, to string) {
+	statuses := []string{services.WhatsAppStatusSent, services.WhatsAppStatusDelivered, services.WhatsAppStatusRead}
+	if strings.HasSuffix(to, "0000") {
+		statuses = []string{services.WhatsAppStatusFailed}
+	}
+	for _, status := range statuses {
+		time.Sleep(f.Delay)
+		st := map[string]any{
+			"id":           id,
+			"status":       status,
+			"timestamp":    strconv.FormatInt(time.Now().Unix(), 10),
+			"recipient_id": to,
+		}
+		if status == services.WhatsAppStatusFailed {
+			st["errors"] = []map[string]any{{"code": 131026, "title": "Message undeliverable", "message": "Message undeliverable"}}
+		}
+		f.post(map[string]any{"statuses": []any{st}})
+	}
+}
+
+// Inbound is the body of POST /inbound
+type Inbound struct {
+	From string `json:"from"`
+	Name string `json:"name"`
+	Text string `json:"text"`
+}
+
+func (f *Server) handleInbound(w http.ResponseWriter, r *http.Request) {
+	var req Inbound
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.From == "" || req.Text == "" {
+		writeError(w, http.StatusBadRequest, 100, "from and text are required")
+		return
+	}
+
+	id := "wamid.fake." + randomHex(12)
+	status := f.post(map[string]any{
+		"contacts": []any{map[string]any{"wa_id": req.From, "profile": map[string]string{"name": req.Name}}},
+		"messages": []any{map[string]any{
+			"from":      req.From,
+			"id":        id,
+			"timestamp": strconv.FormatInt(time.Now().Unix(), 10),
+			"type":      "text",
+			"text":      map[string]string{"body": req.Text},
+		}},
+	})
+	writeJSON(w, http.StatusOK, map[string]any{"id": id, "webhook_status": status})
+}
+
+// post sends value to the webhook wrapped in a signed Meta envelope and
+// returns the webhook's HTTP status (0 if it could not be reached)
+func (f *Server) post(value map[string]any) int {
+	value["messaging_product"] = "whatsapp"
+	body, _ := json.Marshal(map[string]any{
+		"object": "whatsapp_business_account",
+		"entry": []any{map[string]any{
+			"id":      "fake-waba",
+			"changes": []any{map[string]any{"field": "messages", "value": value}},
+		}},
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, f.WebhookURL, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(services.SignWhatsAppPayload(f.AppSecret, body)))
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		log.Printf("webhook call failed: %v", err)
+		return 0
+	}
+	resp.Body.Close()
+	log.Printf("webhook answered %s", resp.Status)
+	return resp.StatusCode
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status, code int, message string) {
+	writeJSON(w, status, map[string]any{"error": map[string]any{"message": message, "type": "OAuthException", "code": code}})
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -1,17 +1,17 @@
 package handlers
 
 import (
-	"context"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
 
 	"github.com/placement-portal-kec/admin-service/internal/database"
 	"github.com/placement-portal-kec/admin-service/internal/models"
+	"github.com/placement-portal-kec/admin-service/internal/password"
 	"github.com/placement-portal-kec/admin-service/internal/repository"
-	"github.com/placement-portal-kec/admin-service/internal/services"
 	"github.com/placement-portal-kec/admin-service/internal/utils"
 	"github.com/gofiber/fiber/v2"
 )
@@ -58,6 +58,29 @@ func BulkUploadStudents(c *fiber.Ctx) error {
 	// Remove header row
 	dataRows := records[1:]
 
+	// Reject weak/breached CSV passwords and out-of-department rows up front so
+	// nothing is half-imported. Every imported account is still forced to change
+	// its password on first login.
+	policy := password.LoadPolicy()
+	scope := utils.DepartmentScope(c)
+	for i, row := range dataRows {
+		if len(row) != 6 {
+			continue // Reported with the row count check in BulkCreateStudents
+		}
+		if scope != nil && strings.TrimSpace(row[3]) != *scope {
+			return c.Status(403).JSON(fiber.Map{
+				"error":   "Forbidden: You can only manage students of your department",
+				"details": fmt.Sprintf("row %d (%s): department %q", i+2, row[0], row[3]),
+			})
+		}
+		if err := policy.Validate(row[5], row[0]); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error":   "Bulk upload failed",
+				"details": fmt.Sprintf("row %d (%s): %v", i+2, row[0], err),
+			})
+		}
+	}
+
 	// 5. Call Repository
 	repo := repository.NewUserRepository(database.DB)
 	count, err := repo.BulkCreateStudents(c.Context(), dataRows)
@@ -96,6 +119,9 @@ func DeleteStudent(c *fiber.Ctx) error {
 	}
 
 	repo := repository.NewUserRepository(database.DB)
+	if !studentsInScope(c, repo, []int64{id}) {
+		return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You can only manage students of your department"})
+	}
 
 	// 1. Cleanup S3 Folder
 	regNo, err := repo.GetRegisterNumber(c.Context(), id)
@@ -158,11 +184,16 @@ func BulkDeleteStudents(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "You must provide a Department or Batch Year"})
 	}
 
+	// Coordinators can only bulk delete within their own department
+	if scope := utils.DepartmentScope(c); scope != nil && input.Department != *scope {
+		return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You can only manage students of your department"})
+	}
+
 	repo := repository.NewUserRepository(database.DB)
 
 	// 1. Cleanup S3 Folders (Fetch students first to get Reg Nos)
 	// Passing limit: 10000 to fetch mostly all for cleanup (or we could fetch just RegNos via a specialized query, but this works given previous context)
-	students, _, err := repo.GetStudents(c.Context(), input.Department, input.BatchYear, "", 10000, 0, "register_number", "asc")
+	students, _, err := repo.GetStudents(c.Context(), input.Department, input.BatchYear, "", 10000, 0, "register_number", "asc", nil)
 	if err == nil {
 		for _, s := range students {
 			if s.RegisterNumber != "" {
@@ -213,6 +244,9 @@ func BulkDeleteStudentsByIds(c *fiber.Ctx) error {
 	}
 
 	repo := repository.NewUserRepository(database.DB)
+	if !studentsInScope(c, repo, input.IDs) {
+		return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You can only manage students of your department"})
+	}
 
 	// 1. Cleanup S3 Folders
 	regNos, err := repo.GetRegisterNumbersByIDs(c.Context(), input.IDs)
@@ -263,6 +297,10 @@ func ToggleBlockUser(c *fiber.Ctx) error {
 	}
 
 	repo := repository.NewUserRepository(database.DB)
+	if !studentsInScope(c, repo, []int64{id}) {
+		return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You can only manage students of your department"})
+	}
+
 	// We need to add this small helper in UserRepo
 	if err := repo.SetUserBlockStatus(c.Context(), id, input.Block); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to update status"})
@@ -330,6 +368,10 @@ func UpdateApplicationStatus(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid status value"})
 	}
 
+	if !studentsInScope(c, repository.NewUserRepository(database.DB), []int64{input.StudentID}) {
+		return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You can only manage students of your department"})
+	}
+
 	// Call Repository (Reusing DriveRepo or ApplicationRepo)
 	// Query: UPDATE drive_applications SET status = $1 WHERE drive_id = $2 AND student_id = $3
 	repo := repository.NewDriveRepository(database.DB)
@@ -342,60 +384,57 @@ func UpdateApplicationStatus(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to update status"})
 	}
 
-	// Send Notification
-	go func() {
-		token, err := repo.GetStudentFCMToken(context.Background(), input.StudentID)
-		if err == nil && token != "" {
-			var companyName string
-			_ = database.DB.QueryRow(context.Background(), "SELECT company_name FROM placement_drives WHERE id = $1", input.DriveID).Scan(&companyName)
-			if companyName == "" {
-				companyName = "the"
-			}
-
-			ns, err := services.NewNotificationService("firebase-service-account.json")
-			if err == nil {
-				title := "Application Update"
-				body := ""
-
-				switch input.Status {
-				case "opted_in":
-					title = "Status: Opted In"
-					body = fmt.Sprintf("You are manually opted in by admin for %s drive", companyName)
-				case "opted_out":
-					title = "Status: Opted Out"
-					body = fmt.Sprintf("You are manually opted out by admin for %s drive", companyName)
-				case "request_to_attend":
-					title = "Status: Pending Request"
-					body = "Your request is changed to pending state by admin"
-				case "shortlisted":
-					title = "Status: Shortlisted"
-					body = fmt.Sprintf("You have been shortlisted for the next round for %s drive", companyName)
-				case "placed":
-					title = "Status: Placed"
-					body = fmt.Sprintf("Congrats, you have been placed for %s drive", companyName)
-				case "rejected":
-					title = "Status: Rejected"
-					body = "Your application was rejected by admin"
-				}
+	// Notify the student (inbox + push)
+	var companyName string
+	_ = database.DB.QueryRow(c.Context(), "SELECT company_name FROM placement_drives WHERE id = $1", input.DriveID).Scan(&companyName)
+	if companyName == "" {
+		companyName = "the"
+	}
+
+	title := "Application Update"
+	body := ""
+
+	switch input.Status {
+	case "opted_in":
+		title = "Status: Opted In"
+		body = fmt.Sprintf("You are manually opted in by admin for %s drive", companyName)
+	case "opted_out":
+		title = "Status: Opted Out"
+		body = fmt.Sprintf("You are manually opted out by admin for %s drive", companyName)
+	case "request_to_attend":
+		title = "Status: Pending Request"
+		body = "Your request is changed to pending state by admin"
+	case "shortlisted":
+		title = "Status: Shortlisted"
+		body = fmt.Sprintf("You have been shortlisted for the next round for %s drive", companyName)
+	case "placed":
+		title = "Status: Placed"
+		body = fmt.Sprintf("Congrats, you have been placed for %s drive", companyName)
+	case "rejected":
+		title = "Status: Rejected"
+		body = "Your application was rejected by admin"
+	}
+
+	if body != "" {
+		if input.Remarks != "" {
+			body += fmt.Sprintf("\nRemarks: %s", input.Remarks)
+		}
 
-				if body != "" {
-					if input.Remarks != "" {
-						body += fmt.Sprintf("\nRemarks: %s", input.Remarks)
-					}
-
-					_, err := ns.SendMulticastNotification(context.Background(), []string{token}, title, body, map[string]string{
-						"type":     "drive_update",
-						"drive_id": fmt.Sprintf("%d", input.DriveID),
-					})
-					if err != nil {
-						fmt.Printf("Failed to send notification: %v\n", err)
-					}
-				}
-			} else {
-				fmt.Printf("Failed to init notification service: %v\n", err)
-			}
+		notifications := repository.NewNotificationJobRepository(database.DB)
+		err := notifications.NotifyUsers(c.Context(), []int64{input.StudentID}, repository.UserNotification{
+			Category: models.InboxApplicationStatus,
+			Type:     "drive_update",
+			Title:    title,
+			Body:     body,
+			Data: map[string]string{
+				"drive_id": fmt.Sprintf("%d", input.DriveID),
+				"status":   input.Status,
+			},
+		})
+		if err != nil {
+			fmt.Printf("Failed to queue notification: %v\n", err)
 		}
-	}()
+	}
 
 	return c.JSON(fiber.Map{"message": "Student status updated"})
 }
@@ -438,7 +477,7 @@ func ListStudents(c *fiber.Ctx) error {
 	sortOrder := c.Query("sortOrder", "asc")       // Default ascending
 
 	repo := repository.NewUserRepository(database.DB)
-	students, total, err := repo.GetStudents(c.Context(), dept, batchYear, search, limit, offset, sortBy, sortOrder)
+	students, total, err := repo.GetStudents(c.Context(), dept, batchYear, search, limit, offset, sortBy, sortOrder, utils.DepartmentScope(c))
 
 	if err != nil {
 		fmt.Println("Error fetching students:", err)
@@ -479,7 +518,7 @@ func GetStudentDetails(c *fiber.Ctx) error {
 	// 1. Strictly find by Register Number
 	// We no longer support fetching by internal integer ID for security and UX consistency.
 	repo := repository.NewUserRepository(database.DB)
-	userProfile, err := repo.GetStudentByRegisterNumber(c.Context(), param)
+	userProfile, err := repo.GetStudentByRegisterNumber(c.Context(), param, utils.DepartmentScope(c))
 	if err == nil {
 		// Presign Profile Photo URL
 		if userProfile.ProfilePhotoURL != "" {
@@ -511,7 +550,7 @@ func GetDriveApplicants(c *fiber.Ctx) error {
 	}
 
 	repo := repository.NewDriveRepository(database.DB)
-	applicants, err := repo.GetDriveApplicants(c.Context(), driveID)
+	applicants, err := repo.GetDriveApplicants(c.Context(), driveID, utils.DepartmentScope(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch applicants"})
 	}
@@ -536,7 +575,7 @@ func GetDriveApplicants(c *fiber.Ctx) error {
 // @Router /v1/admin/drive-requests [get]
 func GetDriveRequests(c *fiber.Ctx) error {
 	repo := repository.NewDriveRepository(database.DB)
-	requests, err := repo.GetDriveRequests(c.Context())
+	requests, err := repo.GetDriveRequests(c.Context(), utils.DepartmentScope(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch drive requests"})
 	}
@@ -552,3 +591,14 @@ func GetDriveRequests(c *fiber.Ctx) error {
 
 	return c.JSON(requests)
 }
+
+// studentsInScope reports whether the caller may act on the given students.
+// Admins always may; coordinators only on students of their own department.
+func studentsInScope(c *fiber.Ctx, repo *repository.UserRepository, ids []int64) bool {
+	scope := utils.DepartmentScope(c)
+	if scope == nil {
+		return true
+	}
+	ok, err := repo.StudentsInDepartment(c.Context(), ids, *scope)
+	return err == nil && ok
+}
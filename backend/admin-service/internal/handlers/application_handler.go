@@ -1,14 +1,13 @@
 package handlers
 
 import (
-	"context"
 	"fmt"
 	"log"
 	"strconv"
 
 	"github.com/placement-portal-kec/admin-service/internal/database"
+	"github.com/placement-portal-kec/admin-service/internal/models"
 	"github.com/placement-portal-kec/admin-service/internal/repository"
-	"github.com/placement-portal-kec/admin-service/internal/services"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -121,50 +120,44 @@ func RequestToAttendDrive(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to submit request: " + err.Error()})
 	}
 
-	// 2. Send notification to admins (async)
-	go func() {
-		driveRepo := repository.NewDriveRepository(database.DB)
-		ns, err := services.NewNotificationService("firebase-service-account.json")
-		if err != nil {
-			log.Printf("Failed to init notification service: %v", err)
-			return
-		}
-
-		// Get student name
-		var studentName string
-		err = database.DB.QueryRow(context.Background(),
-			"SELECT name FROM users WHERE id = $1", studentID).Scan(&studentName)
-		if err != nil {
-			studentName = fmt.Sprintf("Student #%d", studentID)
-		}
-
-		// Get drive company name
-		var companyName string
-		err = database.DB.QueryRow(context.Background(),
-			"SELECT company_name FROM placement_drives WHERE id = $1", driveID).Scan(&companyName)
-		if err != nil {
-			companyName = fmt.Sprintf("Drive #%d", driveID)
-		}
+	// 2. Notify admins (inbox + push)
+	driveRepo := repository.NewDriveRepository(database.DB)
 
-		tokens, err := driveRepo.GetAdminFCMTokens(context.Background())
-		if err != nil || len(tokens) == 0 {
-			log.Printf("No admin tokens found or error: %v", err)
-			return
-		}
+	// Get student name
+	var studentName string
+	err = database.DB.QueryRow(c.Context(),
+		"SELECT name FROM users WHERE id = $1", studentID).Scan(&studentName)
+	if err != nil {
+		studentName = fmt.Sprintf("Student #%d", studentID)
+	}
 
-		title := "Request to Attend Drive"
-		body := fmt.Sprintf("%s has requested to attend the %s drive (does not meet eligibility criteria)", studentName, companyName)
-		data := map[string]string{
-			"type":       "request_to_attend",
-			"drive_id":   fmt.Sprintf("%d", driveID),
-			"student_id": fmt.Sprintf("%d", studentID),
-		}
+	// Get drive company name
+	var companyName string
+	err = database.DB.QueryRow(c.Context(),
+		"SELECT company_name FROM placement_drives WHERE id = $1", driveID).Scan(&companyName)
+	if err != nil {
+		companyName = fmt.Sprintf("Drive #%d", driveID)
+	}
 
-		_, err = ns.SendMulticastNotification(context.Background(), tokens, title, body, data)
+	if adminIDs, err := driveRepo.GetAdminUserIDs(c.Context()); err != nil || len(adminIDs) == 0 {
+		log.Printf("No admins found or error: %v", err)
+	} else {
+		notifications := repository.NewNotificationJobRepository(database.DB)
+		err := notifications.NotifyUsers(c.Context(), adminIDs, repository.UserNotification{
+			Category: models.InboxApplicationStatus,
+			Type:     "request_to_attend",
+			Title:    "Request to Attend Drive",
+			Body:     fmt.Sprintf("%s has requested to attend the %s drive (does not meet eligibility criteria)", studentName, companyName),
+			Data: map[string]string{
+				"drive_id":   fmt.Sprintf("%d", driveID),
+				"student_id": fmt.Sprintf("%d", studentID),
+			},
+			DedupeKey: fmt.Sprintf("request_to_attend:%d:%d", driveID, studentID),
+		})
 		if err != nil {
 			log.Printf("Failed to notify admins about request-to-attend: %v", err)
 		}
-	}()
+	}
 
 	return c.JSON(fiber.Map{"success": true, "message": "Request submitted. Admin will be notified."})
 }
@@ -232,11 +225,18 @@ func BulkUpdateDriveRequestStatus(c *fiber.Ctx) error {
 		DriveID   int64
 		StudentID int64
 	}, len(input.Requests))
+	studentIDs := make([]int64, len(input.Requests))
 	for i, r := range input.Requests {
 		pairs[i] = struct {
 			DriveID   int64
 			StudentID int64
 		}{DriveID: r.DriveID, StudentID: r.StudentID}
+		studentIDs[i] = r.StudentID
+	}
+
+	// Coordinators can only decide requests from their own department
+	if !studentsInScope(c, repository.NewUserRepository(database.DB), studentIDs) {
+		return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You can only manage students of your department"})
 	}
 
 	driveRepo := repository.NewDriveRepository(database.DB)
@@ -250,39 +250,37 @@ func BulkUpdateDriveRequestStatus(c *fiber.Ctx) error {
 		action = "rejected"
 	}
 
-	// [NEW] Notify students about the action
-	go func() {
-		ns, err := services.NewNotificationService("firebase-service-account.json")
-		if err != nil {
-			log.Printf("Failed to initialize notification service: %v", err)
-			return
+	// [NEW] Notify students about the action (inbox + push)
+	notifications := repository.NewNotificationJobRepository(database.DB)
+	for _, req := range pairs {
+		var companyName string
+		_ = database.DB.QueryRow(c.Context(), "SELECT company_name FROM placement_drives WHERE id = $1", req.DriveID).Scan(&companyName)
+		if companyName == "" {
+			continue
+		}
+
+		var title, body string
+		if input.Status == "opted_in" {
+			title = "Drive Request Approved! 🎉"
+			body = fmt.Sprintf("Your request to attend %s has been approved! You are now automatically opted in.", companyName)
+		} else {
+			title = "Drive Request Update"
+			body = fmt.Sprintf("Your request to attend %s has been reviewed. Status: %s. Remarks: %s", companyName, "Rejected", input.Remarks)
 		}
 
-		for _, req := range pairs {
-			var token, studentName, companyName string
-			_ = database.DB.QueryRow(context.Background(), "SELECT fcm_token, COALESCE(name, '') FROM users WHERE id = $1 AND fcm_token IS NOT NULL AND fcm_token != ''", req.StudentID).Scan(&token, &studentName)
-			_ = database.DB.QueryRow(context.Background(), "SELECT company_name FROM placement_drives WHERE id = $1", req.DriveID).Scan(&companyName)
-
-			if token != "" && companyName != "" {
-				var title, body string
-				if input.Status == "opted_in" {
-					title = "Drive Request Approved! 🎉"
-					body = fmt.Sprintf("Your request to attend %s has been approved! You are now automatically opted in.", companyName)
-				} else {
-					title = "Drive Request Update"
-					body = fmt.Sprintf("Your request to attend %s has been reviewed. Status: %s. Remarks: %s", companyName, "Rejected", input.Remarks)
-				}
-
-				_, err := ns.SendMulticastNotification(context.Background(), []string{token}, title, body, map[string]string{
-					"type":     "drive_request_status",
-					"drive_id": fmt.Sprintf("%d", req.DriveID),
-				})
-				if err != nil {
-					log.Printf("Failed to notify student %d: %v", req.StudentID, err)
-				}
-			}
+		err := notifications.NotifyUsers(c.Context(), []int64{req.StudentID}, repository.UserNotification{
+			Category: models.InboxApplicationStatus,
+			Type:     "drive_request_status",
+			Title:    title,
+			Body:     body,
+			Data: map[string]string{
+				"drive_id": fmt.Sprintf("%d", req.DriveID),
+			},
+		})
+		if err != nil {
+			log.Printf("Failed to notify student %d: %v", req.StudentID, err)
 		}
-	}()
+	}
 
 	return c.JSON(fiber.Map{
 		"success":  true,
@@ -1,20 +1,43 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"log"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/placement-portal-kec/admin-service/internal/models"
 	"github.com/placement-portal-kec/admin-service/internal/repository"
+	"github.com/placement-portal-kec/admin-service/internal/utils"
 	"github.com/gofiber/fiber/v2"
 )
 
 type BroadcastHandler struct {
-	Repo *repository.BroadcastRepository
+	Repo   *repository.BroadcastRepository
+	Worker *NotificationWorker
 }
 
-func NewBroadcastHandler(repo *repository.BroadcastRepository) *BroadcastHandler {
-	return &BroadcastHandler{Repo: repo}
+func NewBroadcastHandler(repo *repository.BroadcastRepository, worker *NotificationWorker) *BroadcastHandler {
+	return &BroadcastHandler{Repo: repo, Worker: worker}
+}
+
+// SyncDelivery is the NotificationWorker hook for broadcast jobs: it copies
+// the job's state onto its delivery and completes finished broadcasts.
+func (h *BroadcastHandler) SyncDelivery(ctx context.Context, job models.NotificationJob) {
+	if err := h.Repo.SyncDeliveryFromJob(ctx, job); err != nil {
+		log.Printf("Broadcast: syncing delivery for job %d failed: %v", job.ID, err)
+		return
+	}
+	if job.Status == models.JobDone || job.Status == models.JobDead {
+		if err := h.Repo.CompleteBroadcasts(ctx); err != nil {
+			log.Printf("Broadcast: completing broadcasts failed: %v", err)
+		}
+	}
 }
 
 func (h *BroadcastHandler) CreateTemplate(c *fiber.Ctx) error {
@@ -49,51 +72,354 @@ func (h *BroadcastHandler) DeleteTemplate(c *fiber.Ctx) error {
 }
 
 type BroadcastRequest struct {
-	Type        string    `json:"type"`         // WHATSAPP or EMAIL
-	TargetGroup string    `json:"target_group"` // all_students, placed_students, unplaced_students
-	Batch       *string   `json:"batch"`        // Optional filter
-	Department  *string   `json:"department"`   // Optional filter
-	RollNos     *[]string `json:"roll_nos"`     // Optional roll numbers
-	Names       *[]string `json:"names"`        // Optional names
-	Subject     string    `json:"subject"`      // Email subject
-	Message     string    `json:"message"`
+	Type        string               `json:"type"`         // WHATSAPP, EMAIL or PUSH (used when channels is empty)
+	Channels    []string             `json:"channels"`     // Any of WHATSAPP, EMAIL, PUSH
+	TemplateID  *int64               `json:"template_id"`  // Optional template; its content replaces message
+	TargetGroup string               `json:"target_group"` // all_students, placed_students, unplaced_students
+	Batch       *string              `json:"batch"`        // Optional filter
+	Department  *string              `json:"department"`   // Optional filter
+	RollNos     *[]string            `json:"roll_nos"`     // Optional roll numbers
+	Names       *[]string            `json:"names"`        // Optional names
+	Subject     string               `json:"subject"`      // Email subject / push title
+	Message     string               `json:"message"`
+	SendAt      *time.Time           `json:"send_at"`    // Optional; schedules the broadcast instead of sending now
+	Recurrence  *BroadcastRecurrence `json:"recurrence"` // Optional; repeats the broadcast
+}
+
+// BroadcastRecurrence repeats a scheduled broadcast at a time of day, every
+// day or on chosen weekdays, starting at send_at (or now) until until.
+type BroadcastRecurrence struct {
+	Frequency string     `json:"frequency"` // daily, weekly
+	Weekdays  []string   `json:"weekdays"`  // weekly: MON..SUN
+	Time      string     `json:"time"`      // HH:MM
+	Timezone  string     `json:"timezone"`  // IANA name, default Asia/Kolkata
+	Until     *time.Time `json:"until"`     // Optional end
+}
+
+// broadcastPlaceholder matches template variables written as {{name}}.
+var broadcastPlaceholder = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// broadcastVariables returns the per-student values templates may reference.
+func broadcastVariables(rc models.BroadcastRecipient) map[string]string {
+	firstName := rc.Name
+	if i := strings.IndexByte(firstName, ' '); i > 0 {
+		firstName = firstName[:i]
+	}
+	return map[string]string{
+		"name":            rc.Name,
+		"first_name":      firstName,
+		"register_number": rc.RegisterNumber,
+		"roll_no":         rc.RegisterNumber,
+		"department":      rc.Department,
+		"batch_year":      strconv.Itoa(rc.BatchYear),
+		"email":           rc.Email,
+	}
+}
+
+func renderBroadcast(content string, vars map[string]string) string {
+	return broadcastPlaceholder.ReplaceAllStringFunc(content, func(m string) string {
+		if v, ok := vars[broadcastPlaceholder.FindStringSubmatch(m)[1]]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// unknownBroadcastVariables lists the declared or referenced variables that
+// broadcastVariables cannot fill.
+func unknownBroadcastVariables(declared []string, texts ...string) []string {
+	known := broadcastVariables(models.BroadcastRecipient{})
+	seen := map[string]bool{}
+	var unknown []string
+	check := func(name string) {
+		if _, ok := known[name]; !ok && !seen[name] {
+			seen[name] = true
+			unknown = append(unknown, name)
+		}
+	}
+	for _, v := range declared {
+		check(v)
+	}
+	for _, t := range texts {
+		for _, m := range broadcastPlaceholder.FindAllStringSubmatch(t, -1) {
+			check(m[1])
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// recipientAddress returns the recipient's address on channel and, when it
+// is missing, the reason recorded on the skipped delivery.
+func recipientAddress(rc models.BroadcastRecipient, channel string) (string, string) {
+	switch channel {
+	case models.BroadcastChannelEmail:
+		return rc.Email, "no email address on file"
+	case models.BroadcastChannelWhatsApp:
+		return rc.MobileNumber, "no mobile number on file"
+	default:
+		return rc.FCMToken, "no push token registered"
+	}
+}
+
+// broadcastPlan is a validated broadcast: what to send, on which channels,
+// to whom. It is sent now or stored on a schedule.
+type broadcastPlan struct {
+	Channels     []string
+	TemplateID   *int64
+	TemplateName *string
+	Declared     []string // template variables, passed as WhatsApp parameters
+	Content      string
+	Subject      string
+	Target       models.BroadcastTarget
+}
+
+// planBroadcast validates a broadcast request. Errors carry the status code
+// to answer with.
+func (h *BroadcastHandler) planBroadcast(req BroadcastRequest, scope *string) (broadcastPlan, *fiber.Error) {
+	var plan broadcastPlan
+
+	channels := req.Channels
+	if len(channels) == 0 && req.Type != "" {
+		channels = []string{req.Type}
+	}
+	if len(channels) == 0 {
+		return plan, fiber.NewError(400, "At least one channel is required")
+	}
+	seenChannel := map[string]bool{}
+	for _, ch := range channels {
+		ch = strings.ToUpper(strings.TrimSpace(ch))
+		switch ch {
+		case models.BroadcastChannelEmail, models.BroadcastChannelWhatsApp, models.BroadcastChannelPush:
+		default:
+			return plan, fiber.NewError(400, "Unsupported channel type")
+		}
+		if !seenChannel[ch] {
+			seenChannel[ch] = true
+			plan.Channels = append(plan.Channels, ch)
+		}
+	}
+
+	switch req.TargetGroup {
+	case "":
+		req.TargetGroup = "all_students"
+	case "all_students", "placed_students", "unplaced_students":
+	default:
+		return plan, fiber.NewError(400, "Invalid target group")
+	}
+
+	plan.Content = req.Message
+	plan.Subject = req.Subject
+	if req.TemplateID != nil {
+		tmpl, err := h.Repo.GetTemplateByID(*req.TemplateID)
+		if err != nil {
+			return plan, fiber.NewError(404, "Template not found")
+		}
+		if len(tmpl.Variables) > 0 {
+			if err := json.Unmarshal(tmpl.Variables, &plan.Declared); err != nil {
+				return plan, fiber.NewError(400, "Template variables must be a list of names")
+			}
+		}
+		plan.Content = tmpl.Content
+		plan.TemplateID = req.TemplateID
+		plan.TemplateName = &tmpl.Name
+	}
+	if strings.TrimSpace(plan.Content) == "" {
+		return plan, fiber.NewError(400, "Message is required")
+	}
+	if seenChannel[models.BroadcastChannelEmail] && strings.TrimSpace(req.Subject) == "" {
+		return plan, fiber.NewError(400, "Subject is required for email broadcasts")
+	}
+	if unknown := unknownBroadcastVariables(plan.Declared, plan.Content, req.Subject); len(unknown) > 0 {
+		return plan, fiber.NewError(400, "Unknown template variables: "+strings.Join(unknown, ", "))
+	}
+
+	plan.Target = models.BroadcastTarget{TargetGroup: req.TargetGroup}
+	if req.Batch != nil && *req.Batch != "" {
+		year, err := strconv.Atoi(*req.Batch)
+		if err != nil {
+			return plan, fiber.NewError(400, "Invalid batch")
+		}
+		plan.Target.BatchYear = year
+	}
+	if req.Department != nil {
+		plan.Target.Department = *req.Department
+	}
+	if req.RollNos != nil {
+		plan.Target.RollNos = *req.RollNos
+	}
+	if req.Names != nil {
+		plan.Target.Names = *req.Names
+	}
+
+	if scope != nil && plan.Target.Department != "" && plan.Target.Department != *scope {
+		return plan, fiber.NewError(403, "Coordinators can only broadcast to their own department")
+	}
+	return plan, nil
+}
+
+// queueBroadcast renders one copy of the plan per recipient and channel,
+// saves b with its deliveries and wakes the senders. It fills in b's
+// content fields from the plan.
+func (h *BroadcastHandler) queueBroadcast(ctx context.Context, b *models.Broadcast, plan broadcastPlan, recipients []models.BroadcastRecipient) (queued, skipped int, err error) {
+	deliveries := make([]models.BroadcastDelivery, 0, len(recipients)*len(plan.Channels))
+	for _, rc := range recipients {
+		vars := broadcastVariables(rc)
+		body := renderBroadcast(plan.Content, vars)
+		subject := renderBroadcast(plan.Subject, vars)
+		params := make([]string, len(plan.Declared))
+		for i, name := range plan.Declared {
+			params[i] = vars[name]
+		}
+		userID := rc.UserID
+
+		for _, ch := range plan.Channels {
+			d := models.BroadcastDelivery{
+				UserID:  &userID,
+				Channel: ch,
+				Subject: subject,
+				Body:    body,
+				Params:  params,
+				Status:  models.DeliveryQueued,
+			}
+			if addr, reason := recipientAddress(rc, ch); addr != "" {
+				d.Address = &addr
+				queued++
+			} else {
+				d.Status = models.DeliverySkipped
+				d.LastError = &reason
+				skipped++
+			}
+			deliveries = append(deliveries, d)
+		}
+	}
+
+	b.TemplateID = plan.TemplateID
+	b.TemplateName = plan.TemplateName
+	b.Channels = plan.Channels
+	b.Message = plan.Content
+	b.RecipientCount = len(recipients)
+	if plan.Subject != "" {
+		b.Subject = &plan.Subject
+	}
+	if err := h.Repo.CreateBroadcast(ctx, b, deliveries); err != nil {
+		return 0, 0, err
+	}
+	for _, ch := range plan.Channels {
+		h.Worker.Wake(ch)
+	}
+	return queued, skipped, nil
 }
 
+// SendBroadcast resolves the target students, renders one copy of the message
+// per student and channel, and queues each delivery as a notification job.
+// With send_at or recurrence it saves a schedule instead (see RunScheduler).
 func (h *BroadcastHandler) SendBroadcast(c *fiber.Ctx) error {
 	var req BroadcastRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
 	}
 
-	log.Printf("Received Broadcast Request: Type=%s, Target=%s", req.Type, req.TargetGroup)
-	if req.Batch != nil {
-		log.Printf("Filter: Batch=%s", *req.Batch)
+	scope := utils.DepartmentScope(c)
+	plan, ferr := h.planBroadcast(req, scope)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
 	}
-	if req.Department != nil {
-		log.Printf("Filter: Dept=%s", *req.Department)
+	createdBy := int64(c.Locals("user_id").(float64))
+
+	if req.SendAt != nil || req.Recurrence != nil {
+		return h.scheduleBroadcast(c, req, plan, scope, createdBy)
 	}
-	if req.RollNos != nil {
-		log.Printf("Filter: RollNos=%v", *req.RollNos)
+
+	recipients, err := h.Repo.ResolveRecipients(c.Context(), plan.Target, scope)
+	if err != nil {
+		log.Printf("SendBroadcast: resolving recipients failed: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to resolve recipients"})
+	}
+	if len(recipients) == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "No students match the selected filters"})
 	}
 
-	// Placeholder for Meta WhatsApp API and Amazon SES
-	switch req.Type {
-	case "WHATSAPP":
-		log.Println(">>> [PLACEHOLDER] Sending via Meta WhatsApp Cloud API...")
-		// TODO: Integrate Meta Cloud API SDK here
-	case "EMAIL":
-		log.Println(">>> [PLACEHOLDER] Sending via Amazon SES...")
-		// TODO: Integrate Amazon SES SDK here
-	default:
-		return c.Status(400).JSON(fiber.Map{"error": "Unsupported channel type"})
+	filters, _ := json.Marshal(plan.Target)
+	b := models.Broadcast{
+		TargetGroup: plan.Target.TargetGroup,
+		Filters:     filters,
+		CreatedBy:   &createdBy,
+	}
+	queued, skipped, err := h.queueBroadcast(c.Context(), &b, plan, recipients)
+	if err != nil {
+		log.Printf("SendBroadcast: saving broadcast failed: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to queue broadcast"})
 	}
 
-	return c.JSON(fiber.Map{
+	return c.Status(202).JSON(fiber.Map{
 		"message": "Broadcast queued successfully",
 		"details": fiber.Map{
-			"channel": req.Type,
-			"target":  req.TargetGroup,
-			"status":  "pending_provider_integration",
+			"broadcast_id": b.ID,
+			"channels":     plan.Channels,
+			"target":       plan.Target.TargetGroup,
+			"recipients":   len(recipients),
+			"queued":       queued,
+			"skipped":      skipped,
+			"status":       b.Status,
 		},
 	})
 }
+
+// ListBroadcasts returns sent broadcasts, newest first. Coordinators only see
+// the broadcasts they sent.
+func (h *BroadcastHandler) ListBroadcasts(c *fiber.Ctx) error {
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	var createdBy *int64
+	if utils.DepartmentScope(c) != nil {
+		userID := int64(c.Locals("user_id").(float64))
+		createdBy = &userID
+	}
+
+	broadcasts, err := h.Repo.ListBroadcasts(c.Context(), createdBy, limit, offset)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch broadcasts"})
+	}
+	return c.JSON(broadcasts)
+}
+
+// GetBroadcastReport returns per-recipient delivery status for a broadcast.
+// Query: status, channel, limit (default 100, max 500), offset.
+func (h *BroadcastHandler) GetBroadcastReport(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid broadcast ID"})
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "100"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	report, err := h.Repo.GetBroadcastReport(c.Context(), id, c.Query("status"), strings.ToUpper(c.Query("channel")), limit, offset)
+	if errors.Is(err, repository.ErrBroadcastNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Broadcast not found"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch broadcast report"})
+	}
+
+	if utils.DepartmentScope(c) != nil {
+		userID := int64(c.Locals("user_id").(float64))
+		if report.Broadcast.CreatedBy == nil || *report.Broadcast.CreatedBy != userID {
+			return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+		}
+	}
+	return c.JSON(report)
+}
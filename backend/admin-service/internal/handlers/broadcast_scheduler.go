@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // schedules name IANA zones; the runtime image has no zoneinfo
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/placement-portal-kec/admin-service/internal/models"
+	"github.com/placement-portal-kec/admin-service/internal/repository"
+	"github.com/placement-portal-kec/admin-service/internal/utils"
+)
+
+// defaultScheduleTimezone is the college's timezone, used for recurrences
+// that do not name one
+const defaultScheduleTimezone = "Asia/Kolkata"
+
+var scheduleWeekdays = map[string]int{"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6}
+
+// scheduleBroadcast saves a validated broadcast as a schedule: once at
+// send_at, or on the recurrence from send_at (or now) onwards.
+func (h *BroadcastHandler) scheduleBroadcast(c *fiber.Ctx, req BroadcastRequest, plan broadcastPlan, scope *string, createdBy int64) error {
+	now := time.Now()
+	filters, _ := json.Marshal(plan.Target)
+	s := models.BroadcastSchedule{
+		TemplateID:      plan.TemplateID,
+		TemplateName:    plan.TemplateName,
+		Variables:       plan.Declared,
+		Channels:        plan.Channels,
+		TargetGroup:     plan.Target.TargetGroup,
+		Filters:         filters,
+		DepartmentScope: scope,
+		Message:         plan.Content,
+		Frequency:       models.ScheduleOnce,
+		Weekdays:        []int{},
+		Timezone:        defaultScheduleTimezone,
+		CreatedBy:       &createdBy,
+	}
+	if s.Variables == nil {
+		s.Variables = []string{}
+	}
+	if plan.Subject != "" {
+		s.Subject = &plan.Subject
+	}
+
+	start := now
+	if req.SendAt != nil {
+		if req.SendAt.Before(now.Add(-time.Minute)) {
+			return c.Status(400).JSON(fiber.Map{"error": "send_at must be in the future"})
+		}
+		start = *req.SendAt
+	}
+
+	if rec := req.Recurrence; rec == nil {
+		s.NextRunAt = &start
+	} else {
+		s.Frequency = strings.ToLower(strings.TrimSpace(rec.Frequency))
+		switch s.Frequency {
+		case models.ScheduleDaily:
+		case models.ScheduleWeekly:
+			for _, day := range rec.Weekdays {
+				day = strings.ToUpper(strings.TrimSpace(day))
+				if len(day) > 3 {
+					day = day[:3]
+				}
+				n, ok := scheduleWeekdays[day]
+				if !ok {
+					return c.Status(400).JSON(fiber.Map{"error": "Invalid weekday: " + day})
+				}
+				if !slices.Contains(s.Weekdays, n) {
+					s.Weekdays = append(s.Weekdays, n)
+				}
+			}
+			if len(s.Weekdays) == 0 {
+				return c.Status(400).JSON(fiber.Map{"error": "Weekly recurrence needs at least one weekday"})
+			}
+			slices.Sort(s.Weekdays)
+		default:
+			return c.Status(400).JSON(fiber.Map{"error": "Recurrence frequency must be daily or weekly"})
+		}
+
+		at, err := time.Parse("15:04", strings.TrimSpace(rec.Time))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Recurrence time must be HH:MM"})
+		}
+		timeOfDay := at.Format("15:04")
+		s.TimeOfDay = &timeOfDay
+		if rec.Timezone != "" {
+			if _, err := time.LoadLocation(rec.Timezone); err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "Unknown timezone"})
+			}
+			s.Timezone = rec.Timezone
+		}
+		s.EndsAt = rec.Until
+
+		s.NextRunAt = nextOccurrence(s, start.Add(-time.Nanosecond))
+		if s.NextRunAt == nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Recurrence has no occurrence before its end"})
+		}
+	}
+
+	if err := h.Repo.CreateSchedule(c.Context(), &s); err != nil {
+		log.Printf("SendBroadcast: saving schedule failed: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to schedule broadcast"})
+	}
+	return c.Status(201).JSON(fiber.Map{"message": "Broadcast scheduled", "schedule": s})
+}
+
+// nextOccurrence returns a recurring schedule's first occurrence after
+// after, or nil when it does not recur or has ended.
+func nextOccurrence(s models.BroadcastSchedule, after time.Time) *time.Time {
+	if s.Frequency == models.ScheduleOnce || s.TimeOfDay == nil {
+		return nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		log.Printf("BroadcastScheduler: schedule %d has unknown timezone %q", s.ID, s.Timezone)
+		return nil
+	}
+	tod, err := time.Parse("15:04", *s.TimeOfDay)
+	if err != nil {
+		return nil
+	}
+
+	local := after.In(loc)
+	for i := 0; i <= 7; i++ {
+		day := local.AddDate(0, 0, i)
+		at := time.Date(day.Year(), day.Month(), day.Day(), tod.Hour(), tod.Minute(), 0, 0, loc)
+		if !at.After(after) {
+			continue
+		}
+		if s.Frequency == models.ScheduleWeekly && !slices.Contains(s.Weekdays, int(at.Weekday())) {
+			continue
+		}
+		if s.EndsAt != nil && at.After(*s.EndsAt) {
+			return nil
+		}
+		return &at
+	}
+	return nil
+}
+
+// ListSchedules returns scheduled broadcasts, pending ones first.
+// Coordinators only see their own. Query: status.
+func (h *BroadcastHandler) ListSchedules(c *fiber.Ctx) error {
+	var createdBy *int64
+	if utils.DepartmentScope(c) != nil {
+		userID := int64(c.Locals("user_id").(float64))
+		createdBy = &userID
+	}
+
+	schedules, err := h.Repo.ListSchedules(c.Context(), createdBy, c.Query("status"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch scheduled broadcasts"})
+	}
+	return c.JSON(schedules)
+}
+
+// CancelSchedule stops a scheduled broadcast before its next send.
+// Coordinators can only cancel their own.
+func (h *BroadcastHandler) CancelSchedule(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid schedule ID"})
+	}
+
+	var createdBy *int64
+	if utils.DepartmentScope(c) != nil {
+		userID := int64(c.Locals("user_id").(float64))
+		createdBy = &userID
+	}
+
+	s, err := h.Repo.CancelSchedule(c.Context(), id, createdBy)
+	if errors.Is(err, repository.ErrScheduleNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "No pending schedule with this ID"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to cancel schedule"})
+	}
+	return c.JSON(fiber.Map{"message": "Scheduled broadcast cancelled", "schedule": s})
+}
+
+// RunScheduler sends due scheduled broadcasts and the automatic drive
+// reminders, checking every minute until ctx is cancelled. Replicas can run
+// it side by side: schedules are locked while sent and reminders claimed.
+func (h *BroadcastHandler) RunScheduler(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		h.runDueSchedules(ctx)
+		h.sendDriveReminders(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *BroadcastHandler) runDueSchedules(ctx context.Context) {
+	skip := []int64{}
+	for {
+		id, ok, err := h.Repo.RunDueSchedule(ctx, skip, func(s models.BroadcastSchedule) repository.ScheduleOutcome {
+			return h.sendScheduled(ctx, s)
+		})
+		if err != nil {
+			log.Printf("BroadcastScheduler: running schedule %d failed: %v", id, err)
+		}
+		if !ok || (err != nil && id == 0) {
+			return
+		}
+		// Failed occurrences stay due; leave them for the next tick
+		skip = append(skip, id)
+	}
+}
+
+// sendScheduled sends the occurrence of s due at s.NextRunAt to the students
+// matching its filters now. A recurrence skips occurrences missed while the
+// service was down rather than sending them all at once.
+func (h *BroadcastHandler) sendScheduled(ctx context.Context, s models.BroadcastSchedule) repository.ScheduleOutcome {
+	scheduledFor := *s.NextRunAt
+	after := time.Now()
+	if scheduledFor.After(after) {
+		after = scheduledFor
+	}
+	next := nextOccurrence(s, after)
+
+	var target models.BroadcastTarget
+	if err := json.Unmarshal(s.Filters, &target); err != nil {
+		return repository.ScheduleOutcome{NextRunAt: next, Error: "invalid filters: " + err.Error()}
+	}
+	recipients, err := h.Repo.ResolveRecipients(ctx, target, s.DepartmentScope)
+	if err != nil {
+		log.Printf("BroadcastScheduler: resolving recipients for schedule %d failed: %v", s.ID, err)
+		return repository.ScheduleOutcome{Error: "resolving recipients failed", Retry: true}
+	}
+	if len(recipients) == 0 {
+		return repository.ScheduleOutcome{NextRunAt: next, Error: "No students matched the filters"}
+	}
+
+	plan := broadcastPlan{
+		Channels:     s.Channels,
+		TemplateID:   s.TemplateID,
+		TemplateName: s.TemplateName,
+		Declared:     s.Variables,
+		Content:      s.Message,
+		Target:       target,
+	}
+	if s.Subject != nil {
+		plan.Subject = *s.Subject
+	}
+	b := models.Broadcast{
+		TargetGroup:  s.TargetGroup,
+		Filters:      s.Filters,
+		CreatedBy:    s.CreatedBy,
+		ScheduleID:   &s.ID,
+		ScheduledFor: &scheduledFor,
+	}
+	if _, _, err := h.queueBroadcast(ctx, &b, plan, recipients); err != nil {
+		if errors.Is(err, repository.ErrOccurrenceAlreadySent) {
+			return repository.ScheduleOutcome{NextRunAt: next}
+		}
+		log.Printf("BroadcastScheduler: queueing schedule %d failed: %v", s.ID, err)
+		return repository.ScheduleOutcome{Error: "queueing broadcast failed", Retry: true}
+	}
+	return repository.ScheduleOutcome{BroadcastID: &b.ID, NextRunAt: next}
+}
+
+// sendDriveReminders sends the reminders for drives starting within 24
+// hours, each at most once per drive. Registration deadline reminders are
+// sent by drive-service.
+func (h *BroadcastHandler) sendDriveReminders(ctx context.Context) {
+	var kinds []string
+	for _, kind := range []string{models.ReminderDriveDate} {
+		enabled, err := h.Repo.ReminderEnabled(ctx, kind)
+		if err != nil {
+			log.Printf("BroadcastScheduler: reading reminder setting %s failed: %v", kind, err)
+			continue
+		}
+		if enabled {
+			kinds = append(kinds, kind)
+		}
+	}
+	if len(kinds) == 0 {
+		return
+	}
+
+	due, err := h.Repo.DueDriveReminders(ctx, kinds)
+	if err != nil {
+		log.Printf("BroadcastScheduler: finding due drive reminders failed: %v", err)
+		return
+	}
+	for _, d := range due {
+		claimed, err := h.Repo.ClaimDriveReminder(ctx, d.DriveID, d.Kind)
+		if err != nil || !claimed {
+			if err != nil {
+				log.Printf("BroadcastScheduler: claiming %s reminder for drive %d failed: %v", d.Kind, d.DriveID, err)
+			}
+			continue
+		}
+
+		broadcastID, err := h.sendDriveReminder(ctx, d)
+		if err != nil {
+			log.Printf("BroadcastScheduler: sending %s reminder for drive %d failed: %v", d.Kind, d.DriveID, err)
+		}
+		if err := h.Repo.FinishDriveReminder(ctx, d.DriveID, d.Kind, broadcastID, err != nil); err != nil {
+			log.Printf("BroadcastScheduler: recording %s reminder for drive %d failed: %v", d.Kind, d.DriveID, err)
+		}
+	}
+}
+
+// sendDriveReminder queues one drive reminder as a broadcast by push and
+// email. It returns a nil ID when nobody needs the reminder.
+func (h *BroadcastHandler) sendDriveReminder(ctx context.Context, d models.DriveReminder) (*int64, error) {
+	recipients, err := h.Repo.DriveReminderRecipients(ctx, d.DriveID, d.Kind)
+	if err != nil || len(recipients) == 0 {
+		return nil, err
+	}
+
+	plan := broadcastPlan{
+		Channels: []string{models.BroadcastChannelPush, models.BroadcastChannelEmail},
+		Subject:  fmt.Sprintf("Reminder: %s drive on %s", d.CompanyName, d.DriveDate.Format("Mon, 2 Jan")),
+		Content: fmt.Sprintf("Hi {{first_name}}, the %s drive is on %s at %s. "+
+			"Carry your college ID card and copies of your resume.",
+			d.CompanyName, d.DriveDate.Format("Monday, 2 January 2006"), d.Location),
+	}
+
+	filters, _ := json.Marshal(map[string]any{"drive_id": d.DriveID, "reminder": d.Kind})
+	b := models.Broadcast{TargetGroup: "drive_reminder", Filters: filters}
+	if _, _, err := h.queueBroadcast(ctx, &b, plan, recipients); err != nil {
+		return nil, err
+	}
+	return &b.ID, nil
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/placement-portal-kec/admin-service/internal/models"
+)
+
+func TestNextOccurrence(t *testing.T) {
+	ist, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, ist)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	ptr := func(v time.Time) *time.Time { return &v }
+	eight := "08:00"
+
+	// 2026-10-19 is a Monday.
+	cases := []struct {
+		name  string
+		sched models.BroadcastSchedule
+		after time.Time
+		want  *time.Time
+	}{
+		{
+			name:  "weekly monday after today's slot moves to next monday",
+			sched: models.BroadcastSchedule{Frequency: models.ScheduleWeekly, Weekdays: []int{1}, TimeOfDay: &eight, Timezone: "Asia/Kolkata"},
+			after: at("2026-10-19 09:00"),
+			want:  ptr(at("2026-10-26 08:00")),
+		},
+		{
+			name:  "weekly monday before today's slot is today",
+			sched: models.BroadcastSchedule{Frequency: models.ScheduleWeekly, Weekdays: []int{1}, TimeOfDay: &eight, Timezone: "Asia/Kolkata"},
+			after: at("2026-10-19 07:59"),
+			want:  ptr(at("2026-10-19 08:00")),
+		},
+		{
+			name:  "weekly picks the nearest listed weekday",
+			sched: models.BroadcastSchedule{Frequency: models.ScheduleWeekly, Weekdays: []int{1, 4}, TimeOfDay: &eight, Timezone: "Asia/Kolkata"},
+			after: at("2026-10-19 09:00"),
+			want:  ptr(at("2026-10-22 08:00")),
+		},
+		{
+			name:  "daily exactly at the occurrence moves to the next day",
+			sched: models.BroadcastSchedule{Frequency: models.ScheduleDaily, TimeOfDay: &eight, Timezone: "Asia/Kolkata"},
+			after: at("2026-10-19 08:00"),
+			want:  ptr(at("2026-10-20 08:00")),
+		},
+		{
+			name:  "daily evaluated from UTC still fires at 08:00 IST",
+			sched: models.BroadcastSchedule{Frequency: models.ScheduleDaily, TimeOfDay: &eight, Timezone: "Asia/Kolkata"},
+			after: time.Date(2026, 10, 19, 3, 0, 0, 0, time.UTC), // 08:30 IST
+			want:  ptr(at("2026-10-20 08:00")),
+		},
+		{
+			name:  "daily with end in the past",
+			sched: models.BroadcastSchedule{Frequency: models.ScheduleDaily, TimeOfDay: &eight, Timezone: "Asia/Kolkata", EndsAt: ptr(at("2026-10-01 00:00"))},
+			after: at("2026-10-19 09:00"),
+			want:  nil,
+		},
+		{
+			name:  "daily with end before the next occurrence",
+			sched: models.BroadcastSchedule{Frequency: models.ScheduleDaily, TimeOfDay: &eight, Timezone: "Asia/Kolkata", EndsAt: ptr(at("2026-10-20 07:00"))},
+			after: at("2026-10-19 09:00"),
+			want:  nil,
+		},
+		{
+			name:  "one-off schedules do not repeat",
+			sched: models.BroadcastSchedule{Frequency: models.ScheduleOnce, TimeOfDay: &eight, Timezone: "Asia/Kolkata"},
+			after: at("2026-10-19 09:00"),
+			want:  nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := nextOccurrence(tc.sched, tc.after)
+			switch {
+			case tc.want == nil && got != nil:
+				t.Fatalf("got %v, want nil", *got)
+			case tc.want != nil && got == nil:
+				t.Fatalf("got nil, want %v", *tc.want)
+			case tc.want != nil && !got.Equal(*tc.want):
+				t.Fatalf("got %v, want %v", got.In(ist), *tc.want)
+			}
+		})
+	}
+}
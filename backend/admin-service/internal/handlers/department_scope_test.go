@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/placement-portal-kec/admin-service/internal/database"
+	"github.com/placement-portal-kec/admin-service/internal/repository"
+)
+
+// coordinatorApp mounts handler behind a stand-in for the auth middleware
+// that signs the caller in as a coordinator of dept.
+func coordinatorApp(method, path, dept string, handler fiber.Handler) *fiber.App {
+	app := fiber.New()
+	app.Add(method, path, func(c *fiber.Ctx) error {
+		c.Locals("user_id", float64(7))
+		c.Locals("role", "coordinator")
+		c.Locals("department_code", dept)
+		return c.Next()
+	}, handler)
+	return app
+}
+
+func TestCreateStudentRejectsOtherDepartment(t *testing.T) {
+	app := coordinatorApp("POST", "/students", "CSE", CreateStudent)
+	body, _ := json.Marshal(map[string]string{
+		"full_name":       "Asha R",
+		"email":           "asha@kongu.edu",
+		"register_number": "24ECR001",
+		"department":      "ECE",
+	})
+	req := httptest.NewRequest("POST", "/students", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("status = %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestBulkUploadStudentsRejectsOtherDepartment(t *testing.T) {
+	app := coordinatorApp("POST", "/students/bulk-upload", "CSE", BulkUploadStudents)
+
+	csv := strings.Join([]string{
+		"email,name,regNo,dept,batch_year,password",
+		"asha.r@kongu.edu,Asha R,24CSR001,CSE,2028,Strong#Pass1",
+		"bala.k@kongu.edu,Bala K,24ECR001,ECE,2028,Strong#Pass2",
+	}, "\n")
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	part, _ := form.CreateFormFile("file", "students.csv")
+	part.Write([]byte(csv))
+	form.Close()
+
+	req := httptest.NewRequest("POST", "/students/bulk-upload", &buf)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("status = %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestBulkDeleteStudentsRejectsOtherDepartment(t *testing.T) {
+	app := coordinatorApp("DELETE", "/students/bulk", "CSE", BulkDeleteStudents)
+	body, _ := json.Marshal(map[string]any{"department": "ECE", "batch_year": 2028})
+	req := httptest.NewRequest("DELETE", "/students/bulk", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("status = %d, want 403", resp.StatusCode)
+	}
+}
+
+// scopeFixture is a CSE and an ECE student, each with a pending change
+// request and an application to the same drive.
+type scopeFixture struct {
+	DriveID                int64
+	CSEStudent, ECEStudent int64
+	CSERegNo, ECERegNo     string
+	Tag                    string // shared by both register numbers, for searching
+}
+
+// seedScopeFixture connects database.DB to TEST_DATABASE_URL, a database
+// migrated with scripts/run_schemas.sh, and seeds a scopeFixture that is
+// removed when the test ends. Tests using it are skipped without one.
+func seedScopeFixture(t *testing.T) scopeFixture {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	t.Setenv("DATABASE_URL", url)
+	pool, err := database.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		pool.Close()
+		database.DB = nil
+	})
+
+	ctx := context.Background()
+	f := scopeFixture{Tag: fmt.Sprintf("T%d", time.Now().UnixNano()%1e10)}
+	f.CSERegNo, f.ECERegNo = f.Tag+"CS", f.Tag+"EC"
+
+	student := func(regNo, dept string) int64 {
+		var id int64
+		err := pool.QueryRow(ctx, `
+			INSERT INTO users (email, password_hash, role, name, department_code)
+			VALUES ($1, 'x', 'student', $2, $3) RETURNING id`,
+			strings.ToLower(regNo)+"@scope.test", "Student "+regNo, dept).Scan(&id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, id) })
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO student_personal (user_id, register_number, department, batch_year)
+			VALUES ($1, $2, $3, 2028)`, id, regNo, dept); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO student_change_requests (student_id, field_name, old_value, new_value, reason)
+			VALUES ($1, 'mobile_number', '9000000000', '9000000001', 'New number')`, id); err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	f.CSEStudent = student(f.CSERegNo, "CSE")
+	f.ECEStudent = student(f.ECERegNo, "ECE")
+
+	err = pool.QueryRow(ctx, `
+		INSERT INTO placement_drives (company_name, location, drive_date, deadline_date)
+		VALUES ('Scope Test Ltd', 'Erode', CURRENT_DATE + 7, NOW() + INTERVAL '3 days') RETURNING id`).Scan(&f.DriveID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM placement_drives WHERE id = $1`, f.DriveID) })
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO drive_applications (drive_id, student_id) VALUES ($1, $2), ($1, $3)`,
+		f.DriveID, f.CSEStudent, f.ECEStudent); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// getJSON runs a GET against app and decodes the response into out,
+// returning the status code.
+func getJSON(t *testing.T, app *fiber.App, path string, out any) int {
+	resp, err := app.Test(httptest.NewRequest("GET", path, nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == fiber.StatusOK && out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestListStudentsOnlyShowsOwnDepartment(t *testing.T) {
+	f := seedScopeFixture(t)
+	for dept, want := range map[string]string{"CSE": f.CSERegNo, "ECE": f.ECERegNo} {
+		app := coordinatorApp("GET", "/students", dept, ListStudents)
+		var body struct {
+			Data []struct {
+				RegisterNumber string `json:"register_number"`
+			} `json:"data"`
+		}
+		// dept=All must not widen a coordinator's view
+		if status := getJSON(t, app, "/students?dept=All&search="+f.Tag, &body); status != 200 {
+			t.Fatalf("%s: status = %d", dept, status)
+		}
+		if len(body.Data) != 1 || body.Data[0].RegisterNumber != want {
+			t.Errorf("%s coordinator listed %+v, want only %s", dept, body.Data, want)
+		}
+	}
+}
+
+func TestGetStudentDetailsHidesOtherDepartment(t *testing.T) {
+	f := seedScopeFixture(t)
+	app := coordinatorApp("GET", "/students/:id", "CSE", GetStudentDetails)
+
+	if status := getJSON(t, app, "/students/"+f.CSERegNo, nil); status != 200 {
+		t.Errorf("own student: status = %d, want 200", status)
+	}
+	if status := getJSON(t, app, "/students/"+f.ECERegNo, nil); status != 404 {
+		t.Errorf("other department's student: status = %d, want 404", status)
+	}
+}
+
+func TestGetPendingRequestsOnlyShowsOwnDepartment(t *testing.T) {
+	f := seedScopeFixture(t)
+	h := NewRequestHandler(repository.NewRequestRepository(database.DB), nil, nil)
+	app := coordinatorApp("GET", "/requests", "CSE", h.GetPendingRequests)
+
+	var reqs []struct {
+		StudentID int64 `json:"student_id"`
+	}
+	if status := getJSON(t, app, "/requests", &reqs); status != 200 {
+		t.Fatalf("status = %d", status)
+	}
+	var sawOwn bool
+	for _, r := range reqs {
+		if r.StudentID == f.ECEStudent {
+			t.Fatalf("CSE coordinator saw a change request from an ECE student")
+		}
+		sawOwn = sawOwn || r.StudentID == f.CSEStudent
+	}
+	if !sawOwn {
+		t.Errorf("CSE coordinator did not see their own student's request")
+	}
+}
+
+func TestGetDriveApplicantsOnlyShowsOwnDepartment(t *testing.T) {
+	f := seedScopeFixture(t)
+	app := coordinatorApp("GET", "/drives/:id/applicants", "ECE", GetDriveApplicants)
+
+	var applicants []struct {
+		StudentID int64 `json:"student_id"`
+	}
+	if status := getJSON(t, app, fmt.Sprintf("/drives/%d/applicants", f.DriveID), &applicants); status != 200 {
+		t.Fatalf("status = %d", status)
+	}
+	if len(applicants) != 1 || applicants[0].StudentID != f.ECEStudent {
+		t.Errorf("ECE coordinator saw applicants %+v, want only student %d", applicants, f.ECEStudent)
+	}
+}
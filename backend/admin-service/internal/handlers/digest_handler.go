@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/placement-portal-kec/admin-service/internal/models"
+	"github.com/placement-portal-kec/admin-service/internal/repository"
+	"github.com/placement-portal-kec/admin-service/internal/services"
+)
+
+const (
+	// digestBatch is how many digests are built per query
+	digestBatch = 100
+	// digestListLimit caps each list in a digest; the rest are counted
+	digestListLimit = 10
+)
+
+// digestStatusLabels are the application statuses a student's digest
+// reports, set by the placement cell rather than the student
+var digestStatusLabels = map[string]string{
+	"shortlisted": "Shortlisted",
+	"rejected":    "Not selected",
+	"placed":      "Placed",
+}
+
+// DigestSchedule is when digests go out: daily ones every day at Hour
+// (college time), weekly ones on Weekday at Hour.
+type DigestSchedule struct {
+	Weekday time.Weekday
+	Hour    int
+}
+
+// DigestScheduleFromEnv reads DIGEST_DAY (weekday name, default Monday; "off"
+// disables digests) and DIGEST_HOUR (0-23, default 8). ok is false when
+// digests are off.
+func DigestScheduleFromEnv() (schedule DigestSchedule, ok bool) {
+	schedule = DigestSchedule{Weekday: time.Monday, Hour: 8}
+
+	day := strings.ToUpper(strings.TrimSpace(os.Getenv("DIGEST_DAY")))
+	if day == "OFF" {
+		return schedule, false
+	}
+	if day != "" {
+		if wd, found := scheduleWeekdays[day[:min(3, len(day))]]; found {
+			schedule.Weekday = time.Weekday(wd)
+		} else {
+			log.Printf("DigestScheduler: ignoring invalid DIGEST_DAY %q", day)
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("DIGEST_HOUR")); v != "" {
+		if hour, err := strconv.Atoi(v); err == nil && hour >= 0 && hour < 24 {
+			schedule.Hour = hour
+		} else {
+			log.Printf("DigestScheduler: ignoring invalid DIGEST_HOUR %q", v)
+		}
+	}
+	return schedule, true
+}
+
+// DigestHandler builds digest emails from the same queries the student and
+// admin views use and queues them through the notification worker, which
+// applies the recipient's digest preferences and quiet hours.
+type DigestHandler struct {
+	Repo         *repository.DigestRepository
+	Drives       *repository.DriveRepository
+	Applications *repository.ApplicationRepository
+	Requests     *repository.RequestRepository
+	Worker       *NotificationWorker
+}
+
+func NewDigestHandler(repo *repository.DigestRepository, drives *repository.DriveRepository, applications *repository.ApplicationRepository,
+	requests *repository.RequestRepository, worker *NotificationWorker) *DigestHandler {
+	return &DigestHandler{Repo: repo, Drives: drives, Applications: applications, Requests: requests, Worker: worker}
+}
+
+// RunDigests sends the digests due under schedule, checking every minute
+// until ctx is cancelled.
+func (h *DigestHandler) RunDigests(ctx context.Context, schedule DigestSchedule) {
+	loc, err := time.LoadLocation(defaultScheduleTimezone)
+	if err != nil {
+		log.Printf("DigestScheduler: loading %s failed: %v", defaultScheduleTimezone, err)
+		return
+	}
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		h.sendDueDigests(ctx, schedule, time.Now().In(loc))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDueDigests sends today's digests once the schedule's hour has come.
+// A failed digest is released for the next tick, which stops this one so
+// it is not retried straight away.
+func (h *DigestHandler) sendDueDigests(ctx context.Context, schedule DigestSchedule, now time.Time) {
+	if now.Hour() < schedule.Hour {
+		return
+	}
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	weekly := now.Weekday() == schedule.Weekday
+
+	for {
+		recipients, err := h.Repo.DueDigests(ctx, day, weekly, digestBatch)
+		if err != nil {
+			log.Printf("DigestScheduler: finding due digests failed: %v", err)
+			return
+		}
+		for _, r := range recipients {
+			if err := h.sendDigest(ctx, r, day, now); err != nil {
+				log.Printf("DigestScheduler: digest for user %d failed: %v", r.UserID, err)
+				return
+			}
+		}
+		if len(recipients) < digestBatch {
+			return
+		}
+	}
+}
+
+func (h *DigestHandler) sendDigest(ctx context.Context, r models.DigestRecipient, day, now time.Time) error {
+	period := 7 * 24 * time.Hour
+	if r.Frequency == models.DigestDaily {
+		period = 24 * time.Hour
+	}
+	since := now.Add(-period)
+	if r.Since != nil && r.Since.After(since) {
+		since = *r.Since
+	}
+
+	claimed, err := h.Repo.ClaimDigest(ctx, r, day, since)
+	if err != nil || !claimed {
+		return err
+	}
+
+	var name string
+	var vars map[string]string
+	if r.Role == "coordinator" {
+		name, vars, err = h.coordinatorDigest(ctx, r, since, now)
+	} else {
+		name, vars, err = h.studentDigest(ctx, r, since, now, period)
+	}
+	if err != nil {
+		h.releaseDigest(ctx, r.UserID, day)
+		return err
+	}
+	if vars == nil {
+		// Nothing to report: the claim stays, without a job
+		return nil
+	}
+	vars["name"] = r.Name
+	vars["frequency"] = r.Frequency
+
+	jobID, err := h.Worker.Enqueue(ctx, repository.OutboundNotification{
+		Provider:       models.BroadcastChannelEmail,
+		Kind:           models.JobKindDigest,
+		Source:         "admin-service",
+		Payload:        services.DeliveryMessage{Address: r.Email, EmailTemplate: name, Vars: vars},
+		IdempotencyKey: fmt.Sprintf("digest:%d:%s", r.UserID, day.Format(time.DateOnly)),
+		UserID:         r.UserID,
+		Category:       models.InboxDigest,
+	})
+	if err != nil {
+		h.releaseDigest(ctx, r.UserID, day)
+		return err
+	}
+	return h.Repo.FinishDigest(ctx, r.UserID, day, jobID)
+}
+
+func (h *DigestHandler) releaseDigest(ctx context.Context, userID int64, day time.Time) {
+	if err := h.Repo.ReleaseDigest(ctx, userID, day); err != nil {
+		log.Printf("DigestScheduler: releasing digest for user %d failed: %v", userID, err)
+	}
+}
+
+// studentDigest lists the open drives the student is eligible for that were
+// posted since the last digest, those closing before the next one that they
+// have not responded to, and placement-cell updates on their applications.
+// vars is nil when there is nothing to report.
+func (h *DigestHandler) studentDigest(ctx context.Context, r models.DigestRecipient, since, now time.Time, period time.Duration) (string, map[string]string, error) {
+	drives, err := h.Drives.GetEligibleDrives(ctx, r.UserID, map[string]interface{}{})
+	if err != nil {
+		return "", nil, err
+	}
+	apps, err := h.Applications.GetStudentApplications(ctx, r.UserID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	loc := now.Location()
+	// A day's margin, so a deadline just after the next digest is still listed
+	closesBy := now.Add(period + 24*time.Hour)
+	var newDrives, deadlines, changes [][]string
+	for _, d := range drives {
+		if d.Status != "open" || !d.IsEligible || d.UserStatus != "" || !d.DeadlineDate.After(now) {
+			continue
+		}
+		if d.CreatedAt.After(since) {
+			roles := make([]string, 0, len(d.Roles))
+			for _, role := range d.Roles {
+				roles = append(roles, role.RoleName)
+			}
+			newDrives = append(newDrives, []string{d.CompanyName, strings.Join(roles, ", "), d.DeadlineDate.In(loc).Format("Mon, 2 Jan")})
+		} else if d.DeadlineDate.Before(closesBy) {
+			deadlines = append(deadlines, []string{d.CompanyName, d.DeadlineDate.In(loc).Format("Mon, 2 Jan 3:04 PM")})
+		}
+	}
+	for _, app := range apps {
+		label, ok := digestStatusLabels[app["status"].(string)]
+		if updated, _ := app["updated"].(time.Time); ok && updated.After(since) {
+			changes = append(changes, []string{app["company"].(string), label})
+		}
+	}
+	if len(newDrives)+len(deadlines)+len(changes) == 0 {
+		return "", nil, nil
+	}
+
+	vars := map[string]string{}
+	setDigestList(vars, "new_drives", newDrives)
+	setDigestList(vars, "deadlines", deadlines)
+	setDigestList(vars, "status_changes", changes)
+	return "student_digest", vars, nil
+}
+
+// coordinatorDigest summarises the coordinator's department: drives opened
+// to it since the last digest, change requests awaiting review and students
+// who have not finished onboarding. It is sent even when all are empty, as
+// that is worth knowing too.
+func (h *DigestHandler) coordinatorDigest(ctx context.Context, r models.DigestRecipient, since, now time.Time) (string, map[string]string, error) {
+	dept := r.Department
+	drives, err := h.Repo.NewDepartmentDrives(ctx, dept, since)
+	if err != nil {
+		return "", nil, err
+	}
+	requests, err := h.Requests.GetPendingRequests(&dept)
+	if err != nil {
+		return "", nil, err
+	}
+	students, err := h.Repo.IncompleteProfiles(ctx, dept)
+	if err != nil {
+		return "", nil, err
+	}
+
+	loc := now.Location()
+	var newDrives, pending, incomplete [][]string
+	for _, d := range drives {
+		newDrives = append(newDrives, []string{d.CompanyName, d.DeadlineDate.In(loc).Format("Mon, 2 Jan")})
+	}
+	for _, req := range requests {
+		pending = append(pending, []string{req.StudentName, req.RegisterNumber, fieldLabel(req.FieldName)})
+	}
+	for _, s := range students {
+		incomplete = append(incomplete, []string{s.Name, s.RegisterNumber})
+	}
+
+	vars := map[string]string{
+		"department":                dept,
+		"change_requests_total":     strconv.Itoa(len(pending)),
+		"incomplete_profiles_total": strconv.Itoa(len(incomplete)),
+	}
+	setDigestList(vars, "new_drives", newDrives)
+	setDigestList(vars, "change_requests", pending)
+	setDigestList(vars, "incomplete_profiles", incomplete)
+	return "coordinator_digest", vars, nil
+}
+
+// setDigestList stores up to digestListLimit rows under key in the emails
+// list format (a line per row, tab-separated cells) and how many were left
+// out under key_more.
+func setDigestList(vars map[string]string, key string, rows [][]string) {
+	lines := make([]string, 0, min(len(rows), digestListLimit))
+	for _, row := range rows[:min(len(rows), digestListLimit)] {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = strings.Join(strings.Fields(cell), " ")
+		}
+		lines = append(lines, strings.Join(cells, "\t"))
+	}
+	vars[key] = strings.Join(lines, "\n")
+	if len(rows) > digestListLimit {
+		vars[key+"_more"] = strconv.Itoa(len(rows) - digestListLimit)
+	}
+}
+
+// fieldLabel turns a profile field name (ug_cgpa) into a label (Ug Cgpa)
+func fieldLabel(field string) string {
+	words := strings.Fields(strings.ReplaceAll(field, "_", " "))
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
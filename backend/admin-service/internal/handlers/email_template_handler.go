@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"maps"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/placement-portal-kec/admin-service/internal/emails"
+	"github.com/placement-portal-kec/admin-service/internal/services"
+)
+
+type EmailTemplateHandler struct {
+	Branding *services.EmailBranding
+}
+
+func NewEmailTemplateHandler(branding *services.EmailBranding) *EmailTemplateHandler {
+	return &EmailTemplateHandler{Branding: branding}
+}
+
+// ListEmailTemplates handles GET /api/v1/admin/email-templates
+func (h *EmailTemplateHandler) ListEmailTemplates(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"templates": emails.Definitions(), "languages": emails.Languages})
+}
+
+// PreviewEmailTemplate handles GET /api/v1/admin/email-templates/:name/preview.
+// It renders the template with the current branding and its sample
+// variables; other query parameters override them (?otp=123456). Query:
+// lang (en, ta), format (json, html, text).
+func (h *EmailTemplateHandler) PreviewEmailTemplate(c *fiber.Ctx) error {
+	def, ok := emails.Lookup(c.Params("name"))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Email template not found"})
+	}
+
+	vars := maps.Clone(def.Sample)
+	for key, value := range c.Queries() {
+		if key != "lang" && key != "format" {
+			vars[key] = value
+		}
+	}
+	lang := c.Query("lang", emails.DefaultLanguage)
+	msg, err := emails.Render(def.Name, lang, h.Branding.Brand(c.Context()), vars)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to render template", "details": err.Error()})
+	}
+
+	switch c.Query("format", "json") {
+	case "html":
+		c.Type("html", "utf-8")
+		return c.SendString(msg.HTML)
+	case "text":
+		c.Type("txt", "utf-8")
+		return c.SendString(msg.Text)
+	default:
+		return c.JSON(fiber.Map{"template": def.Name, "language": lang, "subject": msg.Subject, "html": msg.HTML, "text": msg.Text})
+	}
+}
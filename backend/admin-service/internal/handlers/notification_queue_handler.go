@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/placement-portal-kec/admin-service/internal/repository"
+)
+
+// NotificationQueueHandler lets super admins inspect the notify.jobs queue
+// and requeue dead-lettered jobs.
+type NotificationQueueHandler struct {
+	Jobs   *repository.NotificationJobRepository
+	Worker *NotificationWorker
+}
+
+func NewNotificationQueueHandler(jobs *repository.NotificationJobRepository, worker *NotificationWorker) *NotificationQueueHandler {
+	return &NotificationQueueHandler{Jobs: jobs, Worker: worker}
+}
+
+func queuePage(c *fiber.Ctx) (int, int) {
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// GetQueueStats returns job counts per provider and status.
+func (h *NotificationQueueHandler) GetQueueStats(c *fiber.Ctx) error {
+	counts, err := h.Jobs.GetJobCounts(c.Context())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch queue stats"})
+	}
+	return c.JSON(counts)
+}
+
+// ListJobs returns queue jobs newest first. Query: status, provider, limit, offset.
+func (h *NotificationQueueHandler) ListJobs(c *fiber.Ctx) error {
+	limit, offset := queuePage(c)
+	jobs, total, err := h.Jobs.ListJobs(c.Context(), c.Query("status"), strings.ToUpper(c.Query("provider")), limit, offset)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch jobs"})
+	}
+	return c.JSON(fiber.Map{"jobs": jobs, "total": total})
+}
+
+// ListDeadLetters returns dead-lettered jobs. Requeued ones are included
+// with ?all=true.
+func (h *NotificationQueueHandler) ListDeadLetters(c *fiber.Ctx) error {
+	limit, offset := queuePage(c)
+	letters, total, err := h.Jobs.ListDeadLetters(c.Context(), c.QueryBool("all"), limit, offset)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch dead letters"})
+	}
+	return c.JSON(fiber.Map{"dead_letters": letters, "total": total})
+}
+
+// RequeueDeadLetter gives a dead-lettered job a fresh set of attempts.
+func (h *NotificationQueueHandler) RequeueDeadLetter(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid dead letter ID"})
+	}
+	userID := int64(c.Locals("user_id").(float64))
+
+	job, err := h.Worker.Requeue(c.Context(), id, userID)
+	switch {
+	case errors.Is(err, repository.ErrDeadLetterNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Dead letter not found"})
+	case errors.Is(err, repository.ErrAlreadyRequeued):
+		return c.Status(409).JSON(fiber.Map{"error": "Dead letter has already been requeued"})
+	case err != nil:
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to requeue job"})
+	}
+	return c.JSON(fiber.Map{"message": "Job requeued", "job": job})
+}
@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/placement-portal-kec/admin-service/internal/emails"
+	"github.com/placement-portal-kec/admin-service/internal/models"
+	"github.com/placement-portal-kec/admin-service/internal/repository"
+	"github.com/placement-portal-kec/admin-service/internal/services"
+)
+
+const (
+	notificationClaimSize    = 10
+	notificationPollInterval = 2 * time.Second
+	notificationSendTimeout  = 30 * time.Second
+
+	// Jobs still 'running' this long after being claimed are assumed to
+	// belong to a worker that died and are queued again.
+	notificationStaleAfter = 10 * time.Minute
+	// Finished jobs (and so their idempotency keys) are kept this long.
+	notificationRetention = 30 * 24 * time.Hour
+
+	notificationBackoffBase = 30 * time.Second
+	notificationBackoffMax  = time.Hour
+)
+
+// notificationPoolDefaults sizes each provider's worker pool and its send
+// rate (messages per second per replica, shared by the pool).
+// NOTIFY_WORKERS_<PROVIDER> and NOTIFY_RATE_<PROVIDER> override them.
+var notificationPoolDefaults = []struct {
+	provider string
+	workers  int
+	rate     float64
+}{
+	{models.BroadcastChannelEmail, 4, 5},
+	{models.BroadcastChannelWhatsApp, 4, 20},
+	{models.BroadcastChannelPush, 8, 100},
+}
+
+// JobHook is called after a job of the kind it is registered for changes
+// state (claimed, completed, retried, dead-lettered or requeued).
+type JobHook func(ctx context.Context, job models.NotificationJob)
+
+type providerPool struct {
+	provider string
+	workers  int
+	limiter  *rateLimiter
+	wake     chan struct{}
+}
+
+// NotificationJobStore is the queue the worker drains, implemented by
+// repository.NotificationJobRepository.
+type NotificationJobStore interface {
+	Enqueue(ctx context.Context, n repository.OutboundNotification) (int64, error)
+	ClaimJobs(ctx context.Context, provider, workerID string, limit int) ([]models.NotificationJob, error)
+	ReleaseStaleJobs(ctx context.Context, lockedBefore time.Time) (int64, error)
+	CompleteJob(ctx context.Context, id int64, providerMessageID string) error
+	RetryJob(ctx context.Context, id int64, sendErr string, runAt time.Time) error
+	SuppressJob(ctx context.Context, id int64, reason string) error
+	DeferJob(ctx context.Context, id int64, runAt time.Time) error
+	DeadLetterJob(ctx context.Context, id int64, sendErr string) error
+	PruneCompletedJobs(ctx context.Context, before time.Time) (int64, error)
+	RequeueDeadLetter(ctx context.Context, id, requeuedBy int64) (*models.NotificationJob, error)
+	DeliveryPolicy(ctx context.Context, userID int64, category, channel string) (bool, *time.Time, error)
+}
+
+// NotificationWorker drains the shared notify.jobs queue. Every service
+// enqueues jobs; admin-service alone holds the provider credentials and runs
+// one worker pool per provider. Jobs addressed to a user are checked against
+// their preferences and quiet hours first. Failed sends are retried with
+// exponential backoff until max_attempts, then dead-lettered.
+type NotificationWorker struct {
+	jobs      NotificationJobStore
+	providers services.DeliveryProviders
+	pools     map[string]*providerPool
+	hooks     map[string]JobHook
+	id        string
+}
+
+func NewNotificationWorker(jobs NotificationJobStore, providers services.DeliveryProviders) *NotificationWorker {
+	host, _ := os.Hostname()
+	w := &NotificationWorker{
+		jobs:      jobs,
+		providers: providers,
+		pools:     map[string]*providerPool{},
+		hooks:     map[string]JobHook{},
+		id:        fmt.Sprintf("%s-%d", host, os.Getpid()),
+	}
+	for _, d := range notificationPoolDefaults {
+		workers := int(notificationPoolSetting("NOTIFY_WORKERS_"+d.provider, float64(d.workers)))
+		if workers < 1 {
+			workers = 1
+		}
+		w.pools[d.provider] = &providerPool{
+			provider: d.provider,
+			workers:  workers,
+			limiter:  newRateLimiter(notificationPoolSetting("NOTIFY_RATE_"+d.provider, d.rate)),
+			wake:     make(chan struct{}, 1),
+		}
+	}
+	return w
+}
+
+func notificationPoolSetting(key string, def float64) float64 {
+	if v, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && v >= 0 {
+		return v
+	}
+	return def
+}
+
+// OnKind registers hook for jobs of kind. Register hooks before Run.
+func (w *NotificationWorker) OnKind(kind string, hook JobHook) {
+	w.hooks[kind] = hook
+}
+
+// Wake asks provider's pool to claim jobs now instead of at its next poll.
+func (w *NotificationWorker) Wake(provider string) {
+	if pool, ok := w.pools[provider]; ok {
+		select {
+		case pool.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Enqueue adds a job and wakes its provider's pool.
+func (w *NotificationWorker) Enqueue(ctx context.Context, n repository.OutboundNotification) (int64, error) {
+	id, err := w.jobs.Enqueue(ctx, n)
+	if err != nil {
+		return 0, err
+	}
+	w.Wake(n.Provider)
+	return id, nil
+}
+
+// Requeue gives a dead-lettered job a fresh set of attempts.
+func (w *NotificationWorker) Requeue(ctx context.Context, deadLetterID, requeuedBy int64) (*models.NotificationJob, error) {
+	job, err := w.jobs.RequeueDeadLetter(ctx, deadLetterID, requeuedBy)
+	if err != nil {
+		return nil, err
+	}
+	w.runHook(ctx, *job)
+	w.Wake(job.Provider)
+	return job, nil
+}
+
+// Run starts every pool and performs queue maintenance until ctx is cancelled.
+func (w *NotificationWorker) Run(ctx context.Context) {
+	for _, pool := range w.pools {
+		for i := 0; i < pool.workers; i++ {
+			go w.work(ctx, pool)
+		}
+	}
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	lastPrune := time.Time{}
+	for {
+		if n, err := w.jobs.ReleaseStaleJobs(ctx, time.Now().Add(-notificationStaleAfter)); err != nil {
+			log.Printf("NotificationWorker: releasing stale jobs failed: %v", err)
+		} else if n > 0 {
+			log.Printf("NotificationWorker: requeued %d stale jobs", n)
+		}
+		if time.Since(lastPrune) >= time.Hour {
+			if _, err := w.jobs.PruneCompletedJobs(ctx, time.Now().Add(-notificationRetention)); err != nil {
+				log.Printf("NotificationWorker: pruning jobs failed: %v", err)
+			}
+			lastPrune = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *NotificationWorker) work(ctx context.Context, pool *providerPool) {
+	for {
+		jobs, err := w.jobs.ClaimJobs(ctx, pool.provider, w.id, notificationClaimSize)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("NotificationWorker: claiming %s jobs failed: %v", pool.provider, err)
+		}
+		for _, job := range jobs {
+			w.process(ctx, pool, job)
+		}
+		if len(jobs) == notificationClaimSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-pool.wake:
+		case <-time.After(notificationPollInterval):
+		}
+	}
+}
+
+func (w *NotificationWorker) process(ctx context.Context, pool *providerPool, job models.NotificationJob) {
+	w.runHook(ctx, job)
+
+	if w.holdForRecipient(ctx, &job) {
+		w.runHook(ctx, job)
+		return
+	}
+
+	messageID, err := w.send(ctx, pool, job)
+	if ctx.Err() != nil {
+		// Shutting down: leave the job running, it is released as stale.
+		return
+	}
+
+	switch {
+	case err == nil:
+		if err := w.jobs.CompleteJob(ctx, job.ID, messageID); err != nil {
+			log.Printf("NotificationWorker: completing job %d failed: %v", job.ID, err)
+			return
+		}
+		job.Status = models.JobDone
+		job.LastError = nil
+	case isPermanentSendError(err) || job.Attempts >= job.MaxAttempts:
+		if err := w.jobs.DeadLetterJob(ctx, job.ID, err.Error()); err != nil {
+			log.Printf("NotificationWorker: dead-lettering job %d failed: %v", job.ID, err)
+			return
+		}
+		log.Printf("NotificationWorker: job %d (%s %s) dead after %d attempts: %v", job.ID, job.Provider, job.Kind, job.Attempts, err)
+		job.Status = models.JobDead
+		errText := err.Error()
+		job.LastError = &errText
+	default:
+		if err := w.jobs.RetryJob(ctx, job.ID, err.Error(), time.Now().Add(notificationBackoff(job.Attempts))); err != nil {
+			log.Printf("NotificationWorker: rescheduling job %d failed: %v", job.ID, err)
+			return
+		}
+		job.Status = models.JobQueued
+		errText := err.Error()
+		job.LastError = &errText
+	}
+	w.runHook(ctx, job)
+}
+
+// holdForRecipient applies the recipient's preferences to a claimed job. It
+// suppresses jobs for a channel they turned off and defers non-critical jobs
+// to the end of their quiet hours, reporting whether the job was held back.
+// If the preferences cannot be read the job is sent.
+func (w *NotificationWorker) holdForRecipient(ctx context.Context, job *models.NotificationJob) bool {
+	if job.UserID == nil || job.Category == nil {
+		return false
+	}
+	enabled, quietUntil, err := w.jobs.DeliveryPolicy(ctx, *job.UserID, *job.Category, job.Provider)
+	if err != nil {
+		log.Printf("NotificationWorker: reading preferences for job %d failed: %v", job.ID, err)
+		return false
+	}
+
+	switch {
+	case !enabled:
+		reason := fmt.Sprintf("%s %s notifications turned off by recipient", *job.Category, job.Provider)
+		if err := w.jobs.SuppressJob(ctx, job.ID, reason); err != nil {
+			log.Printf("NotificationWorker: suppressing job %d failed: %v", job.ID, err)
+			return false
+		}
+		job.Status = models.JobSuppressed
+		job.LastError = &reason
+		return true
+	case quietUntil != nil && !job.Critical:
+		if err := w.jobs.DeferJob(ctx, job.ID, *quietUntil); err != nil {
+			log.Printf("NotificationWorker: deferring job %d failed: %v", job.ID, err)
+			return false
+		}
+		job.Status = models.JobQueued
+		job.Attempts--
+		job.RunAt = *quietUntil
+		return true
+	}
+	return false
+}
+
+// send delivers job and returns the provider's message ID when the provider
+// reports delivery receipts.
+func (w *NotificationWorker) send(ctx context.Context, pool *providerPool, job models.NotificationJob) (string, error) {
+	var msg services.DeliveryMessage
+	if err := json.Unmarshal(job.Payload, &msg); err != nil {
+		return "", fmt.Errorf("%w: %v", errInvalidJobPayload, err)
+	}
+	msg.Channel = job.Provider
+
+	provider, ok := w.providers[job.Provider]
+	if !ok {
+		return "", fmt.Errorf("provider %s: %w", job.Provider, services.ErrProviderNotConfigured)
+	}
+	if err := pool.limiter.Wait(ctx); err != nil {
+		return "", err
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, notificationSendTimeout)
+	defer cancel()
+	if tracked, ok := provider.(services.TrackedDeliveryProvider); ok {
+		return tracked.SendTracked(sendCtx, msg)
+	}
+	return "", provider.Send(sendCtx, msg)
+}
+
+var errInvalidJobPayload = errors.New("invalid job payload")
+
+// isPermanentSendError reports whether retrying cannot help, so the job is
+// dead-lettered straight away.
+func isPermanentSendError(err error) bool {
+	return errors.Is(err, services.ErrProviderNotConfigured) || errors.Is(err, services.ErrNoDeviceTokens) ||
+		errors.Is(err, emails.ErrUnknownTemplate) || errors.Is(err, errInvalidJobPayload)
+}
+
+func (w *NotificationWorker) runHook(ctx context.Context, job models.NotificationJob) {
+	if hook, ok := w.hooks[job.Kind]; ok {
+		hook(ctx, job)
+	}
+}
+
+// notificationBackoff returns the delay before retrying a job that has failed
+// attempts times: 30s doubling up to an hour, plus up to 20% jitter so a burst
+// of failures does not retry in lockstep.
+func notificationBackoff(attempts int) time.Duration {
+	d := notificationBackoffMax
+	if attempts >= 1 && attempts <= 16 {
+		d = min(notificationBackoffBase<<(attempts-1), notificationBackoffMax)
+	}
+	return d + rand.N(d/5+1)
+}
+
+// rateLimiter spaces calls to Wait at least 1/rate apart across all callers.
+// A rate of 0 disables limiting.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	l := &rateLimiter{}
+	if perSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / perSecond)
+	}
+	return l
+}
+
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l.interval == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
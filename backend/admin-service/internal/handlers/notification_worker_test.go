@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/placement-portal-kec/admin-service/internal/models"
+	"github.com/placement-portal-kec/admin-service/internal/repository"
+	"github.com/placement-portal-kec/admin-service/internal/services"
+)
+
+// fakeJobStore records what the worker does with each job instead of
+// updating notify.jobs.
+// Policy, when set, stands in for the recipient's preferences and quiet
+// hours; otherwise every job may be sent now.
+type fakeJobStore struct {
+	mu         sync.Mutex
+	completed  []int64
+	retried    map[int64]time.Time
+	dead       map[int64]string
+	suppressed map[int64]string
+	deferred   map[int64]time.Time
+	Policy     func(userID int64, category, channel string) (bool, *time.Time, error)
+}
+
+func newFakeJobStore() *fakeJobStore {
+	return &fakeJobStore{
+		retried:    map[int64]time.Time{},
+		dead:       map[int64]string{},
+		suppressed: map[int64]string{},
+		deferred:   map[int64]time.Time{},
+	}
+}
+
+func (s *fakeJobStore) Enqueue(ctx context.Context, n repository.OutboundNotification) (int64, error) {
+	return 0, nil
+}
+func (s *fakeJobStore) ClaimJobs(ctx context.Context, provider, workerID string, limit int) ([]models.NotificationJob, error) {
+	return nil, nil
+}
+func (s *fakeJobStore) ReleaseStaleJobs(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	return 0, nil
+}
+func (s *fakeJobStore) CompleteJob(ctx context.Context, id int64, providerMessageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.completed = append(s.completed, id)
+	return nil
+}
+func (s *fakeJobStore) RetryJob(ctx context.Context, id int64, sendErr string, runAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retried[id] = runAt
+	return nil
+}
+func (s *fakeJobStore) SuppressJob(ctx context.Context, id int64, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.suppressed[id] = reason
+	return nil
+}
+func (s *fakeJobStore) DeferJob(ctx context.Context, id int64, runAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deferred[id] = runAt
+	return nil
+}
+func (s *fakeJobStore) DeadLetterJob(ctx context.Context, id int64, sendErr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dead[id] = sendErr
+	return nil
+}
+func (s *fakeJobStore) PruneCompletedJobs(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+func (s *fakeJobStore) RequeueDeadLetter(ctx context.Context, id, requeuedBy int64) (*models.NotificationJob, error) {
+	return nil, errors.New("not implemented")
+}
+func (s *fakeJobStore) DeliveryPolicy(ctx context.Context, userID int64, category, channel string) (bool, *time.Time, error) {
+	if s.Policy == nil {
+		return true, nil, nil
+	}
+	return s.Policy(userID, category, channel)
+}
+
+// newTestWorker returns a worker whose email pool sends through provider
+// without rate limiting, and the statuses its broadcast hook observed.
+func newTestWorker(t *testing.T, store *fakeJobStore, provider *services.FakeProvider) (*NotificationWorker, *[]string) {
+	t.Setenv("NOTIFY_RATE_EMAIL", "0")
+	w := NewNotificationWorker(store, services.DeliveryProviders{models.BroadcastChannelEmail: provider})
+	var statuses []string
+	w.OnKind(models.JobKindBroadcast, func(ctx context.Context, job models.NotificationJob) {
+		statuses = append(statuses, job.Status)
+	})
+	return w, &statuses
+}
+
+func broadcastJob(t *testing.T, id int64, attempts, maxAttempts int) models.NotificationJob {
+	payload, err := json.Marshal(services.DeliveryMessage{Address: "asha.r@kongu.edu", Subject: "Drive update", Body: "Registration closes today"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return models.NotificationJob{
+		ID: id, Provider: models.BroadcastChannelEmail, Kind: models.JobKindBroadcast, Payload: payload,
+		Status: models.JobRunning, Attempts: attempts, MaxAttempts: maxAttempts,
+	}
+}
+
+func TestNotificationWorkerRetriesTransientFailure(t *testing.T) {
+	calls := 0
+	provider := &services.FakeProvider{Fail: func(msg services.DeliveryMessage) error {
+		calls++
+		if calls == 1 {
+			return errors.New("smtp: connection reset")
+		}
+		return nil
+	}}
+	store := newFakeJobStore()
+	w, statuses := newTestWorker(t, store, provider)
+	pool := w.pools[models.BroadcastChannelEmail]
+
+	before := time.Now()
+	w.process(context.Background(), pool, broadcastJob(t, 1, 1, 3))
+	runAt, ok := store.retried[1]
+	if !ok {
+		t.Fatalf("job was not rescheduled after a transient failure")
+	}
+	if runAt.Before(before.Add(notificationBackoffBase)) {
+		t.Errorf("retry at %v, want at least %v of backoff", runAt.Sub(before), notificationBackoffBase)
+	}
+	if len(provider.Sent()) != 0 {
+		t.Fatalf("failed send was recorded as sent")
+	}
+
+	w.process(context.Background(), pool, broadcastJob(t, 1, 2, 3))
+	if len(store.completed) != 1 || store.completed[0] != 1 {
+		t.Fatalf("completed = %v, want [1]", store.completed)
+	}
+	sent := provider.Sent()
+	if len(sent) != 1 || sent[0].Address != "asha.r@kongu.edu" || sent[0].Channel != models.BroadcastChannelEmail {
+		t.Fatalf("sent = %+v, want one email to asha.r@kongu.edu", sent)
+	}
+
+	want := []string{models.JobRunning, models.JobQueued, models.JobRunning, models.JobDone}
+	if fmt.Sprint(*statuses) != fmt.Sprint(want) {
+		t.Errorf("hook statuses = %v, want %v", *statuses, want)
+	}
+}
+
+func TestNotificationWorkerDeadLettersAfterMaxAttempts(t *testing.T) {
+	provider := &services.FakeProvider{Fail: func(msg services.DeliveryMessage) error {
+		return errors.New("smtp: 451 try again later")
+	}}
+	store := newFakeJobStore()
+	w, statuses := newTestWorker(t, store, provider)
+
+	w.process(context.Background(), w.pools[models.BroadcastChannelEmail], broadcastJob(t, 2, 3, 3))
+	if _, ok := store.dead[2]; !ok {
+		t.Fatalf("job on its last attempt was not dead-lettered")
+	}
+	if len(store.retried) != 0 {
+		t.Errorf("job on its last attempt was rescheduled")
+	}
+	if got := (*statuses)[len(*statuses)-1]; got != models.JobDead {
+		t.Errorf("final hook status = %s, want %s", got, models.JobDead)
+	}
+}
+
+func TestNotificationWorkerDeadLettersPermanentErrors(t *testing.T) {
+	provider := &services.FakeProvider{Fail: func(msg services.DeliveryMessage) error {
+		return fmt.Errorf("email: %w", services.ErrProviderNotConfigured)
+	}}
+	store := newFakeJobStore()
+	w, _ := newTestWorker(t, store, provider)
+
+	w.process(context.Background(), w.pools[models.BroadcastChannelEmail], broadcastJob(t, 3, 1, 5))
+	if _, ok := store.dead[3]; !ok {
+		t.Fatalf("job whose provider is not configured was not dead-lettered")
+	}
+	if len(store.retried) != 0 {
+		t.Errorf("permanent failure was retried")
+	}
+}
+
+func TestNotificationBackoffGrowsAndIsCapped(t *testing.T) {
+	for attempts, base := range map[int]time.Duration{1: 30 * time.Second, 2: time.Minute, 3: 2 * time.Minute, 20: time.Hour} {
+		d := notificationBackoff(attempts)
+		if d < base || d > base+base/5 {
+			t.Errorf("backoff(%d) = %v, want between %v and %v", attempts, d, base, base+base/5)
+		}
+	}
+}
+
+// userJob is broadcastJob addressed to user 42's drive_updates notifications
+func userJob(t *testing.T, id int64, critical bool) models.NotificationJob {
+	job := broadcastJob(t, id, 1, 3)
+	userID, category := int64(42), "drive_updates"
+	job.UserID, job.Category, job.Critical = &userID, &category, critical
+	return job
+}
+
+func TestNotificationWorkerSuppressesJobsForDisabledChannel(t *testing.T) {
+	provider := &services.FakeProvider{}
+	store := newFakeJobStore()
+	store.Policy = func(userID int64, category, channel string) (bool, *time.Time, error) {
+		return channel != models.BroadcastChannelEmail, nil, nil
+	}
+	w, statuses := newTestWorker(t, store, provider)
+
+	w.process(context.Background(), w.pools[models.BroadcastChannelEmail], userJob(t, 4, false))
+	if _, ok := store.suppressed[4]; !ok {
+		t.Fatalf("job for a channel the recipient turned off was not suppressed")
+	}
+	if len(provider.Sent()) != 0 || len(store.completed) != 0 {
+		t.Errorf("suppressed job was sent")
+	}
+	if got := (*statuses)[len(*statuses)-1]; got != models.JobSuppressed {
+		t.Errorf("final hook status = %s, want %s", got, models.JobSuppressed)
+	}
+}
+
+func TestNotificationWorkerDefersJobsToEndOfQuietHours(t *testing.T) {
+	provider := &services.FakeProvider{}
+	store := newFakeJobStore()
+	quietUntil := time.Now().Add(7 * time.Hour).Truncate(time.Second)
+	store.Policy = func(userID int64, category, channel string) (bool, *time.Time, error) {
+		return true, &quietUntil, nil
+	}
+	w, _ := newTestWorker(t, store, provider)
+
+	w.process(context.Background(), w.pools[models.BroadcastChannelEmail], userJob(t, 5, false))
+	runAt, ok := store.deferred[5]
+	if !ok {
+		t.Fatalf("job during quiet hours was not deferred")
+	}
+	if !runAt.Equal(quietUntil) {
+		t.Errorf("deferred to %v, want the end of quiet hours %v", runAt, quietUntil)
+	}
+	if len(provider.Sent()) != 0 {
+		t.Errorf("deferred job was sent")
+	}
+}
+
+func TestNotificationWorkerSendsCriticalJobsDuringQuietHours(t *testing.T) {
+	provider := &services.FakeProvider{}
+	store := newFakeJobStore()
+	quietUntil := time.Now().Add(7 * time.Hour)
+	store.Policy = func(userID int64, category, channel string) (bool, *time.Time, error) {
+		return true, &quietUntil, nil
+	}
+	w, _ := newTestWorker(t, store, provider)
+
+	w.process(context.Background(), w.pools[models.BroadcastChannelEmail], userJob(t, 6, true))
+	if len(store.deferred) != 0 {
+		t.Errorf("critical job was deferred")
+	}
+	if len(store.completed) != 1 || len(provider.Sent()) != 1 {
+		t.Errorf("critical job was not sent during quiet hours")
+	}
+}
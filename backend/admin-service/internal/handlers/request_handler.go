@@ -4,19 +4,22 @@ import (
 	"fmt"
 	"log" // Added for http.StatusInternalServerError
 	"strconv"
+	"strings"
 
 	"github.com/placement-portal-kec/admin-service/internal/models"
 	"github.com/placement-portal-kec/admin-service/internal/repository"
+	"github.com/placement-portal-kec/admin-service/internal/utils"
 	"github.com/gofiber/fiber/v2"
 )
 
 type RequestHandler struct {
-	Repo        *repository.RequestRepository
-	StudentRepo *repository.StudentRepository
+	Repo          *repository.RequestRepository
+	StudentRepo   *repository.StudentRepository
+	Notifications *repository.NotificationJobRepository
 }
 
-func NewRequestHandler(repo *repository.RequestRepository, studentRepo *repository.StudentRepository) *RequestHandler {
-	return &RequestHandler{Repo: repo, StudentRepo: studentRepo}
+func NewRequestHandler(repo *repository.RequestRepository, studentRepo *repository.StudentRepository, notifications *repository.NotificationJobRepository) *RequestHandler {
+	return &RequestHandler{Repo: repo, StudentRepo: studentRepo, Notifications: notifications}
 }
 
 // CreateRequest handles students requesting a mark update
@@ -68,7 +71,7 @@ func (h *RequestHandler) CreateRequest(c *fiber.Ctx) error {
 }
 
 func (h *RequestHandler) GetPendingRequests(c *fiber.Ctx) error {
-	reqs, err := h.Repo.GetPendingRequests()
+	reqs, err := h.Repo.GetPendingRequests(utils.DepartmentScope(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch requests"})
 	}
@@ -88,12 +91,17 @@ func (h *RequestHandler) ReviewRequest(c *fiber.Ctx) error {
 
 	adminID := int64(c.Locals("user_id").(float64))
 
+	// Coordinators may only review requests from their own department
+	req, err := h.Repo.GetRequestByID(id, utils.DepartmentScope(c))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Request not found"})
+	}
+
+	field := strings.ReplaceAll(req.FieldName, "_", " ")
+	var notifType, title, body string
+
 	switch input.Action {
 	case "approve":
-		req, err := h.Repo.GetRequestByID(id)
-		if err != nil {
-			return c.Status(404).JSON(fiber.Map{"error": "Request not found"})
-		}
 
 		// 1. Attempt to set status to APPROVED (Atomic Check)
 		if err := h.Repo.UpdateRequestStatus(id, "approved", adminID, nil); err != nil {
@@ -104,19 +112,19 @@ func (h *RequestHandler) ReviewRequest(c *fiber.Ctx) error {
 		}
 
 		// 2. Apply the change to student profile
-		err = h.StudentRepo.ApplyFieldUpdate(req.StudentID, req.FieldName, req.NewValue)
+		err := h.StudentRepo.ApplyFieldUpdate(req.StudentID, req.FieldName, req.NewValue)
 		if err != nil {
 			log.Printf("CRITICAL: Request %d approved by %d but ApplyFieldUpdate failed: %v", id, adminID, err)
 			return c.Status(500).JSON(fiber.Map{"error": "Request approved but failed to update student record. Please contact super admin."})
 		}
 
 		log.Printf("Request APPROVED for Student %d", req.StudentID)
+		notifType = "change_request_approved"
+		title = "Change Request Approved"
+		body = fmt.Sprintf("Your request to update %s to %s has been approved.", field, req.NewValue)
 
 	case "reject":
-		req, err := h.Repo.GetRequestByID(id)
-		if err == nil {
-			log.Printf("Request REJECTED for Student %d", req.StudentID)
-		}
+		log.Printf("Request REJECTED for Student %d", req.StudentID)
 
 		if err := h.Repo.UpdateRequestStatus(id, "rejected", adminID, &input.RejectionReason); err != nil {
 			if len(err.Error()) > 8 && err.Error()[:8] == "CONFLICT" {
@@ -124,11 +132,33 @@ func (h *RequestHandler) ReviewRequest(c *fiber.Ctx) error {
 			}
 			return c.Status(500).JSON(fiber.Map{"error": "Failed to update request status"})
 		}
+		notifType = "change_request_rejected"
+		title = "Change Request Rejected"
+		body = fmt.Sprintf("Your request to update %s was rejected.", field)
+		if input.RejectionReason != "" {
+			body += fmt.Sprintf("\nReason: %s", input.RejectionReason)
+		}
 
 	default:
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid action"})
 	}
 
+	// Let the student know (inbox + push)
+	err = h.Notifications.NotifyUsers(c.Context(), []int64{req.StudentID}, repository.UserNotification{
+		Category: models.InboxChangeRequest,
+		Type:     notifType,
+		Title:    title,
+		Body:     body,
+		Data: map[string]string{
+			"request_id": strconv.FormatInt(id, 10),
+			"field_name": req.FieldName,
+		},
+		DedupeKey: fmt.Sprintf("change_request:%d", id),
+	})
+	if err != nil {
+		log.Printf("Failed to notify student %d about request %d: %v", req.StudentID, id, err)
+	}
+
 	return c.JSON(fiber.Map{"message": "Request reviewed successfully"})
 }
 
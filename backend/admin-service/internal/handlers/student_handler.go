@@ -6,7 +6,9 @@ import (
 
 	"github.com/placement-portal-kec/admin-service/internal/database"
 	"github.com/placement-portal-kec/admin-service/internal/models"
+	"github.com/placement-portal-kec/admin-service/internal/password"
 	"github.com/placement-portal-kec/admin-service/internal/repository"
+	"github.com/placement-portal-kec/admin-service/internal/services"
 	"github.com/placement-portal-kec/admin-service/internal/utils"
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/crypto/bcrypt"
@@ -238,8 +240,8 @@ func UpdateFCMToken(c *fiber.Ctx) error {
 
 	fmt.Printf("[UpdateFCMToken] User %d updating token: %s...\n", userID, input.Token[:10])
 
-	repo := repository.NewUserRepository(database.DB)
-	if err := repo.UpdateFCMToken(c.Context(), userID, input.Token); err != nil {
+	repo := repository.NewDeviceTokenRepository(database.DB)
+	if err := repo.RegisterDeviceToken(c.Context(), userID, input.Token, "unknown"); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to update token", "details": err.Error()})
 	}
 
@@ -272,10 +274,24 @@ func CreateStudent(c *fiber.Ctx) error {
 	// Enforce Uppercase Register Number
 	input.RegisterNumber = strings.ToUpper(input.RegisterNumber)
 
-	// Password Logic
+	// Coordinators can only add students to their own department
+	if scope := utils.DepartmentScope(c); scope != nil && input.Department != *scope {
+		return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You can only manage students of your department"})
+	}
+
+	// Password Logic: the student sets their own password with the OTP in the queued
+	// welcome email, so when the admin doesn't provide one we use a random throwaway
+	// instead of a shared default
+	policy := password.LoadPolicy()
 	plainPassword := input.Password
 	if plainPassword == "" {
-		plainPassword = "Student@123" // Default password
+		generated, err := policy.GenerateTemporary()
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Could not generate a temporary password"})
+		}
+		plainPassword = generated
+	} else if err := policy.Validate(plainPassword, input.Email); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(plainPassword), bcrypt.DefaultCost)
@@ -300,17 +316,27 @@ func CreateStudent(c *fiber.Ctx) error {
 
 	// Save OTP to database
 	userRepo := repository.NewUserRepository(database.DB)
-	if err := userRepo.SaveOTP(c.Context(), input.Email, otp); err != nil {
+	if err := userRepo.SaveOTP(c.Context(), input.Email, otp, repository.WelcomeOTPValidity); err != nil {
 		fmt.Printf("Failed to save OTP for %s: %v\n", input.Email, err)
 		// Continue anyway - student was created successfully
 	}
 
-	// Send welcome email asynchronously
-	go func() {
-		if err := utils.SendWelcomeEmail(input.Email, input.FullName, otp); err != nil {
-			fmt.Printf("Failed to send welcome email to %s: %v\n", input.Email, err)
-		}
-	}()
+	// Queue the welcome email; the notification worker retries it if SMTP fails
+	jobRepo := repository.NewNotificationJobRepository(database.DB)
+	if _, err := jobRepo.Enqueue(c.Context(), repository.OutboundNotification{
+		Provider: models.BroadcastChannelEmail,
+		Kind:     models.JobKindWelcomeEmail,
+		Source:   "admin-service",
+		Payload: services.DeliveryMessage{
+			Address:       input.Email,
+			EmailTemplate: "welcome",
+			Language:      input.Language,
+			Vars:          map[string]string{"name": input.FullName, "otp": otp},
+		},
+		IdempotencyKey: fmt.Sprintf("welcome:%d", user.ID),
+	}); err != nil {
+		fmt.Printf("Failed to queue welcome email for %s: %v\n", input.Email, err)
+	}
 
 	return c.Status(201).JSON(fiber.Map{
 		"message": "Student created successfully. A welcome email with password setup instructions has been sent.",
@@ -371,34 +397,36 @@ func ChangePassword(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
 	}
 
-	if input.NewPassword == "" || len(input.NewPassword) < 6 {
-		return c.Status(400).JSON(fiber.Map{"error": "New password must be at least 6 characters long"})
-	}
-
 	if input.NewPassword != input.ConfirmPassword {
 		return c.Status(400).JSON(fiber.Map{"error": "New passwords do not match"})
 	}
 
 	repo := repository.NewUserRepository(database.DB)
+	policy := password.LoadPolicy()
 
 	// 1. Verify Old Password
-	currentHash, err := repo.GetPasswordHash(c.Context(), userID)
+	creds, err := repo.GetPasswordCredentials(c.Context(), userID, policy.HistorySize)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch user data"})
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(currentHash), []byte(input.OldPassword)); err != nil {
+	if err := bcrypt.CompareHashAndPassword([]byte(creds.PasswordHash), []byte(input.OldPassword)); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Incorrect old password"})
 	}
 
-	// 2. Hash New Password
+	// 2. Enforce Password Policy
+	if err := policy.CheckNew(input.NewPassword, creds.Email, creds.PasswordHash, creds.History); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// 3. Hash New Password
 	newHash, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to process new password"})
 	}
 
-	// 3. Update Password
-	if err := repo.UpdatePassword(c.Context(), userID, string(newHash)); err != nil {
+	// 4. Update Password
+	if err := repo.UpdatePassword(c.Context(), userID, string(newHash), policy.HistorySize); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to update password"})
 	}
 
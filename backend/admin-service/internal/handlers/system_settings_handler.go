@@ -8,6 +8,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/placement-portal-kec/admin-service/internal/models"
+	"github.com/placement-portal-kec/admin-service/internal/password"
 	"github.com/placement-portal-kec/admin-service/internal/repository"
 	"github.com/placement-portal-kec/admin-service/internal/utils"
 	"golang.org/x/crypto/bcrypt"
@@ -44,6 +45,20 @@ func (h *SystemSettingsHandler) GetSettings(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"settings": settings})
 }
 
+// GetCollegeLogo handles GET /api/v1/settings/college-logo, redirecting to a
+// freshly signed logo URL. Emails link here because signed URLs expire.
+func (h *SystemSettingsHandler) GetCollegeLogo(c *fiber.Ctx) error {
+	settings, err := h.Repo.GetSystemSettings(c.Context())
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch settings"})
+	}
+	if settings["college_logo_url"] == "" {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "No college logo uploaded"})
+	}
+	c.Set("Cache-Control", "public, max-age=300")
+	return c.Redirect(utils.GenerateSignedProfileURL(settings["college_logo_url"]), http.StatusFound)
+}
+
 // UpdateSettings handles POST /api/v1/admin/settings
 func (h *SystemSettingsHandler) UpdateSettings(c *fiber.Ctx) error {
 	var body map[string]string
@@ -236,32 +251,35 @@ func (h *SystemSettingsHandler) ChangePassword(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
 	}
 
-	if input.NewPassword == "" || len(input.NewPassword) < 6 {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "New password must be at least 6 characters long"})
-	}
-
 	if input.NewPassword != input.ConfirmPassword {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "New passwords do not match"})
 	}
 
+	policy := password.LoadPolicy()
+
 	// 1. Verify Old Password
-	currentHash, err := h.UserRepo.GetPasswordHash(c.Context(), userID)
+	creds, err := h.UserRepo.GetPasswordCredentials(c.Context(), userID, policy.HistorySize)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch user data"})
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(currentHash), []byte(input.OldPassword)); err != nil {
+	if err := bcrypt.CompareHashAndPassword([]byte(creds.PasswordHash), []byte(input.OldPassword)); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Incorrect old password"})
 	}
 
-	// 2. Hash New Password
+	// 2. Enforce Password Policy
+	if err := policy.CheckNew(input.NewPassword, creds.Email, creds.PasswordHash, creds.History); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// 3. Hash New Password
 	newHash, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to process new password"})
 	}
 
-	// 3. Update Password
-	if err := h.UserRepo.UpdatePassword(c.Context(), userID, string(newHash)); err != nil {
+	// 4. Update Password
+	if err := h.UserRepo.UpdatePassword(c.Context(), userID, string(newHash), policy.HistorySize); err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update password"})
 	}
 
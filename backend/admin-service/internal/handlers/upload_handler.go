@@ -281,8 +281,10 @@ func UploadCollegeLogo(c *fiber.Ctx) error {
 // @Produce json
 // @Security BearerAuth
 // @Param file formData file true "Attachment"
+// @Param group_id formData int false "Chat group the file is for"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /v1/chat/upload [post]
 func UploadChatAttachment(c *fiber.Ctx) error {
@@ -314,23 +316,32 @@ func UploadChatAttachment(c *fiber.Ctx) error {
 	randomName := fmt.Sprintf("%d_%s%s", time.Now().UnixNano(), utils.GenerateRandomString(8), ext)
 
 	// Structure: chat_groups/{groupID}/{year}/{month}/{filename}
-	groupID := c.FormValue("group_id")
 	var path string
-	if groupID != "" {
+	if groupValue := c.FormValue("group_id"); groupValue != "" {
+		groupID, err := strconv.ParseInt(groupValue, 10, 64)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid group_id"})
+		}
+		isMember, err := repository.NewUserRepository(database.DB).IsChatGroupMember(c.Context(), groupID, userID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to check group membership"})
+		}
+		if !isMember {
+			return c.Status(403).JSON(fiber.Map{"error": "Not a member of this group"})
+		}
 		now := time.Now()
-		path = fmt.Sprintf("chat_groups/%s/%d/%02d/%s", groupID, now.Year(), now.Month(), randomName)
+		path = fmt.Sprintf("chat_groups/%d/%d/%02d/%s", groupID, now.Year(), now.Month(), randomName)
 	} else {
 		// Fallback for DMs that haven't been created yet or legacy
 		path = fmt.Sprintf("chat_attachments/%d/%s", userID, randomName)
 	}
 
 	chatBucket := os.Getenv("GARAGE_CHAT_BUCKET")
-	var url string
 	if chatBucket != "" {
-		url, err = utils.UploadToS3Bucket(file, fileHeader, path, chatBucket)
+		_, err = utils.UploadToS3Bucket(file, fileHeader, path, chatBucket)
 	} else {
 		// Fallback to default
-		url, err = utils.UploadToS3(file, fileHeader, path)
+		_, err = utils.UploadToS3(file, fileHeader, path)
 	}
 
 	if err != nil {
@@ -338,12 +349,10 @@ func UploadChatAttachment(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf("Upload failed: %v", err), "details": err.Error()})
 	}
 
-	// Generate public or signed URL
-	signedURL := utils.GenerateSignedProfileURL(url)
-
+	// The object key is sent as the message content; chat-service presigns
+	// it for group members on request
 	return c.JSON(fiber.Map{
 		"message": "Upload successful",
-		"url":     signedURL,
 		"path":    path,
 		"name":    fileHeader.Filename,
 		"size":    fileHeader.Size,
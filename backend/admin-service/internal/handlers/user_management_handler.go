@@ -6,7 +6,10 @@ import (
 	"strconv"
 
 	"github.com/placement-portal-kec/admin-service/internal/database"
+	"github.com/placement-portal-kec/admin-service/internal/models"
+	"github.com/placement-portal-kec/admin-service/internal/password"
 	"github.com/placement-portal-kec/admin-service/internal/repository"
+	"github.com/placement-portal-kec/admin-service/internal/services"
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -52,6 +55,15 @@ func CreateManagedUser(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Coordinator must have a department assigned"})
 	}
 
+	if bad := firstUnknownPermission(input.Permissions); bad != "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Unknown permission key: " + bad})
+	}
+
+	// Admin-set passwords still have to meet the policy; the user is forced to replace it on first login
+	if err := password.LoadPolicy().Validate(input.Password, input.Email); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -64,13 +76,16 @@ func CreateManagedUser(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to create user: " + err.Error()})
 	}
 
-	// Set permissions if provided
-	if len(input.Permissions) > 0 {
-		superAdminID := int64(c.Locals("user_id").(float64))
-		if err := repo.SetUserPermissions(c.Context(), userID, input.Permissions, superAdminID); err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "User created but failed to set permissions"})
-		}
+	// Set permissions, falling back to the role defaults
+	permissions := input.Permissions
+	if len(permissions) == 0 {
+		permissions = models.DefaultPermissionsForRole(input.Role)
+	}
+	superAdminID := int64(c.Locals("user_id").(float64))
+	if err := repo.SetUserPermissions(c.Context(), userID, permissions, superAdminID); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "User created but failed to set permissions"})
 	}
+	services.InvalidateCache(c.Context(), services.PermissionsCacheKey(userID))
 
 	// Log activity
 	details, _ := json.Marshal(map[string]interface{}{
@@ -78,7 +93,6 @@ func CreateManagedUser(c *fiber.Ctx) error {
 		"email":           input.Email,
 		"role":            input.Role,
 	})
-	superAdminID := int64(c.Locals("user_id").(float64))
 	repo.LogActivity(c.Context(), superAdminID, "create_user", string(details))
 
 	return c.Status(201).JSON(fiber.Map{
@@ -111,6 +125,10 @@ func UpdateManagedUser(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Role must be 'admin' or 'coordinator'"})
 	}
 
+	if bad := firstUnknownPermission(input.Permissions); bad != "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Unknown permission key: " + bad})
+	}
+
 	repo := repository.NewUserManagementRepository(database.DB)
 
 	if err := repo.UpdateManagedUser(c.Context(), userID, input.Name, input.Role, input.DepartmentCode, input.IsActive); err != nil {
@@ -124,6 +142,7 @@ func UpdateManagedUser(c *fiber.Ctx) error {
 			return c.Status(500).JSON(fiber.Map{"error": "User updated but failed to set permissions"})
 		}
 	}
+	services.InvalidateCache(c.Context(), services.PermissionsCacheKey(userID))
 
 	// Log activity
 	details, _ := json.Marshal(map[string]interface{}{
@@ -147,6 +166,7 @@ func DeleteManagedUser(c *fiber.Ctx) error {
 	if err := repo.DeleteManagedUser(c.Context(), userID); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	services.InvalidateCache(c.Context(), services.PermissionsCacheKey(userID))
 
 	// Log activity
 	details, _ := json.Marshal(map[string]interface{}{
@@ -188,6 +208,10 @@ func UpdateUserPermissions(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
 	}
 
+	if bad := firstUnknownPermission(input.Permissions); bad != "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Unknown permission key: " + bad})
+	}
+
 	superAdminID := int64(c.Locals("user_id").(float64))
 	repo := repository.NewUserManagementRepository(database.DB)
 
@@ -195,6 +219,9 @@ func UpdateUserPermissions(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to update permissions"})
 	}
 
+	// Every service caches granted keys; drop them so the change applies immediately
+	services.InvalidateCache(c.Context(), services.PermissionsCacheKey(userID))
+
 	// Log activity
 	details, _ := json.Marshal(map[string]interface{}{
 		"target_user_id": userID,
@@ -238,22 +265,21 @@ func GetActivityLog(c *fiber.Ctx) error {
 
 // GetAllPermissionKeys returns the list of all available permission keys
 func GetAllPermissionKeys(c *fiber.Ctx) error {
-	keys := []map[string]string{
-		{"key": "manage_drives", "label": "Manage Drives", "description": "Create, edit, delete placement drives"},
-		{"key": "manage_students", "label": "Manage Students", "description": "View and edit student records"},
-		{"key": "approve_changes", "label": "Approve Changes", "description": "Approve student change requests"},
-		{"key": "manage_spocs", "label": "Manage SPOCs", "description": "Create and modify SPOCs"},
-		{"key": "manage_storage", "label": "Manage Storage", "description": "Access cloud storage admin panel"},
-		{"key": "export_data", "label": "Export Data", "description": "Export and download archived data"},
-		{"key": "manual_drive_ops", "label": "Manual Drive Ops", "description": "Add/remove students from drives, update status"},
-		{"key": "view_analytics", "label": "View Analytics", "description": "View placement analytics"},
-	}
-
 	return c.JSON(fiber.Map{
-		"permissions": keys,
+		"permissions": models.AllPermissionKeys,
 	})
 }
 
+// firstUnknownPermission returns the first key not in the catalog, or ""
+func firstUnknownPermission(keys []string) string {
+	for _, k := range keys {
+		if !models.IsValidPermissionKey(k) {
+			return k
+		}
+	}
+	return ""
+}
+
 // GetDepartmentsList returns all departments for coordinator assignment
 func GetDepartmentsList(c *fiber.Ctx) error {
 	rows, err := database.DB.Query(c.Context(),
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/placement-portal-kec/admin-service/internal/models"
+	"github.com/placement-portal-kec/admin-service/internal/repository"
+	"github.com/placement-portal-kec/admin-service/internal/services"
+	"github.com/placement-portal-kec/admin-service/internal/utils"
+)
+
+// Replies that switch a sender's WhatsApp notifications off or back on,
+// matched case-insensitively against the whole message
+var (
+	whatsAppOptOutKeywords = []string{"STOP", "STOP ALL", "UNSUBSCRIBE", "OPT OUT", "OPTOUT", "CANCEL"}
+	whatsAppOptInKeywords  = []string{"START", "SUBSCRIBE", "UNSTOP"}
+)
+
+const (
+	whatsAppOptOutReply = "You will no longer receive placement updates on WhatsApp. Reply START to turn them back on."
+	whatsAppOptInReply  = "WhatsApp placement updates are on again. Reply STOP to turn them off."
+)
+
+// WhatsAppWebhookHandler receives the WhatsApp Cloud API webhook: delivery
+// receipts for sent messages and messages sent to the college's number,
+// which are opt-out/opt-in keywords or support inbox messages. It also
+// serves the support inbox.
+type WhatsAppWebhookHandler struct {
+	Jobs    WhatsAppWebhookStore
+	Support SupportStore
+	Worker  *NotificationWorker
+	// AppSecret verifies webhook signatures; VerifyToken answers Meta's
+	// subscription check
+	AppSecret   string
+	VerifyToken string
+}
+
+// WhatsAppWebhookStore records what the webhook reports, implemented by
+// repository.NotificationJobRepository.
+type WhatsAppWebhookStore interface {
+	RecordDeliveryReceipt(ctx context.Context, rc repository.DeliveryReceipt) (bool, error)
+	SetWhatsAppSubscription(ctx context.Context, number string, enabled bool) ([]int64, error)
+}
+
+// SupportStore is the support inbox, implemented by
+// repository.SupportRepository.
+type SupportStore interface {
+	SaveSupportMessage(ctx context.Context, m *models.SupportMessage) (bool, error)
+	ListSupportMessages(ctx context.Context, status string, deptScope *string, limit, offset int) ([]models.SupportMessage, int64, error)
+	GetSupportMessage(ctx context.Context, id int64, deptScope *string) (*models.SupportMessage, error)
+	MarkSupportReplied(ctx context.Context, id int64, reply string, repliedBy, jobID int64) error
+	SetSupportStatus(ctx context.Context, id int64, status string) error
+}
+
+func NewWhatsAppWebhookHandler(jobs WhatsAppWebhookStore, support SupportStore, worker *NotificationWorker) *WhatsAppWebhookHandler {
+	return &WhatsAppWebhookHandler{
+		Jobs:        jobs,
+		Support:     support,
+		Worker:      worker,
+		AppSecret:   os.Getenv("WHATSAPP_APP_SECRET"),
+		VerifyToken: os.Getenv("WHATSAPP_VERIFY_TOKEN"),
+	}
+}
+
+// VerifyWebhook - GET /api/v1/webhooks/whatsapp
+// Meta's subscription check: echo hub.challenge when hub.verify_token matches.
+func (h *WhatsAppWebhookHandler) VerifyWebhook(c *fiber.Ctx) error {
+	if h.VerifyToken == "" || c.Query("hub.mode") != "subscribe" || c.Query("hub.verify_token") != h.VerifyToken {
+		return c.Status(403).JSON(fiber.Map{"error": "Verification failed"})
+	}
+	return c.SendString(c.Query("hub.challenge"))
+}
+
+// ReceiveWebhook - POST /api/v1/webhooks/whatsapp
+// Requests must carry a valid X-Hub-Signature-256. Everything recorded is
+// idempotent, so on a database error the webhook fails and Meta retries it.
+func (h *WhatsAppWebhookHandler) ReceiveWebhook(c *fiber.Ctx) error {
+	if !services.VerifyWhatsAppSignature(h.AppSecret, c.Body(), c.Get("X-Hub-Signature-256")) {
+		return c.Status(401).JSON(fiber.Map{"error": "Invalid signature"})
+	}
+
+	var hook services.WhatsAppWebhook
+	if err := json.Unmarshal(c.Body(), &hook); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid payload"})
+	}
+
+	ctx := c.Context()
+	for _, entry := range hook.Entry {
+		for _, change := range entry.Changes {
+			if change.Field != "messages" {
+				continue
+			}
+			for _, st := range change.Value.Statuses {
+				if err := h.recordStatus(ctx, st); err != nil {
+					log.Printf("WhatsAppWebhook: recording status %s of %s failed: %v", st.Status, st.ID, err)
+					return c.Status(500).JSON(fiber.Map{"error": "Failed to record status"})
+				}
+			}
+			for _, msg := range change.Value.Messages {
+				if err := h.receiveMessage(ctx, change.Value, msg); err != nil {
+					log.Printf("WhatsAppWebhook: handling message %s from %s failed: %v", msg.ID, msg.From, err)
+					return c.Status(500).JSON(fiber.Map{"error": "Failed to handle message"})
+				}
+			}
+		}
+	}
+	return c.SendStatus(200)
+}
+
+func (h *WhatsAppWebhookHandler) recordStatus(ctx context.Context, st services.WhatsAppStatus) error {
+	switch st.Status {
+	case services.WhatsAppStatusSent, services.WhatsAppStatusDelivered, services.WhatsAppStatusRead, services.WhatsAppStatusFailed:
+	default:
+		return nil
+	}
+	_, err := h.Jobs.RecordDeliveryReceipt(ctx, repository.DeliveryReceipt{
+		Provider:          models.BroadcastChannelWhatsApp,
+		ProviderMessageID: st.ID,
+		Recipient:         st.RecipientID,
+		Status:            st.Status,
+		Error:             st.Error(),
+		OccurredAt:        services.WhatsAppTime(st.Timestamp),
+	})
+	return err
+}
+
+// receiveMessage applies an opt-out or opt-in keyword and confirms it, or
+// files anything else in the support inbox. Keywords from numbers that
+// belong to no student are not answered.
+func (h *WhatsAppWebhookHandler) receiveMessage(ctx context.Context, value services.WhatsAppWebhookValue, msg services.WhatsAppInbound) error {
+	body := strings.TrimSpace(msg.Body())
+	keyword := strings.ToUpper(strings.Join(strings.Fields(body), " "))
+
+	var enabled bool
+	var confirmation string
+	switch {
+	case slices.Contains(whatsAppOptOutKeywords, keyword):
+		enabled, confirmation = false, whatsAppOptOutReply
+	case slices.Contains(whatsAppOptInKeywords, keyword):
+		enabled, confirmation = true, whatsAppOptInReply
+	default:
+		m := models.SupportMessage{
+			Channel:     models.BroadcastChannelWhatsApp,
+			FromAddress: msg.From,
+			MessageType: msg.Type,
+			Body:        body,
+			ReceivedAt:  services.WhatsAppTime(msg.Timestamp),
+		}
+		if msg.ID != "" {
+			m.ProviderMessageID = &msg.ID
+		}
+		for _, contact := range value.Contacts {
+			if contact.WaID == msg.From && contact.Profile.Name != "" {
+				m.ContactName = &contact.Profile.Name
+			}
+		}
+		_, err := h.Support.SaveSupportMessage(ctx, &m)
+		return err
+	}
+
+	userIDs, err := h.Jobs.SetWhatsAppSubscription(ctx, msg.From, enabled)
+	if err != nil {
+		return err
+	}
+	if len(userIDs) == 0 {
+		log.Printf("WhatsAppWebhook: ignoring %s from %s, which matches no student", keyword, msg.From)
+		return nil
+	}
+	log.Printf("WhatsAppWebhook: %s turned WhatsApp notifications %s for %d students", msg.From, onOff(enabled), len(userIDs))
+
+	_, err = h.Worker.Enqueue(ctx, repository.OutboundNotification{
+		Provider:       models.BroadcastChannelWhatsApp,
+		Kind:           models.JobKindWhatsAppReply,
+		Source:         "admin-service",
+		Payload:        services.DeliveryMessage{Address: msg.From, Body: confirmation},
+		IdempotencyKey: "whatsapp-optout-reply:" + msg.ID,
+	})
+	return err
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// ListSupportMessages - GET /api/v1/admin/support/messages
+// Query: status (open, replied, closed), limit, offset. Coordinators see
+// messages from their department's students only.
+func (h *WhatsAppWebhookHandler) ListSupportMessages(c *fiber.Ctx) error {
+	status := c.Query("status")
+	switch status {
+	case "", models.SupportOpen, models.SupportReplied, models.SupportClosed:
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid status"})
+	}
+
+	limit, offset := queuePage(c)
+	messages, total, err := h.Support.ListSupportMessages(c.Context(), status, utils.DepartmentScope(c), limit, offset)
+	if err != nil {
+		log.Printf("SupportInbox: listing messages failed: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch support messages"})
+	}
+	return c.JSON(fiber.Map{"messages": messages, "total": total})
+}
+
+type SupportReplyRequest struct {
+	Message string `json:"message"`
+}
+
+// ReplySupportMessage - POST /api/v1/admin/support/messages/:id/reply
+// Sends the reply on WhatsApp as free-form text, which Meta accepts within
+// 24 hours of the sender's last message.
+func (h *WhatsAppWebhookHandler) ReplySupportMessage(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid message ID"})
+	}
+	var req SupportReplyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	req.Message = strings.TrimSpace(req.Message)
+	if req.Message == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Reply message is required"})
+	}
+	userID := int64(c.Locals("user_id").(float64))
+
+	msg, err := h.Support.GetSupportMessage(c.Context(), id, utils.DepartmentScope(c))
+	if errors.Is(err, repository.ErrSupportMessageNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Support message not found"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch support message"})
+	}
+
+	jobID, err := h.Worker.Enqueue(c.Context(), repository.OutboundNotification{
+		Provider: models.BroadcastChannelWhatsApp,
+		Kind:     models.JobKindWhatsAppReply,
+		Source:   "admin-service",
+		Payload:  services.DeliveryMessage{Address: msg.FromAddress, Body: req.Message},
+	})
+	if err != nil {
+		log.Printf("SupportInbox: queueing reply to message %d failed: %v", id, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to send reply"})
+	}
+	if err := h.Support.MarkSupportReplied(c.Context(), id, req.Message, userID, jobID); err != nil {
+		log.Printf("SupportInbox: marking message %d replied failed: %v", id, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Reply queued but message could not be updated"})
+	}
+	return c.JSON(fiber.Map{"message": "Reply queued", "job_id": jobID})
+}
+
+type SupportStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// UpdateSupportMessage - PATCH /api/v1/admin/support/messages/:id
+// Closes a support message or reopens it.
+func (h *WhatsAppWebhookHandler) UpdateSupportMessage(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid message ID"})
+	}
+	var req SupportStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	if req.Status != models.SupportOpen && req.Status != models.SupportClosed {
+		return c.Status(400).JSON(fiber.Map{"error": "Status must be open or closed"})
+	}
+
+	if _, err := h.Support.GetSupportMessage(c.Context(), id, utils.DepartmentScope(c)); err != nil {
+		if errors.Is(err, repository.ErrSupportMessageNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "Support message not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch support message"})
+	}
+	if err := h.Support.SetSupportStatus(c.Context(), id, req.Status); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to update support message"})
+	}
+	return c.JSON(fiber.Map{"message": "Support message updated"})
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/placement-portal-kec/admin-service/internal/fakemeta"
+	"github.com/placement-portal-kec/admin-service/internal/models"
+	"github.com/placement-portal-kec/admin-service/internal/repository"
+	"github.com/placement-portal-kec/admin-service/internal/services"
+)
+
+const testAppSecret = "dev-secret"
+
+// webhookStore stands in for the database behind the webhook handler:
+// receipts, subscriptions, the support inbox and queued replies.
+// Students maps a WhatsApp number to the students registered with it.
+type webhookStore struct {
+	NotificationJobStore
+	SupportStore
+
+	mu       sync.Mutex
+	students map[string][]int64
+	receipts []repository.DeliveryReceipt
+	enabled  map[string]bool
+	support  []models.SupportMessage
+	queued   []repository.OutboundNotification
+}
+
+func (s *webhookStore) RecordDeliveryReceipt(ctx context.Context, rc repository.DeliveryReceipt) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.receipts = append(s.receipts, rc)
+	return true, nil
+}
+
+func (s *webhookStore) SetWhatsAppSubscription(ctx context.Context, number string, enabled bool) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := s.students[number]
+	if len(ids) > 0 {
+		s.enabled[number] = enabled
+	}
+	return ids, nil
+}
+
+func (s *webhookStore) SaveSupportMessage(ctx context.Context, m *models.SupportMessage) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.support = append(s.support, *m)
+	return true, nil
+}
+
+func (s *webhookStore) Enqueue(ctx context.Context, n repository.OutboundNotification) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queued = append(s.queued, n)
+	return int64(len(s.queued)), nil
+}
+
+func (s *webhookStore) statuses() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []string
+	for _, rc := range s.receipts {
+		out = append(out, rc.Status)
+	}
+	return out
+}
+
+// startWebhook serves the admin-service webhook handler, verifying
+// signatures with secret, and returns its URL.
+func startWebhook(t *testing.T, store *webhookStore, secret string) string {
+	worker := NewNotificationWorker(store, services.DeliveryProviders{})
+	h := NewWhatsAppWebhookHandler(store, store, worker)
+	h.AppSecret = secret
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Post("/api/v1/webhooks/whatsapp", h.ReceiveWebhook)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(ln)
+	t.Cleanup(func() { app.Shutdown() })
+	return "http://" + ln.Addr().String() + "/api/v1/webhooks/whatsapp"
+}
+
+// startFakeMeta serves the fake Meta API (cmd/fake-meta), signing webhook
+// calls with secret.
+func startFakeMeta(t *testing.T, webhookURL, secret string) *httptest.Server {
+	f := &fakemeta.Server{WebhookURL: webhookURL, AppSecret: secret, Client: &http.Client{Timeout: 5 * time.Second}}
+	srv := httptest.NewServer(f.Handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newWebhookStore() *webhookStore {
+	return &webhookStore{
+		students: map[string][]int64{"919876543210": {42}},
+		enabled:  map[string]bool{},
+	}
+}
+
+// inbound has the fake Meta API deliver a text message and returns the
+// status the webhook answered with.
+func inbound(t *testing.T, meta *httptest.Server, from, text string) int {
+	body, _ := json.Marshal(fakemeta.Inbound{From: from, Name: "Asha", Text: text})
+	resp, err := http.Post(meta.URL+"/inbound", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		WebhookStatus int `json:"webhook_status"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	return out.WebhookStatus
+}
+
+func waitFor(t *testing.T, what string, done func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDeliveryReceiptsAreRecorded(t *testing.T) {
+	store := newWebhookStore()
+	meta := startFakeMeta(t, startWebhook(t, store, testAppSecret), testAppSecret)
+	wa := &services.WhatsAppService{AccessToken: "x", APIURL: meta.URL + "/v17.0/1/messages"}
+
+	id, err := wa.SendMessage("919876543210", "Drive registration closes today")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "sent, delivered and read receipts", func() bool { return len(store.statuses()) == 3 })
+	want := []string{services.WhatsAppStatusSent, services.WhatsAppStatusDelivered, services.WhatsAppStatusRead}
+	for i, rc := range store.receipts {
+		if rc.ProviderMessageID != id || rc.Recipient != "919876543210" || rc.Status != want[i] {
+			t.Errorf("receipt %d = %+v, want %s for %s", i, rc, want[i], id)
+		}
+	}
+
+	if _, err := wa.SendMessage("919876540000", "Drive registration closes today"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "failed receipt", func() bool { return len(store.statuses()) == 4 })
+	if rc := store.receipts[3]; rc.Status != services.WhatsAppStatusFailed || rc.Error == "" {
+		t.Errorf("receipt = %+v, want failed with the error from Meta", rc)
+	}
+
+	if _, err := wa.SendMessage("919876549999", "Drive registration closes today"); err == nil {
+		t.Errorf("send to a rejected number succeeded")
+	}
+}
+
+func TestStopAndStartToggleSubscription(t *testing.T) {
+	store := newWebhookStore()
+	meta := startFakeMeta(t, startWebhook(t, store, testAppSecret), testAppSecret)
+
+	if status := inbound(t, meta, "919876543210", " stop "); status != 200 {
+		t.Fatalf("webhook answered %d to STOP", status)
+	}
+	if enabled, ok := store.enabled["919876543210"]; !ok || enabled {
+		t.Fatalf("STOP did not turn WhatsApp notifications off")
+	}
+	if status := inbound(t, meta, "919876543210", "START"); status != 200 {
+		t.Fatalf("webhook answered %d to START", status)
+	}
+	if !store.enabled["919876543210"] {
+		t.Fatalf("START did not turn WhatsApp notifications back on")
+	}
+
+	if len(store.queued) != 2 {
+		t.Fatalf("queued %d replies, want a confirmation for STOP and START", len(store.queued))
+	}
+	for i, want := range []string{whatsAppOptOutReply, whatsAppOptInReply} {
+		n := store.queued[i]
+		msg := n.Payload.(services.DeliveryMessage)
+		if n.Kind != models.JobKindWhatsAppReply || msg.Address != "919876543210" || msg.Body != want {
+			t.Errorf("queued %s to %s: %q, want a WhatsApp reply to the sender: %q", n.Kind, msg.Address, msg.Body, want)
+		}
+	}
+	if len(store.support) != 0 {
+		t.Errorf("keywords were filed in the support inbox")
+	}
+}
+
+func TestStopFromUnknownNumberIsNotAnswered(t *testing.T) {
+	store := newWebhookStore()
+	meta := startFakeMeta(t, startWebhook(t, store, testAppSecret), testAppSecret)
+
+	if status := inbound(t, meta, "15550001111", "STOP"); status != 200 {
+		t.Fatalf("webhook answered %d", status)
+	}
+	if len(store.queued) != 0 {
+		t.Errorf("queued %d replies to a number that belongs to no student", len(store.queued))
+	}
+}
+
+func TestOtherMessagesGoToSupportInbox(t *testing.T) {
+	store := newWebhookStore()
+	meta := startFakeMeta(t, startWebhook(t, store, testAppSecret), testAppSecret)
+
+	if status := inbound(t, meta, "919876543210", "When is the Zoho drive?"); status != 200 {
+		t.Fatalf("webhook answered %d", status)
+	}
+	if len(store.support) != 1 || store.support[0].Body != "When is the Zoho drive?" {
+		t.Fatalf("support inbox = %+v, want the message", store.support)
+	}
+	if name := store.support[0].ContactName; name == nil || *name != "Asha" {
+		t.Errorf("contact name = %v, want Asha", name)
+	}
+}
+
+func TestWebhookRejectsBadSignature(t *testing.T) {
+	store := newWebhookStore()
+	meta := startFakeMeta(t, startWebhook(t, store, testAppSecret), "some-other-secret")
+
+	if status := inbound(t, meta, "919876543210", "STOP"); status != 401 {
+		t.Fatalf("webhook answered %d to a payload signed with the wrong secret, want 401", status)
+	}
+	if len(store.enabled) != 0 || len(store.queued) != 0 {
+		t.Errorf("unsigned payload changed state")
+	}
+}
@@ -1,15 +1,13 @@
 package middleware
 
 import (
-	"os"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/placement-portal-kec/admin-service/internal/utils"
 )
 
-var SecretKey = []byte(os.Getenv("JWT_SECRET"))
-
 func Protected(c *fiber.Ctx) error {
 	return JWTMiddleware()(c)
 }
@@ -22,17 +20,33 @@ func JWTMiddleware() fiber.Handler {
 		}
 
 		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return SecretKey, nil
-		})
+		token, err := utils.ParseToken(tokenString)
 
 		if err != nil || !token.Valid {
 			return c.Status(401).JSON(fiber.Map{"error": "Unauthorized: Invalid token"})
 		}
 
 		claims := token.Claims.(jwt.MapClaims)
+
+		// Accounts on an admin-assigned password may only change it (PUT /api/v1/auth/password)
+		if mustChange, _ := claims["pwd_change_required"].(bool); mustChange {
+			return c.Status(403).JSON(fiber.Map{"error": "Password change required", "code": "PASSWORD_CHANGE_REQUIRED"})
+		}
+
+		if handled, err := guardImpersonation(c, claims); handled {
+			return err
+		}
+
+		// Coordinators are scoped to the department carried in their token; tokens
+		// issued before the claim existed must be refreshed rather than see everything
+		deptCode, _ := claims["department_code"].(string)
+		if claims["role"] == "coordinator" && deptCode == "" {
+			return c.Status(401).JSON(fiber.Map{"error": "Unauthorized: Session expired, please log in again"})
+		}
+
 		c.Locals("user_id", claims["user_id"])
 		c.Locals("role", claims["role"])
+		c.Locals("department_code", deptCode)
 		return c.Next()
 	}
 }
@@ -14,3 +14,151 @@ type BroadcastTemplate struct {
 	CreatedBy *int64          `json:"created_by" db:"created_by"`
 	CreatedAt time.Time       `json:"created_at" db:"created_at"`
 }
+
+// Broadcast delivery channels (broadcast_deliveries.channel)
+const (
+	BroadcastChannelEmail    = "EMAIL"
+	BroadcastChannelWhatsApp = "WHATSAPP"
+	BroadcastChannelPush     = "PUSH"
+)
+
+// Broadcast delivery statuses (broadcast_deliveries.status)
+const (
+	DeliveryQueued  = "queued"
+	DeliverySending = "sending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+	DeliverySkipped = "skipped"
+)
+
+// BroadcastTarget selects the students a broadcast is sent to.
+type BroadcastTarget struct {
+	TargetGroup string   `json:"target_group"` // all_students, placed_students, unplaced_students
+	BatchYear   int      `json:"batch_year,omitempty"`
+	Department  string   `json:"department,omitempty"`
+	RollNos     []string `json:"roll_nos,omitempty"`
+	Names       []string `json:"names,omitempty"`
+}
+
+// BroadcastRecipient is a resolved student with the addresses and template
+// variable values needed to render their copy of a broadcast.
+type BroadcastRecipient struct {
+	UserID         int64
+	Name           string
+	Email          string
+	MobileNumber   string
+	FCMToken       string // most recently seen device; pushes go to all of them
+	RegisterNumber string
+	Department     string
+	BatchYear      int
+}
+
+type Broadcast struct {
+	ID             int64           `json:"id"`
+	TemplateID     *int64          `json:"template_id"`
+	TemplateName   *string         `json:"template_name"`
+	Channels       []string        `json:"channels"`
+	TargetGroup    string          `json:"target_group"`
+	Filters        json.RawMessage `json:"filters"`
+	Subject        *string         `json:"subject"`
+	Message        string          `json:"message"`
+	Status         string          `json:"status"`
+	RecipientCount int             `json:"recipient_count"`
+	CreatedBy      *int64          `json:"created_by"`
+	CreatedAt      time.Time       `json:"created_at"`
+	CompletedAt    *time.Time      `json:"completed_at"`
+	ScheduleID     *int64          `json:"schedule_id,omitempty"`
+	ScheduledFor   *time.Time      `json:"scheduled_for,omitempty"`
+}
+
+// Broadcast schedule frequencies and statuses (broadcast_schedules)
+const (
+	ScheduleOnce   = "once"
+	ScheduleDaily  = "daily"
+	ScheduleWeekly = "weekly"
+
+	ScheduleActive    = "scheduled"
+	ScheduleCompleted = "completed"
+	ScheduleCancelled = "cancelled"
+)
+
+// BroadcastSchedule is a broadcast composed now and sent later, once or on a
+// daily/weekly recurrence. Recipients are resolved at each send.
+type BroadcastSchedule struct {
+	ID              int64           `json:"id"`
+	TemplateID      *int64          `json:"template_id"`
+	TemplateName    *string         `json:"template_name"`
+	Variables       []string        `json:"variables"`
+	Channels        []string        `json:"channels"`
+	TargetGroup     string          `json:"target_group"`
+	Filters         json.RawMessage `json:"filters"`
+	DepartmentScope *string         `json:"department_scope,omitempty"`
+	Subject         *string         `json:"subject"`
+	Message         string          `json:"message"`
+	Frequency       string          `json:"frequency"`
+	Weekdays        []int           `json:"weekdays"`    // weekly: 0 = Sunday
+	TimeOfDay       *string         `json:"time_of_day"` // "HH:MM" in Timezone
+	Timezone        string          `json:"timezone"`
+	EndsAt          *time.Time      `json:"ends_at"`
+	NextRunAt       *time.Time      `json:"next_run_at"`
+	Status          string          `json:"status"`
+	RunCount        int             `json:"run_count"`
+	LastRunAt       *time.Time      `json:"last_run_at"`
+	LastBroadcastID *int64          `json:"last_broadcast_id"`
+	LastError       *string         `json:"last_error"`
+	CreatedBy       *int64          `json:"created_by"`
+	CreatedAt       time.Time       `json:"created_at"`
+	CancelledAt     *time.Time      `json:"cancelled_at"`
+}
+
+// Automatic drive reminder kinds (drive_reminders.kind). Registration
+// deadline reminders are drive-service's (drive_deadline_reminders).
+const (
+	ReminderDriveDate = "drive_date"
+)
+
+// DriveReminder is a drive due for one of the automatic reminders
+type DriveReminder struct {
+	DriveID     int64
+	Kind        string
+	CompanyName string
+	Location    string
+	DriveDate   time.Time
+}
+
+// BroadcastDelivery is one rendered message for one recipient on one channel.
+type BroadcastDelivery struct {
+	ID             int64      `json:"id"`
+	BroadcastID    int64      `json:"broadcast_id"`
+	UserID         *int64     `json:"user_id"`
+	RecipientName  string     `json:"recipient_name,omitempty"`
+	RegisterNumber string     `json:"register_number,omitempty"`
+	Channel        string     `json:"channel"`
+	Address        *string    `json:"address"`
+	Subject        string     `json:"subject,omitempty"`
+	Body           string     `json:"body"`
+	Params         []string   `json:"params,omitempty"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	LastError      *string    `json:"last_error"`
+	SentAt         *time.Time `json:"sent_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	// Receipt fields come from the provider's delivery receipts (WhatsApp):
+	// the furthest status reached (sent, delivered, read or failed), when,
+	// and why it failed
+	ReceiptStatus *string    `json:"receipt_status,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	ReadAt        *time.Time `json:"read_at,omitempty"`
+	ReceiptError  *string    `json:"receipt_error,omitempty"`
+}
+
+// BroadcastReport is the per-recipient delivery report for one broadcast.
+// Summary counts deliveries by channel, then by status; Receipts counts
+// them by channel, then by receipt status.
+type BroadcastReport struct {
+	Broadcast  Broadcast                 `json:"broadcast"`
+	Summary    map[string]map[string]int `json:"summary"`
+	Receipts   map[string]map[string]int `json:"receipts"`
+	Deliveries []BroadcastDelivery       `json:"deliveries"`
+	Total      int64                     `json:"total"`
+}
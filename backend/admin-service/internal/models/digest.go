@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Digest frequencies (notify.digest_settings.frequency)
+const (
+	DigestWeekly = "weekly"
+	DigestDaily  = "daily"
+)
+
+// Digest kinds (notify.digests.kind)
+const (
+	DigestStudent     = "student"
+	DigestCoordinator = "coordinator"
+)
+
+// DigestRecipient is a student or coordinator due a digest. Since is when
+// their previous digest went out, nil if they never had one.
+type DigestRecipient struct {
+	UserID     int64
+	Name       string
+	Email      string
+	Role       string
+	Department string
+	Frequency  string
+	Since      *time.Time
+}
+
+// DigestDrive is a drive listed in a coordinator's digest
+type DigestDrive struct {
+	ID           int64
+	CompanyName  string
+	DeadlineDate time.Time
+}
+
+// DigestStudentRef is a student listed in a coordinator's digest
+type DigestStudentRef struct {
+	UserID         int64
+	Name           string
+	RegisterNumber string
+}
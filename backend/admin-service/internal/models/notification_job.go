@@ -0,0 +1,87 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Notification job statuses (notify.jobs.status)
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobDead    = "dead"
+	// JobSuppressed jobs were dropped because the recipient opted out
+	JobSuppressed = "suppressed"
+)
+
+// Notification job kinds produced by admin-service
+const (
+	JobKindBroadcast    = "broadcast"
+	JobKindWelcomeEmail = "welcome_email"
+	// JobKindWhatsAppReply jobs answer a WhatsApp message: support inbox
+	// replies and opt-out/opt-in confirmations
+	JobKindWhatsAppReply = "whatsapp_reply"
+	JobKindDigest        = "digest"
+)
+
+// NotificationJob is one outbound message in the shared notify.jobs queue.
+// Provider is a broadcast channel name (EMAIL, WHATSAPP, PUSH); Payload is the
+// JSON-encoded services.DeliveryMessage. Jobs with a UserID and Category are
+// subject to that user's preferences and quiet hours; Critical ones ignore
+// quiet hours.
+type NotificationJob struct {
+	ID             int64           `json:"id"`
+	Provider       string          `json:"provider"`
+	Kind           string          `json:"kind"`
+	Source         string          `json:"source"`
+	Payload        json.RawMessage `json:"payload"`
+	IdempotencyKey *string         `json:"idempotency_key"`
+	UserID         *int64          `json:"user_id"`
+	Category       *string         `json:"category"`
+	Critical       bool            `json:"critical"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	MaxAttempts    int             `json:"max_attempts"`
+	RunAt          time.Time       `json:"run_at"`
+	LastError      *string         `json:"last_error"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	CompletedAt    *time.Time      `json:"completed_at"`
+	// ProviderMessageID is the provider's ID for the sent message (WhatsApp
+	// wamid), which delivery receipts refer to
+	ProviderMessageID *string `json:"provider_message_id,omitempty"`
+}
+
+// DeadLetter records a job that ran out of attempts.
+type DeadLetter struct {
+	ID         int64           `json:"id"`
+	JobID      int64           `json:"job_id"`
+	Provider   string          `json:"provider"`
+	Kind       string          `json:"kind"`
+	Source     string          `json:"source"`
+	Payload    json.RawMessage `json:"payload"`
+	Attempts   int             `json:"attempts"`
+	LastError  *string         `json:"last_error"`
+	FailedAt   time.Time       `json:"failed_at"`
+	RequeuedAt *time.Time      `json:"requeued_at"`
+	RequeuedBy *int64          `json:"requeued_by"`
+}
+
+// Inbox categories (notify.inbox.category)
+const (
+	InboxDriveAnnouncement = "drive_announcement"
+	InboxApplicationStatus = "application_status"
+	InboxChangeRequest     = "change_request"
+	InboxBroadcast         = "broadcast"
+	InboxChatMention       = "chat_mention"
+	// InboxDigest is the digest emails' category; it has preferences but
+	// never any inbox entries
+	InboxDigest = "digest"
+)
+
+// InboxCategories lists every notification category a user can set
+// preferences for
+var InboxCategories = []string{
+	InboxDriveAnnouncement, InboxApplicationStatus, InboxChangeRequest, InboxChatMention, InboxBroadcast, InboxDigest,
+}
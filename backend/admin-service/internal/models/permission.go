@@ -0,0 +1,69 @@
+package models
+
+// Permission keys checked by RequirePermission across all services.
+// Stored in admin.role_permissions and returned to the UI at login.
+const (
+	PermManageDrives   = "manage_drives"
+	PermManageStudents = "manage_students"
+	PermApproveChanges = "approve_changes"
+	PermManageSpocs    = "manage_spocs"
+	PermManageStorage  = "manage_storage"
+	PermExportData     = "export_data"
+	PermManualDriveOps = "manual_drive_ops"
+	PermViewAnalytics  = "view_analytics"
+	PermManageConfig   = "manage_config"
+	PermSendBroadcasts = "send_broadcasts"
+	PermManageSupport  = "manage_support"
+)
+
+// PermissionKey describes a grantable permission for the super admin UI
+type PermissionKey struct {
+	Key         string `json:"key"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+}
+
+// AllPermissionKeys is the full catalog of grantable permissions
+var AllPermissionKeys = []PermissionKey{
+	{Key: PermManageDrives, Label: "Manage Drives", Description: "Create, edit, delete placement drives"},
+	{Key: PermManageStudents, Label: "Manage Students", Description: "View and edit student records"},
+	{Key: PermApproveChanges, Label: "Approve Changes", Description: "Approve student change requests"},
+	{Key: PermManageSpocs, Label: "Manage SPOCs", Description: "Create and modify SPOCs"},
+	{Key: PermManageStorage, Label: "Manage Storage", Description: "Access cloud storage admin panel"},
+	{Key: PermExportData, Label: "Export Data", Description: "Export and download archived data"},
+	{Key: PermManualDriveOps, Label: "Manual Drive Ops", Description: "Add/remove students from drives, update status"},
+	{Key: PermViewAnalytics, Label: "View Analytics", Description: "View placement analytics"},
+	{Key: PermManageConfig, Label: "Manage Configuration", Description: "Edit departments, batches and portal settings"},
+	{Key: PermSendBroadcasts, Label: "Send Broadcasts", Description: "Manage broadcast templates and send broadcasts"},
+	{Key: PermManageSupport, Label: "Support Inbox", Description: "Read and answer WhatsApp support messages"},
+}
+
+// DefaultPermissionsForRole is applied when a managed user is created without
+// an explicit permission list.
+func DefaultPermissionsForRole(role string) []string {
+	switch role {
+	case "admin":
+		keys := make([]string, 0, len(AllPermissionKeys))
+		for _, p := range AllPermissionKeys {
+			keys = append(keys, p.Key)
+		}
+		return keys
+	case "coordinator":
+		// Migration 0002 grants the same keys to existing coordinators that had
+		// none, and 0009 adds manage_support for everyone with send_broadcasts
+		return []string{PermManageDrives, PermManageStudents, PermApproveChanges, PermExportData, PermViewAnalytics,
+			PermManageConfig, PermSendBroadcasts, PermManageSupport}
+	default:
+		return nil
+	}
+}
+
+// IsValidPermissionKey reports whether key is part of the catalog
+func IsValidPermissionKey(key string) bool {
+	for _, p := range AllPermissionKeys {
+		if p.Key == key {
+			return true
+		}
+	}
+	return false
+}
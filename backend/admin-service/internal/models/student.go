@@ -91,6 +91,7 @@ type CreateStudentInput struct {
 	Department     string `json:"department" validate:"required"`
 	// MobileNumber removed as per request
 	Password string `json:"password"` // Optional, default will be used if empty
+	Language string `json:"language"` // Optional welcome email language: "en" (default) or "ta"
 }
 
 // StudentFullProfile represents the complete view for Admins
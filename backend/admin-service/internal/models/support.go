@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Support message statuses (support_messages.status)
+const (
+	SupportOpen    = "open"
+	SupportReplied = "replied"
+	SupportClosed  = "closed"
+)
+
+// SupportMessage is a free-text message sent to the college's WhatsApp
+// number. Student fields are set when the sender's number matches a student.
+type SupportMessage struct {
+	ID                int64      `json:"id"`
+	Channel           string     `json:"channel"`
+	FromAddress       string     `json:"from_address"`
+	ContactName       *string    `json:"contact_name"`
+	UserID            *int64     `json:"user_id"`
+	StudentName       *string    `json:"student_name,omitempty"`
+	RegisterNumber    *string    `json:"register_number,omitempty"`
+	Department        *string    `json:"department,omitempty"`
+	ProviderMessageID *string    `json:"provider_message_id,omitempty"`
+	MessageType       string     `json:"message_type"`
+	Body              string     `json:"body"`
+	ReceivedAt        time.Time  `json:"received_at"`
+	Status            string     `json:"status"`
+	Reply             *string    `json:"reply"`
+	RepliedBy         *int64     `json:"replied_by"`
+	RepliedAt         *time.Time `json:"replied_at"`
+}
@@ -76,10 +76,13 @@ func (r *ApplicationRepository) WithdrawApplication(ctx context.Context, student
 	return err
 }
 
-// GetStudentApplications lists what a student has applied to
+// GetStudentApplications lists what a student has applied to. "updated"
+// is when the application's status last changed.
 func (r *ApplicationRepository) GetStudentApplications(ctx context.Context, studentID int64) ([]map[string]interface{}, error) {
 	query := `
-        SELECT da.drive_id, pd.company_name, pd.job_role, da.status, da.applied_at
+        SELECT da.drive_id, pd.company_name,
+               COALESCE((SELECT string_agg(jr.role_name, ', ' ORDER BY jr.id) FROM job_roles jr WHERE jr.drive_id = pd.id), ''),
+               da.status, da.applied_at, COALESCE(da.updated_at, da.applied_at)
         FROM drive_applications da
         JOIN placement_drives pd ON da.drive_id = pd.id
         WHERE da.student_id = $1
@@ -95,8 +98,10 @@ func (r *ApplicationRepository) GetStudentApplications(ctx context.Context, stud
 	for rows.Next() {
 		var driveID int64
 		var company, role, status string
-		var appliedAt time.Time
-		rows.Scan(&driveID, &company, &role, &status, &appliedAt)
+		var appliedAt, updatedAt time.Time
+		if err := rows.Scan(&driveID, &company, &role, &status, &appliedAt, &updatedAt); err != nil {
+			return nil, err
+		}
 
 		apps = append(apps, map[string]interface{}{
 			"drive_id": driveID,
@@ -104,9 +109,10 @@ func (r *ApplicationRepository) GetStudentApplications(ctx context.Context, stud
 			"role":     role,
 			"status":   status,
 			"date":     appliedAt,
+			"updated":  updatedAt,
 		})
 	}
-	return apps, nil
+	return apps, rows.Err()
 }
 
 // RequestToAttend records a request-to-attend for an ineligible student
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/placement-portal-kec/admin-service/internal/models"
 	"github.com/jackc/pgx/v5"
@@ -84,64 +85,17 @@ func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*mod
 	return &user, nil
 }
 
-// SaveOTP stores the code with a 15-minute expiry
-func (r *UserRepository) SaveOTP(ctx context.Context, email, otp string) error {
-	// Upsert: If an OTP already exists for this email, overwrite it
-	query := `
-        INSERT INTO password_resets (email, otp_code, expires_at)
-        VALUES ($1, $2, NOW() + INTERVAL '2 minutes')
-        ON CONFLICT (email) 
-        DO UPDATE SET otp_code = $2, expires_at = NOW() + INTERVAL '2 minutes'
-    `
-	_, err := r.DB.Exec(ctx, query, email, otp)
+// WelcomeOTPValidity is how long the code in a new student's welcome email
+// can be used to set their password
+const WelcomeOTPValidity = 72 * time.Hour
+
+// SaveOTP stores a code in auth.password_resets, replacing any earlier one.
+// auth-service's POST /api/v1/auth/reset-password redeems it.
+func (r *UserRepository) SaveOTP(ctx context.Context, email, otp string, validFor time.Duration) error {
+	_, err := r.DB.Exec(ctx, `
+		INSERT INTO auth.password_resets (email, otp_code, expires_at, attempts)
+		VALUES (LOWER($1), $2, NOW() + make_interval(secs => $3), 0)
+		ON CONFLICT (email) DO UPDATE SET otp_code = EXCLUDED.otp_code, expires_at = EXCLUDED.expires_at, attempts = 0`,
+		email, otp, validFor.Seconds())
 	return err
 }
-
-// VerifyOTP checks if the code matches and hasn't expired
-func (r *UserRepository) VerifyOTP(ctx context.Context, email, otp string) (bool, error) {
-	var count int
-	query := `
-        SELECT COUNT(*) FROM password_resets 
-        WHERE email = $1 AND otp_code = $2 AND expires_at > NOW()
-    `
-	err := r.DB.QueryRow(ctx, query, email, otp).Scan(&count)
-	if err != nil {
-		return false, err
-	}
-	return count > 0, nil
-}
-
-// ResetPassword updates the user's password and clears the OTP
-func (r *UserRepository) ResetPassword(ctx context.Context, email, newHash string) error {
-	tx, err := r.DB.Begin(ctx)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback(ctx)
-
-	// 1. Update Password
-	_, err = tx.Exec(ctx, "UPDATE users SET password_hash = $1 WHERE email = $2", newHash, email)
-	if err != nil {
-		return err
-	}
-
-	// 2. Delete OTP (Prevent replay attacks)
-	_, err = tx.Exec(ctx, "DELETE FROM password_resets WHERE email = $1", email)
-	if err != nil {
-		return err
-	}
-
-	return tx.Commit(ctx)
-}
-
-// DeleteExpiredOTPs removes junk data from the password_resets table
-func (r *UserRepository) DeleteExpiredOTPs(ctx context.Context) (int64, error) {
-	query := `DELETE FROM password_resets WHERE expires_at < NOW()`
-
-	result, err := r.DB.Exec(ctx, query)
-	if err != nil {
-		return 0, err
-	}
-
-	return result.RowsAffected(), nil
-}
@@ -0,0 +1,391 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/placement-portal-kec/admin-service/internal/models"
+)
+
+var ErrBroadcastNotFound = errors.New("broadcast not found")
+
+// ErrOccurrenceAlreadySent is returned by CreateBroadcast when the schedule
+// occurrence it is for already has a broadcast.
+var ErrOccurrenceAlreadySent = errors.New("schedule occurrence already sent")
+
+// recipientColumns are the models.BroadcastRecipient fields, for queries
+// over users u JOIN student_personal sp
+const recipientColumns = `u.id, COALESCE(u.name, ''), u.email, COALESCE(sp.mobile_number, ''),
+		       COALESCE((SELECT dt.token FROM notify.device_tokens dt
+		                 WHERE dt.user_id = u.id ORDER BY dt.last_seen_at DESC LIMIT 1), ''),
+		       sp.register_number, COALESCE(sp.department, ''), sp.batch_year`
+
+func scanRecipients(rows pgx.Rows) ([]models.BroadcastRecipient, error) {
+	defer rows.Close()
+	var recipients []models.BroadcastRecipient
+	for rows.Next() {
+		var rc models.BroadcastRecipient
+		if err := rows.Scan(&rc.UserID, &rc.Name, &rc.Email, &rc.MobileNumber, &rc.FCMToken,
+			&rc.RegisterNumber, &rc.Department, &rc.BatchYear); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, rc)
+	}
+	return recipients, rows.Err()
+}
+
+// ResolveRecipients returns the active students matched by target. deptScope
+// restricts coordinators to their own department.
+func (r *BroadcastRepository) ResolveRecipients(ctx context.Context, target models.BroadcastTarget, deptScope *string) ([]models.BroadcastRecipient, error) {
+	whereClause := "WHERE u.role = 'student' AND COALESCE(u.is_active, TRUE) AND NOT COALESCE(u.is_blocked, FALSE)"
+	var args []interface{}
+	argCounter := 1
+
+	switch target.TargetGroup {
+	case "placed_students":
+		whereClause += " AND EXISTS (SELECT 1 FROM drive_applications da WHERE da.student_id = u.id AND da.status = 'placed')"
+	case "unplaced_students":
+		whereClause += " AND NOT EXISTS (SELECT 1 FROM drive_applications da WHERE da.student_id = u.id AND da.status = 'placed')"
+	}
+
+	if target.Department != "" {
+		whereClause += fmt.Sprintf(" AND sp.department = $%d", argCounter)
+		args = append(args, target.Department)
+		argCounter++
+	}
+
+	if deptScope != nil {
+		whereClause += fmt.Sprintf(" AND sp.department = $%d", argCounter)
+		args = append(args, *deptScope)
+		argCounter++
+	}
+
+	if target.BatchYear > 0 {
+		whereClause += fmt.Sprintf(" AND sp.batch_year = $%d", argCounter)
+		args = append(args, target.BatchYear)
+		argCounter++
+	}
+
+	if len(target.RollNos) > 0 {
+		rollNos := make([]string, len(target.RollNos))
+		for i, rn := range target.RollNos {
+			rollNos[i] = strings.ToUpper(strings.TrimSpace(rn))
+		}
+		whereClause += fmt.Sprintf(" AND UPPER(sp.register_number) = ANY($%d)", argCounter)
+		args = append(args, rollNos)
+		argCounter++
+	}
+
+	if len(target.Names) > 0 {
+		names := make([]string, len(target.Names))
+		for i, n := range target.Names {
+			names[i] = strings.ToLower(strings.TrimSpace(n))
+		}
+		whereClause += fmt.Sprintf(" AND LOWER(u.name) = ANY($%d)", argCounter)
+		args = append(args, names)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT `+recipientColumns+`
+		FROM users u
+		JOIN student_personal sp ON u.id = sp.user_id
+		%s
+		ORDER BY sp.register_number`, whereClause)
+
+	rows, err := r.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRecipients(rows)
+}
+
+// CreateBroadcast records the broadcast and all of its rendered deliveries, and
+// enqueues a notify.jobs job for every delivery that has an address, in one
+// transaction so workers never see a half-queued broadcast. A broadcast for a
+// schedule occurrence that was already sent returns ErrOccurrenceAlreadySent.
+func (r *BroadcastRepository) CreateBroadcast(ctx context.Context, b *models.Broadcast, deliveries []models.BroadcastDelivery) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO broadcasts (template_id, template_name, channels, target_group, filters, subject, message, recipient_count, created_by,
+		                        schedule_id, scheduled_for)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (schedule_id, scheduled_for) DO NOTHING
+		RETURNING id, status, created_at`,
+		b.TemplateID, b.TemplateName, b.Channels, b.TargetGroup, b.Filters, b.Subject, b.Message, b.RecipientCount, b.CreatedBy,
+		b.ScheduleID, b.ScheduledFor,
+	).Scan(&b.ID, &b.Status, &b.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrOccurrenceAlreadySent
+	}
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]interface{}, 0, len(deliveries))
+	for _, d := range deliveries {
+		params, err := json.Marshal(d.Params)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, []interface{}{b.ID, d.UserID, d.Channel, d.Address, d.Subject, d.Body, params, d.Status, d.LastError})
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"broadcast_deliveries"},
+		[]string{"broadcast_id", "user_id", "channel", "address", "subject", "body", "params", "status", "last_error"},
+		pgx.CopyFromRows(rows))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		WITH enqueued AS (
+			INSERT INTO notify.jobs (provider, kind, source, payload, idempotency_key, user_id, category)
+			SELECT d.channel, $3, 'admin-service',
+			       jsonb_build_object('address', d.address, 'subject', d.subject, 'body', d.body,
+			                          'template_name', $2::text, 'params', d.params) ||
+			       CASE WHEN d.channel = 'PUSH' THEN jsonb_build_object('user_id', d.user_id) ELSE '{}'::jsonb END,
+			       'broadcast-delivery:' || d.id, d.user_id, $4
+			FROM broadcast_deliveries d
+			WHERE d.broadcast_id = $1 AND d.status = 'queued'
+			ON CONFLICT (idempotency_key) DO NOTHING
+			RETURNING id, idempotency_key
+		)
+		UPDATE broadcast_deliveries d
+		SET job_id = e.id
+		FROM enqueued e
+		WHERE d.broadcast_id = $1 AND e.idempotency_key = 'broadcast-delivery:' || d.id`,
+		b.ID, b.TemplateName, models.JobKindBroadcast, models.InboxBroadcast)
+	if err != nil {
+		return err
+	}
+
+	// Every recipient who has not turned in-app broadcasts off also gets it in
+	// their inbox, with the text rendered for their push delivery when there
+	// is one.
+	_, err = tx.Exec(ctx, `
+		INSERT INTO notify.inbox (user_id, category, type, title, body, data, source, dedupe_key)
+		SELECT DISTINCT ON (d.user_id)
+		       d.user_id, $2, 'broadcast', COALESCE(NULLIF(d.subject, ''), 'Announcement'), d.body,
+		       jsonb_build_object('type', 'broadcast', 'broadcast_id', $1::bigint::text), 'admin-service',
+		       'broadcast:' || $1::bigint || ':' || d.user_id
+		FROM broadcast_deliveries d
+		WHERE d.broadcast_id = $1 AND notify.channel_enabled(d.user_id, $2, 'IN_APP')
+		ORDER BY d.user_id, d.channel = 'PUSH' DESC, d.id
+		ON CONFLICT (dedupe_key) DO NOTHING`,
+		b.ID, models.InboxBroadcast)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// SyncDeliveryFromJob mirrors the state of a broadcast's queue job onto its
+// delivery row, so the report reflects retries and final outcomes.
+func (r *BroadcastRepository) SyncDeliveryFromJob(ctx context.Context, job models.NotificationJob) error {
+	status := models.DeliveryQueued
+	switch job.Status {
+	case models.JobRunning:
+		status = models.DeliverySending
+	case models.JobDone:
+		status = models.DeliverySent
+	case models.JobDead:
+		status = models.DeliveryFailed
+	case models.JobSuppressed:
+		status = models.DeliverySkipped
+	}
+	_, err := r.DB.Exec(ctx, `
+		UPDATE broadcast_deliveries
+		SET status = $2, attempts = $3, last_error = $4,
+		    sent_at = CASE WHEN $2 = 'sent' THEN NOW() ELSE sent_at END,
+		    updated_at = NOW()
+		WHERE job_id = $1`, job.ID, status, job.Attempts, job.LastError)
+	return err
+}
+
+// CompleteBroadcasts marks broadcasts whose deliveries have all settled
+// (sent, failed or skipped) as completed.
+func (r *BroadcastRepository) CompleteBroadcasts(ctx context.Context) error {
+	_, err := r.DB.Exec(ctx, `
+		UPDATE broadcasts b SET status = 'completed', completed_at = NOW()
+		WHERE b.status <> 'completed'
+		  AND NOT EXISTS (
+			SELECT 1 FROM broadcast_deliveries d
+			WHERE d.broadcast_id = b.id AND d.status IN ('queued', 'sending')
+		  )`)
+	return err
+}
+
+const broadcastColumns = `id, template_id, template_name, channels, target_group, filters, subject, message,
+	status, recipient_count, created_by, created_at, completed_at, schedule_id, scheduled_for`
+
+func scanBroadcast(row pgx.Row, b *models.Broadcast) error {
+	return row.Scan(&b.ID, &b.TemplateID, &b.TemplateName, &b.Channels, &b.TargetGroup, &b.Filters, &b.Subject, &b.Message,
+		&b.Status, &b.RecipientCount, &b.CreatedBy, &b.CreatedAt, &b.CompletedAt, &b.ScheduleID, &b.ScheduledFor)
+}
+
+// ListBroadcasts returns broadcasts newest first. createdBy, when set, limits
+// the list to one sender (coordinators only see their own broadcasts).
+func (r *BroadcastRepository) ListBroadcasts(ctx context.Context, createdBy *int64, limit, offset int) ([]models.Broadcast, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT `+broadcastColumns+`
+		FROM broadcasts
+		WHERE ($1::bigint IS NULL OR created_by = $1)
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`, createdBy, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	broadcasts := []models.Broadcast{}
+	for rows.Next() {
+		var b models.Broadcast
+		if err := scanBroadcast(rows, &b); err != nil {
+			return nil, err
+		}
+		broadcasts = append(broadcasts, b)
+	}
+	return broadcasts, rows.Err()
+}
+
+func (r *BroadcastRepository) GetBroadcast(ctx context.Context, id int64) (*models.Broadcast, error) {
+	var b models.Broadcast
+	err := scanBroadcast(r.DB.QueryRow(ctx, `SELECT `+broadcastColumns+` FROM broadcasts WHERE id = $1`, id), &b)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBroadcastNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// deliveryReceiptJoin adds rc, the delivery receipts of delivery d's job:
+// the furthest status reached, when it was delivered and read, and the
+// failure reason. Receipts are matched through the message ID the worker
+// stored on the job, so they may arrive before the job completes.
+const deliveryReceiptJoin = `LEFT JOIN notify.jobs j ON j.id = d.job_id
+		LEFT JOIN LATERAL (
+			SELECT CASE
+			           WHEN bool_or(x.status = 'read') THEN 'read'
+			           WHEN bool_or(x.status = 'delivered') THEN 'delivered'
+			           WHEN bool_or(x.status = 'failed') THEN 'failed'
+			           WHEN bool_or(x.status = 'sent') THEN 'sent'
+			       END AS status,
+			       COALESCE(MIN(x.occurred_at) FILTER (WHERE x.status = 'delivered'),
+			                MIN(x.occurred_at) FILTER (WHERE x.status = 'read')) AS delivered_at,
+			       MIN(x.occurred_at) FILTER (WHERE x.status = 'read') AS read_at,
+			       MAX(x.error) FILTER (WHERE x.status = 'failed') AS error
+			FROM notify.delivery_receipts x
+			WHERE x.provider = j.provider AND x.provider_message_id = j.provider_message_id
+		) rc ON TRUE`
+
+// GetBroadcastReport returns the per-channel status summary and one page of
+// deliveries, optionally filtered by status and channel.
+func (r *BroadcastRepository) GetBroadcastReport(ctx context.Context, id int64, status, channel string, limit, offset int) (*models.BroadcastReport, error) {
+	b, err := r.GetBroadcast(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	report := &models.BroadcastReport{
+		Broadcast:  *b,
+		Summary:    map[string]map[string]int{},
+		Receipts:   map[string]map[string]int{},
+		Deliveries: []models.BroadcastDelivery{},
+	}
+
+	rows, err := r.DB.Query(ctx, `
+		SELECT channel, status, COUNT(*)
+		FROM broadcast_deliveries
+		WHERE broadcast_id = $1
+		GROUP BY channel, status`, id)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var ch, st string
+		var n int
+		if err := rows.Scan(&ch, &st, &n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if report.Summary[ch] == nil {
+			report.Summary[ch] = map[string]int{}
+		}
+		report.Summary[ch][st] = n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = r.DB.Query(ctx, `
+		SELECT d.channel, rc.status, COUNT(*)
+		FROM broadcast_deliveries d
+		`+deliveryReceiptJoin+`
+		WHERE d.broadcast_id = $1 AND rc.status IS NOT NULL
+		GROUP BY d.channel, rc.status`, id)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var ch, st string
+		var n int
+		if err := rows.Scan(&ch, &st, &n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if report.Receipts[ch] == nil {
+			report.Receipts[ch] = map[string]int{}
+		}
+		report.Receipts[ch][st] = n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = r.DB.QueryRow(ctx, `
+		SELECT COUNT(*) FROM broadcast_deliveries
+		WHERE broadcast_id = $1 AND ($2 = '' OR status = $2) AND ($3 = '' OR channel = $3)`,
+		id, status, channel).Scan(&report.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = r.DB.Query(ctx, `
+		SELECT d.id, d.broadcast_id, d.user_id, COALESCE(u.name, ''), COALESCE(sp.register_number, ''),
+		       d.channel, d.address, COALESCE(d.subject, ''), d.body, d.status, d.attempts, d.last_error, d.sent_at, d.updated_at,
+		       rc.status, rc.delivered_at, rc.read_at, rc.error
+		FROM broadcast_deliveries d
+		LEFT JOIN users u ON u.id = d.user_id
+		LEFT JOIN student_personal sp ON sp.user_id = d.user_id
+		`+deliveryReceiptJoin+`
+		WHERE d.broadcast_id = $1 AND ($2 = '' OR d.status = $2) AND ($3 = '' OR d.channel = $3)
+		ORDER BY d.id
+		LIMIT $4 OFFSET $5`, id, status, channel, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d models.BroadcastDelivery
+		if err := rows.Scan(&d.ID, &d.BroadcastID, &d.UserID, &d.RecipientName, &d.RegisterNumber,
+			&d.Channel, &d.Address, &d.Subject, &d.Body, &d.Status, &d.Attempts, &d.LastError, &d.SentAt, &d.UpdatedAt,
+			&d.ReceiptStatus, &d.DeliveredAt, &d.ReadAt, &d.ReceiptError); err != nil {
+			return nil, err
+		}
+		report.Deliveries = append(report.Deliveries, d)
+	}
+	return report, rows.Err()
+}
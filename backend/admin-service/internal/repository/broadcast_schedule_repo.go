@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/placement-portal-kec/admin-service/internal/models"
+)
+
+var ErrScheduleNotFound = errors.New("broadcast schedule not found")
+
+const scheduleColumns = `id, template_id, template_name, variables, channels, target_group, filters, department_scope,
+	subject, message, frequency, weekdays, to_char(time_of_day, 'HH24:MI'), timezone, ends_at, next_run_at,
+	status, run_count, last_run_at, last_broadcast_id, last_error, created_by, created_at, cancelled_at`
+
+func scanSchedule(row pgx.Row, s *models.BroadcastSchedule) error {
+	var variables []byte
+	var weekdays []int16
+	err := row.Scan(&s.ID, &s.TemplateID, &s.TemplateName, &variables, &s.Channels, &s.TargetGroup, &s.Filters, &s.DepartmentScope,
+		&s.Subject, &s.Message, &s.Frequency, &weekdays, &s.TimeOfDay, &s.Timezone, &s.EndsAt, &s.NextRunAt,
+		&s.Status, &s.RunCount, &s.LastRunAt, &s.LastBroadcastID, &s.LastError, &s.CreatedBy, &s.CreatedAt, &s.CancelledAt)
+	if err != nil {
+		return err
+	}
+	s.Weekdays = make([]int, len(weekdays))
+	for i, d := range weekdays {
+		s.Weekdays[i] = int(d)
+	}
+	s.Variables = []string{}
+	return json.Unmarshal(variables, &s.Variables)
+}
+
+// CreateSchedule saves a scheduled broadcast; s.NextRunAt is its first send
+func (r *BroadcastRepository) CreateSchedule(ctx context.Context, s *models.BroadcastSchedule) error {
+	variables, err := json.Marshal(s.Variables)
+	if err != nil {
+		return err
+	}
+	return r.DB.QueryRow(ctx, `
+		INSERT INTO broadcast_schedules (template_id, template_name, variables, channels, target_group, filters,
+		                                 department_scope, subject, message, frequency, weekdays, time_of_day,
+		                                 timezone, ends_at, next_run_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12::time, $13, $14, $15, $16)
+		RETURNING id, status, created_at`,
+		s.TemplateID, s.TemplateName, variables, s.Channels, s.TargetGroup, s.Filters,
+		s.DepartmentScope, s.Subject, s.Message, s.Frequency, s.Weekdays, s.TimeOfDay,
+		s.Timezone, s.EndsAt, s.NextRunAt, s.CreatedBy,
+	).Scan(&s.ID, &s.Status, &s.CreatedAt)
+}
+
+// ListSchedules returns schedules, upcoming first. createdBy, when set,
+// limits them to one sender; status filters when not empty.
+func (r *BroadcastRepository) ListSchedules(ctx context.Context, createdBy *int64, status string) ([]models.BroadcastSchedule, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT `+scheduleColumns+`
+		FROM broadcast_schedules
+		WHERE ($1::bigint IS NULL OR created_by = $1) AND ($2 = '' OR status = $2)
+		ORDER BY status = 'scheduled' DESC, next_run_at, id DESC
+		LIMIT 200`, createdBy, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []models.BroadcastSchedule{}
+	for rows.Next() {
+		var s models.BroadcastSchedule
+		if err := scanSchedule(rows, &s); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+// CancelSchedule stops a pending schedule. createdBy, when set, only lets
+// its sender cancel it.
+func (r *BroadcastRepository) CancelSchedule(ctx context.Context, id int64, createdBy *int64) (*models.BroadcastSchedule, error) {
+	var s models.BroadcastSchedule
+	err := scanSchedule(r.DB.QueryRow(ctx, `
+		UPDATE broadcast_schedules
+		SET status = 'cancelled', cancelled_at = NOW(), next_run_at = NULL
+		WHERE id = $1 AND status = 'scheduled' AND ($2::bigint IS NULL OR created_by = $2)
+		RETURNING `+scheduleColumns, id, createdBy), &s)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrScheduleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ScheduleOutcome is the result of sending one schedule occurrence
+type ScheduleOutcome struct {
+	BroadcastID *int64     // broadcast sent for the occurrence, if any
+	NextRunAt   *time.Time // next occurrence; nil completes the schedule
+	Error       string     // recorded as last_error
+	Retry       bool       // keep next_run_at so the occurrence is tried again
+}
+
+// RunDueSchedule locks the earliest due schedule not in skip, calls send
+// with it and records the outcome. The row stays locked while sending so
+// other replicas pass over it; NO KEY UPDATE still lets the occurrence's
+// broadcast reference it. ok is false when nothing is due.
+func (r *BroadcastRepository) RunDueSchedule(ctx context.Context, skip []int64, send func(models.BroadcastSchedule) ScheduleOutcome) (id int64, ok bool, err error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var s models.BroadcastSchedule
+	err = scanSchedule(tx.QueryRow(ctx, `
+		SELECT `+scheduleColumns+`
+		FROM broadcast_schedules
+		WHERE status = 'scheduled' AND next_run_at <= NOW() AND NOT (id = ANY($1::bigint[]))
+		ORDER BY next_run_at
+		LIMIT 1
+		FOR NO KEY UPDATE SKIP LOCKED`, skip), &s)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	out := send(s)
+	if out.Retry {
+		_, err = tx.Exec(ctx, `UPDATE broadcast_schedules SET last_error = $2 WHERE id = $1`, s.ID, out.Error)
+	} else {
+		_, err = tx.Exec(ctx, `
+			UPDATE broadcast_schedules
+			SET run_count = run_count + CASE WHEN $2::bigint IS NULL THEN 0 ELSE 1 END,
+			    last_run_at = NOW(),
+			    last_broadcast_id = COALESCE($2, last_broadcast_id),
+			    last_error = NULLIF($3, ''),
+			    next_run_at = $4,
+			    status = CASE WHEN $4::timestamptz IS NULL THEN 'completed' ELSE 'scheduled' END
+			WHERE id = $1`, s.ID, out.BroadcastID, out.Error, out.NextRunAt)
+	}
+	if err != nil {
+		return s.ID, true, err
+	}
+	return s.ID, true, tx.Commit(ctx)
+}
+
+// ReminderEnabled reports whether an automatic drive reminder kind is on.
+// They are on unless system setting broadcast_reminder_<kind> is "false".
+func (r *BroadcastRepository) ReminderEnabled(ctx context.Context, kind string) (bool, error) {
+	var value string
+	err := r.DB.QueryRow(ctx, `SELECT COALESCE(value, '') FROM system_settings WHERE key = $1`,
+		"broadcast_reminder_"+kind).Scan(&value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return true, nil
+	}
+	return value != "false", err
+}
+
+// driveStartSQL is when a drive is taken to start: 9 AM college time on
+// drive_date, which has no time of day
+const driveStartSQL = `((pd.drive_date + TIME '09:00') AT TIME ZONE 'Asia/Kolkata')`
+
+// DueDriveReminders returns the drives within 24 hours of their start that
+// have not had that reminder yet. Drives created inside the
+// window are left alone; their announcement is recent enough.
+func (r *BroadcastRepository) DueDriveReminders(ctx context.Context, kinds []string) ([]models.DriveReminder, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT pd.id, k.kind, pd.company_name, pd.location, pd.drive_date
+		FROM placement_drives pd
+		CROSS JOIN LATERAL (VALUES
+			('drive_date', `+driveStartSQL+`, COALESCE(pd.status, '') NOT IN ('cancelled', 'on_hold'))
+		) AS k(kind, at, active)
+		WHERE k.kind = ANY($1) AND k.active
+		  AND k.at > NOW() AND k.at <= NOW() + INTERVAL '24 hours'
+		  AND pd.created_at <= k.at - INTERVAL '24 hours'
+		  AND NOT EXISTS (SELECT 1 FROM drive_reminders dr WHERE dr.drive_id = pd.id AND dr.kind = k.kind)
+		ORDER BY k.at`, kinds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []models.DriveReminder
+	for rows.Next() {
+		var d models.DriveReminder
+		if err := rows.Scan(&d.DriveID, &d.Kind, &d.CompanyName, &d.Location, &d.DriveDate); err != nil {
+			return nil, err
+		}
+		due = append(due, d)
+	}
+	return due, rows.Err()
+}
+
+// ClaimDriveReminder records that a drive's reminder is being sent. It
+// returns false when another replica already claimed it.
+func (r *BroadcastRepository) ClaimDriveReminder(ctx context.Context, driveID int64, kind string) (bool, error) {
+	tag, err := r.DB.Exec(ctx, `
+		INSERT INTO drive_reminders (drive_id, kind) VALUES ($1, $2)
+		ON CONFLICT (drive_id, kind) DO NOTHING`, driveID, kind)
+	return tag.RowsAffected() == 1, err
+}
+
+// FinishDriveReminder links a claimed reminder to its broadcast (nil when
+// nobody needed it), or with release drops the claim so the next run tries
+// again.
+func (r *BroadcastRepository) FinishDriveReminder(ctx context.Context, driveID int64, kind string, broadcastID *int64, release bool) error {
+	if release {
+		_, err := r.DB.Exec(ctx, `DELETE FROM drive_reminders WHERE drive_id = $1 AND kind = $2`, driveID, kind)
+		return err
+	}
+	_, err := r.DB.Exec(ctx, `
+		UPDATE drive_reminders SET broadcast_id = $3, sent_at = NOW()
+		WHERE drive_id = $1 AND kind = $2`, driveID, kind, broadcastID)
+	return err
+}
+
+// DriveReminderRecipients returns who a drive reminder goes to: for the
+// drive date, students who opted in or were shortlisted.
+func (r *BroadcastRepository) DriveReminderRecipients(ctx context.Context, driveID int64, kind string) ([]models.BroadcastRecipient, error) {
+	var filter string
+	switch kind {
+	case models.ReminderDriveDate:
+		filter = `
+		  AND EXISTS (SELECT 1 FROM drive_applications da
+		              WHERE da.drive_id = pd.id AND da.student_id = u.id AND da.status IN ('opted_in', 'shortlisted'))`
+	default:
+		return nil, errors.New("unknown reminder kind: " + kind)
+	}
+
+	rows, err := r.DB.Query(ctx, `
+		SELECT `+recipientColumns+`
+		FROM users u
+		JOIN student_personal sp ON u.id = sp.user_id
+		JOIN placement_drives pd ON pd.id = $1
+		WHERE u.role = 'student' AND COALESCE(u.is_active, TRUE) AND NOT COALESCE(u.is_blocked, FALSE)`+filter+`
+		ORDER BY sp.register_number`, driveID)
+	if err != nil {
+		return nil, err
+	}
+	return scanRecipients(rows)
+}
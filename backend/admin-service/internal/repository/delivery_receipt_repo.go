@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// DeliveryReceipt is a provider's report on a sent message
+type DeliveryReceipt struct {
+	Provider          string // models.BroadcastChannel*
+	ProviderMessageID string
+	Recipient         string
+	Status            string // sent, delivered, read or failed
+	Error             string
+	OccurredAt        time.Time
+}
+
+// RecordDeliveryReceipt stores a receipt, ignoring one already recorded
+// (webhooks are retried). It reports whether the receipt was new.
+func (r *NotificationJobRepository) RecordDeliveryReceipt(ctx context.Context, rc DeliveryReceipt) (bool, error) {
+	tag, err := r.DB.Exec(ctx, `
+		INSERT INTO notify.delivery_receipts (provider, provider_message_id, recipient, status, error, occurred_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), $6)
+		ON CONFLICT (provider, provider_message_id, status) DO NOTHING`,
+		rc.Provider, rc.ProviderMessageID, rc.Recipient, rc.Status, rc.Error, rc.OccurredAt)
+	return tag.RowsAffected() == 1, err
+}
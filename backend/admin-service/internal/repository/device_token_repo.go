@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DeviceTokenRepository manages push device tokens in notify.device_tokens.
+// It is the token store behind the FCM delivery provider.
+type DeviceTokenRepository struct {
+	DB *pgxpool.Pool
+}
+
+func NewDeviceTokenRepository(db *pgxpool.Pool) *DeviceTokenRepository {
+	return &DeviceTokenRepository{DB: db}
+}
+
+// RegisterDeviceToken records token as one of userID's devices, taking it
+// over from another user if the device changed hands, and keeps
+// users.fcm_token pointing at the latest one.
+func (r *DeviceTokenRepository) RegisterDeviceToken(ctx context.Context, userID int64, token, platform string) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO notify.device_tokens (user_id, token, platform)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (token)
+		DO UPDATE SET user_id = EXCLUDED.user_id, platform = EXCLUDED.platform, last_seen_at = NOW()`,
+		userID, token, platform)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE users SET fcm_token = $1 WHERE id = $2`, token, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UserDeviceTokens returns userID's tokens, most recently seen first.
+func (r *DeviceTokenRepository) UserDeviceTokens(ctx context.Context, userID int64) ([]string, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT token FROM notify.device_tokens
+		WHERE user_id = $1
+		ORDER BY last_seen_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []string
+	for rows.Next() {
+		var token string
+		if err := rows.Scan(&token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// DeleteDeviceTokens removes tokens FCM no longer accepts, and clears them
+// from users.fcm_token too.
+func (r *DeviceTokenRepository) DeleteDeviceTokens(ctx context.Context, tokens []string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM notify.device_tokens WHERE token = ANY($1)`, tokens); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE users SET fcm_token = NULL WHERE fcm_token = ANY($1)`, tokens); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
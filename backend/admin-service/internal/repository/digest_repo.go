@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/placement-portal-kec/admin-service/internal/models"
+)
+
+type DigestRepository struct {
+	DB *pgxpool.Pool
+}
+
+func NewDigestRepository(db *pgxpool.Pool) *DigestRepository {
+	return &DigestRepository{DB: db}
+}
+
+// DueDigests returns up to limit users due a digest on day: active students
+// interested in placement and coordinators with a department, who have not
+// turned digest emails off and have not had one on day yet. Daily digests
+// are due every day, weekly ones only when weekly is set.
+func (r *DigestRepository) DueDigests(ctx context.Context, day time.Time, weekly bool, limit int) ([]models.DigestRecipient, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT u.id, COALESCE(u.name, ''), u.email, u.role,
+		       COALESCE(CASE WHEN u.role = 'student' THEN sp.department ELSE u.department_code END, ''),
+		       COALESCE(ds.frequency, 'weekly'),
+		       (SELECT MAX(d.created_at) FROM notify.digests d WHERE d.user_id = u.id)
+		FROM users u
+		LEFT JOIN student_personal sp ON sp.user_id = u.id
+		LEFT JOIN notify.digest_settings ds ON ds.user_id = u.id
+		WHERE u.is_active = true AND u.email <> ''
+		  AND ((u.role = 'student' AND sp.user_id IS NOT NULL
+		        AND (sp.placement_willingness IS NULL OR sp.placement_willingness = 'Interested'))
+		       OR (u.role = 'coordinator' AND COALESCE(u.department_code, '') <> ''))
+		  AND (COALESCE(ds.frequency, 'weekly') = 'daily' OR $2)
+		  AND notify.channel_enabled(u.id, 'digest', 'EMAIL')
+		  AND NOT EXISTS (SELECT 1 FROM notify.digests d WHERE d.user_id = u.id AND d.digest_date = $1)
+		ORDER BY u.id
+		LIMIT $3`, day, weekly, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []models.DigestRecipient
+	for rows.Next() {
+		var d models.DigestRecipient
+		if err := rows.Scan(&d.UserID, &d.Name, &d.Email, &d.Role, &d.Department, &d.Frequency, &d.Since); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, d)
+	}
+	return recipients, rows.Err()
+}
+
+// ClaimDigest records userID's digest for day before it is built, so
+// concurrent runs never send two. It reports false when it was already
+// claimed.
+func (r *DigestRepository) ClaimDigest(ctx context.Context, d models.DigestRecipient, day, periodStart time.Time) (bool, error) {
+	kind := models.DigestStudent
+	if d.Role == "coordinator" {
+		kind = models.DigestCoordinator
+	}
+	tag, err := r.DB.Exec(ctx, `
+		INSERT INTO notify.digests (user_id, digest_date, kind, frequency, period_start)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, digest_date) DO NOTHING`,
+		d.UserID, day, kind, d.Frequency, periodStart)
+	return tag.RowsAffected() == 1, err
+}
+
+// FinishDigest links a claimed digest to the job sending it
+func (r *DigestRepository) FinishDigest(ctx context.Context, userID int64, day time.Time, jobID int64) error {
+	_, err := r.DB.Exec(ctx, `
+		UPDATE notify.digests SET job_id = $3 WHERE user_id = $1 AND digest_date = $2`, userID, day, jobID)
+	return err
+}
+
+// ReleaseDigest forgets a claimed digest that could not be queued, so the
+// next run tries again.
+func (r *DigestRepository) ReleaseDigest(ctx context.Context, userID int64, day time.Time) error {
+	_, err := r.DB.Exec(ctx, `
+		DELETE FROM notify.digests WHERE user_id = $1 AND digest_date = $2`, userID, day)
+	return err
+}
+
+// NewDepartmentDrives returns the open drives posted since that dept's
+// students may apply to, soonest deadline first.
+func (r *DigestRepository) NewDepartmentDrives(ctx context.Context, dept string, since time.Time) ([]models.DigestDrive, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT pd.id, pd.company_name, pd.deadline_date
+		FROM placement_drives pd
+		WHERE pd.status = 'open' AND pd.created_at > $2
+		  AND (NOT EXISTS (SELECT 1 FROM drive_eligible_departments ded WHERE ded.drive_id = pd.id)
+		       OR EXISTS (SELECT 1 FROM drive_eligible_departments ded WHERE ded.drive_id = pd.id AND ded.department_code = $1))
+		ORDER BY pd.deadline_date`, dept, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var drives []models.DigestDrive
+	for rows.Next() {
+		var d models.DigestDrive
+		if err := rows.Scan(&d.ID, &d.CompanyName, &d.DeadlineDate); err != nil {
+			return nil, err
+		}
+		drives = append(drives, d)
+	}
+	return drives, rows.Err()
+}
+
+// IncompleteProfiles returns dept's active students, by register number,
+// who have not filled in everything onboarding collects: mobile number,
+// date of birth, gender and profile photo (a blank or 'NA' counts as
+// missing, as in UserRepository.IsStudentProfileComplete).
+func (r *DigestRepository) IncompleteProfiles(ctx context.Context, dept string) ([]models.DigestStudentRef, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT u.id, COALESCE(u.name, ''), COALESCE(sp.register_number, '')
+		FROM users u
+		JOIN student_personal sp ON sp.user_id = u.id
+		WHERE u.role = 'student' AND u.is_active = true AND sp.department = $1
+		  AND (COALESCE(sp.mobile_number, '') IN ('', 'NA')
+		    OR COALESCE(sp.dob::text, '') IN ('', 'NA')
+		    OR COALESCE(sp.gender, '') IN ('', 'NA')
+		    OR COALESCE(u.profile_photo_url, '') IN ('', 'NA'))
+		ORDER BY sp.register_number`, dept)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var students []models.DigestStudentRef
+	for rows.Next() {
+		var s models.DigestStudentRef
+		if err := rows.Scan(&s.UserID, &s.Name, &s.RegisterNumber); err != nil {
+			return nil, err
+		}
+		students = append(students, s)
+	}
+	return students, rows.Err()
+}
@@ -902,8 +902,9 @@ func (r *DriveRepository) BulkUpdateApplicationStatus(ctx context.Context, reque
 	return affected, nil
 }
 
-// GetDriveApplicants fetches all students applied to a drive
-func (r *DriveRepository) GetDriveApplicants(ctx context.Context, driveID int64) ([]models.DriveApplicant, error) {
+// GetDriveApplicants fetches all students applied to a drive, limited to
+// deptScope when set
+func (r *DriveRepository) GetDriveApplicants(ctx context.Context, driveID int64, deptScope *string) ([]models.DriveApplicant, error) {
 	query := `
         SELECT 
             u.id, COALESCE(u.name, ''), COALESCE(sp.register_number, ''), u.email, COALESCE(sp.department, ''), 
@@ -913,11 +914,11 @@ func (r *DriveRepository) GetDriveApplicants(ctx context.Context, driveID int64)
         JOIN student_personal sp ON u.id = sp.user_id
         LEFT JOIN student_degrees d_ug ON u.id = d_ug.user_id AND d_ug.degree_level = 'UG'
         LEFT JOIN student_documents sd ON u.id = sd.user_id
-        WHERE da.drive_id = $1
+        WHERE da.drive_id = $1 AND ($2::text IS NULL OR sp.department = $2)
         ORDER BY da.applied_at DESC
     `
 
-	rows, err := r.DB.Query(ctx, query, driveID)
+	rows, err := r.DB.Query(ctx, query, driveID, deptScope)
 	if err != nil {
 		return nil, err
 	}
@@ -1137,12 +1138,11 @@ func (r *DriveRepository) GetEligibleStudentsPreview(ctx context.Context, input
 	return students, nil
 }
 
-// GetAdminFCMTokens fetches FCM tokens of all active admin users
-func (r *DriveRepository) GetAdminFCMTokens(ctx context.Context) ([]string, error) {
+// GetAdminUserIDs fetches the IDs of all active admin users
+func (r *DriveRepository) GetAdminUserIDs(ctx context.Context) ([]int64, error) {
 	query := `
-		SELECT fcm_token FROM users
+		SELECT id FROM public.users
 		WHERE role = 'admin' AND is_active = true
-		AND fcm_token IS NOT NULL AND fcm_token != ''
 	`
 	rows, err := r.DB.Query(ctx, query)
 	if err != nil {
@@ -1150,14 +1150,14 @@ func (r *DriveRepository) GetAdminFCMTokens(ctx context.Context) ([]string, erro
 	}
 	defer rows.Close()
 
-	var tokens []string
+	var ids []int64
 	for rows.Next() {
-		var token string
-		if err := rows.Scan(&token); err == nil {
-			tokens = append(tokens, token)
+		var id int64
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
 		}
 	}
-	return tokens, nil
+	return ids, nil
 }
 
 // GetDriveApplicantsDetailed fetches detailed profile of students who applied to a drive
@@ -1330,7 +1330,7 @@ func (r *DriveRepository) GetStudentFCMToken(ctx context.Context, studentID int6
 }
 
 // GetDriveRequests fetches all applications with status 'request_to_attend'
-func (r *DriveRepository) GetDriveRequests(ctx context.Context) ([]models.DriveApplicant, error) {
+func (r *DriveRepository) GetDriveRequests(ctx context.Context, deptScope *string) ([]models.DriveApplicant, error) {
 	query := `
         SELECT 
             pd.id, pd.company_name,
@@ -1357,11 +1357,11 @@ func (r *DriveRepository) GetDriveRequests(ctx context.Context) ([]models.DriveA
         LEFT JOIN student_degrees d_ug ON u.id = d_ug.user_id AND d_ug.degree_level = 'UG'
         LEFT JOIN student_degrees d_pg ON u.id = d_pg.user_id AND d_pg.degree_level = 'PG'
         LEFT JOIN student_documents sd ON u.id = sd.user_id
-        WHERE da.status = 'request_to_attend'
+        WHERE da.status = 'request_to_attend' AND ($1::text IS NULL OR sp.department = $1)
         ORDER BY da.applied_at DESC
     `
 
-	rows, err := r.DB.Query(ctx, query)
+	rows, err := r.DB.Query(ctx, query, deptScope)
 	if err != nil {
 		return nil, err
 	}
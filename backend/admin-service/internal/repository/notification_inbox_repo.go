@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// UserNotification is an in-app notification for a set of users. Each user
+// gets a notify.inbox row unless they turned in-app notifications for the
+// category off, and users with a registered device also get a PUSH job
+// carrying the same title, body and data for all of their devices, which the
+// workers check against their preferences and quiet hours.
+type UserNotification struct {
+	Category string // models.Inbox*
+	Type     string // event type, also the job kind and data["type"]
+	Title    string
+	Body     string
+	Data     map[string]string
+	// DedupeKey, when set, is suffixed with each user ID so notifying the same
+	// event twice leaves one inbox item (and one push) per user.
+	DedupeKey string
+	// Critical notifications (round reschedules, cancellations) are pushed
+	// even during the recipient's quiet hours.
+	Critical bool
+}
+
+// NotifyUsers writes n to each user's inbox and queues the matching pushes.
+func (r *NotificationJobRepository) NotifyUsers(ctx context.Context, userIDs []int64, n UserNotification) error {
+	return notifyUsers(ctx, r.DB, userIDs, n)
+}
+
+// execer is satisfied by both *pgxpool.Pool and pgx.Tx.
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+func notifyUsers(ctx context.Context, db execer, userIDs []int64, n UserNotification) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	data := n.Data
+	if data == nil {
+		data = map[string]string{}
+	}
+	if _, ok := data["type"]; !ok {
+		data["type"] = n.Type
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(ctx, `
+		WITH recipients AS (
+			SELECT DISTINCT unnest($1::bigint[]) AS user_id
+		), inboxed AS (
+			INSERT INTO notify.inbox (user_id, category, type, title, body, data, source, dedupe_key)
+			SELECT r.user_id, $2, $3, $4, $5, $6::jsonb, 'admin-service',
+			       CASE WHEN $7 = '' THEN NULL ELSE $7 || ':' || r.user_id END
+			FROM recipients r
+			WHERE notify.channel_enabled(r.user_id, $2, 'IN_APP')
+			ON CONFLICT (dedupe_key) DO NOTHING
+			RETURNING id, user_id
+		)
+		INSERT INTO notify.jobs (provider, kind, source, payload, idempotency_key, user_id, category, critical)
+		SELECT 'PUSH', $3, 'admin-service',
+		       jsonb_build_object('user_id', r.user_id, 'address', d.token, 'subject', $4::text, 'body', $5::text,
+		                          'data', $6::jsonb || jsonb_strip_nulls(jsonb_build_object('notification_id', i.id::text))),
+		       CASE WHEN $7 = '' THEN NULL ELSE 'push:' || $7 || ':' || r.user_id END,
+		       r.user_id, $2, $8
+		FROM recipients r
+		JOIN LATERAL (
+			SELECT token FROM notify.device_tokens
+			WHERE user_id = r.user_id ORDER BY last_seen_at DESC LIMIT 1
+		) d ON TRUE
+		LEFT JOIN inboxed i ON i.user_id = r.user_id
+		ON CONFLICT (idempotency_key) DO NOTHING`,
+		userIDs, n.Category, n.Type, n.Title, n.Body, encoded, n.DedupeKey, n.Critical)
+	return err
+}
@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/placement-portal-kec/admin-service/internal/models"
+)
+
+var (
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	ErrAlreadyRequeued    = errors.New("dead letter already requeued")
+)
+
+// defaultMaxAttempts matches the notify.jobs.max_attempts column default.
+const defaultMaxAttempts = 5
+
+// OutboundNotification describes a job to add to notify.jobs. Payload is
+// marshalled to JSON as-is (normally a services.DeliveryMessage). UserID and
+// Category, when set, make the job subject to the recipient's preferences.
+type OutboundNotification struct {
+	Provider       string
+	Kind           string
+	Source         string
+	Payload        interface{}
+	IdempotencyKey string
+	MaxAttempts    int
+	UserID         int64
+	Category       string
+	Critical       bool
+}
+
+// queryRower is satisfied by both *pgxpool.Pool and pgx.Tx, so jobs can be
+// enqueued in the same transaction as the record they notify about.
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type NotificationJobRepository struct {
+	DB *pgxpool.Pool
+}
+
+func NewNotificationJobRepository(db *pgxpool.Pool) *NotificationJobRepository {
+	return &NotificationJobRepository{DB: db}
+}
+
+// Enqueue adds a job and returns its ID. When a job with the same idempotency
+// key already exists the existing job's ID is returned and nothing is added.
+func (r *NotificationJobRepository) Enqueue(ctx context.Context, n OutboundNotification) (int64, error) {
+	return enqueueNotification(ctx, r.DB, n)
+}
+
+func enqueueNotification(ctx context.Context, q queryRower, n OutboundNotification) (int64, error) {
+	payload, err := json.Marshal(n.Payload)
+	if err != nil {
+		return 0, err
+	}
+	var key *string
+	if n.IdempotencyKey != "" {
+		key = &n.IdempotencyKey
+	}
+	maxAttempts := n.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	var id int64
+	err = q.QueryRow(ctx, `
+		WITH inserted AS (
+			INSERT INTO notify.jobs (provider, kind, source, payload, idempotency_key, max_attempts, user_id, category, critical)
+			VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, 0), NULLIF($8, ''), $9)
+			ON CONFLICT (idempotency_key) DO NOTHING
+			RETURNING id
+		)
+		SELECT id FROM inserted
+		UNION ALL
+		SELECT id FROM notify.jobs WHERE idempotency_key = $5 AND NOT EXISTS (SELECT 1 FROM inserted)
+		LIMIT 1`,
+		n.Provider, n.Kind, n.Source, payload, key, maxAttempts, n.UserID, n.Category, n.Critical,
+	).Scan(&id)
+	return id, err
+}
+
+const jobColumns = `id, provider, kind, source, payload, idempotency_key, user_id, category, critical, status,
+	attempts, max_attempts, run_at, last_error, created_at, updated_at, completed_at, provider_message_id`
+
+func scanJobs(rows pgx.Rows) ([]models.NotificationJob, error) {
+	defer rows.Close()
+	jobs := []models.NotificationJob{}
+	for rows.Next() {
+		var j models.NotificationJob
+		if err := rows.Scan(&j.ID, &j.Provider, &j.Kind, &j.Source, &j.Payload, &j.IdempotencyKey, &j.UserID,
+			&j.Category, &j.Critical, &j.Status, &j.Attempts, &j.MaxAttempts, &j.RunAt, &j.LastError, &j.CreatedAt, &j.UpdatedAt, &j.CompletedAt,
+			&j.ProviderMessageID); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// ClaimJobs moves up to limit due jobs for provider to 'running' and returns
+// them. SKIP LOCKED lets every worker in every replica claim concurrently
+// without handing the same job out twice.
+func (r *NotificationJobRepository) ClaimJobs(ctx context.Context, provider, workerID string, limit int) ([]models.NotificationJob, error) {
+	rows, err := r.DB.Query(ctx, `
+		UPDATE notify.jobs j
+		SET status = 'running', attempts = j.attempts + 1, locked_at = NOW(), locked_by = $2, updated_at = NOW()
+		WHERE j.id IN (
+			SELECT id FROM notify.jobs
+			WHERE provider = $1 AND status = 'queued' AND run_at <= NOW()
+			ORDER BY run_at, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+jobColumns, provider, workerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanJobs(rows)
+}
+
+// ReleaseStaleJobs queues again jobs left 'running' by a worker that died
+// mid-send. The attempt they used still counts.
+func (r *NotificationJobRepository) ReleaseStaleJobs(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	tag, err := r.DB.Exec(ctx, `
+		UPDATE notify.jobs
+		SET status = 'queued', locked_at = NULL, locked_by = NULL, updated_at = NOW()
+		WHERE status = 'running' AND locked_at < $1`, lockedBefore)
+	return tag.RowsAffected(), err
+}
+
+// CompleteJob marks a job sent, keeping the provider's message ID if it
+// gave one.
+func (r *NotificationJobRepository) CompleteJob(ctx context.Context, id int64, providerMessageID string) error {
+	_, err := r.DB.Exec(ctx, `
+		UPDATE notify.jobs
+		SET status = 'done', completed_at = NOW(), last_error = NULL, locked_at = NULL, locked_by = NULL, updated_at = NOW(),
+		    provider_message_id = NULLIF($2, '')
+		WHERE id = $1`, id, providerMessageID)
+	return err
+}
+
+// RetryJob queues a failed job again for runAt.
+func (r *NotificationJobRepository) RetryJob(ctx context.Context, id int64, sendErr string, runAt time.Time) error {
+	_, err := r.DB.Exec(ctx, `
+		UPDATE notify.jobs
+		SET status = 'queued', run_at = $3, last_error = $2, locked_at = NULL, locked_by = NULL, updated_at = NOW()
+		WHERE id = $1`, id, sendErr, runAt)
+	return err
+}
+
+// SuppressJob finishes a job without sending it, e.g. because the recipient
+// turned the channel off.
+func (r *NotificationJobRepository) SuppressJob(ctx context.Context, id int64, reason string) error {
+	_, err := r.DB.Exec(ctx, `
+		UPDATE notify.jobs
+		SET status = 'suppressed', completed_at = NOW(), last_error = $2, locked_at = NULL, locked_by = NULL, updated_at = NOW()
+		WHERE id = $1`, id, reason)
+	return err
+}
+
+// DeferJob queues a claimed job again for runAt without counting the claim
+// as an attempt.
+func (r *NotificationJobRepository) DeferJob(ctx context.Context, id int64, runAt time.Time) error {
+	_, err := r.DB.Exec(ctx, `
+		UPDATE notify.jobs
+		SET status = 'queued', run_at = $2, attempts = GREATEST(attempts - 1, 0), locked_at = NULL, locked_by = NULL, updated_at = NOW()
+		WHERE id = $1`, id, runAt)
+	return err
+}
+
+// DeadLetterJob marks a job dead and records it in notify.dead_letters.
+func (r *NotificationJobRepository) DeadLetterJob(ctx context.Context, id int64, sendErr string) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE notify.jobs
+		SET status = 'dead', last_error = $2, locked_at = NULL, locked_by = NULL, updated_at = NOW()
+		WHERE id = $1`, id, sendErr)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO notify.dead_letters (job_id, provider, kind, source, payload, attempts, last_error)
+		SELECT id, provider, kind, source, payload, attempts, last_error
+		FROM notify.jobs WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// PruneCompletedJobs deletes jobs that finished before the cutoff. Dead jobs
+// are kept for their dead letters.
+func (r *NotificationJobRepository) PruneCompletedJobs(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.DB.Exec(ctx, `DELETE FROM notify.jobs WHERE status IN ('done', 'suppressed') AND completed_at < $1`, before)
+	return tag.RowsAffected(), err
+}
+
+// ListJobs returns jobs newest first, optionally filtered by status and provider.
+func (r *NotificationJobRepository) ListJobs(ctx context.Context, status, provider string, limit, offset int) ([]models.NotificationJob, int64, error) {
+	var total int64
+	err := r.DB.QueryRow(ctx, `
+		SELECT COUNT(*) FROM notify.jobs
+		WHERE ($1 = '' OR status = $1) AND ($2 = '' OR provider = $2)`, status, provider).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.DB.Query(ctx, `
+		SELECT `+jobColumns+` FROM notify.jobs
+		WHERE ($1 = '' OR status = $1) AND ($2 = '' OR provider = $2)
+		ORDER BY id DESC
+		LIMIT $3 OFFSET $4`, status, provider, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	jobs, err := scanJobs(rows)
+	return jobs, total, err
+}
+
+// GetJobCounts returns the number of jobs per provider and status.
+func (r *NotificationJobRepository) GetJobCounts(ctx context.Context) (map[string]map[string]int, error) {
+	rows, err := r.DB.Query(ctx, `SELECT provider, status, COUNT(*) FROM notify.jobs GROUP BY provider, status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]map[string]int{}
+	for rows.Next() {
+		var provider, status string
+		var n int
+		if err := rows.Scan(&provider, &status, &n); err != nil {
+			return nil, err
+		}
+		if counts[provider] == nil {
+			counts[provider] = map[string]int{}
+		}
+		counts[provider][status] = n
+	}
+	return counts, rows.Err()
+}
+
+// ListDeadLetters returns dead letters newest first. Unless includeRequeued
+// is set, only those not yet requeued are returned.
+func (r *NotificationJobRepository) ListDeadLetters(ctx context.Context, includeRequeued bool, limit, offset int) ([]models.DeadLetter, int64, error) {
+	var total int64
+	err := r.DB.QueryRow(ctx, `
+		SELECT COUNT(*) FROM notify.dead_letters
+		WHERE $1 OR requeued_at IS NULL`, includeRequeued).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.DB.Query(ctx, `
+		SELECT id, job_id, provider, kind, source, payload, attempts, last_error, failed_at, requeued_at, requeued_by
+		FROM notify.dead_letters
+		WHERE $1 OR requeued_at IS NULL
+		ORDER BY failed_at DESC
+		LIMIT $2 OFFSET $3`, includeRequeued, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	letters := []models.DeadLetter{}
+	for rows.Next() {
+		var d models.DeadLetter
+		if err := rows.Scan(&d.ID, &d.JobID, &d.Provider, &d.Kind, &d.Source, &d.Payload, &d.Attempts,
+			&d.LastError, &d.FailedAt, &d.RequeuedAt, &d.RequeuedBy); err != nil {
+			return nil, 0, err
+		}
+		letters = append(letters, d)
+	}
+	return letters, total, rows.Err()
+}
+
+// RequeueDeadLetter gives the dead letter's job a fresh set of attempts and
+// returns the job.
+func (r *NotificationJobRepository) RequeueDeadLetter(ctx context.Context, id, requeuedBy int64) (*models.NotificationJob, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var jobID int64
+	var requeuedAt *time.Time
+	err = tx.QueryRow(ctx, `SELECT job_id, requeued_at FROM notify.dead_letters WHERE id = $1 FOR UPDATE`, id).Scan(&jobID, &requeuedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if requeuedAt != nil {
+		return nil, ErrAlreadyRequeued
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE notify.dead_letters SET requeued_at = NOW(), requeued_by = $2 WHERE id = $1`, id, requeuedBy); err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, `
+		UPDATE notify.jobs
+		SET status = 'queued', attempts = 0, run_at = NOW(), completed_at = NULL, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+jobColumns, jobID)
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := scanJobs(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, ErrDeadLetterNotFound
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/placement-portal-kec/admin-service/internal/models"
+)
+
+// DeliveryPolicy reports whether userID wants category notifications over
+// channel and, when they are in their quiet hours right now, when those end.
+func (r *NotificationJobRepository) DeliveryPolicy(ctx context.Context, userID int64, category, channel string) (bool, *time.Time, error) {
+	var enabled bool
+	var quietUntil *time.Time
+	err := r.DB.QueryRow(ctx,
+		`SELECT notify.channel_enabled($1, $2, $3), notify.quiet_until($1, NOW())`,
+		userID, category, channel,
+	).Scan(&enabled, &quietUntil)
+	return enabled, quietUntil, err
+}
+
+// SetWhatsAppSubscription switches WhatsApp notifications of every category
+// on or off for the students whose mobile number is number (a WhatsApp ID,
+// digits with country code), as when they reply STOP or START. It returns
+// the students it updated.
+func (r *NotificationJobRepository) SetWhatsAppSubscription(ctx context.Context, number string, enabled bool) ([]int64, error) {
+	rows, err := r.DB.Query(ctx, `
+		WITH students AS (
+			SELECT sp.user_id FROM student.student_personal sp
+			WHERE `+matchesWhatsAppNumber("$1")+`
+		)
+		INSERT INTO notify.preferences (user_id, category, channel, enabled, updated_at)
+		SELECT s.user_id, c.category, 'WHATSAPP', $2, NOW()
+		FROM students s CROSS JOIN unnest($3::text[]) AS c(category)
+		ON CONFLICT (user_id, category, channel) DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = NOW()
+		RETURNING user_id`, number, enabled, models.InboxCategories)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		if !slices.Contains(userIDs, id) {
+			userIDs = append(userIDs, id)
+		}
+	}
+	return userIDs, rows.Err()
+}
+
+// matchesWhatsAppNumber is a condition on student_personal sp matching the
+// WhatsApp ID in param. Stored numbers vary in formatting and country code,
+// so the last ten digits are compared.
+func matchesWhatsAppNumber(param string) string {
+	return `length(` + param + `) >= 10
+	  AND right(regexp_replace(COALESCE(sp.mobile_number, ''), '\D', '', 'g'), 10) = right(` + param + `, 10)`
+}
@@ -27,13 +27,14 @@ func (r *StudentRepository) CreateStudent(ctx context.Context, user *models.User
 
 	// 1. Insert into Users
 	var userID int64
-	queryUser := `INSERT INTO users (email, password_hash, role, is_active, created_at, updated_at) 
-                  VALUES ($1, $2, $3, $4, NOW(), NOW()) RETURNING id`
+	queryUser := `INSERT INTO users (email, password_hash, role, is_active, must_change_password, created_at, updated_at) 
+                  VALUES ($1, $2, $3, $4, TRUE, NOW(), NOW()) RETURNING id`
 
 	err = tx.QueryRow(ctx, queryUser, user.Email, user.PasswordHash, user.Role, user.IsActive).Scan(&userID)
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
+	user.ID = userID
 
 	// 2. Insert into Student Personal
 	// Note: We are setting the initial data provided by admin
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/placement-portal-kec/admin-service/internal/models"
+)
+
+var ErrSupportMessageNotFound = errors.New("support message not found")
+
+type SupportRepository struct {
+	DB *pgxpool.Pool
+}
+
+func NewSupportRepository(db *pgxpool.Pool) *SupportRepository {
+	return &SupportRepository{DB: db}
+}
+
+const supportColumns = `m.id, m.channel, m.from_address, m.contact_name, m.user_id, u.name, sp.register_number, sp.department,
+	m.provider_message_id, m.message_type, m.body, m.received_at, m.status, m.reply, m.replied_by, m.replied_at`
+
+const supportFrom = `support_messages m
+	LEFT JOIN users u ON u.id = m.user_id
+	LEFT JOIN student_personal sp ON sp.user_id = m.user_id`
+
+func scanSupportMessage(row pgx.Row, m *models.SupportMessage) error {
+	return row.Scan(&m.ID, &m.Channel, &m.FromAddress, &m.ContactName, &m.UserID, &m.StudentName, &m.RegisterNumber, &m.Department,
+		&m.ProviderMessageID, &m.MessageType, &m.Body, &m.ReceivedAt, &m.Status, &m.Reply, &m.RepliedBy, &m.RepliedAt)
+}
+
+// SaveSupportMessage stores an incoming message, linking it to the student
+// whose mobile number matches the sender. A message already stored (the
+// webhook was retried) is ignored; saved reports whether it was new.
+func (r *SupportRepository) SaveSupportMessage(ctx context.Context, m *models.SupportMessage) (saved bool, err error) {
+	err = r.DB.QueryRow(ctx, `
+		INSERT INTO support_messages (channel, from_address, contact_name, user_id, provider_message_id, message_type, body, received_at)
+		VALUES ($1, $2, $3, (SELECT sp.user_id FROM student_personal sp WHERE `+matchesWhatsAppNumber("$2::text")+` LIMIT 1),
+		        $4, $5, $6, $7)
+		ON CONFLICT (provider_message_id) DO NOTHING
+		RETURNING id, user_id, status`,
+		m.Channel, m.FromAddress, m.ContactName, m.ProviderMessageID, m.MessageType, m.Body, m.ReceivedAt,
+	).Scan(&m.ID, &m.UserID, &m.Status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ListSupportMessages returns support messages, newest first, with the
+// total matching. status filters when not empty; deptScope limits them to
+// students of one department.
+func (r *SupportRepository) ListSupportMessages(ctx context.Context, status string, deptScope *string, limit, offset int) ([]models.SupportMessage, int64, error) {
+	where := `WHERE ($1 = '' OR m.status = $1) AND ($2::text IS NULL OR sp.department = $2)`
+
+	var total int64
+	if err := r.DB.QueryRow(ctx, `SELECT COUNT(*) FROM `+supportFrom+` `+where, status, deptScope).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.DB.Query(ctx, `
+		SELECT `+supportColumns+`
+		FROM `+supportFrom+`
+		`+where+`
+		ORDER BY m.received_at DESC, m.id DESC
+		LIMIT $3 OFFSET $4`, status, deptScope, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	messages := []models.SupportMessage{}
+	for rows.Next() {
+		var m models.SupportMessage
+		if err := scanSupportMessage(rows, &m); err != nil {
+			return nil, 0, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, total, rows.Err()
+}
+
+// GetSupportMessage returns one support message. deptScope, when set,
+// hides messages from other departments' students and unknown senders.
+func (r *SupportRepository) GetSupportMessage(ctx context.Context, id int64, deptScope *string) (*models.SupportMessage, error) {
+	var m models.SupportMessage
+	err := scanSupportMessage(r.DB.QueryRow(ctx, `
+		SELECT `+supportColumns+`
+		FROM `+supportFrom+`
+		WHERE m.id = $1 AND ($2::text IS NULL OR sp.department = $2)`, id, deptScope), &m)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSupportMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// MarkSupportReplied records the reply sent to a support message and the
+// job carrying it.
+func (r *SupportRepository) MarkSupportReplied(ctx context.Context, id int64, reply string, repliedBy, jobID int64) error {
+	_, err := r.DB.Exec(ctx, `
+		UPDATE support_messages
+		SET status = 'replied', reply = $2, replied_by = $3, replied_at = NOW(), reply_job_id = $4
+		WHERE id = $1`, id, reply, repliedBy, jobID)
+	return err
+}
+
+// SetSupportStatus opens or closes a support message.
+func (r *SupportRepository) SetSupportStatus(ctx context.Context, id int64, status string) error {
+	tag, err := r.DB.Exec(ctx, `UPDATE support_messages SET status = $2 WHERE id = $1`, id, status)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrSupportMessageNotFound
+	}
+	return err
+}
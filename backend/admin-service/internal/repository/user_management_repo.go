@@ -129,8 +129,8 @@ func (r *UserManagementRepository) CreateManagedUser(ctx context.Context, email,
 
 	var userID int64
 	err = tx.QueryRow(ctx,
-		`INSERT INTO users (email, password_hash, role, name, department_code, is_active, is_blocked)
-		 VALUES ($1, $2, $3, $4, $5, TRUE, FALSE) RETURNING id`,
+		`INSERT INTO users (email, password_hash, role, name, department_code, is_active, is_blocked, must_change_password)
+		 VALUES ($1, $2, $3, $4, $5, TRUE, FALSE, TRUE) RETURNING id`,
 		email, passwordHash, role, name, deptCode,
 	).Scan(&userID)
 	if err != nil {
@@ -22,7 +22,8 @@ func (r *UserRepository) BulkCreateStudents(ctx context.Context, students [][]st
 	count := 0
 
 	// 1. Insert User
-	stmtUser := `INSERT INTO users (email, password_hash, role, is_active) VALUES ($1, $2, 'student', true) RETURNING id`
+	// CSV passwords are admin-assigned, so the student must replace it on first login
+	stmtUser := `INSERT INTO users (email, password_hash, role, is_active, must_change_password) VALUES ($1, $2, 'student', true, true) RETURNING id`
 
 	// 2. Insert Profile
 	// 2. Insert Profile
@@ -120,6 +121,14 @@ func (r *UserRepository) GetRegisterNumber(ctx context.Context, userID int64) (s
 	return regNo, nil
 }
 
+// IsChatGroupMember reports whether userID belongs to the chat group, so
+// uploads can only be written under groups the user can post to
+func (r *UserRepository) IsChatGroupMember(ctx context.Context, groupID, userID int64) (bool, error) {
+	var isMember bool
+	err := r.DB.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM chat.chat_group_members WHERE group_id = $1 AND user_id = $2)`, groupID, userID).Scan(&isMember)
+	return isMember, err
+}
+
 // IsStudentProfileComplete checks if a student has completed their profile setup
 // Returns true if all critical onboarding fields are filled
 func (r *UserRepository) IsStudentProfileComplete(ctx context.Context, userID int64) bool {
@@ -150,6 +159,20 @@ func (r *UserRepository) IsStudentProfileComplete(ctx context.Context, userID in
 		profilePhotoURL != "" && profilePhotoURL != "NA"
 }
 
+// StudentsInDepartment reports whether every id belongs to a student of dept
+func (r *UserRepository) StudentsInDepartment(ctx context.Context, ids []int64, dept string) (bool, error) {
+	query := `
+        SELECT NOT EXISTS (
+            SELECT 1 FROM unnest($1::bigint[]) AS t(id)
+            LEFT JOIN student_personal sp ON sp.user_id = t.id
+            WHERE sp.department IS DISTINCT FROM $2
+        )
+    `
+	var ok bool
+	err := r.DB.QueryRow(ctx, query, ids, dept).Scan(&ok)
+	return ok, err
+}
+
 // GetRegisterNumbersByIDs fetches register numbers for multiple user IDs
 func (r *UserRepository) GetRegisterNumbersByIDs(ctx context.Context, ids []int64) ([]string, error) {
 	if len(ids) == 0 {
@@ -173,8 +196,9 @@ func (r *UserRepository) GetRegisterNumbersByIDs(ctx context.Context, ids []int6
 	return regNos, nil
 }
 
-// GetStudentByRegisterNumber fetches full profile by RegNo
-func (r *UserRepository) GetStudentByRegisterNumber(ctx context.Context, regNo string) (*models.StudentFullProfile, error) {
+// GetStudentByRegisterNumber loads a student's full profile. When deptScope is
+// set, students outside that department are reported as not found.
+func (r *UserRepository) GetStudentByRegisterNumber(ctx context.Context, regNo string, deptScope *string) (*models.StudentFullProfile, error) {
 	query := `
         SELECT 
             u.id, u.email, u.is_blocked, u.last_login,
@@ -210,7 +234,7 @@ func (r *UserRepository) GetStudentByRegisterNumber(ctx context.Context, regNo s
         LEFT JOIN student_degrees d_pg ON u.id = d_pg.user_id AND d_pg.degree_level = 'PG'
         
         LEFT JOIN student_documents sd ON u.id = sd.user_id
-        WHERE sp.register_number ILIKE $1
+        WHERE sp.register_number ILIKE $1 AND ($2::text IS NULL OR sp.department = $2)
     `
 	var s models.StudentFullProfile
 	var socialLinksBytes, languageSkillsBytes []byte
@@ -470,15 +494,9 @@ func (r *UserRepository) SetUserBlockStatus(ctx context.Context, userID int64, i
 	return err
 }
 
-// UpdateFCMToken updates the Firebase Cloud Messaging token for a user
-func (r *UserRepository) UpdateFCMToken(ctx context.Context, userID int64, token string) error {
-	query := `UPDATE users SET fcm_token = $1 WHERE id = $2`
-	_, err := r.DB.Exec(ctx, query, token, userID)
-	return err
-}
-
-// GetStudents fetches students with dynamic filters and pagination, returning FULL profiles
-func (r *UserRepository) GetStudents(ctx context.Context, department string, batchYear int, search string, limit, offset int, sortBy, sortOrder string) ([]models.StudentFullProfile, int64, error) {
+// GetStudents fetches students with dynamic filters and pagination, returning FULL profiles.
+// deptScope restricts results to one department regardless of the department filter.
+func (r *UserRepository) GetStudents(ctx context.Context, department string, batchYear int, search string, limit, offset int, sortBy, sortOrder string, deptScope *string) ([]models.StudentFullProfile, int64, error) {
 	// Base Query conditions
 	whereClause := "WHERE u.role = 'student'"
 	var args []interface{}
@@ -491,6 +509,12 @@ func (r *UserRepository) GetStudents(ctx context.Context, department string, bat
 		argCounter++
 	}
 
+	if deptScope != nil {
+		whereClause += fmt.Sprintf(" AND sp.department = $%d", argCounter)
+		args = append(args, *deptScope)
+		argCounter++
+	}
+
 	if batchYear > 0 {
 		whereClause += fmt.Sprintf(" AND sp.batch_year = $%d", argCounter)
 		args = append(args, batchYear)
@@ -715,22 +739,57 @@ func (r *UserRepository) GetStudents(ctx context.Context, department string, bat
 	return students, totalCount, nil
 }
 
-// GetPasswordHash fetches the password hash for a user
-func (r *UserRepository) GetPasswordHash(ctx context.Context, userID int64) (string, error) {
-	var hash string
-	query := `SELECT password_hash FROM users WHERE id = $1`
-	err := r.DB.QueryRow(ctx, query, userID).Scan(&hash)
+// PasswordCredentials is what a password change is checked against
+type PasswordCredentials struct {
+	Email        string
+	PasswordHash string
+	History      []string // previous hashes, newest first
+}
+
+// GetPasswordCredentials fetches a user's email, current hash and newest
+// historySize previous hashes from auth.password_history.
+func (r *UserRepository) GetPasswordCredentials(ctx context.Context, userID int64, historySize int) (*PasswordCredentials, error) {
+	var creds PasswordCredentials
+	err := r.DB.QueryRow(ctx, `
+		SELECT email, password_hash,
+		       COALESCE(ARRAY(SELECT h.password_hash FROM auth.password_history h
+		                      WHERE h.user_id = u.id ORDER BY h.created_at DESC, h.id DESC LIMIT $2), '{}')
+		FROM users u WHERE u.id = $1`, userID, max(historySize, 0)).Scan(&creds.Email, &creds.PasswordHash, &creds.History)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return hash, nil
+	return &creds, nil
 }
 
-// UpdatePassword updates the password for a user
-func (r *UserRepository) UpdatePassword(ctx context.Context, userID int64, passwordHash string) error {
-	query := `UPDATE users SET password_hash = $1 WHERE id = $2`
-	_, err := r.DB.Exec(ctx, query, passwordHash, userID)
-	return err
+// UpdatePassword updates the password for a user, clears any forced-change flag
+// and records the new hash in auth.password_history (keeping the newest historySize).
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID int64, passwordHash string, historySize int) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE users SET password_hash = $1, must_change_password = FALSE, password_changed_at = NOW() WHERE id = $2`
+	if _, err := tx.Exec(ctx, query, passwordHash, userID); err != nil {
+		return err
+	}
+
+	if historySize > 0 {
+		if _, err := tx.Exec(ctx, `INSERT INTO auth.password_history (user_id, password_hash) VALUES ($1, $2)`, userID, passwordHash); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `
+			DELETE FROM auth.password_history
+			WHERE user_id = $1 AND id NOT IN (
+				SELECT id FROM auth.password_history WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2
+			)`, userID, historySize)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
 }
 
 // UpdateUserProfile updates name and profile photo for any user
@@ -5,10 +5,20 @@ import (
 	"github.com/placement-portal-kec/admin-service/internal/database"
 	"github.com/placement-portal-kec/admin-service/internal/handlers"
 	"github.com/placement-portal-kec/admin-service/internal/middleware"
+	"github.com/placement-portal-kec/admin-service/internal/models"
 	"github.com/placement-portal-kec/admin-service/internal/repository"
+	"github.com/placement-portal-kec/admin-service/internal/services"
 )
 
-func SetupRoutes(app *fiber.App) {
+// Background holds the long-running jobs SetupRoutes wires up. main starts
+// them with a context that is cancelled on shutdown.
+type Background struct {
+	NotificationWorker *handlers.NotificationWorker
+	Broadcasts         *handlers.BroadcastHandler
+	Digests            *handlers.DigestHandler
+}
+
+func SetupRoutes(app *fiber.App) *Background {
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"status": "Admin Service is running"})
 	})
@@ -16,6 +26,20 @@ func SetupRoutes(app *fiber.App) {
 	// Public Settings (no auth needed)
 	systemSettingsHandler := handlers.NewSystemSettingsHandler(database.DB)
 	app.Get("/api/v1/settings", systemSettingsHandler.GetSettings)
+	app.Get("/api/v1/settings/college-logo", systemSettingsHandler.GetCollegeLogo)
+
+	// Outbound notifications (email, WhatsApp, push) from every service go
+	// through the notify.jobs queue drained here
+	notificationJobs := repository.NewNotificationJobRepository(database.DB)
+	emailBranding := services.NewEmailBranding(repository.NewSettingsRepository(database.DB))
+	deliveryProviders := services.NewDeliveryProviders(repository.NewDeviceTokenRepository(database.DB), emailBranding)
+	notificationWorker := handlers.NewNotificationWorker(notificationJobs, deliveryProviders)
+
+	// WhatsApp Cloud API webhook: delivery receipts, opt-out keywords and
+	// support inbox messages. Authenticated by Meta's signature, not a JWT.
+	whatsAppHandler := handlers.NewWhatsAppWebhookHandler(notificationJobs, repository.NewSupportRepository(database.DB), notificationWorker)
+	app.Get("/api/v1/webhooks/whatsapp", whatsAppHandler.VerifyWebhook)
+	app.Post("/api/v1/webhooks/whatsapp", whatsAppHandler.ReceiveWebhook)
 
 	api := app.Group("/api/v1", middleware.Protected)
 
@@ -33,48 +57,50 @@ func SetupRoutes(app *fiber.App) {
 	api.Delete("/user/account", systemSettingsHandler.DeleteAccount)
 
 	// --- Admin Only ---
+	// Every admin route also names the permission key it needs (admin.role_permissions);
+	// super_admin bypasses the key check.
 	admin := api.Group("/admin", middleware.AdminOnly)
 
 	// Student Management
-	admin.Post("/students/bulk-upload", handlers.BulkUploadStudents)
-	admin.Post("/students", handlers.CreateStudent)
-	admin.Get("/students", handlers.ListStudents)
-	admin.Get("/students/:id", handlers.GetStudentDetails)
-	admin.Delete("/students/:id", handlers.DeleteStudent)
-	admin.Delete("/students/bulk", handlers.BulkDeleteStudents)
-	admin.Post("/students/delete-many", handlers.BulkDeleteStudentsByIds)
-	admin.Put("/users/:id/block", handlers.ToggleBlockUser)
-	admin.Get("/drive-requests", handlers.GetDriveRequests)
-	admin.Put("/applications/status", handlers.UpdateApplicationStatus)
-	admin.Put("/applications/bulk-status", handlers.BulkUpdateDriveRequestStatus)
+	admin.Post("/students/bulk-upload", middleware.RequirePermission(models.PermManageStudents), handlers.BulkUploadStudents)
+	admin.Post("/students", middleware.RequirePermission(models.PermManageStudents), handlers.CreateStudent)
+	admin.Get("/students", middleware.RequirePermission(models.PermManageStudents), handlers.ListStudents)
+	admin.Get("/students/:id", middleware.RequirePermission(models.PermManageStudents), handlers.GetStudentDetails)
+	admin.Delete("/students/:id", middleware.RequirePermission(models.PermManageStudents), handlers.DeleteStudent)
+	admin.Delete("/students/bulk", middleware.RequirePermission(models.PermManageStudents), handlers.BulkDeleteStudents)
+	admin.Post("/students/delete-many", middleware.RequirePermission(models.PermManageStudents), handlers.BulkDeleteStudentsByIds)
+	admin.Put("/users/:id/block", middleware.RequirePermission(models.PermManageStudents), handlers.ToggleBlockUser)
+	admin.Get("/drive-requests", middleware.RequirePermission(models.PermManualDriveOps), handlers.GetDriveRequests)
+	admin.Put("/applications/status", middleware.RequirePermission(models.PermManualDriveOps), handlers.UpdateApplicationStatus)
+	admin.Put("/applications/bulk-status", middleware.RequirePermission(models.PermManualDriveOps), handlers.BulkUpdateDriveRequestStatus)
 
 	// System Monitoring
-	admin.Get("/system/storage", handlers.GetStorageInfo)
-	admin.Get("/archive/placements", handlers.ArchivePlacementRecords)
+	admin.Get("/system/storage", middleware.RequirePermission(models.PermManageStorage), handlers.GetStorageInfo)
+	admin.Get("/archive/placements", middleware.RequirePermission(models.PermExportData), handlers.ArchivePlacementRecords)
 
 	// Garage Storage Admin
-	admin.Get("/storage/objects", handlers.ListGarageObjects)
-	admin.Get("/storage/chat-objects", handlers.ListChatBucketObjects)
-	admin.Get("/storage/object", handlers.GetGarageObject)
-	admin.Get("/storage/download", handlers.DownloadGarageObject)
-	admin.Delete("/storage/object", handlers.DeleteGarageObject)
-	admin.Post("/storage/bulk-delete", handlers.BulkDeleteGarageObjects)
-	admin.Post("/storage/download-zip", handlers.DownloadGarageZip)
-	admin.Post("/storage/download-archive", handlers.DownloadGarageArchive)
+	admin.Get("/storage/objects", middleware.RequirePermission(models.PermManageStorage), handlers.ListGarageObjects)
+	admin.Get("/storage/chat-objects", middleware.RequirePermission(models.PermManageStorage), handlers.ListChatBucketObjects)
+	admin.Get("/storage/object", middleware.RequirePermission(models.PermManageStorage), handlers.GetGarageObject)
+	admin.Get("/storage/download", middleware.RequirePermission(models.PermManageStorage), handlers.DownloadGarageObject)
+	admin.Delete("/storage/object", middleware.RequirePermission(models.PermManageStorage), handlers.DeleteGarageObject)
+	admin.Post("/storage/bulk-delete", middleware.RequirePermission(models.PermManageStorage), handlers.BulkDeleteGarageObjects)
+	admin.Post("/storage/download-zip", middleware.RequirePermission(models.PermManageStorage), handlers.DownloadGarageZip)
+	admin.Post("/storage/download-archive", middleware.RequirePermission(models.PermManageStorage), handlers.DownloadGarageArchive)
 
 	// SPOCs Management
-	admin.Post("/spocs", handlers.CreateSpoc)
-	admin.Put("/spocs/:id", handlers.UpdateSpoc)
-	admin.Delete("/spocs/:id", handlers.DeleteSpoc)
-	admin.Put("/spocs/:id/status", handlers.ToggleSpocStatus)
+	admin.Post("/spocs", middleware.RequirePermission(models.PermManageSpocs), handlers.CreateSpoc)
+	admin.Put("/spocs/:id", middleware.RequirePermission(models.PermManageSpocs), handlers.UpdateSpoc)
+	admin.Delete("/spocs/:id", middleware.RequirePermission(models.PermManageSpocs), handlers.DeleteSpoc)
+	admin.Put("/spocs/:id/status", middleware.RequirePermission(models.PermManageSpocs), handlers.ToggleSpocStatus)
 
 	// Config Management
-	admin.Post("/config/departments", handlers.AddDepartment)
-	admin.Put("/config/departments/:id", handlers.UpdateDepartment)
-	admin.Delete("/config/departments/:id", handlers.DeleteDepartment)
-	admin.Post("/config/batches", handlers.AddBatch)
-	admin.Put("/config/batches/:id", handlers.UpdateBatch)
-	admin.Delete("/config/batches/:id", handlers.DeleteBatch)
+	admin.Post("/config/departments", middleware.RequirePermission(models.PermManageConfig), handlers.AddDepartment)
+	admin.Put("/config/departments/:id", middleware.RequirePermission(models.PermManageConfig), handlers.UpdateDepartment)
+	admin.Delete("/config/departments/:id", middleware.RequirePermission(models.PermManageConfig), handlers.DeleteDepartment)
+	admin.Post("/config/batches", middleware.RequirePermission(models.PermManageConfig), handlers.AddBatch)
+	admin.Put("/config/batches/:id", middleware.RequirePermission(models.PermManageConfig), handlers.UpdateBatch)
+	admin.Delete("/config/batches/:id", middleware.RequirePermission(models.PermManageConfig), handlers.DeleteBatch)
 
 	// Field Permissions & Requests
 	permRepo := repository.NewPermissionRepository(database.DB)
@@ -82,35 +108,57 @@ func SetupRoutes(app *fiber.App) {
 	settingsHandler := handlers.NewSettingsHandler(permRepo, studentRepo)
 
 	requestRepo := repository.NewRequestRepository(database.DB)
-	requestHandler := handlers.NewRequestHandler(requestRepo, studentRepo)
+	requestHandler := handlers.NewRequestHandler(requestRepo, studentRepo, repository.NewNotificationJobRepository(database.DB))
 
 	broadcastRepo := repository.NewBroadcastRepository(database.DB)
-	broadcastHandler := handlers.NewBroadcastHandler(broadcastRepo)
+	notificationQueueHandler := handlers.NewNotificationQueueHandler(notificationJobs, notificationWorker)
+
+	broadcastHandler := handlers.NewBroadcastHandler(broadcastRepo, notificationWorker)
+	notificationWorker.OnKind(models.JobKindBroadcast, broadcastHandler.SyncDelivery)
+
+	// Weekly (or daily) digest emails for students and coordinators
+	digestHandler := handlers.NewDigestHandler(repository.NewDigestRepository(database.DB), repository.NewDriveRepository(database.DB),
+		repository.NewApplicationRepository(database.DB), requestRepo, notificationWorker)
+
+	emailTemplateHandler := handlers.NewEmailTemplateHandler(emailBranding)
 
 	eligibilityRepo := repository.NewEligibilityRepository(database.DB)
 	eligibilityHandler := handlers.NewEligibilityHandler(eligibilityRepo)
 
-	admin.Get("/settings/fields", settingsHandler.GetFieldPermissions)
-	admin.Put("/settings/fields/:name", settingsHandler.ToggleFieldPermission)
-	admin.Post("/settings", systemSettingsHandler.UpdateSettings)
+	admin.Get("/settings/fields", middleware.RequirePermission(models.PermManageConfig), settingsHandler.GetFieldPermissions)
+	admin.Put("/settings/fields/:name", middleware.RequirePermission(models.PermManageConfig), settingsHandler.ToggleFieldPermission)
+	admin.Post("/settings", middleware.RequirePermission(models.PermManageConfig), systemSettingsHandler.UpdateSettings)
 
-	admin.Get("/requests", requestHandler.GetPendingRequests)
-	admin.Put("/requests/:id", requestHandler.ReviewRequest)
+	admin.Get("/requests", middleware.RequirePermission(models.PermApproveChanges), requestHandler.GetPendingRequests)
+	admin.Put("/requests/:id", middleware.RequirePermission(models.PermApproveChanges), requestHandler.ReviewRequest)
 
 	// Broadcast
 	broadcast := admin.Group("/broadcast")
-	broadcast.Post("/template", broadcastHandler.CreateTemplate)
-	broadcast.Get("/template", broadcastHandler.GetTemplates)
-	broadcast.Delete("/template/:id", broadcastHandler.DeleteTemplate)
-	broadcast.Post("/send", broadcastHandler.SendBroadcast)
+	broadcast.Post("/template", middleware.RequirePermission(models.PermSendBroadcasts), broadcastHandler.CreateTemplate)
+	broadcast.Get("/template", middleware.RequirePermission(models.PermSendBroadcasts), broadcastHandler.GetTemplates)
+	broadcast.Delete("/template/:id", middleware.RequirePermission(models.PermSendBroadcasts), broadcastHandler.DeleteTemplate)
+	broadcast.Post("/send", middleware.RequirePermission(models.PermSendBroadcasts), broadcastHandler.SendBroadcast)
+	broadcast.Get("/history", middleware.RequirePermission(models.PermSendBroadcasts), broadcastHandler.ListBroadcasts)
+	broadcast.Get("/schedules", middleware.RequirePermission(models.PermSendBroadcasts), broadcastHandler.ListSchedules)
+	broadcast.Delete("/schedules/:id", middleware.RequirePermission(models.PermSendBroadcasts), broadcastHandler.CancelSchedule)
+	broadcast.Get("/:id/report", middleware.RequirePermission(models.PermSendBroadcasts), broadcastHandler.GetBroadcastReport)
+
+	// Support inbox (WhatsApp replies)
+	admin.Get("/support/messages", middleware.RequirePermission(models.PermManageSupport), whatsAppHandler.ListSupportMessages)
+	admin.Post("/support/messages/:id/reply", middleware.RequirePermission(models.PermManageSupport), whatsAppHandler.ReplySupportMessage)
+	admin.Patch("/support/messages/:id", middleware.RequirePermission(models.PermManageSupport), whatsAppHandler.UpdateSupportMessage)
+
+	// Email Templates
+	admin.Get("/email-templates", middleware.RequirePermission(models.PermManageConfig), emailTemplateHandler.ListEmailTemplates)
+	admin.Get("/email-templates/:name/preview", middleware.RequirePermission(models.PermManageConfig), emailTemplateHandler.PreviewEmailTemplate)
 
 	// Eligibility Templates
 	eligibility := admin.Group("/eligibility-templates")
-	eligibility.Get("/", eligibilityHandler.ListTemplates)
-	eligibility.Post("/", eligibilityHandler.CreateTemplate)
-	eligibility.Get("/:id", eligibilityHandler.GetTemplate)
-	eligibility.Put("/:id", eligibilityHandler.UpdateTemplate)
-	eligibility.Delete("/:id", eligibilityHandler.DeleteTemplate)
+	eligibility.Get("/", middleware.RequirePermission(models.PermManageDrives), eligibilityHandler.ListTemplates)
+	eligibility.Post("/", middleware.RequirePermission(models.PermManageDrives), eligibilityHandler.CreateTemplate)
+	eligibility.Get("/:id", middleware.RequirePermission(models.PermManageDrives), eligibilityHandler.GetTemplate)
+	eligibility.Put("/:id", middleware.RequirePermission(models.PermManageDrives), eligibilityHandler.UpdateTemplate)
+	eligibility.Delete("/:id", middleware.RequirePermission(models.PermManageDrives), eligibilityHandler.DeleteTemplate)
 
 	// --- Super Admin Only ---
 	superAdmin := api.Group("/super-admin", middleware.SuperAdminOnly)
@@ -124,4 +172,12 @@ func SetupRoutes(app *fiber.App) {
 	superAdmin.Get("/permissions", handlers.GetAllPermissionKeys)
 	superAdmin.Get("/departments", handlers.GetDepartmentsList)
 	superAdmin.Post("/upload/college-logo", handlers.UploadCollegeLogo)
+
+	// Notification queue
+	superAdmin.Get("/notifications/queue", notificationQueueHandler.GetQueueStats)
+	superAdmin.Get("/notifications/jobs", notificationQueueHandler.ListJobs)
+	superAdmin.Get("/notifications/dead-letters", notificationQueueHandler.ListDeadLetters)
+	superAdmin.Post("/notifications/dead-letters/:id/requeue", notificationQueueHandler.RequeueDeadLetter)
+
+	return &Background{NotificationWorker: notificationWorker, Broadcasts: broadcastHandler, Digests: digestHandler}
 }
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/placement-portal-kec/admin-service/internal/emails"
+	"github.com/placement-portal-kec/admin-service/internal/models"
+	"github.com/placement-portal-kec/admin-service/internal/utils"
+)
+
+// ErrProviderNotConfigured is returned by a provider whose credentials are
+// missing. Notification workers dead-letter the job instead of retrying.
+var ErrProviderNotConfigured = errors.New("delivery provider not configured")
+
+// ErrNoDeviceTokens is returned for a push to a user with no device that
+// accepts pushes. Retrying cannot help, so the job is dead-lettered.
+var ErrNoDeviceTokens = errors.New("no registered push devices")
+
+// DeliveryMessage is one rendered message for one recipient. It is also the
+// payload of notify.jobs, so every producing service writes this JSON shape.
+type DeliveryMessage struct {
+	Channel       string            `json:"-"`
+	Address       string            `json:"address"`                  // email address, WhatsApp number or FCM token
+	UserID        int64             `json:"user_id,omitempty"`        // push: send to all of this user's devices instead of Address
+	Subject       string            `json:"subject,omitempty"`        // email subject / push title
+	Body          string            `json:"body"`                     // rendered message text
+	HTML          string            `json:"html,omitempty"`           // pre-rendered email HTML, sent as-is
+	EmailTemplate string            `json:"email_template,omitempty"` // emails template rendered with Vars; Subject/Body are ignored
+	Language      string            `json:"language,omitempty"`       // email template language, English when empty
+	Vars          map[string]string `json:"vars,omitempty"`           // email template variables
+	TemplateName  string            `json:"template_name,omitempty"`  // WhatsApp template to use, empty for free-form text
+	Params        []string          `json:"params,omitempty"`         // template variable values, in declaration order
+	Data          map[string]string `json:"data,omitempty"`           // push data payload
+}
+
+// DeliveryProvider sends a message over one channel.
+type DeliveryProvider interface {
+	Send(ctx context.Context, msg DeliveryMessage) error
+}
+
+// TrackedDeliveryProvider is a provider whose sends get delivery receipts.
+// SendTracked returns the provider's ID for the sent message, which the
+// worker stores on the job so receipts can be matched to it.
+type TrackedDeliveryProvider interface {
+	DeliveryProvider
+	SendTracked(ctx context.Context, msg DeliveryMessage) (string, error)
+}
+
+// DeliveryProviders maps a broadcast channel (models.BroadcastChannel*) to
+// the provider that delivers it. Providers are only called by the
+// notification workers, which apply the recipient's preferences and quiet
+// hours first, so they send whatever they are given.
+type DeliveryProviders map[string]DeliveryProvider
+
+// NewDeliveryProviders returns the real SMTP, WhatsApp Cloud API and FCM
+// providers, or logging fakes when BROADCAST_PROVIDERS=fake (local
+// development and tests). Push goes through the FCM provider either way, on
+// a FakeMessagingClient in fake mode, so device lookup and pruning still run.
+func NewDeliveryProviders(tokens DeviceTokenStore, branding *EmailBranding) DeliveryProviders {
+	if os.Getenv("BROADCAST_PROVIDERS") == "fake" {
+		fake := &FakeProvider{}
+		return DeliveryProviders{
+			models.BroadcastChannelEmail:    fake,
+			models.BroadcastChannelWhatsApp: fake,
+			models.BroadcastChannelPush:     &FCMProvider{Tokens: tokens, Client: &FakeMessagingClient{}},
+		}
+	}
+
+	language := os.Getenv("WHATSAPP_TEMPLATE_LANGUAGE")
+	if language == "" {
+		language = "en"
+	}
+	return DeliveryProviders{
+		models.BroadcastChannelEmail:    &SMTPProvider{Branding: branding},
+		models.BroadcastChannelWhatsApp: &WhatsAppProvider{Service: NewWhatsAppService(), Language: language},
+		models.BroadcastChannelPush:     &FCMProvider{CredentialsFile: "firebase-service-account.json", Tokens: tokens},
+	}
+}
+
+// SMTPProvider sends messages as email with HTML and plain-text parts.
+// Messages naming an EmailTemplate are rendered from the emails registry;
+// plain ones (broadcasts) are wrapped in its "notification" template, so
+// every email carries the college branding.
+type SMTPProvider struct {
+	Branding *EmailBranding
+}
+
+func (p *SMTPProvider) Send(ctx context.Context, msg DeliveryMessage) error {
+	if utils.LoadSMTPConfig().From == "" {
+		return fmt.Errorf("email: %w", ErrProviderNotConfigured)
+	}
+	if msg.HTML != "" {
+		return utils.SendEmail(msg.Address, msg.Subject, msg.HTML, "")
+	}
+	rendered, err := p.Render(ctx, msg)
+	if err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+	return utils.SendEmail(msg.Address, rendered.Subject, rendered.HTML, rendered.Text)
+}
+
+// Render renders msg as it would be sent
+func (p *SMTPProvider) Render(ctx context.Context, msg DeliveryMessage) (emails.Message, error) {
+	name, vars := msg.EmailTemplate, msg.Vars
+	if name == "" {
+		name, vars = "notification", map[string]string{"title": msg.Subject, "body": msg.Body}
+	}
+	return emails.Render(name, msg.Language, p.Branding.Brand(ctx), vars)
+}
+
+// WhatsAppProvider sends through the Meta Cloud API. Broadcasts built from a
+// template go out as that (pre-approved) template with the rendered variables
+// as body parameters; plain messages are sent as free-form text, which Meta
+// only delivers inside a 24h customer service window.
+type WhatsAppProvider struct {
+	Service  *WhatsAppService
+	Language string
+}
+
+func (p *WhatsAppProvider) Send(ctx context.Context, msg DeliveryMessage) error {
+	_, err := p.SendTracked(ctx, msg)
+	return err
+}
+
+// SendTracked sends msg and returns its WhatsApp message ID (wamid)
+func (p *WhatsAppProvider) SendTracked(ctx context.Context, msg DeliveryMessage) (string, error) {
+	if p.Service == nil || p.Service.AccessToken == "" || p.Service.PhoneNumberID == "" {
+		return "", fmt.Errorf("whatsapp: %w", ErrProviderNotConfigured)
+	}
+	to := NormalizeWhatsAppNumber(msg.Address)
+
+	if msg.TemplateName == "" {
+		return p.Service.SendMessage(to, msg.Body)
+	}
+
+	var components []interface{}
+	if len(msg.Params) > 0 {
+		params := make([]map[string]string, len(msg.Params))
+		for i, v := range msg.Params {
+			params[i] = map[string]string{"type": "text", "text": v}
+		}
+		components = append(components, map[string]interface{}{
+			"type":       "body",
+			"parameters": params,
+		})
+	}
+	return p.Service.SendTemplateMessage(to, msg.TemplateName, p.Language, components)
+}
+
+// NormalizeWhatsAppNumber strips formatting from a stored mobile number and
+// prefixes India's country code to bare 10-digit numbers.
+func NormalizeWhatsAppNumber(number string) string {
+	var b strings.Builder
+	for _, r := range number {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+	if len(digits) == 10 {
+		return "91" + digits
+	}
+	return digits
+}
+
+// DeviceTokenStore looks up and prunes users' push device tokens.
+type DeviceTokenStore interface {
+	UserDeviceTokens(ctx context.Context, userID int64) ([]string, error)
+	TokenPruner
+}
+
+// FCMProvider sends messages as push notifications, to every device of
+// msg.UserID or to the single token in msg.Address. Tokens FCM reports as
+// dead are deleted from Tokens. The Firebase client is created on first use
+// so a missing service account only fails push jobs.
+type FCMProvider struct {
+	CredentialsFile string
+	Tokens          DeviceTokenStore
+	// Client, when set, is used instead of Firebase (e.g. FakeMessagingClient)
+	Client MessagingClient
+
+	once    sync.Once
+	service *NotificationService
+	initErr error
+}
+
+func (p *FCMProvider) Send(ctx context.Context, msg DeliveryMessage) error {
+	p.once.Do(func() {
+		if p.Client != nil {
+			p.service = &NotificationService{Client: p.Client}
+		} else {
+			p.service, p.initErr = NewNotificationService(p.CredentialsFile)
+		}
+		if p.service != nil && p.Tokens != nil {
+			p.service.Pruner = p.Tokens
+		}
+	})
+	if p.initErr != nil {
+		return fmt.Errorf("push: %w: %v", ErrProviderNotConfigured, p.initErr)
+	}
+
+	var tokens []string
+	switch {
+	case msg.UserID != 0 && p.Tokens != nil:
+		var err error
+		if tokens, err = p.Tokens.UserDeviceTokens(ctx, msg.UserID); err != nil {
+			return fmt.Errorf("push: loading devices: %w", err)
+		}
+	case msg.Address != "":
+		tokens = []string{msg.Address}
+	}
+	if len(tokens) == 0 {
+		return fmt.Errorf("push: %w", ErrNoDeviceTokens)
+	}
+
+	title := msg.Subject
+	if title == "" {
+		title = "Placement Portal"
+	}
+	data := msg.Data
+	if data == nil {
+		data = map[string]string{"type": "broadcast"}
+	}
+	result, err := p.service.Push(ctx, tokens, title, msg.Body, data)
+	if err != nil {
+		return err
+	}
+	switch {
+	case result.Sent > 0:
+		return nil
+	case len(result.Dead) == len(tokens):
+		return fmt.Errorf("push: %w (all %d tokens rejected)", ErrNoDeviceTokens, len(tokens))
+	default:
+		return fmt.Errorf("push: FCM rejected the message for all %d devices", len(tokens))
+	}
+}
+
+// FakeProvider records messages instead of sending them. Fail, when set, is
+// consulted for every message so tests can simulate provider errors.
+type FakeProvider struct {
+	Fail func(msg DeliveryMessage) error
+
+	mu   sync.Mutex
+	sent []DeliveryMessage
+}
+
+func (p *FakeProvider) Send(ctx context.Context, msg DeliveryMessage) error {
+	if p.Fail != nil {
+		if err := p.Fail(msg); err != nil {
+			return err
+		}
+	}
+	p.mu.Lock()
+	p.sent = append(p.sent, msg)
+	p.mu.Unlock()
+	log.Printf("FakeProvider: %s to %s: %q", msg.Channel, msg.Address, msg.Body)
+	return nil
+}
+
+// Sent returns a copy of the messages recorded so far.
+func (p *FakeProvider) Sent() []DeliveryMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]DeliveryMessage(nil), p.sent...)
+}
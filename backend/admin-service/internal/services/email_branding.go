@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/placement-portal-kec/admin-service/internal/emails"
+	"github.com/placement-portal-kec/admin-service/internal/utils"
+)
+
+// brandCacheTTL bounds how long a college name or logo change takes to show
+// up in outgoing email
+const brandCacheTTL = 5 * time.Minute
+
+// SettingsSource reads system_settings
+type SettingsSource interface {
+	GetSystemSettings(ctx context.Context) (map[string]string, error)
+}
+
+// EmailBranding supplies the college name and logo (system_settings
+// college_name and college_logo_url) for the email layout.
+type EmailBranding struct {
+	Settings SettingsSource
+
+	mu       sync.Mutex
+	brand    emails.Brand
+	loadedAt time.Time
+}
+
+func NewEmailBranding(settings SettingsSource) *EmailBranding {
+	return &EmailBranding{Settings: settings}
+}
+
+// Brand returns the current branding, falling back to the defaults when the
+// settings cannot be read.
+func (b *EmailBranding) Brand(ctx context.Context) emails.Brand {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.loadedAt.IsZero() && time.Since(b.loadedAt) < brandCacheTTL {
+		return b.brand
+	}
+
+	brand := emails.Brand{
+		PortalName:  "Placement Portal",
+		CollegeName: "Kongu Engineering College",
+		Year:        time.Now().Year(),
+	}
+	settings, err := b.Settings.GetSystemSettings(ctx)
+	if err != nil {
+		log.Printf("Email branding: reading settings failed: %v", err)
+		return brand
+	}
+	if name := strings.TrimSpace(settings["college_name"]); name != "" {
+		brand.CollegeName = name
+	}
+	if settings["college_logo_url"] != "" {
+		// Presigned links expire within the hour, long before the mail is
+		// read, so prefer the public redirect when the API's address is known
+		if base := strings.TrimRight(os.Getenv("PUBLIC_API_URL"), "/"); base != "" {
+			brand.LogoURL = base + "/api/v1/settings/college-logo"
+		} else {
+			brand.LogoURL = utils.GenerateSignedProfileURL(settings["college_logo_url"])
+		}
+	}
+	b.brand, b.loadedAt = brand, time.Now()
+	return brand
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"firebase.google.com/go/v4/messaging"
+)
+
+// FakeMessagingClient stands in for the FCM client in local development and
+// tests. Fail, when set, is consulted per token; returning
+// ErrTokenUnregistered simulates an uninstalled app.
+type FakeMessagingClient struct {
+	Fail func(token string) error
+
+	mu   sync.Mutex
+	sent []*messaging.MulticastMessage
+}
+
+func (f *FakeMessagingClient) SendEachForMulticast(ctx context.Context, message *messaging.MulticastMessage) (*messaging.BatchResponse, error) {
+	f.mu.Lock()
+	f.sent = append(f.sent, message)
+	f.mu.Unlock()
+	log.Printf("FakeMessagingClient: push to %d devices: %q", len(message.Tokens), message.Notification.Title)
+
+	br := &messaging.BatchResponse{Responses: make([]*messaging.SendResponse, len(message.Tokens))}
+	for i, token := range message.Tokens {
+		var err error
+		if f.Fail != nil {
+			err = f.Fail(token)
+		}
+		if err != nil {
+			br.Responses[i] = &messaging.SendResponse{Error: err}
+			br.FailureCount++
+			continue
+		}
+		br.Responses[i] = &messaging.SendResponse{Success: true, MessageID: "fake-" + token}
+		br.SuccessCount++
+	}
+	return br, nil
+}
+
+// Sent returns the multicast calls made so far; batches over FCM's limit show
+// up as several calls.
+func (f *FakeMessagingClient) Sent() []*messaging.MulticastMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*messaging.MulticastMessage(nil), f.sent...)
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 
@@ -10,8 +11,37 @@ import (
 	"google.golang.org/api/option"
 )
 
+// fcmMulticastLimit is the most tokens FCM accepts in one multicast call
+const fcmMulticastLimit = 500
+
+// ErrTokenUnregistered marks a device token that can no longer receive
+// pushes. FCM reports this as an unregistered (or invalid-argument) error;
+// FakeMessagingClient returns it directly.
+var ErrTokenUnregistered = errors.New("device token not registered")
+
+// MessagingClient is the part of the FCM client the service uses, so a fake
+// can stand in for Firebase.
+type MessagingClient interface {
+	SendEachForMulticast(ctx context.Context, message *messaging.MulticastMessage) (*messaging.BatchResponse, error)
+}
+
+// TokenPruner removes device tokens that FCM rejected as dead.
+type TokenPruner interface {
+	DeleteDeviceTokens(ctx context.Context, tokens []string) error
+}
+
 type NotificationService struct {
-	Client *messaging.Client
+	Client MessagingClient
+	// Pruner, when set, is given the tokens each send found to be dead
+	Pruner TokenPruner
+}
+
+// PushResult summarises a multicast send. Dead lists the tokens FCM reported
+// as unregistered or invalid.
+type PushResult struct {
+	Sent   int
+	Failed int
+	Dead   []string
 }
 
 // NewNotificationService initializes the Firebase Messaging Client
@@ -37,35 +67,64 @@ func NewNotificationService(credentialsFile string) (*NotificationService, error
 // body: Notification Body
 // data: Custom data payload (optional)
 func (s *NotificationService) SendMulticastNotification(ctx context.Context, tokens []string, title, body string, data map[string]string) (int, error) {
+	result, err := s.Push(ctx, tokens, title, body, data)
+	return result.Sent, err
+}
+
+// Push sends the message to every token in batches of fcmMulticastLimit and
+// prunes the tokens FCM reports as dead. Per-token failures are counted, not
+// returned; err is only set when a whole batch could not be sent.
+func (s *NotificationService) Push(ctx context.Context, tokens []string, title, body string, data map[string]string) (PushResult, error) {
+	var result PushResult
 	if s.Client == nil {
 		log.Println("NotificationService: Client is nil, skipping notification")
-		return 0, nil
+		return result, nil
 	}
 
-	if len(tokens) == 0 {
-		return 0, nil
-	}
+	for start := 0; start < len(tokens); start += fcmMulticastLimit {
+		batch := tokens[start:min(start+fcmMulticastLimit, len(tokens))]
+		message := &messaging.MulticastMessage{
+			Tokens: batch,
+			Notification: &messaging.Notification{
+				Title: title,
+				Body:  body,
+			},
+			Data: data,
+		}
 
-	// FCM Multicast handles up to 500 tokens at once.
-	// Basic implementation:
-	message := &messaging.MulticastMessage{
-		Tokens: tokens,
-		Notification: &messaging.Notification{
-			Title: title,
-			Body:  body,
-		},
-		Data: data,
+		// SendEachForMulticast uses the new HTTP v1 API
+		br, err := s.Client.SendEachForMulticast(ctx, message)
+		if err != nil {
+			s.prune(ctx, result.Dead)
+			return result, err
+		}
+		result.Sent += br.SuccessCount
+		result.Failed += br.FailureCount
+		for i, resp := range br.Responses {
+			if resp != nil && !resp.Success && i < len(batch) && isDeadTokenError(resp.Error) {
+				result.Dead = append(result.Dead, batch[i])
+			}
+		}
 	}
 
-	// SendEachForMulticast uses the new HTTP v1 API
-	br, err := s.Client.SendEachForMulticast(ctx, message)
-	if err != nil {
-		return 0, err
+	if result.Failed > 0 {
+		log.Printf("NotificationService: %d of %d messages failed (%d dead tokens)", result.Failed, len(tokens), len(result.Dead))
 	}
+	s.prune(ctx, result.Dead)
+	return result, nil
+}
 
-	if br.FailureCount > 0 {
-		log.Printf("CreateDrive: %d messages failed to send.\n", br.FailureCount)
+func (s *NotificationService) prune(ctx context.Context, dead []string) {
+	if s.Pruner == nil || len(dead) == 0 {
+		return
+	}
+	if err := s.Pruner.DeleteDeviceTokens(ctx, dead); err != nil {
+		log.Printf("NotificationService: pruning %d dead tokens failed: %v", len(dead), err)
 	}
+}
 
-	return br.SuccessCount, nil
+// isDeadTokenError reports whether FCM rejected a token for good: it was
+// unregistered (app uninstalled, token rotated) or is not a valid token.
+func isDeadTokenError(err error) bool {
+	return err != nil && (errors.Is(err, ErrTokenUnregistered) || messaging.IsUnregistered(err) || messaging.IsInvalidArgument(err))
 }
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeTokenStore serves a fixed set of device tokens and records pruning.
+type fakeTokenStore struct {
+	mu     sync.Mutex
+	tokens map[int64][]string
+	pruned []string
+}
+
+func (s *fakeTokenStore) UserDeviceTokens(ctx context.Context, userID int64) ([]string, error) {
+	return s.tokens[userID], nil
+}
+
+func (s *fakeTokenStore) DeleteDeviceTokens(ctx context.Context, tokens []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruned = append(s.pruned, tokens...)
+	return nil
+}
+
+func deviceTokens(n int) []string {
+	tokens := make([]string, n)
+	for i := range tokens {
+		tokens[i] = fmt.Sprintf("token-%04d", i)
+	}
+	return tokens
+}
+
+func TestPushSplitsIntoMulticastBatches(t *testing.T) {
+	client := &FakeMessagingClient{}
+	service := &NotificationService{Client: client}
+
+	tokens := deviceTokens(1203)
+	result, err := service.Push(context.Background(), tokens, "Drive update", "Registration closes today", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Sent != len(tokens) || result.Failed != 0 {
+		t.Fatalf("result = %+v, want %d sent", result, len(tokens))
+	}
+
+	calls := client.Sent()
+	var sizes []int
+	var sent []string
+	for _, call := range calls {
+		sizes = append(sizes, len(call.Tokens))
+		sent = append(sent, call.Tokens...)
+	}
+	if !slices.Equal(sizes, []int{500, 500, 203}) {
+		t.Errorf("batch sizes = %v, want [500 500 203]", sizes)
+	}
+	if !slices.Equal(sent, tokens) {
+		t.Errorf("batches did not cover every token exactly once")
+	}
+}
+
+func TestPushPrunesDeadTokens(t *testing.T) {
+	client := &FakeMessagingClient{Fail: func(token string) error {
+		switch {
+		case strings.HasSuffix(token, "7"):
+			return ErrTokenUnregistered
+		case strings.HasSuffix(token, "3"):
+			return errors.New("fcm: quota exceeded")
+		}
+		return nil
+	}}
+	store := &fakeTokenStore{}
+	service := &NotificationService{Client: client, Pruner: store}
+
+	tokens := deviceTokens(600)
+	result, err := service.Push(context.Background(), tokens, "Drive update", "Registration closes today", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Sent != 480 || result.Failed != 120 {
+		t.Errorf("result = %d sent, %d failed, want 480 and 120", result.Sent, result.Failed)
+	}
+
+	var want []string
+	for _, token := range tokens {
+		if strings.HasSuffix(token, "7") {
+			want = append(want, token)
+		}
+	}
+	if !slices.Equal(result.Dead, want) {
+		t.Errorf("dead = %d tokens, want the %d unregistered ones", len(result.Dead), len(want))
+	}
+	if !slices.Equal(store.pruned, want) {
+		t.Errorf("pruned = %d tokens, want the %d unregistered ones; transient failures must be kept", len(store.pruned), len(want))
+	}
+}
+
+func TestFCMProviderSendsToEveryDeviceOfUser(t *testing.T) {
+	client := &FakeMessagingClient{Fail: func(token string) error {
+		if token == "old-phone" {
+			return ErrTokenUnregistered
+		}
+		return nil
+	}}
+	store := &fakeTokenStore{tokens: map[int64][]string{42: {"old-phone", "new-phone", "tablet"}}}
+	provider := &FCMProvider{Tokens: store, Client: client}
+
+	if err := provider.Send(context.Background(), DeliveryMessage{UserID: 42, Subject: "Shortlist", Body: "You are shortlisted"}); err != nil {
+		t.Fatal(err)
+	}
+	calls := client.Sent()
+	if len(calls) != 1 || len(calls[0].Tokens) != 3 {
+		t.Fatalf("calls = %d, want one multicast to 3 devices", len(calls))
+	}
+	if !slices.Equal(store.pruned, []string{"old-phone"}) {
+		t.Errorf("pruned = %v, want [old-phone]", store.pruned)
+	}
+}
+
+func TestFCMProviderFailsPermanentlyWhenAllTokensDead(t *testing.T) {
+	client := &FakeMessagingClient{Fail: func(token string) error { return ErrTokenUnregistered }}
+	store := &fakeTokenStore{tokens: map[int64][]string{42: {"old-phone", "old-tablet"}}}
+	provider := &FCMProvider{Tokens: store, Client: client}
+
+	err := provider.Send(context.Background(), DeliveryMessage{UserID: 42, Body: "You are shortlisted"})
+	if !errors.Is(err, ErrNoDeviceTokens) {
+		t.Fatalf("err = %v, want ErrNoDeviceTokens so the job is not retried", err)
+	}
+	if len(store.pruned) != 2 {
+		t.Errorf("pruned = %v, want both tokens", store.pruned)
+	}
+}
@@ -6,8 +6,14 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 )
 
+// defaultWhatsAppAPIURL is the Meta Graph API; WHATSAPP_API_URL points the
+// service elsewhere, e.g. at cmd/fake-meta during local development
+const defaultWhatsAppAPIURL = "https://graph.facebook.com/v17.0"
+
 type WhatsAppService struct {
 	PhoneNumberID string
 	AccessToken   string
@@ -17,15 +23,20 @@ type WhatsAppService struct {
 func NewWhatsAppService() *WhatsAppService {
 	phoneID := os.Getenv("WHATSAPP_PHONE_NUMBER_ID")
 	token := os.Getenv("WHATSAPP_ACCESS_TOKEN")
+	base := strings.TrimRight(os.Getenv("WHATSAPP_API_URL"), "/")
+	if base == "" {
+		base = defaultWhatsAppAPIURL
+	}
 	return &WhatsAppService{
 		PhoneNumberID: phoneID,
 		AccessToken:   token,
-		APIURL:        fmt.Sprintf("https://graph.facebook.com/v17.0/%s/messages", phoneID),
+		APIURL:        fmt.Sprintf("%s/%s/messages", base, phoneID),
 	}
 }
 
 // SendMessage sends a free-form text message (Only allowed for replies within 24h)
-func (s *WhatsAppService) SendMessage(to string, body string) error {
+// and returns Meta's message ID, which delivery receipts refer to
+func (s *WhatsAppService) SendMessage(to string, body string) (string, error) {
 	payload := map[string]interface{}{
 		"messaging_product": "whatsapp",
 		"recipient_type":    "individual",
@@ -39,7 +50,8 @@ func (s *WhatsAppService) SendMessage(to string, body string) error {
 }
 
 // SendTemplateMessage sends a pre-approved template (Required for initiating conversation)
-func (s *WhatsAppService) SendTemplateMessage(to string, templateName string, language string, components []interface{}) error {
+// and returns Meta's message ID
+func (s *WhatsAppService) SendTemplateMessage(to string, templateName string, language string, components []interface{}) (string, error) {
 	payload := map[string]interface{}{
 		"messaging_product": "whatsapp",
 		"to":                to,
@@ -67,35 +79,56 @@ func (s *WhatsAppService) SendBroadcast(recipients []string, templateName string
 	return 0, nil
 }
 
-func (s *WhatsAppService) sendRequest(payload interface{}) error {
+// sendResponse is the Cloud API's reply to a send: the message ID on
+// success, the error otherwise
+type sendResponse struct {
+	Messages []struct {
+		ID string `json:"id"`
+	} `json:"messages"`
+	Error *struct {
+		Message string `json:"message"`
+		Code    int    `json:"code"`
+	} `json:"error"`
+}
+
+func (s *WhatsAppService) sendRequest(payload interface{}) (string, error) {
 	jsonBody, err := json.Marshal(payload)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	req, err := http.NewRequest("POST", s.APIURL, bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+s.AccessToken)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := &http.Client{Timeout: 20 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer resp.Body.Close()
 
+	var body sendResponse
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+
 	if resp.StatusCode == 401 {
 		// Silent error for unauthorized to avoid flooding logs if token is missing
-		return fmt.Errorf("API error: 401 Unauthorized (Check WHATSAPP_ACCESS_TOKEN)")
+		return "", fmt.Errorf("API error: 401 Unauthorized (Check WHATSAPP_ACCESS_TOKEN)")
 	}
 
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("API error: %s", resp.Status)
+		if body.Error != nil {
+			return "", fmt.Errorf("API error: %s: %s (code %d)", resp.Status, body.Error.Message, body.Error.Code)
+		}
+		return "", fmt.Errorf("API error: %s", resp.Status)
 	}
 
-	return nil
+	if len(body.Messages) == 0 {
+		return "", nil
+	}
+	return body.Messages[0].ID, nil
 }
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WhatsApp message statuses reported by the webhook
+const (
+	WhatsAppStatusSent      = "sent"
+	WhatsAppStatusDelivered = "delivered"
+	WhatsAppStatusRead      = "read"
+	WhatsAppStatusFailed    = "failed"
+)
+
+// VerifyWhatsAppSignature checks the X-Hub-Signature-256 header Meta sends
+// with every webhook: "sha256=" and the hex HMAC-SHA256 of the raw body,
+// keyed with the app secret.
+func VerifyWhatsAppSignature(appSecret string, body []byte, header string) bool {
+	if appSecret == "" {
+		return false
+	}
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(got, SignWhatsAppPayload(appSecret, body))
+}
+
+// SignWhatsAppPayload returns the HMAC-SHA256 of body keyed with appSecret
+func SignWhatsAppPayload(appSecret string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(appSecret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// WhatsAppWebhook is the body of a WhatsApp Cloud API webhook call. Only
+// the "messages" field is subscribed to; its value carries message statuses
+// and incoming messages.
+type WhatsAppWebhook struct {
+	Object string `json:"object"`
+	Entry  []struct {
+		ID      string `json:"id"`
+		Changes []struct {
+			Field string               `json:"field"`
+			Value WhatsAppWebhookValue `json:"value"`
+		} `json:"changes"`
+	} `json:"entry"`
+}
+
+type WhatsAppWebhookValue struct {
+	Contacts []struct {
+		WaID    string `json:"wa_id"`
+		Profile struct {
+			Name string `json:"name"`
+		} `json:"profile"`
+	} `json:"contacts"`
+	Messages []WhatsAppInbound `json:"messages"`
+	Statuses []WhatsAppStatus  `json:"statuses"`
+}
+
+// WhatsAppStatus is a status update for a message the portal sent
+type WhatsAppStatus struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"`
+	Timestamp   string `json:"timestamp"`
+	RecipientID string `json:"recipient_id"`
+	Errors      []struct {
+		Code    int    `json:"code"`
+		Title   string `json:"title"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// Error describes why a failed message was not delivered
+func (s WhatsAppStatus) Error() string {
+	var parts []string
+	for _, e := range s.Errors {
+		text := e.Title
+		if e.Message != "" && e.Message != e.Title {
+			text += ": " + e.Message
+		}
+		parts = append(parts, strconv.Itoa(e.Code)+" "+text)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// WhatsAppInbound is a message sent to the college's WhatsApp number
+type WhatsAppInbound struct {
+	From      string `json:"from"`
+	ID        string `json:"id"`
+	Timestamp string `json:"timestamp"`
+	Type      string `json:"type"`
+	Text      *struct {
+		Body string `json:"body"`
+	} `json:"text"`
+	Button *struct {
+		Text    string `json:"text"`
+		Payload string `json:"payload"`
+	} `json:"button"`
+	Interactive *struct {
+		ButtonReply *struct {
+			Title string `json:"title"`
+		} `json:"button_reply"`
+		ListReply *struct {
+			Title string `json:"title"`
+		} `json:"list_reply"`
+	} `json:"interactive"`
+}
+
+// Body returns the message's text: the typed text, or the label of a tapped
+// button. Media and other messages become "[<type>]".
+func (m WhatsAppInbound) Body() string {
+	switch {
+	case m.Text != nil:
+		return m.Text.Body
+	case m.Button != nil:
+		return m.Button.Text
+	case m.Interactive != nil && m.Interactive.ButtonReply != nil:
+		return m.Interactive.ButtonReply.Title
+	case m.Interactive != nil && m.Interactive.ListReply != nil:
+		return m.Interactive.ListReply.Title
+	}
+	return "[" + m.Type + "]"
+}
+
+// WhatsAppTime parses a webhook timestamp (Unix seconds), falling back to now
+func WhatsAppTime(timestamp string) time.Time {
+	if sec, err := strconv.ParseInt(timestamp, 10, 64); err == nil {
+		return time.Unix(sec, 0)
+	}
+	return time.Now()
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestVerifyWhatsAppSignature(t *testing.T) {
+	body := []byte(`{"object":"whatsapp_business_account","entry":[]}`)
+	valid := hex.EncodeToString(SignWhatsAppPayload("dev-secret", body))
+
+	tests := []struct {
+		name   string
+		secret string
+		body   []byte
+		header string
+		want   bool
+	}{
+		{"valid", "dev-secret", body, "sha256=" + valid, true},
+		{"missing sha256= prefix", "dev-secret", body, valid, false},
+		{"other algorithm prefix", "dev-secret", body, "sha1=" + valid, false},
+		{"invalid hex", "dev-secret", body, "sha256=not-hex-" + valid[15:], false},
+		{"empty header", "dev-secret", body, "", false},
+		{"empty secret", "", body, "sha256=" + hex.EncodeToString(SignWhatsAppPayload("", body)), false},
+		{"wrong secret", "other-secret", body, "sha256=" + valid, false},
+		{"tampered body", "dev-secret", append([]byte(" "), body...), "sha256=" + valid, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyWhatsAppSignature(tt.secret, tt.body, tt.header); got != tt.want {
+				t.Errorf("VerifyWhatsAppSignature = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,221 +1,173 @@
 package utils
 
 import (
+	"bytes"
+	"crypto/tls"
 	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
 	"net/smtp"
+	"net/textproto"
 	"os"
+	"time"
 )
 
-func SendOTPEmail(toEmail, otp string) error {
-	from := os.Getenv("SMTP_EMAIL")
-	password := os.Getenv("SMTP_PASSWORD")
-	host := "smtp.gmail.com"
-	port := "587"
-
-	// Email Body with professional styling
-	subject := "Subject: Password Reset Request - Placement Portal\n"
-	mime := "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
-	body := fmt.Sprintf(`
-<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Password Reset</title>
-</head>
-<body style="margin: 0; padding: 0; font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, sans-serif; background-color: #f8f9fa;">
-    <table role="presentation" cellpadding="0" cellspacing="0" width="100%%" style="background-color: #f8f9fa; padding: 40px 20px;">
-        <tr>
-            <td align="center">
-                <table role="presentation" cellpadding="0" cellspacing="0" width="600" style="max-width: 600px; background-color: #ffffff; border-radius: 12px; box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1); overflow: hidden;">
-                    
-                    <!-- Header -->
-                    <tr>
-                        <td style="background: linear-gradient(135deg, #002147 0%%, #003d82 100%%); padding: 40px 30px; text-align: center;">
-                            <h1 style="margin: 0; color: #ffffff; font-size: 28px; font-weight: 700;">Placement Portal</h1>
-                            <p style="margin: 8px 0 0 0; color: #e0e7ff; font-size: 14px;">Kongu Engineering College</p>
-                        </td>
-                    </tr>
-                    
-                    <!-- Body -->
-                    <tr>
-                        <td style="padding: 40px 30px;">
-                            <h2 style="margin: 0 0 16px 0; color: #1a1a1a; font-size: 24px; font-weight: 600;">Password Reset Request</h2>
-                            <p style="margin: 0 0 24px 0; color: #6b7280; font-size: 16px; line-height: 1.6;">
-                                We received a request to reset your password. Use the One-Time Password (OTP) below to proceed:
-                            </p>
-                            
-                            <!-- OTP Box -->
-                            <table role="presentation" cellpadding="0" cellspacing="0" width="100%%" style="margin: 0 0 32px 0;">
-                                <tr>
-                                    <td align="center" style="background-color: #f0f4ff; border: 2px dashed #002147; border-radius: 8px; padding: 24px;">
-                                        <p style="margin: 0 0 8px 0; color: #6b7280; font-size: 14px; font-weight: 500; text-transform: uppercase; letter-spacing: 0.5px;">Your OTP Code</p>
-                                        <p style="margin: 0; color: #002147; font-size: 42px; font-weight: 700; letter-spacing: 8px; font-family: 'Courier New', monospace;">%s</p>
-                                    </td>
-                                </tr>
-                            </table>
-                            
-                            <!-- Security Info -->
-                            <div style="background-color: #fff3cd; border-left: 4px solid #ffc107; padding: 16px 20px; margin: 0 0 24px 0; border-radius: 4px;">
-                                <p style="margin: 0 0 8px 0; color: #856404; font-size: 14px; font-weight: 600;">⚠️ Security Notice</p>
-                                <ul style="margin: 0; padding-left: 20px; color: #856404; font-size: 14px; line-height: 1.6;">
-                                    <li>This code expires in <strong>2 minutes</strong></li>
-                                    <li>Never share this code with anyone</li>
-                                    <li>If you didn't request this, please ignore this email</li>
-                                </ul>
-                            </div>
-                            
-                            <p style="margin: 0; color: #6b7280; font-size: 14px; line-height: 1.6;">
-                                After entering the OTP, you'll be able to create a new password for your account.
-                            </p>
-                        </td>
-                    </tr>
-                    
-                    <!-- Footer -->
-                    <tr>
-                        <td style="background-color: #f8f9fa; padding: 30px; text-align: center; border-top: 1px solid #e5e7eb;">
-                            <p style="margin: 0 0 8px 0; color: #9ca3af; font-size: 13px;">
-                                This is an automated message from Placement Portal
-                            </p>
-                            <p style="margin: 0; color: #9ca3af; font-size: 12px;">
-                                © 2026 Kongu Engineering College. All rights reserved.
-                            </p>
-                        </td>
-                    </tr>
-                    
-                </table>
-            </td>
-        </tr>
-    </table>
-</body>
-</html>
-    `, otp)
-
-	msg := []byte(subject + mime + body)
+// SMTPConfig is the outgoing mail server. It defaults to Gmail on port 587
+// with STARTTLS; a local sink such as Mailpit works with SMTP_HOST=localhost,
+// SMTP_PORT=1025, SMTP_TLS=none and no password.
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	TLS      string // "starttls", "tls" (implicit, usually port 465) or "none"
+	Username string
+	Password string
+	From     string
+}
 
-	// Authentication
-	auth := smtp.PlainAuth("", from, password, host)
+// LoadSMTPConfig reads SMTP_HOST, SMTP_PORT, SMTP_TLS, SMTP_EMAIL,
+// SMTP_PASSWORD and SMTP_FROM (defaults to SMTP_EMAIL).
+func LoadSMTPConfig() SMTPConfig {
+	cfg := SMTPConfig{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     os.Getenv("SMTP_PORT"),
+		TLS:      os.Getenv("SMTP_TLS"),
+		Username: os.Getenv("SMTP_EMAIL"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	}
+	if cfg.Host == "" {
+		cfg.Host = "smtp.gmail.com"
+	}
+	if cfg.Port == "" {
+		cfg.Port = "587"
+	}
+	if cfg.TLS == "" {
+		cfg.TLS = "starttls"
+	}
+	if cfg.From == "" {
+		cfg.From = cfg.Username
+	}
+	return cfg
+}
 
-	// Send
-	addr := host + ":" + port
-	err := smtp.SendMail(addr, auth, from, []string{toEmail}, msg)
+// SendEmail sends an email with an HTML body and, when textBody is set, a
+// plain-text alternative for clients that do not show HTML.
+func SendEmail(toEmail, subject, htmlBody, textBody string) error {
+	cfg := LoadSMTPConfig()
+	msg, err := buildEmail(cfg.From, toEmail, subject, htmlBody, textBody)
 	if err != nil {
+		return err
+	}
+	if err := sendSMTP(cfg, toEmail, msg); err != nil {
 		return fmt.Errorf("failed to send email: %w", err)
 	}
-
 	return nil
 }
 
-func SendWelcomeEmail(toEmail, studentName, otp string) error {
-	from := os.Getenv("SMTP_EMAIL")
-	password := os.Getenv("SMTP_PASSWORD")
-	host := "smtp.gmail.com"
-	port := "587"
+func buildEmail(from, to, subject, htmlBody, textBody string) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
 
-	// Email Body with professional styling
-	subject := "Subject: Welcome to Placement Portal - Set Up Your Account\n"
-	mime := "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
-	body := fmt.Sprintf(`
-<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Welcome to Placement Portal</title>
-</head>
-<body style="margin: 0; padding: 0; font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, sans-serif; background-color: #f8f9fa;">
-    <table role="presentation" cellpadding="0" cellspacing="0" width="100%%" style="background-color: #f8f9fa; padding: 40px 20px;">
-        <tr>
-            <td align="center">
-                <table role="presentation" cellpadding="0" cellspacing="0" width="600" style="max-width: 600px; background-color: #ffffff; border-radius: 12px; box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1); overflow: hidden;">
-                    
-                    <!-- Header -->
-                    <tr>
-                        <td style="background: linear-gradient(135deg, #002147 0%%, #003d82 100%%); padding: 40px 30px; text-align: center;">
-                            <h1 style="margin: 0; color: #ffffff; font-size: 28px; font-weight: 700;">Welcome to Placement Portal</h1>
-                            <p style="margin: 8px 0 0 0; color: #e0e7ff; font-size: 14px;">Kongu Engineering College</p>
-                        </td>
-                    </tr>
-                    
-                    <!-- Body -->
-                    <tr>
-                        <td style="padding: 40px 30px;">
-                            <h2 style="margin: 0 0 16px 0; color: #1a1a1a; font-size: 24px; font-weight: 600;">Hello %s! 👋</h2>
-                            <p style="margin: 0 0 16px 0; color: #6b7280; font-size: 16px; line-height: 1.6;">
-                                Your student account has been created successfully by your placement administrator.
-                            </p>
-                            <p style="margin: 0 0 24px 0; color: #6b7280; font-size: 16px; line-height: 1.6;">
-                                To get started and secure your account, please use the One-Time Password (OTP) below to set up your password:
-                            </p>
-                            
-                            <!-- OTP Box -->
-                            <table role="presentation" cellpadding="0" cellspacing="0" width="100%%" style="margin: 0 0 32px 0;">
-                                <tr>
-                                    <td align="center" style="background-color: #f0f4ff; border: 2px dashed #002147; border-radius: 8px; padding: 24px;">
-                                        <p style="margin: 0 0 8px 0; color: #6b7280; font-size: 14px; font-weight: 500; text-transform: uppercase; letter-spacing: 0.5px;">Your OTP Code</p>
-                                        <p style="margin: 0; color: #002147; font-size: 42px; font-weight: 700; letter-spacing: 8px; font-family: 'Courier New', monospace;">%s</p>
-                                    </td>
-                                </tr>
-                            </table>
-                            
-                            <!-- Instructions -->
-                            <div style="background-color: #f0f9ff; border-left: 4px solid #0284c7; padding: 16px 20px; margin: 0 0 24px 0; border-radius: 4px;">
-                                <p style="margin: 0 0 8px 0; color: #075985; font-size: 14px; font-weight: 600;">📱 Next Steps:</p>
-                                <ol style="margin: 0; padding-left: 20px; color: #075985; font-size: 14px; line-height: 1.6;">
-                                    <li>Download the Placement Portal mobile app</li>
-                                    <li>Use the "Forgot Password" option on the login screen</li>
-                                    <li>Enter your email and the OTP code above</li>
-                                    <li>Create a strong password for your account</li>
-                                </ol>
-                            </div>
-                            
-                            <!-- Security Info -->
-                            <div style="background-color: #fff3cd; border-left: 4px solid #ffc107; padding: 16px 20px; margin: 0 0 24px 0; border-radius: 4px;">
-                                <p style="margin: 0 0 8px 0; color: #856404; font-size: 14px; font-weight: 600;">⚠️ Security Notice</p>
-                                <ul style="margin: 0; padding-left: 20px; color: #856404; font-size: 14px; line-height: 1.6;">
-                                    <li>This code expires in <strong>2 minutes</strong></li>
-                                    <li>Never share this code with anyone</li>
-                                    <li>Keep your password secure and confidential</li>
-                                </ul>
-                            </div>
-                            
-                            <p style="margin: 0; color: #6b7280; font-size: 14px; line-height: 1.6;">
-                                If you have any questions or need assistance, please contact your placement coordinator.
-                            </p>
-                        </td>
-                    </tr>
-                    
-                    <!-- Footer -->
-                    <tr>
-                        <td style="background-color: #f8f9fa; padding: 30px; text-align: center; border-top: 1px solid #e5e7eb;">
-                            <p style="margin: 0 0 8px 0; color: #9ca3af; font-size: 13px;">
-                                This is an automated message from Placement Portal
-                            </p>
-                            <p style="margin: 0; color: #9ca3af; font-size: 12px;">
-                                © 2026 Kongu Engineering College. All rights reserved.
-                            </p>
-                        </td>
-                    </tr>
-                    
-                </table>
-            </td>
-        </tr>
-    </table>
-</body>
-</html>
-    `, studentName, otp)
+	if textBody == "" {
+		buf.WriteString("Content-Type: text/html; charset=\"UTF-8\"\r\n")
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&buf, htmlBody); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
 
-	msg := []byte(subject + mime + body)
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", parts.Boundary())
+	// Clients show the last alternative they understand, so HTML goes last
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain", textBody},
+		{"text/html", htmlBody},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=\"UTF-8\""},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
 
-	// Authentication
-	auth := smtp.PlainAuth("", from, password, host)
+func writeQuotedPrintable(w io.Writer, s string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(s)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func sendSMTP(cfg SMTPConfig, to string, msg []byte) error {
+	addr := net.JoinHostPort(cfg.Host, cfg.Port)
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
 
-	// Send
-	addr := host + ":" + port
-	err := smtp.SendMail(addr, auth, from, []string{toEmail}, msg)
+	var conn net.Conn
+	var err error
+	if cfg.TLS == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+		return err
 	}
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
 
-	return nil
+	if cfg.TLS == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS (set SMTP_TLS=none for a local sink)", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if cfg.Password != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(cfg.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
 }
@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Tokens are minted only by auth-service (RS256, identified by kid). This
+// service verifies them against the public keys published at JWKS_URL, which
+// are cached in memory and refreshed periodically or whenever a token arrives
+// signed with a kid we have not seen yet (i.e. right after a key rotation).
+
+const (
+	jwksRefreshInterval = 10 * time.Minute
+	jwksMinRefetchGap   = 30 * time.Second
+)
+
+var (
+	jwksMu          sync.RWMutex
+	jwksKeys        = map[string]*rsa.PublicKey{}
+	jwksLastAttempt time.Time
+	jwksClient      = &http.Client{Timeout: 5 * time.Second}
+)
+
+// JWKSURL returns the auth-service key endpoint, defaulting to the docker network address.
+func JWKSURL() string {
+	if url := os.Getenv("JWKS_URL"); url != "" {
+		return url
+	}
+	return "http://auth-service:8081/api/v1/auth/.well-known/jwks.json"
+}
+
+// InitJWKS performs the first key fetch and starts the background refresh.
+// A failed first fetch is not fatal: the keys are fetched lazily on first use.
+func InitJWKS() {
+	if err := refreshJWKS(); err != nil {
+		log.Printf("Warning: initial JWKS fetch failed: %v", err)
+	}
+	go func() {
+		ticker := time.NewTicker(jwksRefreshInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := refreshJWKS(); err != nil {
+				log.Printf("Warning: JWKS refresh failed: %v", err)
+			}
+		}
+	}()
+}
+
+// ParseToken verifies a token issued by auth-service and returns it.
+func ParseToken(tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, jwksKeyfunc, jwt.WithValidMethods([]string{"RS256"}))
+}
+
+func jwksKeyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no kid")
+	}
+
+	if key := lookupJWK(kid); key != nil {
+		return key, nil
+	}
+
+	// Unknown kid: the signer may have rotated. Refetch, but not more than
+	// once per jwksMinRefetchGap so junk tokens cannot hammer auth-service.
+	jwksMu.RLock()
+	recent := time.Since(jwksLastAttempt) < jwksMinRefetchGap
+	jwksMu.RUnlock()
+	if !recent {
+		if err := refreshJWKS(); err != nil {
+			log.Printf("Warning: JWKS refresh failed: %v", err)
+		}
+	}
+
+	if key := lookupJWK(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func lookupJWK(kid string) *rsa.PublicKey {
+	jwksMu.RLock()
+	defer jwksMu.RUnlock()
+	return jwksKeys[kid]
+}
+
+func refreshJWKS() error {
+	jwksMu.Lock()
+	jwksLastAttempt = time.Now()
+	jwksMu.Unlock()
+
+	resp, err := jwksClient.Get(JWKSURL())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS endpoint returned %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(body.Keys))
+	for _, k := range body.Keys {
+		if k.Kty != "RSA" || k.Kid == "" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS contained no usable keys")
+	}
+
+	jwksMu.Lock()
+	jwksKeys = keys
+	jwksMu.Unlock()
+	return nil
+}
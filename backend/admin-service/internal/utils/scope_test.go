@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestDepartmentScope(t *testing.T) {
+	tests := []struct {
+		name string
+		role string
+		dept string
+		want *string
+	}{
+		{name: "admin sees every department", role: "admin", dept: "CSE", want: nil},
+		{name: "super admin sees every department", role: "super_admin", want: nil},
+		{name: "coordinator is scoped to their department", role: "coordinator", dept: "CSE", want: ptr("CSE")},
+		{name: "coordinator without a department sees nothing", role: "coordinator", want: ptr("")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			var got *string
+			app.Get("/", func(c *fiber.Ctx) error {
+				c.Locals("role", tt.role)
+				if tt.dept != "" {
+					c.Locals("department_code", tt.dept)
+				}
+				got = DepartmentScope(c)
+				return nil
+			})
+			if _, err := app.Test(httptest.NewRequest("GET", "/", nil)); err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case tt.want == nil && got != nil:
+				t.Fatalf("DepartmentScope() = %q, want nil", *got)
+			case tt.want != nil && (got == nil || *got != *tt.want):
+				t.Fatalf("DepartmentScope() = %v, want %q", got, *tt.want)
+			}
+		})
+	}
+}
+
+func ptr(s string) *string { return &s }
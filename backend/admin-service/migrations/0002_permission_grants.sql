@@ -0,0 +1,43 @@
+-- ==========================================
+-- ADMIN SERVICE — Migration 0002
+-- Permission keys are now enforced on every admin route (RequirePermission).
+-- Backfill grants so existing admins keep the access they had when only the
+-- role was checked, and existing coordinators hold the coordinator defaults
+-- (SPOC, storage and manual drive operations stay admin-only unless granted).
+-- ==========================================
+
+SET search_path TO admin, public;
+
+-- 1. Admins with no grants at all get every key; coordinators with none get
+--    the coordinator defaults (models.DefaultPermissionsForRole), so they hold
+--    the same keys as coordinators created from now on
+INSERT INTO role_permissions (user_id, permission_key, is_granted)
+SELECT u.id, k.key, TRUE
+FROM users u
+CROSS JOIN (VALUES
+    ('manage_drives'), ('manage_students'), ('approve_changes'), ('manage_spocs'), ('manage_storage'),
+    ('export_data'), ('manual_drive_ops'), ('view_analytics'), ('manage_config'), ('send_broadcasts')
+) AS k(key)
+WHERE u.role = 'admin'
+  AND NOT EXISTS (SELECT 1 FROM role_permissions rp WHERE rp.user_id = u.id)
+ON CONFLICT (user_id, permission_key) DO NOTHING;
+
+INSERT INTO role_permissions (user_id, permission_key, is_granted)
+SELECT u.id, k.key, TRUE
+FROM users u
+CROSS JOIN (VALUES
+    ('manage_drives'), ('manage_students'), ('approve_changes'), ('export_data'), ('view_analytics'),
+    ('manage_config'), ('send_broadcasts')
+) AS k(key)
+WHERE u.role = 'coordinator'
+  AND NOT EXISTS (SELECT 1 FROM role_permissions rp WHERE rp.user_id = u.id)
+ON CONFLICT (user_id, permission_key) DO NOTHING;
+
+-- 2. Keys introduced with this migration guard routes that were previously open
+--    to every admin/coordinator, so grant them to everyone who already had grants
+INSERT INTO role_permissions (user_id, permission_key, is_granted)
+SELECT u.id, k.key, TRUE
+FROM users u
+CROSS JOIN (VALUES ('manage_config'), ('send_broadcasts')) AS k(key)
+WHERE u.role IN ('admin', 'coordinator')
+ON CONFLICT (user_id, permission_key) DO NOTHING;
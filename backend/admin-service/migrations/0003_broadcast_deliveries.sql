@@ -0,0 +1,57 @@
+-- ==========================================
+-- ADMIN SERVICE — Migration 0003
+-- Broadcast delivery pipeline. Each send is recorded in broadcasts and fanned
+-- out to one broadcast_deliveries row per recipient and channel, rendered at
+-- send time. The dispatcher drains queued rows and stores the provider outcome
+-- so the broadcast report can show per-recipient status.
+-- ==========================================
+
+SET search_path TO admin, public;
+
+CREATE TABLE IF NOT EXISTS broadcasts (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    template_id INTEGER REFERENCES broadcast_templates(id) ON DELETE SET NULL,
+    template_name VARCHAR(100),
+    channels TEXT[] NOT NULL,
+    target_group VARCHAR(30) NOT NULL DEFAULT 'all_students',
+    filters JSONB NOT NULL DEFAULT '{}'::jsonb,
+    subject VARCHAR(255),
+    message TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'sending', 'completed')),
+    recipient_count INTEGER NOT NULL DEFAULT 0,
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_broadcasts_created ON broadcasts(created_at DESC);
+
+-- address is the email, WhatsApp number or FCM token; NULL when the recipient
+-- has none for the channel (the row is then created as skipped).
+-- params holds the template variable values in declaration order, used as the
+-- body parameters of WhatsApp template messages.
+CREATE TABLE IF NOT EXISTS broadcast_deliveries (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    broadcast_id BIGINT NOT NULL REFERENCES broadcasts(id) ON DELETE CASCADE,
+    user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    channel VARCHAR(20) NOT NULL CHECK (channel IN ('EMAIL', 'WHATSAPP', 'PUSH')),
+    address TEXT,
+    subject VARCHAR(255),
+    body TEXT NOT NULL,
+    params JSONB NOT NULL DEFAULT '[]'::jsonb,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'sending', 'sent', 'failed', 'skipped')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (broadcast_id, user_id, channel)
+);
+
+CREATE INDEX IF NOT EXISTS idx_broadcast_deliveries_queued
+    ON broadcast_deliveries(next_attempt_at) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS idx_broadcast_deliveries_broadcast
+    ON broadcast_deliveries(broadcast_id, status);
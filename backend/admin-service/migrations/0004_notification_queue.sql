@@ -0,0 +1,88 @@
+-- ==========================================
+-- ADMIN SERVICE — Migration 0004
+-- Durable outbound notification queue shared by every service.
+-- Owns: notify.jobs, notify.dead_letters
+-- Producers (admin, drive, chat) insert into notify.jobs; admin-service runs
+-- the worker pools that drain it with SKIP LOCKED, retry with exponential
+-- backoff and move jobs that exhaust their attempts to notify.dead_letters.
+-- ==========================================
+
+CREATE SCHEMA IF NOT EXISTS notify;
+SET search_path TO notify, public;
+
+-- payload is the provider message: address, subject, body, html,
+-- template_name, params, data. idempotency_key makes re-enqueueing the same
+-- notification (a retried request, a re-run sync) a no-op.
+CREATE TABLE IF NOT EXISTS jobs (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    provider VARCHAR(20) NOT NULL CHECK (provider IN ('EMAIL', 'WHATSAPP', 'PUSH')),
+    kind VARCHAR(50) NOT NULL,
+    source VARCHAR(30) NOT NULL,
+    payload JSONB NOT NULL,
+    idempotency_key TEXT UNIQUE,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'running', 'done', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 5,
+    run_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_at TIMESTAMPTZ,
+    locked_by TEXT,
+    last_error TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_notify_jobs_due
+    ON jobs(provider, run_at) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS idx_notify_jobs_running
+    ON jobs(locked_at) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS idx_notify_jobs_done
+    ON jobs(completed_at) WHERE status = 'done';
+
+-- One row per time a job ran out of attempts. The job itself stays in
+-- notify.jobs as 'dead' so its idempotency key keeps blocking duplicates.
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    job_id BIGINT NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    provider VARCHAR(20) NOT NULL,
+    kind VARCHAR(50) NOT NULL,
+    source VARCHAR(30) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INTEGER NOT NULL,
+    last_error TEXT,
+    failed_at TIMESTAMPTZ DEFAULT NOW(),
+    requeued_at TIMESTAMPTZ,
+    requeued_by BIGINT REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_notify_dead_letters_open
+    ON dead_letters(failed_at DESC) WHERE requeued_at IS NULL;
+
+-- Broadcast deliveries are now sent through the queue; job_id links each
+-- delivery to the job that carries it.
+ALTER TABLE admin.broadcast_deliveries ADD COLUMN IF NOT EXISTS job_id BIGINT REFERENCES jobs(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_broadcast_deliveries_job ON admin.broadcast_deliveries(job_id);
+ALTER TABLE admin.broadcast_deliveries DROP COLUMN IF EXISTS next_attempt_at;
+DROP INDEX IF EXISTS admin.idx_broadcast_deliveries_queued;
+
+-- Hand deliveries still waiting from the old dispatcher to the queue
+WITH pending AS (
+    SELECT d.id, d.channel, d.address, d.subject, d.body, d.params, b.template_name
+    FROM admin.broadcast_deliveries d
+    JOIN admin.broadcasts b ON b.id = d.broadcast_id
+    WHERE d.status IN ('queued', 'sending') AND d.job_id IS NULL
+), enqueued AS (
+    INSERT INTO jobs (provider, kind, source, payload, idempotency_key)
+    SELECT channel, 'broadcast', 'admin-service',
+           jsonb_build_object('address', address, 'subject', subject, 'body', body,
+                              'template_name', template_name, 'params', params),
+           'broadcast-delivery:' || id
+    FROM pending
+    ON CONFLICT (idempotency_key) DO NOTHING
+    RETURNING id, idempotency_key
+)
+UPDATE admin.broadcast_deliveries d
+SET job_id = e.id, status = 'queued'
+FROM enqueued e
+WHERE e.idempotency_key = 'broadcast-delivery:' || d.id;
@@ -0,0 +1,49 @@
+-- ==========================================
+-- ADMIN SERVICE — Migration 0005
+-- In-app notification inbox for students and staff.
+-- Owns: notify.inbox
+-- Every push notification is also written here (admin and drive services
+-- insert the inbox row and its PUSH job together). chat-service serves the
+-- inbox API and delivers new rows live over its WebSocket, woken by the
+-- notify_inbox channel.
+-- ==========================================
+
+CREATE SCHEMA IF NOT EXISTS notify;
+SET search_path TO notify, public;
+
+-- category groups notifications for filtering: drive_announcement,
+-- application_status, change_request, broadcast. type is the finer-grained
+-- event (new_drive, drive_cancelled, ...) the clients route on, and data
+-- carries the same string map as the push payload. dedupe_key makes
+-- re-sending the same event to a user a no-op.
+CREATE TABLE IF NOT EXISTS inbox (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category VARCHAR(30) NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    source VARCHAR(30) NOT NULL,
+    dedupe_key TEXT UNIQUE,
+    read_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notify_inbox_user
+    ON inbox(user_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_notify_inbox_unread
+    ON inbox(user_id) WHERE read_at IS NULL;
+
+-- Tell chat-service about new items so it can push them to connected clients
+CREATE OR REPLACE FUNCTION notify.notify_inbox_insert() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('notify_inbox', NEW.user_id::text || ':' || NEW.id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS inbox_insert ON inbox;
+CREATE TRIGGER inbox_insert
+    AFTER INSERT ON inbox
+    FOR EACH ROW EXECUTE FUNCTION notify.notify_inbox_insert();
@@ -0,0 +1,73 @@
+-- ==========================================
+-- ADMIN SERVICE — Migration 0006
+-- Per-user notification preferences and quiet hours.
+-- Owns: notify.preferences, notify.quiet_hours
+-- Jobs now carry the recipient and category they were sent for; the
+-- notification workers consult these tables before every send, and the
+-- inbox writers honour the IN_APP channel.
+-- ==========================================
+
+CREATE SCHEMA IF NOT EXISTS notify;
+SET search_path TO notify, public;
+
+-- One row per switched channel; a missing row means enabled.
+CREATE TABLE IF NOT EXISTS preferences (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category VARCHAR(30) NOT NULL
+        CHECK (category IN ('drive_announcement', 'application_status', 'change_request', 'chat_mention', 'broadcast')),
+    channel VARCHAR(20) NOT NULL CHECK (channel IN ('PUSH', 'EMAIL', 'WHATSAPP', 'IN_APP')),
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (user_id, category, channel)
+);
+
+-- start_time/end_time are wall-clock times in timezone; a window with
+-- start_time > end_time runs past midnight (22:00-07:00).
+CREATE TABLE IF NOT EXISTS quiet_hours (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    timezone TEXT NOT NULL DEFAULT 'Asia/Kolkata',
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Who and what a job is for. Jobs without a user or category (welcome
+-- emails, OTPs) are transactional and always sent; critical jobs (round
+-- reschedules, cancellations) ignore quiet hours but not opt-outs.
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS user_id BIGINT REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS category VARCHAR(30);
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS critical BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- 'suppressed' jobs were dropped because the recipient opted out
+ALTER TABLE jobs DROP CONSTRAINT IF EXISTS jobs_status_check;
+ALTER TABLE jobs ADD CONSTRAINT jobs_status_check
+    CHECK (status IN ('queued', 'running', 'done', 'dead', 'suppressed'));
+
+-- channel_enabled reports whether user_id wants category over channel
+CREATE OR REPLACE FUNCTION notify.channel_enabled(p_user_id BIGINT, p_category TEXT, p_channel TEXT)
+RETURNS BOOLEAN AS $$
+    SELECT COALESCE(
+        (SELECT enabled FROM notify.preferences
+         WHERE user_id = p_user_id AND category = p_category AND channel = p_channel),
+        TRUE)
+$$ LANGUAGE sql STABLE;
+
+-- quiet_until returns when user_id's quiet hours end if p_at falls inside
+-- them, and NULL otherwise
+CREATE OR REPLACE FUNCTION notify.quiet_until(p_user_id BIGINT, p_at TIMESTAMPTZ)
+RETURNS TIMESTAMPTZ AS $$
+    SELECT CASE
+        WHEN q.start_time = q.end_time THEN NULL
+        WHEN q.start_time < q.end_time THEN
+            CASE WHEN l.t >= q.start_time AND l.t < q.end_time
+                 THEN (l.d + q.end_time) AT TIME ZONE q.timezone END
+        ELSE
+            CASE WHEN l.t >= q.start_time THEN ((l.d + 1) + q.end_time) AT TIME ZONE q.timezone
+                 WHEN l.t < q.end_time THEN (l.d + q.end_time) AT TIME ZONE q.timezone END
+    END
+    FROM notify.quiet_hours q,
+         LATERAL (SELECT (p_at AT TIME ZONE q.timezone)::date AS d,
+                         (p_at AT TIME ZONE q.timezone)::time AS t) l
+    WHERE q.user_id = p_user_id AND q.enabled
+$$ LANGUAGE sql STABLE;
@@ -0,0 +1,31 @@
+-- ==========================================
+-- ADMIN SERVICE — Migration 0007
+-- Push device tokens, several per user.
+-- Owns: notify.device_tokens
+-- Apps register a token per device (student-service POST /user/fcm-token).
+-- PUSH jobs are addressed to a user; the FCM provider sends to all of their
+-- tokens and deletes the ones FCM reports as unregistered or invalid.
+-- users.fcm_token is still written with the latest token for older readers.
+-- ==========================================
+
+CREATE SCHEMA IF NOT EXISTS notify;
+SET search_path TO notify, public;
+
+CREATE TABLE IF NOT EXISTS device_tokens (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token TEXT NOT NULL UNIQUE,
+    platform VARCHAR(20) NOT NULL DEFAULT 'unknown'
+        CHECK (platform IN ('android', 'ios', 'web', 'unknown')),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notify_device_tokens_user
+    ON device_tokens(user_id, last_seen_at DESC);
+
+-- Carry over the single token each user had so far
+INSERT INTO device_tokens (user_id, token)
+SELECT id, fcm_token FROM users
+WHERE fcm_token IS NOT NULL AND fcm_token != ''
+ON CONFLICT (token) DO NOTHING;
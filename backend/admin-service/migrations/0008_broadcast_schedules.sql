@@ -0,0 +1,64 @@
+-- ==========================================
+-- ADMIN SERVICE — Migration 0008
+-- Scheduled and recurring broadcasts, and the built-in drive reminders.
+-- Owns: broadcast_schedules, drive_reminders
+-- A schedule keeps the broadcast as composed (message, channels, filters)
+-- and is sent by the admin-service scheduler when next_run_at passes,
+-- resolving recipients at that moment. Each occurrence becomes an ordinary
+-- broadcast tagged with schedule_id/scheduled_for, which is unique so an
+-- occurrence is never sent twice.
+-- ==========================================
+
+SET search_path TO admin, public;
+
+-- frequency 'once' runs at next_run_at only; 'daily' and 'weekly' (on
+-- weekdays, 0 = Sunday) run at time_of_day in timezone until ends_at.
+CREATE TABLE IF NOT EXISTS broadcast_schedules (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    template_id INTEGER REFERENCES broadcast_templates(id) ON DELETE SET NULL,
+    template_name VARCHAR(100),
+    variables JSONB NOT NULL DEFAULT '[]'::jsonb,
+    channels TEXT[] NOT NULL,
+    target_group VARCHAR(30) NOT NULL DEFAULT 'all_students',
+    filters JSONB NOT NULL DEFAULT '{}'::jsonb,
+    department_scope VARCHAR(20),
+    subject VARCHAR(255),
+    message TEXT NOT NULL,
+    frequency VARCHAR(10) NOT NULL DEFAULT 'once'
+        CHECK (frequency IN ('once', 'daily', 'weekly')),
+    weekdays SMALLINT[] NOT NULL DEFAULT '{}',
+    time_of_day TIME,
+    timezone TEXT NOT NULL DEFAULT 'Asia/Kolkata',
+    ends_at TIMESTAMPTZ,
+    next_run_at TIMESTAMPTZ,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled'
+        CHECK (status IN ('scheduled', 'completed', 'cancelled')),
+    run_count INTEGER NOT NULL DEFAULT 0,
+    last_run_at TIMESTAMPTZ,
+    last_broadcast_id BIGINT REFERENCES broadcasts(id) ON DELETE SET NULL,
+    last_error TEXT,
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    cancelled_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_broadcast_schedules_due
+    ON broadcast_schedules(next_run_at) WHERE status = 'scheduled';
+
+ALTER TABLE broadcasts ADD COLUMN IF NOT EXISTS schedule_id BIGINT REFERENCES broadcast_schedules(id) ON DELETE SET NULL;
+ALTER TABLE broadcasts ADD COLUMN IF NOT EXISTS scheduled_for TIMESTAMPTZ;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_broadcasts_schedule_occurrence
+    ON broadcasts(schedule_id, scheduled_for);
+
+-- One row per automatic reminder sent for a drive: 'drive_date' (24h before
+-- the drive, to opted-in students). Switched off with the system setting
+-- broadcast_reminder_drive_date = 'false'. Registration deadline reminders,
+-- including the one 24h before deadline_date, are sent by drive-service
+-- (drive.drive_deadline_reminders, DEADLINE_REMINDER_OFFSETS).
+CREATE TABLE IF NOT EXISTS drive_reminders (
+    drive_id BIGINT NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('drive_date')),
+    broadcast_id BIGINT REFERENCES broadcasts(id) ON DELETE SET NULL,
+    sent_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (drive_id, kind)
+);
@@ -0,0 +1,65 @@
+-- ==========================================
+-- ADMIN SERVICE — Migration 0009
+-- Two-way WhatsApp: delivery receipts and replies from the Meta webhook.
+-- Owns: notify.delivery_receipts, support_messages
+-- Sent WhatsApp jobs keep the message ID Meta returned; the webhook records
+-- each sent/delivered/read/failed status against it. Replies are either
+-- opt-out/opt-in keywords, which switch the sender's WHATSAPP preferences,
+-- or free text, which lands in the support inbox (permission manage_support).
+-- ==========================================
+
+SET search_path TO admin, public;
+
+ALTER TABLE notify.jobs ADD COLUMN IF NOT EXISTS provider_message_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_notify_jobs_provider_message
+    ON notify.jobs(provider, provider_message_id) WHERE provider_message_id IS NOT NULL;
+
+-- One row per status Meta reports for a message; webhooks are retried, so
+-- a repeated status is ignored. Receipts can arrive before the worker has
+-- stored the message ID on its job, so they are matched to jobs on read.
+CREATE TABLE IF NOT EXISTS notify.delivery_receipts (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    provider VARCHAR(20) NOT NULL,
+    provider_message_id TEXT NOT NULL,
+    recipient TEXT,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('sent', 'delivered', 'read', 'failed')),
+    error TEXT,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    received_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (provider, provider_message_id, status)
+);
+
+-- Free-text messages students (or anyone) send to the college's WhatsApp
+-- number. user_id is the student whose mobile number matches, if any.
+CREATE TABLE IF NOT EXISTS support_messages (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    channel VARCHAR(20) NOT NULL DEFAULT 'WHATSAPP',
+    from_address TEXT NOT NULL,
+    contact_name TEXT,
+    user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    provider_message_id TEXT UNIQUE,
+    message_type VARCHAR(20) NOT NULL DEFAULT 'text',
+    body TEXT NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    status VARCHAR(20) NOT NULL DEFAULT 'open'
+        CHECK (status IN ('open', 'replied', 'closed')),
+    reply TEXT,
+    replied_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    replied_at TIMESTAMPTZ,
+    reply_job_id BIGINT REFERENCES notify.jobs(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_support_messages_status
+    ON support_messages(status, received_at DESC);
+CREATE INDEX IF NOT EXISTS idx_support_messages_from
+    ON support_messages(from_address, received_at DESC);
+
+-- The support inbox is guarded by its own key, manage_support. Grant it to
+-- admins/coordinators who reach the inbox today through send_broadcasts.
+INSERT INTO role_permissions (user_id, permission_key, is_granted)
+SELECT u.id, 'manage_support', TRUE
+FROM users u
+JOIN role_permissions rp ON rp.user_id = u.id
+    AND rp.permission_key = 'send_broadcasts' AND rp.is_granted
+WHERE u.role IN ('admin', 'coordinator')
+ON CONFLICT (user_id, permission_key) DO NOTHING;
@@ -0,0 +1,36 @@
+-- ==========================================
+-- ADMIN SERVICE — Migration 0010
+-- Digest emails: a weekly (or daily) summary for students and a
+-- department summary for coordinators.
+-- Owns: notify.digest_settings, notify.digests
+-- Digests are a notification category of their own, so turning off the
+-- 'digest' EMAIL preference stops them and quiet hours hold them.
+-- digest_settings picks the frequency (weekly when missing); digests logs
+-- one row per user and day, written before the email is queued, so a user
+-- never gets two digests on the same day.
+-- ==========================================
+
+SET search_path TO notify, public;
+
+ALTER TABLE preferences DROP CONSTRAINT IF EXISTS preferences_category_check;
+ALTER TABLE preferences ADD CONSTRAINT preferences_category_check
+    CHECK (category IN ('drive_announcement', 'application_status', 'change_request', 'chat_mention', 'broadcast', 'digest'));
+
+CREATE TABLE IF NOT EXISTS digest_settings (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    frequency VARCHAR(10) NOT NULL CHECK (frequency IN ('daily', 'weekly')),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- period_start is where the digest's "new since" window began; job_id is
+-- NULL when there was nothing to report and no email was sent.
+CREATE TABLE IF NOT EXISTS digests (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    digest_date DATE NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('student', 'coordinator')),
+    frequency VARCHAR(10) NOT NULL,
+    period_start TIMESTAMPTZ NOT NULL,
+    job_id BIGINT REFERENCES jobs(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (user_id, digest_date)
+);
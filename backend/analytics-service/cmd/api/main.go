@@ -12,10 +12,12 @@ import (
 	"github.com/placement-portal-kec/analytics-service/internal/database"
 	"github.com/placement-portal-kec/analytics-service/internal/middleware"
 	"github.com/placement-portal-kec/analytics-service/internal/routes"
+	"github.com/placement-portal-kec/analytics-service/internal/services"
 )
 
 func main() {
-	middleware.SecretKey = []byte(os.Getenv("JWT_SECRET"))
+	// Load auth-service public keys for JWT verification
+	middleware.InitJWKS()
 
 	pool, err := database.Connect()
 	if err != nil {
@@ -25,6 +27,9 @@ func main() {
 
 	fmt.Println("Analytics Service connected to database")
 
+	// Redis caches permission grants for RequirePermission
+	services.InitRedis()
+
 	app := fiber.New(fiber.Config{
 		AppName: "Placement Portal - Analytics Service",
 	})
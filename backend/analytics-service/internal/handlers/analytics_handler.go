@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/placement-portal-kec/analytics-service/internal/database"
+	"github.com/placement-portal-kec/analytics-service/internal/middleware"
 	"github.com/placement-portal-kec/analytics-service/internal/repository"
 	"github.com/gofiber/fiber/v2"
 )
@@ -22,7 +23,7 @@ func GetDashboardAnalytics(c *fiber.Ctx) error {
 	repo := repository.NewAnalyticsRepository(database.DB)
 	timeframe := c.Query("timeframe", "all_time")
 
-	analytics, err := repo.GetDashboardAnalytics(c.Context(), timeframe)
+	analytics, err := repo.GetDashboardAnalytics(c.Context(), timeframe, middleware.DepartmentScope(c))
 	if err != nil {
 		fmt.Printf("Error fetching dashboard analytics: %v\n", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
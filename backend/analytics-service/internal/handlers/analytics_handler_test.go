@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/placement-portal-kec/analytics-service/internal/database"
+)
+
+// seedDepartmentStudents connects database.DB to TEST_DATABASE_URL, a
+// database migrated with scripts/run_schemas.sh, and adds a placed student
+// to each of CSE and ECE, removed when the test ends. Tests using it are
+// skipped without one.
+func seedDepartmentStudents(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	t.Setenv("DATABASE_URL", url)
+	pool, err := database.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		pool.Close()
+		database.DB = nil
+	})
+
+	ctx := context.Background()
+	tag := fmt.Sprintf("T%d", time.Now().UnixNano()%1e10)
+	var driveID int64
+	err = pool.QueryRow(ctx, `
+		INSERT INTO placement_drives (company_name, location, drive_date, deadline_date)
+		VALUES ('Scope Test Ltd', 'Erode', CURRENT_DATE, NOW()) RETURNING id`).Scan(&driveID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM placement_drives WHERE id = $1`, driveID) })
+
+	for _, dept := range []string{"CSE", "ECE"} {
+		var id int64
+		err := pool.QueryRow(ctx, `
+			INSERT INTO users (email, password_hash, role, name, department_code)
+			VALUES ($1, 'x', 'student', $2, $3) RETURNING id`,
+			strings.ToLower(tag+dept)+"@scope.test", "Student "+tag, dept).Scan(&id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, id) })
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO student_personal (user_id, register_number, department, batch_year)
+			VALUES ($1, $2, $3, 2028)`, id, tag+dept, dept); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO drive_applications (drive_id, student_id, status) VALUES ($1, $2, 'placed')`, driveID, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDashboardAnalyticsOnlyCountsOwnDepartment(t *testing.T) {
+	seedDepartmentStudents(t)
+
+	app := fiber.New()
+	app.Get("/dashboard", func(c *fiber.Ctx) error {
+		c.Locals("role", "coordinator")
+		c.Locals("department_code", "CSE")
+		return c.Next()
+	}, GetDashboardAnalytics)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/dashboard", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	var body struct {
+		YearWise []struct {
+			BatchYear     int `json:"batch_year"`
+			TotalStudents int `json:"total_students"`
+		} `json:"year_wise"`
+		DepartmentWise []struct {
+			DepartmentCode string `json:"department_code"`
+		} `json:"department_wise"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(body.DepartmentWise) != 1 || body.DepartmentWise[0].DepartmentCode != "CSE" {
+		t.Errorf("department_wise = %+v, want CSE only", body.DepartmentWise)
+	}
+	var cse2028 int
+	if err := database.DB.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM student_personal WHERE department = 'CSE' AND batch_year = 2028`).Scan(&cse2028); err != nil {
+		t.Fatal(err)
+	}
+	for _, y := range body.YearWise {
+		if y.BatchYear == 2028 && y.TotalStudents != cse2028 {
+			t.Errorf("2028 batch counts %d students, want the %d in CSE", y.TotalStudents, cse2028)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/placement-portal-kec/analytics-service/internal/database"
+	"github.com/placement-portal-kec/analytics-service/internal/services"
+)
+
+// permissionsCacheTTL bounds how stale a grant can be if an invalidation is missed.
+const permissionsCacheTTL = 10 * time.Minute
+
+// RequirePermission allows the request only if the caller has been granted
+// permissionKey in admin.role_permissions. Super admins bypass the check.
+// Granted keys are cached in Redis under services.PermissionsCacheKey and
+// invalidated by admin-service whenever a user's grants change.
+func RequirePermission(permissionKey string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, _ := c.Locals("role").(string)
+		if role == "super_admin" {
+			return c.Next()
+		}
+
+		uid, ok := c.Locals("user_id").(float64)
+		if !ok {
+			return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		granted, err := grantedPermissions(c.Context(), int64(uid))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to verify permissions"})
+		}
+		if !granted[permissionKey] {
+			return c.Status(403).JSON(fiber.Map{
+				"error":      "Forbidden: You don't have permission for this action",
+				"permission": permissionKey,
+			})
+		}
+		return c.Next()
+	}
+}
+
+func grantedPermissions(ctx context.Context, userID int64) (map[string]bool, error) {
+	key := services.PermissionsCacheKey(userID)
+
+	var keys []string
+	if !services.GetCache(ctx, key, &keys) {
+		rows, err := database.DB.Query(ctx,
+			`SELECT permission_key FROM admin.role_permissions WHERE user_id = $1 AND is_granted = TRUE`, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load permissions: %w", err)
+		}
+		defer rows.Close()
+
+		keys = []string{}
+		for rows.Next() {
+			var k string
+			if err := rows.Scan(&k); err != nil {
+				return nil, err
+			}
+			keys = append(keys, k)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		services.SetCache(ctx, key, keys, permissionsCacheTTL)
+	}
+
+	granted := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		granted[k] = true
+	}
+	return granted, nil
+}
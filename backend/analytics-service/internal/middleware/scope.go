@@ -0,0 +1,13 @@
+package middleware
+
+import "github.com/gofiber/fiber/v2"
+
+// DepartmentScope returns the department a coordinator is restricted to, or nil
+// for admins and super admins who see every department.
+func DepartmentScope(c *fiber.Ctx) *string {
+	if role, _ := c.Locals("role").(string); role != "coordinator" {
+		return nil
+	}
+	dept, _ := c.Locals("department_code").(string)
+	return &dept
+}
@@ -16,7 +16,9 @@ func NewAnalyticsRepository(db *pgxpool.Pool) *AnalyticsRepository {
 	return &AnalyticsRepository{DB: db}
 }
 
-func (r *AnalyticsRepository) GetDashboardAnalytics(ctx context.Context, timeframe string) (*models.DashboardAnalytics, error) {
+// GetDashboardAnalytics aggregates placement statistics. When deptScope is set
+// (coordinators), every figure is restricted to students of that department.
+func (r *AnalyticsRepository) GetDashboardAnalytics(ctx context.Context, timeframe string, deptScope *string) (*models.DashboardAnalytics, error) {
 	analytics := &models.DashboardAnalytics{
 		YearWise:       []models.YearWiseAnalytics{},
 		DepartmentWise: []models.DepartmentWiseAnalytics{},
@@ -41,6 +43,16 @@ func (r *AnalyticsRepository) GetDashboardAnalytics(ctx context.Context, timefra
 		timeFilter = "AND da.updated_at >= NOW() - INTERVAL '1 year'"
 	}
 
+	// Department scope: studentFilter applies where student_personal is joined,
+	// applicationFilter where only drive_applications is.
+	studentFilter, applicationFilter := "", ""
+	args := []interface{}{}
+	if deptScope != nil {
+		studentFilter = "AND sp.department = $1"
+		applicationFilter = "AND da.student_id IN (SELECT user_id FROM student_personal WHERE department = $1)"
+		args = append(args, *deptScope)
+	}
+
 	// 1. Year-wise analytics
 	yearQuery := fmt.Sprintf(`
 		SELECT 
@@ -51,11 +63,11 @@ func (r *AnalyticsRepository) GetDashboardAnalytics(ctx context.Context, timefra
 			COUNT(CASE WHEN da.status = 'placed' %s THEN 1 END) as total_offers
 		FROM student_personal sp
 		LEFT JOIN drive_applications da ON sp.user_id = da.student_id
-		WHERE sp.batch_year IS NOT NULL AND sp.batch_year > 0
+		WHERE sp.batch_year IS NOT NULL AND sp.batch_year > 0 %s
 		GROUP BY sp.batch_year
 		ORDER BY sp.batch_year DESC
-	`, timeFilter, timeFilter)
-	rows, err := r.DB.Query(ctx, yearQuery)
+	`, timeFilter, timeFilter, studentFilter)
+	rows, err := r.DB.Query(ctx, yearQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch year-wise analytics: %v", err)
 	}
@@ -81,11 +93,11 @@ func (r *AnalyticsRepository) GetDashboardAnalytics(ctx context.Context, timefra
 			COUNT(DISTINCT CASE WHEN da.status = 'placed' %s THEN sp.user_id END) as placed_students
 		FROM student_personal sp
 		LEFT JOIN drive_applications da ON sp.user_id = da.student_id
-		WHERE sp.department IS NOT NULL
+		WHERE sp.department IS NOT NULL %s
 		GROUP BY sp.department
 		ORDER BY sp.department
-	`, timeFilter)
-	rows, err = r.DB.Query(ctx, deptQuery)
+	`, timeFilter, studentFilter)
+	rows, err = r.DB.Query(ctx, deptQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch department-wise analytics: %v", err)
 	}
@@ -109,10 +121,10 @@ func (r *AnalyticsRepository) GetDashboardAnalytics(ctx context.Context, timefra
 			COUNT(DISTINCT sp.user_id) as placed_students
 		FROM student_personal sp
 		JOIN drive_applications da ON sp.user_id = da.student_id
-		WHERE da.status = 'placed' %s
+		WHERE da.status = 'placed' %s %s
 		GROUP BY COALESCE(sp.gender, 'Unspecified')
-	`, timeFilter)
-	rows, err = r.DB.Query(ctx, genderQuery)
+	`, timeFilter, studentFilter)
+	rows, err = r.DB.Query(ctx, genderQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch gender-wise analytics: %v", err)
 	}
@@ -133,10 +145,10 @@ func (r *AnalyticsRepository) GetDashboardAnalytics(ctx context.Context, timefra
 			COUNT(da.student_id) as placed_students
 		FROM placement_drives pd
 		JOIN drive_applications da ON pd.id = da.drive_id
-		WHERE da.status = 'placed' AND pd.company_category IS NOT NULL %s
+		WHERE da.status = 'placed' AND pd.company_category IS NOT NULL %s %s
 		GROUP BY pd.company_category
-	`, timeFilter)
-	rows, err = r.DB.Query(ctx, categoryQuery)
+	`, timeFilter, applicationFilter)
+	rows, err = r.DB.Query(ctx, categoryQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch category-wise analytics: %v", err)
 	}
@@ -157,10 +169,10 @@ func (r *AnalyticsRepository) GetDashboardAnalytics(ctx context.Context, timefra
 			COUNT(da.student_id) as placed_students
 		FROM placement_drives pd
 		JOIN drive_applications da ON pd.id = da.drive_id
-		WHERE da.status = 'placed' AND pd.offer_type IS NOT NULL %s
+		WHERE da.status = 'placed' AND pd.offer_type IS NOT NULL %s %s
 		GROUP BY pd.offer_type
-	`, timeFilter)
-	rows, err = r.DB.Query(ctx, offerTypeQuery)
+	`, timeFilter, applicationFilter)
+	rows, err = r.DB.Query(ctx, offerTypeQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch offer-type analytics: %v", err)
 	}
@@ -186,7 +198,7 @@ func (r *AnalyticsRepository) GetDashboardAnalytics(ctx context.Context, timefra
 				END as bracket,
 				da.student_id
 			FROM drive_applications da
-			WHERE da.status = 'placed' AND da.package_offered IS NOT NULL AND da.package_offered > 0 %s
+			WHERE da.status = 'placed' AND da.package_offered IS NOT NULL AND da.package_offered > 0 %s %s
 		)
 		SELECT bracket, COUNT(student_id) as placed_students
 		FROM salary_data
@@ -198,8 +210,8 @@ func (r *AnalyticsRepository) GetDashboardAnalytics(ctx context.Context, timefra
 				WHEN '10-20 LPA' THEN 3
 				ELSE 4
 			END
-	`, timeFilter)
-	rows, err = r.DB.Query(ctx, salaryQuery)
+	`, timeFilter, applicationFilter)
+	rows, err = r.DB.Query(ctx, salaryQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch salary bracket analytics: %v", err)
 	}
@@ -220,12 +232,12 @@ func (r *AnalyticsRepository) GetDashboardAnalytics(ctx context.Context, timefra
 			COUNT(da.student_id) as placed_students
 		FROM placement_drives pd
 		JOIN drive_applications da ON pd.id = da.drive_id
-		WHERE da.status = 'placed' %s
+		WHERE da.status = 'placed' %s %s
 		GROUP BY pd.company_name
 		ORDER BY placed_students DESC
 		LIMIT 5
-	`, timeFilter)
-	rows, err = r.DB.Query(ctx, recruitersQuery)
+	`, timeFilter, applicationFilter)
+	rows, err = r.DB.Query(ctx, recruitersQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch top recruiters analytics: %v", err)
 	}
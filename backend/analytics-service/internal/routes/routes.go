@@ -4,6 +4,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/placement-portal-kec/analytics-service/internal/handlers"
 	"github.com/placement-portal-kec/analytics-service/internal/middleware"
+	"github.com/placement-portal-kec/analytics-service/internal/models"
 )
 
 func SetupRoutes(app *fiber.App) {
@@ -14,5 +15,5 @@ func SetupRoutes(app *fiber.App) {
 	api := app.Group("/api/v1", middleware.Protected)
 	admin := api.Group("/admin", middleware.AdminOnly)
 
-	admin.Get("/analytics/dashboard", handlers.GetDashboardAnalytics)
+	admin.Get("/analytics/dashboard", middleware.RequirePermission(models.PermViewAnalytics), handlers.GetDashboardAnalytics)
 }
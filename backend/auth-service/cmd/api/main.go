@@ -13,6 +13,7 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/placement-portal-kec/auth-service/internal/routes"
+	"github.com/placement-portal-kec/auth-service/internal/utils"
 )
 
 func main() {
@@ -50,6 +51,12 @@ func main() {
 
 	log.Println("Connected to Database for Auth Service (with persistent search_path)")
 
+	// Load JWT signing keys (published at /api/v1/auth/.well-known/jwks.json)
+	if err := utils.InitKeys(); err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
+	utils.Keys.StartAutoReload(time.Minute)
+
 	// 2. Initialize Fiber App
 	app := fiber.New(fiber.Config{
 		AppName: "Placement Portal - Auth Service",
@@ -3,9 +3,12 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/placement-portal-kec/auth-service/internal/models"
+	"github.com/placement-portal-kec/auth-service/internal/password"
 	"github.com/placement-portal-kec/auth-service/internal/repository"
 	"github.com/placement-portal-kec/auth-service/internal/utils"
 	"golang.org/x/crypto/bcrypt"
@@ -26,6 +29,11 @@ func (h *AuthHandler) RegisterUser(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid input format"})
 	}
 
+	policy := password.LoadPolicy()
+	if err := policy.Validate(input.Password, input.Email); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Could not process password"})
@@ -36,6 +44,10 @@ func (h *AuthHandler) RegisterUser(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "Could not create user, email might already exist", "details": err.Error()})
 	}
 
+	if err := h.repo.AddPasswordHistory(c.Context(), user.ID, string(hashedPassword), policy.HistorySize); err != nil {
+		fmt.Printf("Failed to record password history for user %d: %v\n", user.ID, err)
+	}
+
 	return c.Status(201).JSON(fiber.Map{
 		"message": "User registered successfully",
 		"user_id": user.ID,
@@ -81,7 +93,7 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		return c.Status(401).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	token, err := utils.GenerateToken(user.ID, user.Role)
+	token, err := sessionToken(user)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Could not generate token"})
 	}
@@ -98,13 +110,14 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	}
 
 	response := fiber.Map{
-		"message":             "Login successful",
-		"token":               token,
-		"id":                  user.ID,
-		"role":                user.Role,
-		"email":               user.Email,
-		"permissions":         permissions,
-		"is_profile_complete": isProfileComplete,
+		"message":              "Login successful",
+		"token":                token,
+		"id":                   user.ID,
+		"role":                 user.Role,
+		"email":                user.Email,
+		"permissions":          permissions,
+		"is_profile_complete":  isProfileComplete,
+		"must_change_password": user.MustChangePassword,
 	}
 
 	if user.Name != nil {
@@ -119,3 +132,19 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 
 	return c.JSON(response)
 }
+
+// sessionToken issues the login token for user. Accounts on an admin-assigned
+// password get a short-lived token that only allows changing it; the change
+// answers with a full token.
+func sessionToken(user *models.User) (string, error) {
+	extraClaims := jwt.MapClaims{}
+	if user.MustChangePassword {
+		extraClaims["pwd_change_required"] = true
+		extraClaims["exp"] = time.Now().Add(utils.PasswordChangeTokenTTL).Unix()
+	}
+	// Coordinators are scoped to their department by every downstream service
+	if user.Role == "coordinator" && user.DepartmentCode != nil {
+		extraClaims["department_code"] = *user.DepartmentCode
+	}
+	return utils.GenerateTokenWithClaims(user.ID, user.Role, extraClaims)
+}
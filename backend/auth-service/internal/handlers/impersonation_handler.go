@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/placement-portal-kec/auth-service/internal/utils"
+)
+
+// StartImpersonation - POST /api/v1/auth/impersonate/:user_id
+// Lets a super admin see the portal exactly as a student does. The returned
+// token is read-only, expires after ImpersonationTTL (or when the session is
+// ended) and every request made with it is audited under the super admin's ID
+// by the receiving service.
+func (h *AuthHandler) StartImpersonation(c *fiber.Ctx) error {
+	studentID, err := strconv.ParseInt(c.Params("user_id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	actorID := int64(c.Locals("user_id").(float64))
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	c.BodyParser(&input)
+
+	student, err := h.repo.GetUserByID(c.Context(), studentID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "User not found"})
+	}
+	if student.Role != "student" {
+		return c.Status(400).JSON(fiber.Map{"error": "Only student accounts can be impersonated"})
+	}
+	if student.IsBlocked || !student.IsActive {
+		return c.Status(400).JSON(fiber.Map{"error": "Blocked or inactive accounts cannot be impersonated"})
+	}
+
+	expiresAt := time.Now().Add(utils.ImpersonationTTL())
+	sessionID, err := h.repo.StartImpersonationSession(c.Context(), actorID, student.ID, input.Reason, expiresAt)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to record impersonation session"})
+	}
+	token, err := utils.GenerateImpersonationToken(student.ID, actorID, sessionID, expiresAt)
+	if err != nil {
+		h.repo.EndImpersonationSession(c.Context(), sessionID, actorID)
+		return c.Status(500).JSON(fiber.Map{"error": "Could not generate token"})
+	}
+
+	// The session must not exist without its audit record
+	details := map[string]interface{}{
+		"session_id":           sessionID,
+		"impersonated_user_id": student.ID,
+		"email":                student.Email,
+		"reason":               input.Reason,
+		"expires_at":           expiresAt.Format(time.RFC3339),
+	}
+	if err := h.repo.LogActivity(c.Context(), actorID, "IMPERSONATION_START", "USER", strconv.FormatInt(student.ID, 10), details, c.IP()); err != nil {
+		h.repo.EndImpersonationSession(c.Context(), sessionID, actorID)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to record impersonation session"})
+	}
+
+	response := fiber.Map{
+		"token":      token,
+		"session_id": sessionID,
+		"expires_at": expiresAt,
+		"read_only":  true,
+		"id":         student.ID,
+		"role":       student.Role,
+		"email":      student.Email,
+	}
+	if student.Name != nil {
+		response["name"] = *student.Name
+	}
+	return c.JSON(response)
+}
+
+// EndImpersonation - DELETE /api/v1/auth/impersonate/:session_id
+// Ends a session before it expires; services refuse its token from then on.
+func (h *AuthHandler) EndImpersonation(c *fiber.Ctx) error {
+	sessionID, err := strconv.ParseInt(c.Params("session_id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid session ID"})
+	}
+	actorID := int64(c.Locals("user_id").(float64))
+
+	ended, err := h.repo.EndImpersonationSession(c.Context(), sessionID, actorID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to end impersonation session"})
+	}
+	if !ended {
+		return c.Status(404).JSON(fiber.Map{"error": "Session not found or already ended"})
+	}
+
+	details := map[string]interface{}{"session_id": sessionID}
+	if err := h.repo.LogActivity(c.Context(), actorID, "IMPERSONATION_END", "IMPERSONATION_SESSION", strconv.FormatInt(sessionID, 10), details, c.IP()); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Session ended, but the audit record failed"})
+	}
+	return c.JSON(fiber.Map{"message": "Impersonation session ended"})
+}
@@ -0,0 +1,13 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/placement-portal-kec/auth-service/internal/utils"
+)
+
+// GetJWKS publishes the public half of every signing key so other services can
+// verify tokens without holding any secret.
+func GetJWKS(c *fiber.Ctx) error {
+	c.Set("Cache-Control", "public, max-age=300")
+	return c.JSON(fiber.Map{"keys": utils.Keys.JWKS()})
+}
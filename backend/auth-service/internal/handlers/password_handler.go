@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/placement-portal-kec/auth-service/internal/models"
+	"github.com/placement-portal-kec/auth-service/internal/password"
+	"github.com/placement-portal-kec/auth-service/internal/repository"
+	"github.com/placement-portal-kec/auth-service/internal/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// resetOTPValidity is how long a forgot-password code can be used
+	resetOTPValidity = 10 * time.Minute
+	// resetOTPAttempts is how many guesses a code allows before it is deleted
+	resetOTPAttempts = 5
+)
+
+// ChangePassword - PUT /api/v1/auth/password
+// Changes the caller's password under the password policy. It accepts the
+// restricted token issued for an admin-assigned password and answers with a
+// full token, which the client must use from then on.
+func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
+	userID := int64(c.Locals("user_id").(float64))
+
+	var input models.ChangePasswordInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	if input.NewPassword != input.ConfirmPassword {
+		return c.Status(400).JSON(fiber.Map{"error": "New passwords do not match"})
+	}
+
+	user, err := h.repo.GetUserByID(c.Context(), userID)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "Unauthorized: Session expired, please log in again"})
+	}
+	if user.IsBlocked || !user.IsActive {
+		return c.Status(403).JSON(fiber.Map{"error": "your account has been blocked by Admin"})
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.OldPassword)); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Incorrect old password"})
+	}
+
+	if status, problem := h.setPassword(c, user, input.NewPassword); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": problem})
+	}
+	h.logPasswordEvent(c, user.ID, "CHANGE_PASSWORD_SELF")
+
+	user.MustChangePassword = false
+	token, err := sessionToken(user)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Password updated, but could not generate a new token. Please log in again."})
+	}
+	return c.JSON(fiber.Map{"message": "Password updated successfully", "token": token})
+}
+
+// ForgotPassword - POST /api/v1/auth/forgot-password
+// Emails a one-time code for ResetPassword. The answer is the same whether
+// or not the email is registered, so it cannot be used to find accounts.
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var input models.ForgotPasswordInput
+	if err := c.BodyParser(&input); err != nil || strings.TrimSpace(input.Email) == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Email is required"})
+	}
+	sent := fiber.Map{"message": "If the email is registered, a verification code has been sent to it"}
+
+	user, err := h.repo.GetUserByEmail(c.Context(), strings.TrimSpace(input.Email))
+	if err != nil || user.IsBlocked || !user.IsActive {
+		return c.JSON(sent)
+	}
+
+	otp, err := utils.GenerateOTP()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not generate a verification code"})
+	}
+	if err := h.repo.SaveOTP(c.Context(), user.Email, otp, resetOTPValidity); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not save the verification code"})
+	}
+	err = h.repo.QueueEmail(c.Context(), repository.QueuedEmail{
+		Kind:           repository.JobKindPasswordReset,
+		Address:        user.Email,
+		Template:       "password_reset",
+		Vars:           map[string]string{"otp": otp},
+		IdempotencyKey: fmt.Sprintf("password_reset:%d:%s", user.ID, otp),
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not send the verification code"})
+	}
+	return c.JSON(sent)
+}
+
+// ResetPassword - POST /api/v1/auth/reset-password
+// Sets a new password with the code from ForgotPassword (or the welcome
+// email). The policy and history apply as for any change, and it clears a
+// forced change: the user chose this password themselves.
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var input models.ResetPasswordInput
+	if err := c.BodyParser(&input); err != nil || input.Email == "" || input.OTP == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Email, OTP and new password are required"})
+	}
+	input.Email = strings.TrimSpace(input.Email)
+
+	// Reject a weak password before the code is spent on it
+	if err := password.LoadPolicy().Validate(input.NewPassword, input.Email); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ok, err := h.repo.VerifyOTP(c.Context(), input.Email, input.OTP, resetOTPAttempts)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not verify the code"})
+	}
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid or expired OTP"})
+	}
+	user, err := h.repo.GetUserByEmail(c.Context(), input.Email)
+	if err != nil || user.IsBlocked || !user.IsActive {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid or expired OTP"})
+	}
+
+	if status, problem := h.setPassword(c, user, input.NewPassword); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": problem})
+	}
+	h.logPasswordEvent(c, user.ID, "RESET_PASSWORD")
+	return c.JSON(fiber.Map{"message": "Password reset successfully. Please log in with your new password."})
+}
+
+// setPassword checks newPassword against the policy and user's history and
+// stores it. On failure it returns the response status and error message.
+func (h *AuthHandler) setPassword(c *fiber.Ctx, user *models.User, newPassword string) (int, string) {
+	policy := password.LoadPolicy()
+	history, err := h.repo.GetPasswordHistory(c.Context(), user.ID, policy.HistorySize)
+	if err != nil {
+		return 500, "Failed to verify password history"
+	}
+	if err := policy.CheckNew(newPassword, user.Email, user.PasswordHash, history); err != nil {
+		return 400, err.Error()
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return 500, "Failed to process new password"
+	}
+	if err := h.repo.SetPassword(c.Context(), user.ID, string(hash), policy.HistorySize); err != nil {
+		return 500, "Failed to update password"
+	}
+	return 0, ""
+}
+
+func (h *AuthHandler) logPasswordEvent(c *fiber.Ctx, userID int64, action string) {
+	if err := h.repo.LogActivity(c.Context(), userID, action, "USER", strconv.FormatInt(userID, 10), nil, c.IP()); err != nil {
+		fmt.Printf("Failed to log %s for user %d: %v\n", action, userID, err)
+	}
+}
@@ -15,6 +15,8 @@ type User struct {
 	IsActive        bool    `json:"is_active"`
 	IsBlocked       bool    `json:"is_blocked"`
 
+	MustChangePassword bool `json:"must_change_password"` // Set for admin-assigned / default passwords
+
 	LastLogin *time.Time `json:"last_login"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
@@ -34,3 +36,22 @@ type LoginInput struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
+
+// ChangePasswordInput is the body of PUT /api/v1/auth/password
+type ChangePasswordInput struct {
+	OldPassword     string `json:"old_password"`
+	NewPassword     string `json:"new_password"`
+	ConfirmPassword string `json:"confirm_password"`
+}
+
+// ForgotPasswordInput starts an OTP password reset
+type ForgotPasswordInput struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordInput completes an OTP password reset
+type ResetPasswordInput struct {
+	Email       string `json:"email"`
+	OTP         string `json:"otp"`
+	NewPassword string `json:"new_password"`
+}
@@ -0,0 +1,3 @@
+package password
+
+//go:generate bash ../../../scripts/sync_password_policy.sh
@@ -0,0 +1,174 @@
+// Package password holds the portal's password policy. auth-service owns the
+// source; admin-service and student-service build on their own, so they get
+// copies generated by scripts/sync_password_policy.sh (go generate here).
+package password
+
+import (
+	"bufio"
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+//go:embed breached_passwords.txt
+var breachedPasswordsRaw string
+
+// breachedPasswords is the offline list of known-leaked passwords, lowercased.
+var breachedPasswords = loadBreachedPasswords()
+
+// Policy describes the rules every new password must satisfy. Values come
+// from the environment so the placement cell can tighten them without a
+// rebuild.
+type Policy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	HistorySize   int // number of previous hashes that may not be reused
+	CheckBreached bool
+}
+
+// LoadPolicy reads PASSWORD_* env vars, falling back to sane defaults.
+func LoadPolicy() Policy {
+	return Policy{
+		MinLength:     envInt("PASSWORD_MIN_LENGTH", 8),
+		RequireUpper:  envBool("PASSWORD_REQUIRE_UPPER", true),
+		RequireLower:  envBool("PASSWORD_REQUIRE_LOWER", true),
+		RequireDigit:  envBool("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol: envBool("PASSWORD_REQUIRE_SYMBOL", false),
+		HistorySize:   envInt("PASSWORD_HISTORY_SIZE", 5),
+		CheckBreached: envBool("PASSWORD_CHECK_BREACHED", true),
+	}
+}
+
+// ErrReused is returned when a password matches one of the recent hashes.
+var ErrReused = errors.New("password was used recently, please choose a different one")
+
+// Validate checks a candidate password against the policy. The email is used
+// to reject passwords that are just the user's login name.
+func (p Policy) Validate(password, email string) error {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters long", p.MinLength)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		return errors.New("password must contain an uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		return errors.New("password must contain a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		return errors.New("password must contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		return errors.New("password must contain a special character")
+	}
+
+	lower := strings.ToLower(password)
+	if local, _, ok := strings.Cut(strings.ToLower(email), "@"); ok && local != "" && strings.Contains(lower, local) {
+		return errors.New("password must not contain your email address")
+	}
+	if p.CheckBreached {
+		if _, found := breachedPasswords[lower]; found {
+			return errors.New("this password has appeared in a data breach, please choose a different one")
+		}
+	}
+	return nil
+}
+
+// CheckReuse returns ErrReused if password matches any of the given bcrypt
+// hashes (current password first, then history).
+func (p Policy) CheckReuse(password string, previousHashes []string) error {
+	for _, hash := range previousHashes {
+		if hash == "" {
+			continue
+		}
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return ErrReused
+		}
+	}
+	return nil
+}
+
+// CheckNew is what every password change runs: Validate, then CheckReuse
+// against the current hash and the user's history.
+func (p Policy) CheckNew(password, email, currentHash string, history []string) error {
+	if err := p.Validate(password, email); err != nil {
+		return err
+	}
+	return p.CheckReuse(password, append([]string{currentHash}, history...))
+}
+
+// temporaryAttempts bounds GenerateTemporary, so a policy no random password
+// can meet fails instead of looping forever.
+const temporaryAttempts = 20
+
+// GenerateTemporary returns a random password that satisfies the policy, for
+// accounts that are forced to change it on first login.
+func (p Policy) GenerateTemporary() (string, error) {
+	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	b := make([]byte, max(p.MinLength, 12))
+	for range temporaryAttempts {
+		for i := range b {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+			if err != nil {
+				return "", err
+			}
+			b[i] = charset[n.Int64()]
+		}
+		candidate := string(b) + "#7"
+		if p.Validate(candidate, "") == nil {
+			return candidate, nil
+		}
+	}
+	return "", errors.New("could not generate a password that meets the password policy")
+}
+
+func loadBreachedPasswords() map[string]struct{} {
+	set := make(map[string]struct{})
+	scanner := bufio.NewScanner(strings.NewReader(breachedPasswordsRaw))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		set[strings.ToLower(line)] = struct{}{}
+	}
+	return set
+}
+
+func envInt(key string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return v
+	}
+	return fallback
+}
+
+func envBool(key string, fallback bool) bool {
+	if v, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return v
+	}
+	return fallback
+}
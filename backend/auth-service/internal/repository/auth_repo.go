@@ -2,8 +2,12 @@ package repository
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/placement-portal-kec/auth-service/internal/models"
 )
@@ -40,7 +44,8 @@ func (r *AuthRepository) CreateUser(ctx context.Context, input *models.RegisterI
 // GetUserByEmail for login and password resets
 func (r *AuthRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
-		SELECT id, email, password_hash, role, name, department_code, profile_photo_url, is_active, is_blocked, last_login 
+		SELECT id, email, password_hash, role, name, department_code, profile_photo_url, is_active, is_blocked, last_login,
+		       COALESCE(must_change_password, FALSE)
 		FROM public.users 
 		WHERE LOWER(email) = LOWER($1)
 	`
@@ -48,6 +53,7 @@ func (r *AuthRepository) GetUserByEmail(ctx context.Context, email string) (*mod
 	err := r.DB.QueryRow(ctx, query, email).Scan(
 		&user.ID, &user.Email, &user.PasswordHash, &user.Role, &user.Name,
 		&user.DepartmentCode, &user.ProfilePhotoURL, &user.IsActive, &user.IsBlocked, &user.LastLogin,
+		&user.MustChangePassword,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("user not found")
@@ -55,36 +61,152 @@ func (r *AuthRepository) GetUserByEmail(ctx context.Context, email string) (*mod
 	return &user, nil
 }
 
+// GetUserByID loads a user for impersonation checks and password changes
+func (r *AuthRepository) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
+	query := `
+		SELECT id, email, password_hash, role, name, department_code, is_active, is_blocked,
+		       COALESCE(must_change_password, FALSE)
+		FROM public.users
+		WHERE id = $1
+	`
+	var user models.User
+	err := r.DB.QueryRow(ctx, query, id).Scan(
+		&user.ID, &user.Email, &user.PasswordHash, &user.Role, &user.Name, &user.DepartmentCode, &user.IsActive, &user.IsBlocked,
+		&user.MustChangePassword,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	return &user, nil
+}
+
+// LogActivity writes an audit entry to admin.activity_logs
+func (r *AuthRepository) LogActivity(ctx context.Context, userID int64, action, entityType, entityID string, details map[string]interface{}, ip string) error {
+	query := `
+		INSERT INTO admin.activity_logs (user_id, action, entity_type, entity_id, details, ip_address, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	`
+	_, err := r.DB.Exec(ctx, query, userID, action, entityType, entityID, details, ip)
+	return err
+}
+
 // UpdateLastLogin updates the login timestamp
 func (r *AuthRepository) UpdateLastLogin(ctx context.Context, userID int64) error {
 	_, err := r.DB.Exec(ctx, `UPDATE public.users SET last_login = NOW() WHERE id = $1`, userID)
 	return err
 }
 
-// ResetPassword
-func (r *AuthRepository) ResetPassword(ctx context.Context, email, passwordHash string) error {
-	_, err := r.DB.Exec(ctx, `UPDATE public.users SET password_hash = $1, updated_at = NOW() WHERE email = $2`, passwordHash, email)
+// SetPassword replaces a user's password after a change or an OTP reset: it
+// clears the forced-change flag, records the hash in the history (keeping the
+// newest `keep`) and drops any outstanding reset OTP, all in one transaction.
+func (r *AuthRepository) SetPassword(ctx context.Context, userID int64, passwordHash string, keep int) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var email string
+	err = tx.QueryRow(ctx, `
+		UPDATE public.users SET password_hash = $1, must_change_password = FALSE, password_changed_at = NOW(), updated_at = NOW()
+		WHERE id = $2
+		RETURNING email`, passwordHash, userID).Scan(&email)
+	if err != nil {
+		return err
+	}
+	if keep > 0 {
+		if _, err := tx.Exec(ctx, `INSERT INTO auth.password_history (user_id, password_hash) VALUES ($1, $2)`, userID, passwordHash); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			DELETE FROM auth.password_history
+			WHERE user_id = $1 AND id NOT IN (
+				SELECT id FROM auth.password_history WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2
+			)`, userID, keep)
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM auth.password_resets WHERE LOWER(email) = LOWER($1)`, email); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetPasswordHistory returns the newest `limit` previous hashes of a user
+func (r *AuthRepository) GetPasswordHistory(ctx context.Context, userID int64, limit int) ([]string, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	rows, err := r.DB.Query(ctx, `
+		SELECT password_hash FROM auth.password_history
+		WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+// AddPasswordHistory records a password hash and trims the history to the newest `keep` entries
+func (r *AuthRepository) AddPasswordHistory(ctx context.Context, userID int64, passwordHash string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	_, err := r.DB.Exec(ctx, `INSERT INTO auth.password_history (user_id, password_hash) VALUES ($1, $2)`, userID, passwordHash)
+	if err != nil {
+		return err
+	}
+	_, err = r.DB.Exec(ctx, `
+		DELETE FROM auth.password_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM auth.password_history WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2
+		)`, userID, keep)
 	return err
 }
 
-// SaveOTP saves OTP securely (if keeping simple table or redis. Assuming previous implementation used password_resets)
-func (r *AuthRepository) SaveOTP(ctx context.Context, email, otp string) error {
-	query := `
-		INSERT INTO auth.password_resets (email, otp, expires_at) 
-		VALUES ($1, $2, NOW() + INTERVAL '10 minutes')
-		ON CONFLICT (email) DO UPDATE SET otp = EXCLUDED.otp, expires_at = EXCLUDED.expires_at
-	`
-	// Wait, we need to create auth.password_resets in the schema.
-	_, err := r.DB.Exec(ctx, query, email, otp)
+// SaveOTP stores a reset code for email, replacing any earlier one
+func (r *AuthRepository) SaveOTP(ctx context.Context, email, otp string, validFor time.Duration) error {
+	_, err := r.DB.Exec(ctx, `
+		INSERT INTO auth.password_resets (email, otp_code, expires_at, attempts)
+		VALUES (LOWER($1), $2, NOW() + make_interval(secs => $3), 0)
+		ON CONFLICT (email) DO UPDATE SET otp_code = EXCLUDED.otp_code, expires_at = EXCLUDED.expires_at, attempts = 0`,
+		email, otp, validFor.Seconds())
 	return err
 }
 
-// VerifyOTP verifies token TTL
-func (r *AuthRepository) VerifyOTP(ctx context.Context, email, otp string) (bool, error) {
-	var count int
-	query := `SELECT count(*) FROM auth.password_resets WHERE email = $1 AND otp = $2 AND expires_at > NOW()`
-	err := r.DB.QueryRow(ctx, query, email, otp).Scan(&count)
-	return count > 0, err
+// VerifyOTP reports whether otp is email's current, unexpired reset code.
+// Every check counts as an attempt; after maxAttempts the code is deleted,
+// so it cannot be guessed.
+func (r *AuthRepository) VerifyOTP(ctx context.Context, email, otp string, maxAttempts int) (bool, error) {
+	var stored string
+	var attempts int
+	err := r.DB.QueryRow(ctx, `
+		UPDATE auth.password_resets SET attempts = attempts + 1
+		WHERE email = LOWER($1) AND expires_at > NOW()
+		RETURNING otp_code, attempts`, email).Scan(&stored, &attempts)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if subtle.ConstantTimeCompare([]byte(stored), []byte(otp)) == 1 {
+		return true, nil
+	}
+	if attempts >= maxAttempts {
+		_, err = r.DB.Exec(ctx, `DELETE FROM auth.password_resets WHERE email = LOWER($1)`, email)
+	}
+	return false, err
 }
 
 // GetUserPermissions fetches permissions specifically from admin.role_permissions
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// StartImpersonationSession records a new "view as student" session and
+// returns its ID, which the session's token carries.
+func (r *AuthRepository) StartImpersonationSession(ctx context.Context, actorID, userID int64, reason string, expiresAt time.Time) (int64, error) {
+	var id int64
+	err := r.DB.QueryRow(ctx, `
+		INSERT INTO auth.impersonation_sessions (actor_id, user_id, reason, expires_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+		RETURNING id`,
+		actorID, userID, reason, expiresAt).Scan(&id)
+	return id, err
+}
+
+// EndImpersonationSession closes an open session. It reports false when the
+// session does not exist or has already ended or expired.
+func (r *AuthRepository) EndImpersonationSession(ctx context.Context, sessionID, endedBy int64) (bool, error) {
+	tag, err := r.DB.Exec(ctx, `
+		UPDATE auth.impersonation_sessions
+		SET ended_at = NOW(), ended_by = $2
+		WHERE id = $1 AND ended_at IS NULL AND expires_at > NOW()`,
+		sessionID, endedBy)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
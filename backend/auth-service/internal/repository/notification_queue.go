@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Email job kinds queued by auth-service
+const (
+	JobKindPasswordReset = "password_reset"
+)
+
+// QueuedEmail is a templated email for the shared notify.jobs queue, which
+// admin-service's notification workers render and send with retries.
+type QueuedEmail struct {
+	Kind           string
+	Address        string
+	Template       string // admin-service emails template name
+	Vars           map[string]string
+	IdempotencyKey string // a repeated key is not queued again
+}
+
+// QueueEmail puts e on the notify.jobs queue. Account emails carry no
+// notification category, so no preference can turn them off.
+func (r *AuthRepository) QueueEmail(ctx context.Context, e QueuedEmail) error {
+	payload, err := json.Marshal(map[string]any{
+		"address":        e.Address,
+		"email_template": e.Template,
+		"vars":           e.Vars,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.DB.Exec(ctx, `
+		INSERT INTO notify.jobs (provider, kind, source, payload, idempotency_key)
+		VALUES ('EMAIL', $1, 'auth-service', $2::jsonb, NULLIF($3, ''))
+		ON CONFLICT (idempotency_key) DO NOTHING`,
+		e.Kind, payload, e.IdempotencyKey)
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/placement-portal-kec/auth-service/internal/handlers"
+	"github.com/placement-portal-kec/auth-service/internal/middleware"
 	"github.com/placement-portal-kec/auth-service/internal/repository"
 )
 
@@ -19,4 +20,14 @@ func SetupRoutes(app *fiber.App, db *pgxpool.Pool) {
 	// Public Routes
 	api.Post("/register", authHandler.RegisterUser)
 	api.Post("/login", authHandler.Login)
+	api.Get("/.well-known/jwks.json", handlers.GetJWKS)
+	api.Post("/forgot-password", authHandler.ForgotPassword)
+	api.Post("/reset-password", authHandler.ResetPassword)
+
+	// Password change, also the only route a restricted (forced-change) token can use
+	api.Put("/password", middleware.Protected, authHandler.ChangePassword)
+
+	// Super Admin: read-only "view as student" sessions
+	api.Post("/impersonate/:user_id", middleware.SuperAdminOnly, authHandler.StartImpersonation)
+	api.Delete("/impersonate/:session_id", middleware.SuperAdminOnly, authHandler.EndImpersonation)
 }
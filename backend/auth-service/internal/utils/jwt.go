@@ -1,31 +1,72 @@
 package utils
 
 import (
+	"errors"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// GetJWTSecret retrieves the JWT secret with a local fallback
-func GetJWTSecret() string {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return "fallback_secret_for_local_dev_only"
-	}
-	return secret
+// GenerateToken creates a JWT for inter-service and frontend auth.
+// Tokens are signed with the active RS256 key and carry its kid so verifiers
+// can pick the matching public key from the JWKS.
+func GenerateToken(userID int64, role string) (string, error) {
+	return GenerateTokenWithClaims(userID, role, nil)
 }
 
-var SecretKey = []byte(GetJWTSecret())
-
-// GenerateToken creates a JWT for inter-service and frontend auth
-func GenerateToken(userID int64, role string) (string, error) {
+// GenerateTokenWithClaims is GenerateToken with additional claims merged in.
+func GenerateTokenWithClaims(userID int64, role string, extra jwt.MapClaims) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
 		"role":    role,
 		"exp":     time.Now().Add(time.Hour * 24 * 30).Unix(), // Token valid for 30 days
 	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+
+	kid, key := Keys.Active()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	return token.SignedString(key)
+}
+
+// PasswordChangeTokenTTL is how long a token restricted to changing an
+// admin-assigned password lasts; changing it returns a full token.
+const PasswordChangeTokenTTL = 15 * time.Minute
+
+// GenerateImpersonationToken mints a student token for the impersonation
+// session sessionID, valid until expiresAt. Downstream services recognise the
+// impersonator_id claim: they refuse writes made with it, audit every request
+// under the real actor and stop accepting it once the session is ended.
+func GenerateImpersonationToken(studentID, actorID, sessionID int64, expiresAt time.Time) (string, error) {
+	return GenerateTokenWithClaims(studentID, "student", jwt.MapClaims{
+		"impersonator_id":       actorID,
+		"impersonation_session": sessionID,
+		"exp":                   expiresAt.Unix(),
+	})
+}
+
+// ImpersonationTTL is how long a "view as student" session lasts
+// (IMPERSONATION_TTL_MINUTES, default 15).
+func ImpersonationTTL() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("IMPERSONATION_TTL_MINUTES"))
+	if err != nil || minutes <= 0 {
+		minutes = 15
+	}
+	return time.Duration(minutes) * time.Minute
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(SecretKey)
+// ParseToken verifies a token issued by this service against the local key ring.
+func ParseToken(tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := Keys.PublicKey(kid)
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		return key, nil
+	}, jwt.WithValidMethods([]string{"RS256"}))
 }
@@ -0,0 +1,257 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// signingKey is one RSA key pair known to the auth service. Keys loaded from a
+// public-only PEM are still published in the JWKS (so tokens signed by a retired
+// key keep verifying) but can never be used to sign.
+type signingKey struct {
+	ID      string
+	Private *rsa.PrivateKey
+	Public  *rsa.PublicKey
+}
+
+// KeyRing holds every key the auth service publishes, plus the one currently
+// used for signing.
+//
+// Keys are read from JWT_KEYS_DIR, one PEM file per key, named "<kid>.pem".
+// JWT_ACTIVE_KID pins the signing key; when unset the private key with the
+// highest kid (e.g. "2026-10") signs. Rotation is done without downtime by:
+//  1. dropping the new key into the directory (it is published on the next reload),
+//  2. making it active, either by name order or by switching JWT_ACTIVE_KID,
+//  3. replacing the old private key with its public half until issued tokens expire.
+//
+// Verifiers refetch the JWKS whenever they see an unknown kid, so step 2 does
+// not need to wait for their periodic refresh.
+type KeyRing struct {
+	mu       sync.RWMutex
+	dir      string
+	pinnedID string // JWT_ACTIVE_KID; empty means "newest private key"
+	activeID string
+	keys     map[string]*signingKey
+}
+
+// Keys is the process-wide key ring, initialised by InitKeys.
+var Keys *KeyRing
+
+// InitKeys loads the key ring from the environment. When no key directory is
+// configured an ephemeral key is generated so local development keeps working;
+// tokens signed with it do not survive a restart.
+func InitKeys() error {
+	ring := &KeyRing{
+		dir:      os.Getenv("JWT_KEYS_DIR"),
+		pinnedID: os.Getenv("JWT_ACTIVE_KID"),
+		keys:     make(map[string]*signingKey),
+	}
+
+	if ring.dir == "" {
+		log.Println("Warning: JWT_KEYS_DIR is not set, generating an ephemeral signing key (dev only)")
+		priv, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return fmt.Errorf("failed to generate signing key: %w", err)
+		}
+		kid := fmt.Sprintf("dev-%d", time.Now().Unix())
+		ring.keys[kid] = &signingKey{ID: kid, Private: priv, Public: &priv.PublicKey}
+		ring.activeID = kid
+		Keys = ring
+		return nil
+	}
+
+	if err := ring.Reload(); err != nil {
+		return err
+	}
+	Keys = ring
+	return nil
+}
+
+// Reload re-reads the key directory. The previous key set is kept if the new one
+// is unusable, so a half-copied file never takes signing offline.
+func (r *KeyRing) Reload() error {
+	if r.dir == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read JWT_KEYS_DIR: %w", err)
+	}
+
+	keys := make(map[string]*signingKey)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".pem") {
+			continue
+		}
+		kid := strings.TrimSuffix(entry.Name(), ".pem")
+		key, err := loadPEMKey(filepath.Join(r.dir, entry.Name()))
+		if err != nil {
+			log.Printf("Warning: skipping JWT key %s: %v", entry.Name(), err)
+			continue
+		}
+		key.ID = kid
+		keys[kid] = key
+	}
+
+	activeID := r.pinnedID
+	if activeID == "" {
+		// Default to the newest private key by name (e.g. date-stamped kids)
+		ids := make([]string, 0, len(keys))
+		for id, k := range keys {
+			if k.Private != nil {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+		if len(ids) > 0 {
+			activeID = ids[len(ids)-1]
+		}
+	}
+
+	active, ok := keys[activeID]
+	if !ok || active.Private == nil {
+		return fmt.Errorf("no private key found for active kid %q", activeID)
+	}
+
+	r.mu.Lock()
+	r.keys = keys
+	r.activeID = activeID
+	r.mu.Unlock()
+	return nil
+}
+
+// StartAutoReload polls the key directory so newly added keys are published
+// without restarting the service.
+func (r *KeyRing) StartAutoReload(interval time.Duration) {
+	if r.dir == "" {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := r.Reload(); err != nil {
+				log.Printf("Warning: JWT key reload failed: %v", err)
+			}
+		}
+	}()
+}
+
+// Active returns the kid and private key used for signing new tokens.
+func (r *KeyRing) Active() (string, *rsa.PrivateKey) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key := r.keys[r.activeID]
+	return key.ID, key.Private
+}
+
+// PublicKey returns the verification key for kid, if it is still published.
+func (r *KeyRing) PublicKey(kid string) (*rsa.PublicKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keys[kid]
+	if !ok {
+		return nil, false
+	}
+	return key.Public, true
+}
+
+// JWK is a single RSA public key in JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS returns all published public keys, active key first.
+func (r *KeyRing) JWKS() []JWK {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]string, 0, len(r.keys))
+	for id := range r.keys {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if ids[i] == r.activeID {
+			return true
+		}
+		if ids[j] == r.activeID {
+			return false
+		}
+		return ids[i] > ids[j]
+	})
+
+	out := make([]JWK, 0, len(ids))
+	for _, id := range ids {
+		pub := r.keys[id].Public
+		out = append(out, JWK{
+			Kty: "RSA",
+			Kid: id,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		})
+	}
+	return out
+}
+
+func loadPEMKey(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		priv, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return &signingKey{Private: priv, Public: &priv.PublicKey}, nil
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		priv, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("only RSA keys are supported")
+		}
+		return &signingKey{Private: priv, Public: &priv.PublicKey}, nil
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		pub, ok := parsed.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("only RSA keys are supported")
+		}
+		return &signingKey{Public: pub}, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
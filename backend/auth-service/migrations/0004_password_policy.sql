@@ -0,0 +1,22 @@
+-- ==========================================
+-- AUTH SERVICE — Migration 0004
+-- Password policy: history + forced change on first login
+-- ==========================================
+
+-- 1. Flag accounts whose password was set by someone else (admin, CSV import)
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS must_change_password BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMPTZ;
+
+-- 2. Previous password hashes, used to block reuse of the last N passwords
+CREATE TABLE IF NOT EXISTS auth.password_history (
+    id            BIGSERIAL PRIMARY KEY,
+    user_id       BIGINT NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+    password_hash TEXT NOT NULL,
+    created_at    TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_history_user ON auth.password_history(user_id, created_at DESC);
+
+-- 3. Seed accounts still on the published default password must rotate it
+UPDATE public.users SET must_change_password = TRUE
+WHERE password_hash = '$2a$10$HmVgB9pmuaydKC3cVE/hrO566v.zlhH6VX.D7ZTz4bu6rO1nasixu';
@@ -0,0 +1,10 @@
+-- ==========================================
+-- AUTH SERVICE — Migration 0005
+-- Password reset OTPs: count verification attempts so a code is deleted
+-- after too many wrong guesses. Emails are stored lowercased; codes saved
+-- in mixed case are dropped (they live minutes and can be requested again).
+-- ==========================================
+
+ALTER TABLE auth.password_resets ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
+
+DELETE FROM auth.password_resets WHERE email <> LOWER(email);
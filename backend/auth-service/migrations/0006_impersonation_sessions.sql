@@ -0,0 +1,19 @@
+-- ==========================================
+-- AUTH SERVICE — Migration 0006
+-- Impersonation ("view as student") sessions, so a super admin can end one
+-- before its token expires. Services refuse tokens whose session has ended.
+-- ==========================================
+
+CREATE TABLE IF NOT EXISTS auth.impersonation_sessions (
+    id          BIGSERIAL PRIMARY KEY,
+    actor_id    BIGINT NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+    user_id     BIGINT NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+    reason      TEXT,
+    started_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at  TIMESTAMPTZ NOT NULL,
+    ended_at    TIMESTAMPTZ,
+    ended_by    BIGINT REFERENCES public.users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonation_sessions_open
+    ON auth.impersonation_sessions(actor_id) WHERE ended_at IS NULL;
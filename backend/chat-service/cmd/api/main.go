@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 
@@ -9,7 +10,9 @@ import (
 
 	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/handlers"
 	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/middleware"
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/models"
 	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/repository"
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/utils"
 	"github.com/ansrivas/fiberprometheus/v2"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -60,6 +63,9 @@ func main() {
 
 	log.Println("Connected to Database for Chat Service (with persistent search_path)")
 
+	// Load auth-service public keys for JWT verification
+	utils.InitJWKS()
+
 	// Initialize Fiber App
 	app := fiber.New(fiber.Config{
 		AppName: "KEC Placement Chat Service",
@@ -85,12 +91,27 @@ func main() {
 		log.Printf("Warning: Database migration failed: %v", err)
 	}
 
-	chatHandler := handlers.NewChatHandler(chatRepo)
-
-	// WebSocket Hub
-	hub := handlers.NewHub(chatRepo)
+	// WebSocket Hub: fan out through Redis so several replicas can run behind Caddy
+	var hubBackend handlers.HubBackend = handlers.NewLocalBackend()
+	utils.InitRedis()
+	if utils.RedisClient != nil {
+		hubBackend = handlers.NewRedisBackend(utils.RedisClient, instanceID(), handlers.PresenceTTL)
+	}
+	hub := handlers.NewHub(chatRepo, hubBackend)
 	go hub.Run()
 
+	// Keep department, batch and drive groups in line with student and drive records
+	go handlers.NewGroupSyncer(chatRepo, hub, handlers.GroupSyncInterval()).Run(context.Background())
+
+	// Deliver new in-app notifications to connected clients
+	go handlers.NewInboxNotifier(chatRepo, hub).Run(context.Background())
+
+	// Report chat bucket objects that no message references
+	attachmentReconciler := handlers.NewAttachmentReconciler(chatRepo, handlers.AttachmentReconcileInterval())
+	go attachmentReconciler.Run(context.Background())
+
+	chatHandler := handlers.NewChatHandler(chatRepo, hub)
+
 	// Routes
 	api := app.Group("/api/chat")
 
@@ -101,9 +122,31 @@ func main() {
 	api.Post("/groups", chatHandler.CreateGroup)
 	api.Get("/users/chat-eligible", chatHandler.GetPotentialUsers)
 	api.Get("/groups/:groupId/messages", chatHandler.GetHistory)
+	api.Get("/groups/:groupId/messages/sync", chatHandler.SyncMessages)
+	api.Get("/search", chatHandler.SearchMessages)
+	api.Get("/groups/:groupId/members", chatHandler.GetGroupMembers)
+	api.Post("/groups/:groupId/members", chatHandler.AddGroupMembers)
+	api.Delete("/groups/:groupId/members/:userId", chatHandler.RemoveGroupMember)
+	api.Put("/groups/:groupId/members/:userId/role", chatHandler.SetMemberRole)
+	api.Post("/groups/:groupId/members/:userId/mute", chatHandler.MuteMember)
+	api.Patch("/groups/:groupId/settings", chatHandler.UpdateGroupSettings)
 	api.Post("/messages/:msgId/pin", chatHandler.PinMessage)
+	api.Patch("/messages/:msgId", chatHandler.EditMessage)
+	api.Get("/messages/:msgId/edits", chatHandler.GetMessageEdits)
 	api.Delete("/messages/:msgId", chatHandler.DeleteMessage)
-	api.Post("/broadcast", handlers.BroadcastMessage)
+	api.Get("/messages/:msgId/attachments", chatHandler.GetMessageAttachments)
+	api.Get("/admin/attachments/orphans", middleware.RequirePermission(chatRepo, models.PermManageStorage), attachmentReconciler.GetOrphanReport)
+	api.Post("/broadcast", middleware.RequirePermission(chatRepo, models.PermSendBroadcasts), chatHandler.BroadcastMessage)
+
+	// In-app notification inbox
+	api.Get("/notifications", chatHandler.GetNotifications)
+	api.Get("/notifications/unread-count", chatHandler.GetUnreadNotificationCount)
+	api.Post("/notifications/read-all", chatHandler.MarkAllNotificationsRead)
+	api.Post("/notifications/:id/read", chatHandler.MarkNotificationRead)
+	api.Get("/notifications/preferences", chatHandler.GetNotificationPreferences)
+	api.Put("/notifications/preferences", chatHandler.UpdateNotificationPreferences)
+	api.Put("/notifications/quiet-hours", chatHandler.UpdateQuietHours)
+	api.Put("/notifications/digest", chatHandler.UpdateDigestFrequency)
 
 	// WebSocket Route
 	app.Get("/ws", handlers.ServeWs(hub))
@@ -117,3 +160,9 @@ func main() {
 	log.Printf("Chat Service listening on port %s", port)
 	log.Fatal(app.Listen(":" + port))
 }
+
+// instanceID identifies this replica in shared presence records
+func instanceID() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
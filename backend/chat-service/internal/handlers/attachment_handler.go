@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/repository"
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// attachmentURLMinutes is how long an attachment link handed to a member stays valid
+const attachmentURLMinutes = 10
+
+// Objects younger than this are not reported as orphans: files are uploaded
+// before the message that references them is sent
+const orphanGracePeriod = 24 * time.Hour
+
+// chatObjectPrefixes are the key prefixes chat uploads are written under
+var chatObjectPrefixes = []string{"chat_groups/", "chat_attachments/"}
+
+// AttachmentLink is an attachment with a short-lived download URL
+type AttachmentLink struct {
+	repository.Attachment
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// GetMessageAttachments presigns a message's attachments for group members
+func (h *ChatHandler) GetMessageAttachments(c *fiber.Ctx) error {
+	msgID, _ := strconv.ParseInt(c.Params("msgId"), 10, 64)
+	userID := utils.GetUserID(c)
+
+	attachments, err := h.Repo.GetVisibleAttachments(c.Context(), msgID, userID)
+	if errors.Is(err, repository.ErrMessageNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Message not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	links := make([]AttachmentLink, 0, len(attachments))
+	expiresAt := time.Now().Add(attachmentURLMinutes * time.Minute)
+	for _, a := range attachments {
+		url, err := utils.GetPresignedURL(a.Bucket, a.ObjectKey, attachmentURLMinutes)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to sign attachment URL"})
+		}
+		links = append(links, AttachmentLink{Attachment: a, URL: url, ExpiresAt: expiresAt})
+	}
+	return c.JSON(fiber.Map{"attachments": links})
+}
+
+// OrphanObject is a chat bucket object no message points to
+type OrphanObject struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// OrphanReport summarises one scan of the chat bucket
+type OrphanReport struct {
+	Bucket       string         `json:"bucket"`
+	ScannedAt    time.Time      `json:"scanned_at"`
+	ObjectCount  int            `json:"object_count"`
+	TotalBytes   int64          `json:"total_bytes"`
+	OrphanCount  int            `json:"orphan_count"`
+	OrphanBytes  int64          `json:"orphan_bytes"`
+	OrphanSample []OrphanObject `json:"orphan_sample"` // largest orphans first
+}
+
+// orphanSampleSize caps how many orphans a report lists individually
+const orphanSampleSize = 100
+
+// AttachmentReconcileInterval is how often the chat bucket is scanned for
+// orphaned objects. Configured with CHAT_ATTACHMENT_RECONCILE_HOURS, default 24.
+func AttachmentReconcileInterval() time.Duration {
+	if v, err := strconv.Atoi(os.Getenv("CHAT_ATTACHMENT_RECONCILE_HOURS")); err == nil && v > 0 {
+		return time.Duration(v) * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// AttachmentReconciler periodically compares the chat bucket with the
+// attachment records and reports objects that no message references, e.g.
+// uploads that were never sent or files of deleted groups. It only reports;
+// nothing is deleted.
+type AttachmentReconciler struct {
+	repo     *repository.ChatRepository
+	interval time.Duration
+
+	mu   sync.Mutex
+	last *OrphanReport
+}
+
+func NewAttachmentReconciler(repo *repository.ChatRepository, interval time.Duration) *AttachmentReconciler {
+	return &AttachmentReconciler{repo: repo, interval: interval}
+}
+
+// Run scans once at startup and then every interval
+func (a *AttachmentReconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		if report, err := a.Reconcile(ctx); err != nil {
+			log.Printf("Attachment reconcile failed: %v", err)
+		} else if report.OrphanCount > 0 {
+			log.Printf("Attachment reconcile: %d orphaned objects (%d bytes) in bucket %s", report.OrphanCount, report.OrphanBytes, report.Bucket)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile scans the chat bucket now and stores the report
+func (a *AttachmentReconciler) Reconcile(ctx context.Context) (*OrphanReport, error) {
+	bucket := utils.GetChatBucket()
+	referenced, err := a.repo.ReferencedObjectKeys(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &OrphanReport{Bucket: bucket, ScannedAt: time.Now(), OrphanSample: []OrphanObject{}}
+	cutoff := report.ScannedAt.Add(-orphanGracePeriod)
+	var orphans []OrphanObject
+	for _, prefix := range chatObjectPrefixes {
+		objects, err := utils.ListS3Objects(ctx, bucket, prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objects {
+			report.ObjectCount++
+			report.TotalBytes += obj.Size
+			if referenced[obj.Key] || obj.LastModified.After(cutoff) {
+				continue
+			}
+			report.OrphanCount++
+			report.OrphanBytes += obj.Size
+			orphans = append(orphans, OrphanObject{Key: obj.Key, Size: obj.Size, LastModified: obj.LastModified})
+		}
+	}
+
+	slices.SortFunc(orphans, func(x, y OrphanObject) int { return cmp.Compare(y.Size, x.Size) })
+	if len(orphans) > orphanSampleSize {
+		orphans = orphans[:orphanSampleSize]
+	}
+	report.OrphanSample = append(report.OrphanSample, orphans...)
+
+	a.mu.Lock()
+	a.last = report
+	a.mu.Unlock()
+	return report, nil
+}
+
+// LastReport returns the most recent scan, or nil before the first one
+func (a *AttachmentReconciler) LastReport() *OrphanReport {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.last
+}
+
+// GetOrphanReport returns the last orphan scan; ?refresh=true scans now.
+// The route requires the manage_storage permission.
+func (a *AttachmentReconciler) GetOrphanReport(c *fiber.Ctx) error {
+	report := a.LastReport()
+	if report == nil || c.QueryBool("refresh") {
+		var err error
+		if report, err = a.Reconcile(c.Context()); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+	return c.JSON(report)
+}
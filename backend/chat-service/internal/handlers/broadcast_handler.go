@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"fmt"
 	"log"
+	"strings"
 
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/repository"
 	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/utils"
 	"github.com/gofiber/fiber/v2"
 )
@@ -14,32 +17,55 @@ type BroadcastRequest struct {
 	Message    string   `json:"message"`
 }
 
-func BroadcastMessage(c *fiber.Ctx) error {
+// broadcastProviders maps request channels to notify.jobs providers.
+var broadcastProviders = map[string]string{
+	"email":    "EMAIL",
+	"whatsapp": "WHATSAPP",
+}
+
+// BroadcastMessage queues one notification per recipient and channel on the
+// shared notification queue. Recipients are email addresses for email and
+// numbers with country code (e.g. +919876543210) for WhatsApp. An
+// Idempotency-Key header makes retrying the same request a no-op.
+func (h *ChatHandler) BroadcastMessage(c *fiber.Ctx) error {
 	var req BroadcastRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
 	}
+	if strings.TrimSpace(req.Message) == "" || len(req.Recipients) == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "Message and recipients are required"})
+	}
 
-	// This should be optimized with a worker pool for large numbers of recipients
-	go func() {
+	requestKey := c.Get("Idempotency-Key")
+	userID := utils.GetUserID(c)
+
+	var notes []repository.OutboundNotification
+	for _, channel := range req.Channels {
+		provider, ok := broadcastProviders[channel]
+		if !ok {
+			return c.Status(400).JSON(fiber.Map{"error": "Unsupported channel: " + channel})
+		}
 		for _, recipient := range req.Recipients {
-			for _, channel := range req.Channels {
-				switch channel {
-				case "email":
-					// Assuming recipient is an email address
-					if err := utils.SendEmail(recipient, req.Subject, req.Message); err != nil {
-						log.Printf("Failed to send email to %s: %v", recipient, err)
-					}
-				case "whatsapp":
-					// Assuming recipient includes country code or we standardize it
-					// e.g., recipient = "+919876543210"
-					if err := utils.SendWhatsAppMessage(recipient, req.Message); err != nil {
-						log.Printf("Failed to send whatsapp to %s: %v", recipient, err)
-					}
-				}
+			n := repository.OutboundNotification{
+				Provider: provider,
+				Kind:     "chat_broadcast",
+				Address:  recipient,
+				Subject:  req.Subject,
+				Body:     req.Message,
+				Category: "broadcast",
 			}
+			if requestKey != "" {
+				n.IdempotencyKey = fmt.Sprintf("chat-broadcast:%d:%s:%s:%s", userID, requestKey, channel, recipient)
+			}
+			notes = append(notes, n)
 		}
-	}()
+	}
+
+	queued, err := h.Repo.EnqueueNotifications(c.Context(), notes)
+	if err != nil {
+		log.Printf("BroadcastMessage: queueing failed: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to queue broadcast"})
+	}
 
-	return c.JSON(fiber.Map{"message": "Broadcast initiated"})
+	return c.JSON(fiber.Map{"message": "Broadcast queued", "queued": queued})
 }
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/repository"
@@ -12,10 +15,11 @@ import (
 
 type ChatHandler struct {
 	Repo *repository.ChatRepository
+	Hub  *Hub
 }
 
-func NewChatHandler(repo *repository.ChatRepository) *ChatHandler {
-	return &ChatHandler{Repo: repo}
+func NewChatHandler(repo *repository.ChatRepository, hub *Hub) *ChatHandler {
+	return &ChatHandler{Repo: repo, Hub: hub}
 }
 
 // GetGroups retrieves all groups for the authenticated user
@@ -41,7 +45,7 @@ func (h *ChatHandler) GetPotentialUsers(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized: User ID not found"})
 	}
 
-	users, err := h.Repo.GetPotentialUsers(c.Context(), userID)
+	users, err := h.Repo.GetPotentialUsers(c.Context(), userID, utils.DepartmentScope(c))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -77,6 +81,7 @@ func (h *ChatHandler) CreateGroup(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+	h.Hub.JoinGroup(groupID, append(req.MemberIDs, userID)...)
 
 	response := fiber.Map{
 		"id":         groupID,
@@ -96,20 +101,75 @@ func (h *ChatHandler) CreateGroup(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(response)
 }
 
-// GetHistory retrieves chat history for a group
+// Page sizes for cursor-based history
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 100
+)
+
+// GetHistory retrieves chat history for a group. Pages are selected with
+// ?before=<id>, ?after=<id> or ?around=<id> (jump to a reply or pinned
+// message) plus ?limit; without a cursor the newest page is returned.
+// The older ?days window is still honoured when no cursor is given.
 func (h *ChatHandler) GetHistory(c *fiber.Ctx) error {
 	groupID, _ := strconv.ParseInt(c.Params("groupId"), 10, 64)
-	daysStr := c.Query("days", "30") // Default to 30 days
-	days, err := strconv.Atoi(daysStr)
-	if err != nil {
-		days = 30
-	}
 
 	userID := utils.GetUserID(c)
-	if userID != 0 {
+	if isMember, err := h.Repo.IsGroupMember(c.Context(), groupID, userID); err != nil || !isMember {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You are not a member of this group"})
+	}
+
+	cursor := repository.HistoryCursor{
+		Before: int64(c.QueryInt("before")),
+		After:  int64(c.QueryInt("after")),
+		Around: int64(c.QueryInt("around")),
+		Limit:  c.QueryInt("limit", defaultHistoryLimit),
+	}
+	set := 0
+	for _, id := range []int64{cursor.Before, cursor.After, cursor.Around} {
+		if id > 0 {
+			set++
+		}
+	}
+	if set > 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Use only one of before, after or around"})
+	}
+	if cursor.Limit <= 0 || cursor.Limit > maxHistoryLimit {
+		cursor.Limit = maxHistoryLimit
+	}
+
+	if set == 0 && c.Query("days") != "" {
+		return h.getHistorySince(c, groupID, userID)
+	}
+
+	// Only the newest page means the user has caught up
+	if cursor.Before == 0 && cursor.Around == 0 {
 		h.Repo.MarkMessagesRead(c.Context(), groupID, userID)
 	}
 
+	messages, hasOlder, hasNewer, err := h.Repo.GetMessagesPage(c.Context(), groupID, userID, cursor)
+	if errors.Is(err, repository.ErrMessageNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Message not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"messages":  messages,
+		"has_older": hasOlder,
+		"has_newer": hasNewer,
+	})
+}
+
+// getHistorySince serves the legacy ?days window
+func (h *ChatHandler) getHistorySince(c *fiber.Ctx, groupID, userID int64) error {
+	days, err := strconv.Atoi(c.Query("days", "30"))
+	if err != nil {
+		days = 30
+	}
+	h.Repo.MarkMessagesRead(c.Context(), groupID, userID)
+
 	cutoff := time.Now().AddDate(0, 0, -days)
 	messages, hasOlder, err := h.Repo.GetMessages(c.Context(), groupID, cutoff, userID)
 	if err != nil {
@@ -123,6 +183,101 @@ func (h *ChatHandler) GetHistory(c *fiber.Ctx) error {
 	})
 }
 
+// SyncMessages returns messages after the given seq so a reconnecting client
+// can fill gaps without refetching the whole history
+func (h *ChatHandler) SyncMessages(c *fiber.Ctx) error {
+	groupID, _ := strconv.ParseInt(c.Params("groupId"), 10, 64)
+	afterSeq, _ := strconv.ParseInt(c.Query("after_seq", "0"), 10, 64)
+	limit := c.QueryInt("limit", resyncPageSize)
+	if limit <= 0 || limit > resyncPageSize {
+		limit = resyncPageSize
+	}
+
+	userID := utils.GetUserID(c)
+	if isMember, err := h.Repo.IsGroupMember(c.Context(), groupID, userID); err != nil || !isMember {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You are not a member of this group"})
+	}
+
+	messages, hasMore, err := h.Repo.GetMessagesAfterSeq(c.Context(), groupID, afterSeq, userID, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"messages": messages,
+		"has_more": hasMore,
+	})
+}
+
+// Page sizes for message search
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+)
+
+// SearchMessages runs a full-text search over the user's groups.
+// Filters: group_id, sender_id, type, from and to (YYYY-MM-DD or RFC3339;
+// a plain to date is inclusive), with limit/offset paging.
+func (h *ChatHandler) SearchMessages(c *fiber.Ctx) error {
+	query := strings.TrimSpace(c.Query("q"))
+	if len(query) < 2 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Search query must be at least 2 characters"})
+	}
+
+	filter := repository.MessageSearchFilter{
+		Query:    query,
+		GroupID:  int64(c.QueryInt("group_id")),
+		SenderID: int64(c.QueryInt("sender_id")),
+		Type:     c.Query("type"),
+		Limit:    c.QueryInt("limit", defaultSearchLimit),
+		Offset:   c.QueryInt("offset"),
+	}
+	if filter.Limit <= 0 || filter.Limit > maxSearchLimit {
+		filter.Limit = maxSearchLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	var err error
+	if filter.From, _, err = parseSearchDate(c.Query("from")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid from date"})
+	}
+	to, dateOnly, err := parseSearchDate(c.Query("to"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid to date"})
+	}
+	if dateOnly {
+		to = to.AddDate(0, 0, 1)
+	}
+	filter.To = to
+
+	userID := utils.GetUserID(c)
+	results, total, err := h.Repo.SearchMessages(c.Context(), userID, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"results": results,
+		"total":   total,
+		"limit":   filter.Limit,
+		"offset":  filter.Offset,
+	})
+}
+
+// parseSearchDate accepts YYYY-MM-DD or RFC3339 and reports which was used
+func parseSearchDate(value string) (time.Time, bool, error) {
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
+
 // PinMessage toggles pin status
 func (h *ChatHandler) PinMessage(c *fiber.Ctx) error {
 	msgID, _ := strconv.ParseInt(c.Params("msgId"), 10, 64)
@@ -134,6 +289,55 @@ func (h *ChatHandler) PinMessage(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"is_pinned": isPinned})
 }
 
+// EditMessage changes the content of the caller's own text message within the
+// edit window and notifies the group
+func (h *ChatHandler) EditMessage(c *fiber.Ctx) error {
+	msgID, _ := strconv.ParseInt(c.Params("msgId"), 10, 64)
+	userID := utils.GetUserID(c)
+
+	var req struct {
+		Content string `json:"content"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	event, err := editMessage(c.Context(), h.Hub, msgID, userID, req.Content)
+	if err != nil {
+		status := editErrorStatus(err)
+		if status == fiber.StatusInternalServerError {
+			fmt.Printf("Error editing message %d: %v\n", msgID, err)
+		}
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(event)
+}
+
+// GetMessageEdits returns the edit history of a message to group members.
+// Deleted messages, and messages the caller hid, have no history to show.
+func (h *ChatHandler) GetMessageEdits(c *fiber.Ctx) error {
+	msgID, _ := strconv.ParseInt(c.Params("msgId"), 10, 64)
+	userID := utils.GetUserID(c)
+
+	groupID, err := h.Repo.GetVisibleMessageGroupID(c.Context(), msgID, userID)
+	if errors.Is(err, repository.ErrMessageNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Message not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if isMember, err := h.Repo.IsGroupMember(c.Context(), groupID, userID); err != nil || !isMember {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You are not a member of this group"})
+	}
+
+	edits, err := h.Repo.GetMessageEdits(c.Context(), msgID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"edits": edits})
+}
+
 // DeleteMessage deletes a message
 func (h *ChatHandler) DeleteMessage(c *fiber.Ctx) error {
 	msgID, _ := strconv.ParseInt(c.Params("msgId"), 10, 64)
@@ -141,7 +345,7 @@ func (h *ChatHandler) DeleteMessage(c *fiber.Ctx) error {
 
 	deleteForEveryone := c.Query("delete_for_everyone") == "true"
 
-	// For "delete for everyone", also delete the file from S3 if applicable
+	// For "delete for everyone", only the sender may delete
 	if deleteForEveryone {
 		msg, err := h.Repo.GetMessageByID(c.Context(), msgID)
 		if err != nil {
@@ -153,24 +357,33 @@ func (h *ChatHandler) DeleteMessage(c *fiber.Ctx) error {
 		if msg.SenderID != userID {
 			return c.Status(403).JSON(fiber.Map{"error": "Not authorized to delete this message"})
 		}
-
-		// Delete S3 object if it's a file or image
-		if (msg.Type == "file" || msg.Type == "image") && msg.Content != "" {
-			key, err := utils.ExtractS3KeyFromURL(msg.Content)
-			if err == nil && key != "" {
-				bucket := utils.GetChatBucket()
-				if delErr := utils.DeleteS3Object(c.Context(), bucket, key); delErr != nil {
-					// Log but don't fail the delete — the DB record should still be cleaned
-					fmt.Printf("Warning: Failed to delete S3 object (bucket=%s, key=%s): %v\n", bucket, key, delErr)
-				}
-			}
-		}
 	}
 
-	err := h.Repo.DeleteMessage(c.Context(), msgID, userID, deleteForEveryone)
+	deleted, err := h.Repo.DeleteMessage(c.Context(), msgID, userID, deleteForEveryone)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	if deleted == nil {
+		return c.JSON(fiber.Map{"success": true})
+	}
+
+	if payload, err := json.Marshal(MessageDeletedEvent{
+		Type:      "message_deleted",
+		GroupID:   deleted.GroupID,
+		MessageID: msgID,
+		Seq:       deleted.Seq,
+		SenderID:  userID,
+	}); err == nil {
+		h.Hub.SendToGroup(deleted.GroupID, "", payload)
+	}
+
+	// Remove the uploaded objects; anything left behind is reported by the
+	// orphan reconciliation
+	for _, a := range deleted.Attachments {
+		if delErr := utils.DeleteS3Object(c.Context(), a.Bucket, a.ObjectKey); delErr != nil {
+			fmt.Printf("Warning: Failed to delete S3 object (bucket=%s, key=%s): %v\n", a.Bucket, a.ObjectKey, delErr)
+		}
+	}
 
 	return c.JSON(fiber.Map{"success": true})
 }
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/repository"
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetGroupMembers lists a group's members and their roles
+func (h *ChatHandler) GetGroupMembers(c *fiber.Ctx) error {
+	groupID, _ := strconv.ParseInt(c.Params("groupId"), 10, 64)
+	userID := utils.GetUserID(c)
+
+	if isMember, err := h.Repo.IsGroupMember(c.Context(), groupID, userID); err != nil || !isMember {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You are not a member of this group"})
+	}
+
+	members, err := h.Repo.GetGroupMembers(c.Context(), groupID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"members": members})
+}
+
+// AddGroupMembers adds users to a group (group admins only)
+func (h *ChatHandler) AddGroupMembers(c *fiber.Ctx) error {
+	groupID, _ := strconv.ParseInt(c.Params("groupId"), 10, 64)
+	userID := utils.GetUserID(c)
+
+	var req struct {
+		UserIDs []int64 `json:"user_ids"`
+	}
+	if err := c.BodyParser(&req); err != nil || len(req.UserIDs) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "user_ids is required"})
+	}
+
+	if err := h.requireGroupAdmin(c.Context(), groupID, userID); err != nil {
+		return groupAdminError(c, err)
+	}
+
+	added, err := h.Repo.AddGroupMembers(c.Context(), groupID, req.UserIDs)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if len(added) > 0 {
+		// Join first so the new members receive the announcement
+		h.Hub.JoinGroup(groupID, added...)
+		names := make([]string, 0, len(added))
+		for _, id := range added {
+			names = append(names, h.userName(c.Context(), id))
+		}
+		h.postSystemMessage(c.Context(), groupID, userID,
+			fmt.Sprintf("%s added %s", h.userName(c.Context(), userID), strings.Join(names, ", ")),
+			map[string]any{"action": "members_added", "user_ids": added})
+	}
+
+	return c.JSON(fiber.Map{"added": added})
+}
+
+// RemoveGroupMember removes a member (group admins), or lets a member leave
+// when they remove themselves
+func (h *ChatHandler) RemoveGroupMember(c *fiber.Ctx) error {
+	groupID, _ := strconv.ParseInt(c.Params("groupId"), 10, 64)
+	targetID, _ := strconv.ParseInt(c.Params("userId"), 10, 64)
+	userID := utils.GetUserID(c)
+
+	if targetID == userID {
+		if err := h.requireManageableGroup(c.Context(), groupID); err != nil {
+			return groupAdminError(c, err)
+		}
+	} else if err := h.requireGroupAdmin(c.Context(), groupID, userID); err != nil {
+		return groupAdminError(c, err)
+	}
+
+	if err := h.Repo.RemoveGroupMember(c.Context(), groupID, targetID); err != nil {
+		return groupAdminError(c, err)
+	}
+
+	action, content := "member_removed", fmt.Sprintf("%s removed %s", h.userName(c.Context(), userID), h.userName(c.Context(), targetID))
+	if targetID == userID {
+		action, content = "member_left", fmt.Sprintf("%s left the group", h.userName(c.Context(), userID))
+	}
+	h.postSystemMessage(c.Context(), groupID, userID, content, map[string]any{"action": action, "user_id": targetID})
+	h.Hub.LeaveGroup(groupID, targetID)
+
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// SetMemberRole promotes a member to group admin or demotes them
+func (h *ChatHandler) SetMemberRole(c *fiber.Ctx) error {
+	groupID, _ := strconv.ParseInt(c.Params("groupId"), 10, 64)
+	targetID, _ := strconv.ParseInt(c.Params("userId"), 10, 64)
+	userID := utils.GetUserID(c)
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := c.BodyParser(&req); err != nil || (req.Role != repository.GroupRoleAdmin && req.Role != repository.GroupRoleMember) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "role must be 'admin' or 'member'"})
+	}
+
+	if err := h.requireGroupAdmin(c.Context(), groupID, userID); err != nil {
+		return groupAdminError(c, err)
+	}
+	if err := h.Repo.SetMemberRole(c.Context(), groupID, targetID, req.Role); err != nil {
+		return groupAdminError(c, err)
+	}
+
+	verb := "made %s a group admin"
+	if req.Role == repository.GroupRoleMember {
+		verb = "removed %s as group admin"
+	}
+	h.postSystemMessage(c.Context(), groupID, userID,
+		h.userName(c.Context(), userID)+" "+fmt.Sprintf(verb, h.userName(c.Context(), targetID)),
+		map[string]any{"action": "role_changed", "user_id": targetID, "role": req.Role})
+
+	return c.JSON(fiber.Map{"success": true, "role": req.Role})
+}
+
+// MuteMember stops a member from posting for duration_minutes; 0 unmutes
+func (h *ChatHandler) MuteMember(c *fiber.Ctx) error {
+	groupID, _ := strconv.ParseInt(c.Params("groupId"), 10, 64)
+	targetID, _ := strconv.ParseInt(c.Params("userId"), 10, 64)
+	userID := utils.GetUserID(c)
+
+	var req struct {
+		DurationMinutes int `json:"duration_minutes"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.DurationMinutes < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "duration_minutes must be zero or positive"})
+	}
+
+	if err := h.requireGroupAdmin(c.Context(), groupID, userID); err != nil {
+		return groupAdminError(c, err)
+	}
+	targetRole, err := h.Repo.GetMemberRole(c.Context(), groupID, targetID)
+	if err != nil {
+		return groupAdminError(c, err)
+	}
+	if targetRole == repository.GroupRoleAdmin {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Group admins cannot be muted"})
+	}
+
+	var until *time.Time
+	content := fmt.Sprintf("%s unmuted %s", h.userName(c.Context(), userID), h.userName(c.Context(), targetID))
+	if req.DurationMinutes > 0 {
+		t := time.Now().Add(time.Duration(req.DurationMinutes) * time.Minute)
+		until = &t
+		content = fmt.Sprintf("%s muted %s for %s", h.userName(c.Context(), userID), h.userName(c.Context(), targetID), formatMuteDuration(req.DurationMinutes))
+	}
+	if err := h.Repo.MuteMember(c.Context(), groupID, targetID, until); err != nil {
+		return groupAdminError(c, err)
+	}
+	h.postSystemMessage(c.Context(), groupID, userID, content,
+		map[string]any{"action": "member_muted", "user_id": targetID, "muted_until": until})
+
+	return c.JSON(fiber.Map{"success": true, "muted_until": until})
+}
+
+// UpdateGroupSettings changes group-wide settings (announcement-only mode)
+func (h *ChatHandler) UpdateGroupSettings(c *fiber.Ctx) error {
+	groupID, _ := strconv.ParseInt(c.Params("groupId"), 10, 64)
+	userID := utils.GetUserID(c)
+
+	var req struct {
+		AnnouncementOnly *bool `json:"announcement_only"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.AnnouncementOnly == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "announcement_only is required"})
+	}
+
+	if err := h.requireGroupAdmin(c.Context(), groupID, userID); err != nil {
+		return groupAdminError(c, err)
+	}
+	if err := h.Repo.SetAnnouncementOnly(c.Context(), groupID, *req.AnnouncementOnly); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	content := h.userName(c.Context(), userID) + " allowed all members to send messages"
+	if *req.AnnouncementOnly {
+		content = h.userName(c.Context(), userID) + " set the group so only admins can send messages"
+	}
+	h.postSystemMessage(c.Context(), groupID, userID, content,
+		map[string]any{"action": "announcement_only_changed", "announcement_only": *req.AnnouncementOnly})
+
+	return c.JSON(fiber.Map{"success": true, "announcement_only": *req.AnnouncementOnly})
+}
+
+// requireManageableGroup rejects direct chats, whose membership is fixed
+func (h *ChatHandler) requireManageableGroup(ctx context.Context, groupID int64) error {
+	groupType, err := h.Repo.GetGroupType(ctx, groupID)
+	if err != nil {
+		return repository.ErrNotGroupMember
+	}
+	if groupType == "direct" {
+		return repository.ErrDirectGroup
+	}
+	return nil
+}
+
+// requireGroupAdmin checks that userID administers a manageable group
+func (h *ChatHandler) requireGroupAdmin(ctx context.Context, groupID, userID int64) error {
+	if err := h.requireManageableGroup(ctx, groupID); err != nil {
+		return err
+	}
+	role, err := h.Repo.GetMemberRole(ctx, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if role != repository.GroupRoleAdmin {
+		return repository.ErrNotGroupAdmin
+	}
+	return nil
+}
+
+// groupAdminError maps group administration errors to responses
+func groupAdminError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotGroupMember):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Member not found in this group"})
+	case errors.Is(err, repository.ErrNotGroupAdmin):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repository.ErrLastGroupAdmin), errors.Is(err, repository.ErrDirectGroup):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+// postSystemMessage stores a group event and pushes it to the members
+func (h *ChatHandler) postSystemMessage(ctx context.Context, groupID, actorID int64, content string, metadata map[string]any) {
+	msg, err := h.Repo.SaveSystemMessage(ctx, groupID, actorID, content, metadata)
+	if err != nil {
+		log.Printf("Failed to save system message for group %d: %v", groupID, err)
+		return
+	}
+	if payload, err := json.Marshal(msg); err == nil {
+		h.Hub.SendToGroup(groupID, "", payload)
+	}
+}
+
+// userName returns a display name for system messages
+func (h *ChatHandler) userName(ctx context.Context, userID int64) string {
+	if name, err := h.Repo.GetUserSimple(ctx, userID); err == nil && name != "" {
+		return name
+	}
+	return "A user"
+}
+
+func formatMuteDuration(minutes int) string {
+	switch {
+	case minutes%(24*60) == 0:
+		return pluralize(minutes/(24*60), "day")
+	case minutes%60 == 0:
+		return pluralize(minutes/60, "hour")
+	default:
+		return pluralize(minutes, "minute")
+	}
+}
+
+func pluralize(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/repository"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Channels notified by triggers on student.student_personal and the drive tables
+const (
+	studentChangesChannel = "chat_student_changes"
+	driveChangesChannel   = "chat_drive_changes"
+)
+
+// changeDebounce batches notifications from bulk updates into one sync
+const changeDebounce = 2 * time.Second
+
+// GroupSyncInterval is how often department, batch and drive groups are fully
+// reconciled. Configured with CHAT_GROUP_SYNC_MINUTES, default 15.
+func GroupSyncInterval() time.Duration {
+	if v, err := strconv.Atoi(os.Getenv("CHAT_GROUP_SYNC_MINUTES")); err == nil && v > 0 {
+		return time.Duration(v) * time.Minute
+	}
+	return 15 * time.Minute
+}
+
+// syncFunc reconciles the given IDs (everything when nil)
+type syncFunc func(ctx context.Context, ids []int64) ([]repository.MembershipChange, bool, error)
+
+// pendingSync collects IDs changed since the last run of one sync kind
+type pendingSync struct {
+	name string
+	run  syncFunc
+	full bool
+	ids  map[int64]bool
+}
+
+// GroupSyncer keeps department, batch and drive groups in line with student
+// and drive records, both periodically and as those records change.
+type GroupSyncer struct {
+	repo     *repository.ChatRepository
+	hub      *Hub
+	interval time.Duration
+}
+
+func NewGroupSyncer(repo *repository.ChatRepository, hub *Hub, interval time.Duration) *GroupSyncer {
+	return &GroupSyncer{repo: repo, hub: hub, interval: interval}
+}
+
+// Run reconciles on start, every interval, and shortly after changes
+func (s *GroupSyncer) Run(ctx context.Context) {
+	syncs := map[string]*pendingSync{
+		studentChangesChannel: {name: "directory groups", run: s.repo.SyncDirectoryGroups, full: true, ids: map[int64]bool{}},
+		driveChangesChannel:   {name: "drive rooms", run: s.repo.SyncDriveRooms, full: true, ids: map[int64]bool{}},
+	}
+
+	changed := make(chan *pgconn.Notification, 1024)
+	go s.listen(ctx, changed)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	debounce := time.NewTimer(0)
+	defer debounce.Stop()
+
+	waiting := true
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, p := range syncs {
+				p.full = true
+			}
+			debounce.Reset(0)
+			waiting = true
+		case n := <-changed:
+			p, ok := syncs[n.Channel]
+			id, err := strconv.ParseInt(n.Payload, 10, 64)
+			if !ok || err != nil {
+				continue
+			}
+			p.ids[id] = true
+			if !waiting {
+				debounce.Reset(changeDebounce)
+				waiting = true
+			}
+		case <-debounce.C:
+			waiting = false
+			for _, p := range syncs {
+				s.flush(ctx, p)
+			}
+		}
+	}
+}
+
+// flush runs one sync kind if anything is pending and applies the changes
+// to the hub's routing
+func (s *GroupSyncer) flush(ctx context.Context, p *pendingSync) {
+	var ids []int64
+	if !p.full {
+		if len(p.ids) == 0 {
+			return
+		}
+		ids = make([]int64, 0, len(p.ids))
+		for id := range p.ids {
+			ids = append(ids, id)
+		}
+	}
+	p.full = false
+	p.ids = map[int64]bool{}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	changes, ran, err := p.run(ctx, ids)
+	if err != nil {
+		log.Printf("Group sync: %s failed: %v", p.name, err)
+		return
+	}
+	if !ran {
+		return
+	}
+	for _, change := range changes {
+		if change.Joined {
+			s.hub.JoinGroup(change.GroupID, change.UserID)
+		} else {
+			s.hub.LeaveGroup(change.GroupID, change.UserID)
+		}
+	}
+	if len(changes) > 0 {
+		log.Printf("Group sync: %s applied %d membership changes", p.name, len(changes))
+	}
+}
+
+// listen forwards change notifications, reconnecting on failure
+func (s *GroupSyncer) listen(ctx context.Context, changed chan<- *pgconn.Notification) {
+	for ctx.Err() == nil {
+		if err := s.listenOnce(ctx, changed); err != nil && ctx.Err() == nil {
+			log.Printf("Group sync: listening for changes failed: %v", err)
+			time.Sleep(10 * time.Second)
+		}
+	}
+}
+
+func (s *GroupSyncer) listenOnce(ctx context.Context, changed chan<- *pgconn.Notification) error {
+	pooled, err := s.repo.DB.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// LISTEN state belongs to the session, so take the connection out of the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	for _, channel := range []string{studentChangesChannel, driveChangesChannel} {
+		if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+			return err
+		}
+	}
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		changed <- notification
+	}
+}
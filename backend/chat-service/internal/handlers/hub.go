@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/repository"
+)
+
+const (
+	// PresenceTTL is how long an instance's presence records survive without a heartbeat
+	PresenceTTL       = 60 * time.Second
+	presenceHeartbeat = 20 * time.Second
+)
+
+// GroupEvent is a payload from a local client addressed to the members of one
+// group. SenderID is empty for server-originated events; otherwise the event
+// is dropped unless the sender belongs to the group.
+type GroupEvent struct {
+	GroupID  int64
+	SenderID string
+	Payload  []byte
+}
+
+// directMessage is a reply for a single local socket (acks, resync pages)
+type directMessage struct {
+	client  *Client
+	payload []byte
+}
+
+// userMessage is a payload for every local socket of one user
+type userMessage struct {
+	userID  string
+	payload []byte
+}
+
+// Hub maintains this instance's clients. Events are published through the
+// backend and delivered to local sockets when they come back from it, so
+// members connected to other replicas receive them too.
+type Hub struct {
+	Clients    map[*Client]bool
+	UserCounts map[string]int // UserID -> local connection count
+	countsMu   sync.RWMutex   // guards UserCounts for the heartbeat goroutine
+
+	// Routing index, only touched from Run. userClients holds every local
+	// socket of a user, userGroups/groupMembers the memberships of locally
+	// connected users as loaded from chat_group_members.
+	userClients  map[string]map[*Client]bool
+	userGroups   map[string]map[int64]bool
+	groupMembers map[int64]map[string]bool
+
+	Broadcast  chan GroupEvent
+	direct     chan directMessage
+	toUser     chan userMessage
+	Register   chan *Client
+	Unregister chan *Client
+	Repo       *repository.ChatRepository
+
+	backend  HubBackend
+	outbound chan Envelope
+}
+
+func NewHub(repo *repository.ChatRepository, backend HubBackend) *Hub {
+	return &Hub{
+		Broadcast:    make(chan GroupEvent),
+		direct:       make(chan directMessage, 256),
+		toUser:       make(chan userMessage, 256),
+		Register:     make(chan *Client),
+		Unregister:   make(chan *Client),
+		Clients:      make(map[*Client]bool),
+		UserCounts:   make(map[string]int),
+		userClients:  make(map[string]map[*Client]bool),
+		userGroups:   make(map[string]map[int64]bool),
+		groupMembers: make(map[int64]map[string]bool),
+		Repo:         repo,
+		backend:      backend,
+		outbound:     make(chan Envelope, 1024),
+	}
+}
+
+// SendToGroup queues payload for the members of groupID
+func (h *Hub) SendToGroup(groupID int64, senderID string, payload []byte) {
+	h.Broadcast <- GroupEvent{GroupID: groupID, SenderID: senderID, Payload: payload}
+}
+
+// SendToClient queues payload for one socket. It is dropped if the client has
+// already been unregistered.
+func (h *Hub) SendToClient(client *Client, payload []byte) {
+	h.direct <- directMessage{client: client, payload: payload}
+}
+
+// SendToUser queues payload for every socket userID has on this instance.
+// Users connected elsewhere are reached by that instance.
+func (h *Hub) SendToUser(userID string, payload []byte) {
+	h.toUser <- userMessage{userID: userID, payload: payload}
+}
+
+// HasLocalUser reports whether userID has a socket on this instance
+func (h *Hub) HasLocalUser(userID string) bool {
+	h.countsMu.RLock()
+	defer h.countsMu.RUnlock()
+	return h.UserCounts[userID] > 0
+}
+
+// JoinGroup records on every instance that userIDs are now members of groupID
+func (h *Hub) JoinGroup(groupID int64, userIDs ...int64) {
+	for _, id := range userIDs {
+		h.outbound <- Envelope{Kind: EnvelopeMembership, GroupID: groupID, UserID: strconv.FormatInt(id, 10), Joined: true}
+	}
+}
+
+// LeaveGroup records on every instance that userIDs are no longer members of groupID
+func (h *Hub) LeaveGroup(groupID int64, userIDs ...int64) {
+	for _, id := range userIDs {
+		h.outbound <- Envelope{Kind: EnvelopeMembership, GroupID: groupID, UserID: strconv.FormatInt(id, 10), Joined: false}
+	}
+}
+
+// OnlineUsers lists users connected to any instance
+func (h *Hub) OnlineUsers(ctx context.Context) ([]string, error) {
+	return h.backend.OnlineUsers(ctx)
+}
+
+func (h *Hub) Run() {
+	inbound, err := h.backend.Subscribe(context.Background())
+	if err != nil {
+		log.Fatalf("Hub: %v", err)
+	}
+	go h.publishLoop()
+	go h.heartbeatLoop()
+
+	for {
+		select {
+		case client := <-h.Register:
+			h.Clients[client] = true
+			if h.userClients[client.ID] == nil {
+				h.userClients[client.ID] = make(map[*Client]bool)
+			}
+			h.userClients[client.ID][client] = true
+			for _, groupID := range client.Groups {
+				h.addMember(groupID, client.ID)
+			}
+
+			h.countsMu.Lock()
+			h.UserCounts[client.ID]++
+			first := h.UserCounts[client.ID] == 1
+			h.countsMu.Unlock()
+			if first {
+				// Broadcast User Online
+				h.outbound <- Envelope{Kind: EnvelopePresence, UserID: client.ID, Status: "online"}
+			}
+
+		case client := <-h.Unregister:
+			h.removeClient(client)
+
+		case msg := <-h.direct:
+			if h.Clients[msg.client] {
+				h.deliver(msg.client, msg.payload)
+			}
+
+		case msg := <-h.toUser:
+			for client := range h.userClients[msg.userID] {
+				h.deliver(client, msg.payload)
+			}
+
+		case event := <-h.Broadcast:
+			// Senders are local, so only this instance can vouch for their membership
+			if event.SenderID != "" && !h.groupMembers[event.GroupID][event.SenderID] {
+				continue
+			}
+			h.outbound <- Envelope{Kind: EnvelopeGroup, GroupID: event.GroupID, UserID: event.SenderID, Payload: event.Payload}
+
+		case env, ok := <-inbound:
+			if !ok {
+				log.Fatalf("Hub: event subscription closed")
+			}
+			h.handleEnvelope(env)
+		}
+	}
+}
+
+// handleEnvelope applies an event received from the backend to local sockets
+func (h *Hub) handleEnvelope(env Envelope) {
+	switch env.Kind {
+	case EnvelopeGroup:
+		for userID := range h.groupMembers[env.GroupID] {
+			for client := range h.userClients[userID] {
+				h.deliver(client, env.Payload)
+			}
+		}
+
+	case EnvelopeMembership:
+		// Only connected users are indexed; others load their groups on connect
+		if _, online := h.userClients[env.UserID]; !online {
+			return
+		}
+		if env.Joined {
+			h.addMember(env.GroupID, env.UserID)
+		} else {
+			h.removeMember(env.GroupID, env.UserID)
+		}
+
+	case EnvelopePresence:
+		msg, _ := json.Marshal(PresenceEvent{
+			Type:   "presence",
+			UserID: env.UserID,
+			Status: env.Status,
+		})
+		for client := range h.Clients {
+			h.deliver(client, msg)
+		}
+	}
+}
+
+// publishLoop sends outbound events in order. Presence changes update the
+// shared presence set first, and an offline event is only published once the
+// user has no socket left on any instance.
+func (h *Hub) publishLoop() {
+	for env := range h.outbound {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if env.Kind == EnvelopePresence {
+			if env.Status == "online" {
+				if err := h.backend.MarkOnline(ctx, env.UserID); err != nil {
+					log.Printf("Hub: failed to mark %s online: %v", env.UserID, err)
+				}
+			} else if stillOnline, err := h.backend.MarkOffline(ctx, env.UserID); err == nil && stillOnline {
+				cancel()
+				continue
+			}
+		}
+		if err := h.backend.Publish(ctx, env); err != nil {
+			log.Printf("Hub: failed to publish %s event: %v", env.Kind, err)
+		}
+		cancel()
+	}
+}
+
+// heartbeatLoop keeps this instance's presence records alive and reports
+// users whose instance disappeared without saying goodbye.
+func (h *Hub) heartbeatLoop() {
+	ticker := time.NewTicker(presenceHeartbeat)
+	defer ticker.Stop()
+	for range ticker.C {
+		h.countsMu.RLock()
+		users := make([]string, 0, len(h.UserCounts))
+		for userID := range h.UserCounts {
+			users = append(users, userID)
+		}
+		h.countsMu.RUnlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := h.backend.Heartbeat(ctx, users); err != nil {
+			log.Printf("Hub: presence heartbeat failed: %v", err)
+		}
+		gone, err := h.backend.ReapExpired(ctx)
+		cancel()
+		if err != nil {
+			log.Printf("Hub: presence reap failed: %v", err)
+		}
+		for _, userID := range gone {
+			h.outbound <- Envelope{Kind: EnvelopePresence, UserID: userID, Status: "offline"}
+		}
+	}
+}
+
+func (h *Hub) addMember(groupID int64, userID string) {
+	if h.groupMembers[groupID] == nil {
+		h.groupMembers[groupID] = make(map[string]bool)
+	}
+	h.groupMembers[groupID][userID] = true
+	if h.userGroups[userID] == nil {
+		h.userGroups[userID] = make(map[int64]bool)
+	}
+	h.userGroups[userID][groupID] = true
+}
+
+func (h *Hub) removeMember(groupID int64, userID string) {
+	delete(h.groupMembers[groupID], userID)
+	if len(h.groupMembers[groupID]) == 0 {
+		delete(h.groupMembers, groupID)
+	}
+	delete(h.userGroups[userID], groupID)
+}
+
+// deliver sends without blocking. A client whose buffer is full is dropped
+// rather than stalling the hub; it catches up via resync when it reconnects.
+func (h *Hub) deliver(client *Client, msg []byte) {
+	select {
+	case client.Send <- msg:
+	default:
+		log.Printf("Hub: dropping slow client for UserID %s (send buffer full)", client.ID)
+		h.removeClient(client)
+	}
+}
+
+// removeClient closes a client and, once a user's last local socket is gone,
+// drops them from the routing index and reports them offline.
+func (h *Hub) removeClient(client *Client) {
+	if _, ok := h.Clients[client]; !ok {
+		return
+	}
+	delete(h.Clients, client)
+	close(client.Send)
+	delete(h.userClients[client.ID], client)
+
+	h.countsMu.Lock()
+	h.UserCounts[client.ID]--
+	last := h.UserCounts[client.ID] <= 0
+	if last {
+		delete(h.UserCounts, client.ID)
+	}
+	h.countsMu.Unlock()
+	if !last {
+		return
+	}
+
+	delete(h.userClients, client.ID)
+	for groupID := range h.userGroups[client.ID] {
+		h.removeMember(groupID, client.ID)
+	}
+	delete(h.userGroups, client.ID)
+
+	// Update Last Seen in DB
+	userIDInt, _ := strconv.ParseInt(client.ID, 10, 64)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	_, _ = h.Repo.DB.Exec(ctx, "UPDATE users SET last_seen = NOW() WHERE id = $1", userIDInt)
+	cancel()
+
+	// Broadcast User Offline (suppressed if connected to another instance)
+	h.outbound <- Envelope{Kind: EnvelopePresence, UserID: client.ID, Status: "offline"}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+// Envelope kinds exchanged between hub instances
+const (
+	EnvelopeGroup      = "group"      // Payload for the members of GroupID
+	EnvelopePresence   = "presence"   // UserID went Status ("online"/"offline")
+	EnvelopeMembership = "membership" // UserID Joined or left GroupID
+)
+
+// Envelope is a hub event as it travels between chat-service instances
+type Envelope struct {
+	Kind    string          `json:"kind"`
+	GroupID int64           `json:"group_id,omitempty"`
+	UserID  string          `json:"user_id,omitempty"`
+	Status  string          `json:"status,omitempty"`
+	Joined  bool            `json:"joined,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// HubBackend fans hub events out to every chat-service instance and keeps
+// track of which users are online anywhere. Each instance only delivers the
+// events it receives to its own sockets.
+type HubBackend interface {
+	// Publish sends env to every instance, including this one
+	Publish(ctx context.Context, env Envelope) error
+	// Subscribe returns the stream of events published by all instances
+	Subscribe(ctx context.Context) (<-chan Envelope, error)
+
+	// MarkOnline records that userID has a socket on this instance
+	MarkOnline(ctx context.Context, userID string) error
+	// MarkOffline removes this instance's record and reports whether the
+	// user is still connected to another instance
+	MarkOffline(ctx context.Context, userID string) (bool, error)
+	// Heartbeat refreshes this instance's records for userIDs
+	Heartbeat(ctx context.Context, userIDs []string) error
+	// ReapExpired drops records whose instance stopped heartbeating and
+	// returns the users that are now offline everywhere
+	ReapExpired(ctx context.Context) ([]string, error)
+	// OnlineUsers lists users connected to any instance
+	OnlineUsers(ctx context.Context) ([]string, error)
+}
+
+// LocalBackend is an in-process HubBackend for a single instance. It is used
+// when Redis is unavailable and as a stand-in for tests.
+type LocalBackend struct {
+	mu     sync.Mutex
+	subs   []chan Envelope
+	online map[string]bool
+}
+
+func NewLocalBackend() *LocalBackend {
+	return &LocalBackend{online: make(map[string]bool)}
+}
+
+func (b *LocalBackend) Publish(ctx context.Context, env Envelope) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, sub := range b.subs {
+		select {
+		case sub <- env:
+		default:
+			// Matches Redis pub/sub semantics: a stalled subscriber loses events
+			log.Printf("Hub: dropping %s event, subscriber is full", env.Kind)
+		}
+	}
+	return nil
+}
+
+func (b *LocalBackend) Subscribe(ctx context.Context) (<-chan Envelope, error) {
+	ch := make(chan Envelope, 1024)
+	b.mu.Lock()
+	b.subs = append(b.subs, ch)
+	b.mu.Unlock()
+	return ch, nil
+}
+
+func (b *LocalBackend) MarkOnline(ctx context.Context, userID string) error {
+	b.mu.Lock()
+	b.online[userID] = true
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *LocalBackend) MarkOffline(ctx context.Context, userID string) (bool, error) {
+	b.mu.Lock()
+	delete(b.online, userID)
+	b.mu.Unlock()
+	return false, nil
+}
+
+func (b *LocalBackend) Heartbeat(ctx context.Context, userIDs []string) error {
+	return nil
+}
+
+func (b *LocalBackend) ReapExpired(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (b *LocalBackend) OnlineUsers(ctx context.Context) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	users := make([]string, 0, len(b.online))
+	for userID := range b.online {
+		users = append(users, userID)
+	}
+	return users, nil
+}
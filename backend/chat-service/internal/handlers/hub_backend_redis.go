@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisEventsChannel = "chat:events"
+	// Sorted set of "<userID>@<instanceID>" scored by the unix time the entry
+	// expires; instances push the score forward on every heartbeat
+	redisPresenceKey = "chat:presence"
+)
+
+// RedisBackend shares hub events and presence between chat-service replicas
+// through Redis pub/sub and a presence sorted set.
+type RedisBackend struct {
+	client     *redis.Client
+	instanceID string
+	ttl        time.Duration
+}
+
+// NewRedisBackend creates a backend for one instance. Presence entries not
+// refreshed within ttl are treated as gone (e.g. after a crash).
+func NewRedisBackend(client *redis.Client, instanceID string, ttl time.Duration) *RedisBackend {
+	return &RedisBackend{client: client, instanceID: instanceID, ttl: ttl}
+}
+
+func (b *RedisBackend) member(userID string) string {
+	return userID + "@" + b.instanceID
+}
+
+func (b *RedisBackend) expiry() float64 {
+	return float64(time.Now().Add(b.ttl).Unix())
+}
+
+func (b *RedisBackend) Publish(ctx context.Context, env Envelope) error {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, redisEventsChannel, data).Err()
+}
+
+func (b *RedisBackend) Subscribe(ctx context.Context) (<-chan Envelope, error) {
+	sub := b.client.Subscribe(ctx, redisEventsChannel)
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, fmt.Errorf("failed to subscribe to %s: %w", redisEventsChannel, err)
+	}
+
+	out := make(chan Envelope, 1024)
+	go func() {
+		defer close(out)
+		for msg := range sub.Channel() {
+			var env Envelope
+			if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+				log.Printf("Hub: ignoring malformed event: %v", err)
+				continue
+			}
+			out <- env
+		}
+	}()
+	return out, nil
+}
+
+func (b *RedisBackend) MarkOnline(ctx context.Context, userID string) error {
+	return b.client.ZAdd(ctx, redisPresenceKey, redis.Z{Score: b.expiry(), Member: b.member(userID)}).Err()
+}
+
+func (b *RedisBackend) MarkOffline(ctx context.Context, userID string) (bool, error) {
+	if err := b.client.ZRem(ctx, redisPresenceKey, b.member(userID)).Err(); err != nil {
+		return false, err
+	}
+	return b.isOnline(ctx, userID)
+}
+
+// isOnline reports whether any instance holds a live entry for userID
+func (b *RedisBackend) isOnline(ctx context.Context, userID string) (bool, error) {
+	now := float64(time.Now().Unix())
+	iter := b.client.ZScan(ctx, redisPresenceKey, 0, userID+"@*", 100).Iterator()
+	for iter.Next(ctx) {
+		// ZSCAN yields member, score pairs
+		member := iter.Val()
+		if !iter.Next(ctx) {
+			break
+		}
+		score, _ := strconv.ParseFloat(iter.Val(), 64)
+		if strings.HasPrefix(member, userID+"@") && score > now {
+			return true, nil
+		}
+	}
+	return false, iter.Err()
+}
+
+func (b *RedisBackend) Heartbeat(ctx context.Context, userIDs []string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	expiry := b.expiry()
+	members := make([]redis.Z, 0, len(userIDs))
+	for _, userID := range userIDs {
+		members = append(members, redis.Z{Score: expiry, Member: b.member(userID)})
+	}
+	return b.client.ZAdd(ctx, redisPresenceKey, members...).Err()
+}
+
+func (b *RedisBackend) ReapExpired(ctx context.Context) ([]string, error) {
+	now := fmt.Sprintf("%d", time.Now().Unix())
+	expired, err := b.client.ZRangeByScore(ctx, redisPresenceKey, &redis.ZRangeBy{Min: "-inf", Max: now}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var gone []string
+	for _, member := range expired {
+		// Only the instance whose ZREM wins reports the user, so each
+		// expiry produces a single offline event
+		removed, err := b.client.ZRem(ctx, redisPresenceKey, member).Result()
+		if err != nil || removed == 0 {
+			continue
+		}
+		userID, _, _ := strings.Cut(member, "@")
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		if online, err := b.isOnline(ctx, userID); err == nil && !online {
+			gone = append(gone, userID)
+		}
+	}
+	return gone, nil
+}
+
+func (b *RedisBackend) OnlineUsers(ctx context.Context) ([]string, error) {
+	now := fmt.Sprintf("(%d", time.Now().Unix())
+	members, err := b.client.ZRangeByScore(ctx, redisPresenceKey, &redis.ZRangeBy{Min: now, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	users := make([]string, 0, len(members))
+	for _, member := range members {
+		userID, _, _ := strings.Cut(member, "@")
+		if !seen[userID] {
+			seen[userID] = true
+			users = append(users, userID)
+		}
+	}
+	return users, nil
+}
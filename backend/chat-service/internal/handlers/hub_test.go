@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/repository"
+)
+
+// startHub runs a hub on an in-process backend. Clients without a socket
+// must not be unregistered, since that records last_seen through Repo.
+func startHub(repo *repository.ChatRepository) *Hub {
+	hub := NewHub(repo, NewLocalBackend())
+	go hub.Run()
+	return hub
+}
+
+// localClient registers a socket-less client for userID, a member of groups
+func localClient(hub *Hub, userID string, groups ...int64) *Client {
+	client := &Client{Hub: hub, Send: make(chan []byte, 256), ID: userID, Groups: groups}
+	hub.Register <- client
+	return client
+}
+
+func event(kind string) []byte {
+	payload, _ := json.Marshal(map[string]string{"type": kind})
+	return payload
+}
+
+// receivedUntil returns the type of every event client received before
+// marker, ignoring presence updates
+func receivedUntil(t *testing.T, client *Client, marker string) []string {
+	t.Helper()
+	var got []string
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case msg := <-client.Send:
+			var e struct {
+				Type string `json:"type"`
+			}
+			if err := json.Unmarshal(msg, &e); err != nil {
+				t.Fatal(err)
+			}
+			if e.Type == marker {
+				return got
+			}
+			if !strings.HasPrefix(e.Type, "presence") {
+				got = append(got, e.Type)
+			}
+		case <-timeout:
+			t.Fatalf("user %s never received %q (got %v)", client.ID, marker, got)
+			return nil
+		}
+	}
+}
+
+func TestHubOnlyDeliversGroupEventsToMembers(t *testing.T) {
+	hub := startHub(nil)
+	alice := localClient(hub, "1", 10)
+	bob := localClient(hub, "2", 20)
+
+	hub.SendToGroup(10, "", event("for_10"))
+	// bob is not a member, so he cannot post to group 10 either
+	hub.SendToGroup(10, "2", event("from_bob"))
+	hub.SendToGroup(10, "", event("done"))
+	hub.SendToGroup(20, "", event("done"))
+
+	if got := receivedUntil(t, alice, "done"); !slices.Equal(got, []string{"for_10"}) {
+		t.Errorf("member of group 10 received %v, want [for_10]", got)
+	}
+	if got := receivedUntil(t, bob, "done"); len(got) != 0 {
+		t.Errorf("non-member received %v from group 10", got)
+	}
+}
+
+func TestHubAppliesJoinAndLeave(t *testing.T) {
+	hub := startHub(nil)
+	alice := localClient(hub, "1", 40)
+	bob := localClient(hub, "2", 40)
+
+	hub.JoinGroup(30, 1)
+	hub.SendToGroup(30, "", event("after_join"))
+	receivedUntil(t, alice, "after_join")
+
+	// Joined members may post as well as receive
+	hub.SendToGroup(30, "1", event("from_alice"))
+	if got := receivedUntil(t, alice, "from_alice"); len(got) != 0 {
+		t.Errorf("unexpected events before from_alice: %v", got)
+	}
+
+	hub.LeaveGroup(30, 1)
+	hub.SendToGroup(30, "", event("after_leave"))
+	hub.SendToGroup(40, "", event("done"))
+	if got := receivedUntil(t, alice, "done"); len(got) != 0 {
+		t.Errorf("user who left group 30 received %v", got)
+	}
+	if got := receivedUntil(t, bob, "done"); len(got) != 0 {
+		t.Errorf("user who never joined group 30 received %v", got)
+	}
+}
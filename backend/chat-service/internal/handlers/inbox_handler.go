@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/repository"
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultInboxLimit = 30
+	maxInboxLimit     = 100
+)
+
+// GetNotifications lists the caller's in-app notifications, newest first.
+// Query: unread=true, category, before (notification ID), limit.
+func (h *ChatHandler) GetNotifications(c *fiber.Ctx) error {
+	userID := utils.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized: User ID not found"})
+	}
+
+	filter := repository.InboxFilter{
+		UnreadOnly: c.QueryBool("unread"),
+		Category:   c.Query("category"),
+		Before:     int64(c.QueryInt("before")),
+		Limit:      c.QueryInt("limit", defaultInboxLimit),
+	}
+	if filter.Limit <= 0 || filter.Limit > maxInboxLimit {
+		filter.Limit = maxInboxLimit
+	}
+
+	items, hasMore, err := h.Repo.ListInbox(c.Context(), userID, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch notifications"})
+	}
+	unread, err := h.Repo.CountUnreadInbox(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch notifications"})
+	}
+	return c.JSON(fiber.Map{"notifications": items, "has_more": hasMore, "unread_count": unread})
+}
+
+// GetUnreadNotificationCount returns how many of the caller's notifications are unread
+func (h *ChatHandler) GetUnreadNotificationCount(c *fiber.Ctx) error {
+	userID := utils.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized: User ID not found"})
+	}
+
+	unread, err := h.Repo.CountUnreadInbox(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to count notifications"})
+	}
+	return c.JSON(fiber.Map{"unread_count": unread})
+}
+
+// MarkNotificationRead marks one of the caller's notifications read
+func (h *ChatHandler) MarkNotificationRead(c *fiber.Ctx) error {
+	userID := utils.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized: User ID not found"})
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid notification ID"})
+	}
+
+	found, err := h.Repo.MarkInboxRead(c.Context(), userID, id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update notification"})
+	}
+	if !found {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Notification not found"})
+	}
+	return c.JSON(fiber.Map{"message": "Notification marked as read"})
+}
+
+// MarkAllNotificationsRead marks all of the caller's notifications read, or
+// only those in ?category=
+func (h *ChatHandler) MarkAllNotificationsRead(c *fiber.Ctx) error {
+	userID := utils.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized: User ID not found"})
+	}
+
+	updated, err := h.Repo.MarkAllInboxRead(c.Context(), userID, c.Query("category"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update notifications"})
+	}
+	return c.JSON(fiber.Map{"message": "Notifications marked as read", "updated": updated})
+}
+
+// GetNotificationPreferences returns the caller's channel switches for every
+// category (true unless turned off), their quiet hours and how often they
+// get digest emails
+func (h *ChatHandler) GetNotificationPreferences(c *fiber.Ctx) error {
+	userID := utils.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized: User ID not found"})
+	}
+
+	prefs, quiet, err := h.Repo.GetNotificationPreferences(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch preferences"})
+	}
+	matrix := map[string]map[string]bool{}
+	for _, category := range repository.NotificationCategories {
+		matrix[category] = map[string]bool{}
+		for _, channel := range repository.NotificationChannels {
+			matrix[category][channel] = true
+		}
+	}
+	for _, p := range prefs {
+		if matrix[p.Category] != nil {
+			matrix[p.Category][p.Channel] = p.Enabled
+		}
+	}
+	frequency, err := h.Repo.GetDigestFrequency(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch preferences"})
+	}
+	return c.JSON(fiber.Map{"preferences": matrix, "quiet_hours": quiet, "digest_frequency": frequency})
+}
+
+// UpdateNotificationPreferences switches channels per category. Body:
+// {"preferences": {"broadcast": {"WHATSAPP": false}}}; categories and
+// channels left out keep their setting.
+func (h *ChatHandler) UpdateNotificationPreferences(c *fiber.Ctx) error {
+	userID := utils.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized: User ID not found"})
+	}
+
+	var req struct {
+		Preferences map[string]map[string]bool `json:"preferences"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	var prefs []repository.NotificationPreference
+	for category, channels := range req.Preferences {
+		if !slices.Contains(repository.NotificationCategories, category) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown category: " + category})
+		}
+		for channel, enabled := range channels {
+			channel = strings.ToUpper(channel)
+			if !slices.Contains(repository.NotificationChannels, channel) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown channel: " + channel})
+			}
+			prefs = append(prefs, repository.NotificationPreference{Category: category, Channel: channel, Enabled: enabled})
+		}
+	}
+
+	if err := h.Repo.SetNotificationPreferences(c.Context(), userID, prefs); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save preferences"})
+	}
+	return h.GetNotificationPreferences(c)
+}
+
+// UpdateQuietHours sets the caller's quiet hours. Body: {"enabled": true,
+// "start": "22:00", "end": "07:00", "timezone": "Asia/Kolkata"}; the
+// timezone defaults to Asia/Kolkata.
+func (h *ChatHandler) UpdateQuietHours(c *fiber.Ctx) error {
+	userID := utils.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized: User ID not found"})
+	}
+
+	var q repository.QuietHours
+	if err := c.BodyParser(&q); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if _, err := time.Parse("15:04", q.Start); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "start must be HH:MM"})
+	}
+	if _, err := time.Parse("15:04", q.End); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "end must be HH:MM"})
+	}
+	if q.Timezone == "" {
+		q.Timezone = "Asia/Kolkata"
+	}
+	if ok, err := h.Repo.IsKnownTimezone(c.Context(), q.Timezone); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save quiet hours"})
+	} else if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown timezone: " + q.Timezone})
+	}
+
+	if err := h.Repo.SetQuietHours(c.Context(), userID, q); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save quiet hours"})
+	}
+	return c.JSON(fiber.Map{"quiet_hours": q})
+}
+
+// UpdateDigestFrequency sets how often the caller gets digest emails. Body:
+// {"frequency": "daily"} or "weekly"; turning digests off is the "digest"
+// EMAIL preference.
+func (h *ChatHandler) UpdateDigestFrequency(c *fiber.Ctx) error {
+	userID := utils.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized: User ID not found"})
+	}
+
+	var req struct {
+		Frequency string `json:"frequency"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	req.Frequency = strings.ToLower(strings.TrimSpace(req.Frequency))
+	if !slices.Contains(repository.DigestFrequencies, req.Frequency) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "frequency must be weekly or daily"})
+	}
+
+	if err := h.Repo.SetDigestFrequency(c.Context(), userID, req.Frequency); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save digest frequency"})
+	}
+	return c.JSON(fiber.Map{"digest_frequency": req.Frequency})
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/repository"
+)
+
+// inboxChannel is notified by a trigger on notify.inbox with "user_id:id"
+const inboxChannel = "notify_inbox"
+
+const (
+	// inboxFlushDelay batches the rows of one bulk insert (a drive going out
+	// to a whole batch) into a single lookup
+	inboxFlushDelay = 250 * time.Millisecond
+	inboxFlushMax   = 500
+)
+
+// InboxEvent is the WebSocket frame for a new in-app notification
+type InboxEvent struct {
+	Type         string               `json:"type"` // "notification"
+	Notification repository.InboxItem `json:"notification"`
+}
+
+// InboxNotifier pushes new inbox items to their recipients' sockets. Every
+// replica listens and delivers only to its own clients, so each socket gets
+// an item once.
+type InboxNotifier struct {
+	repo *repository.ChatRepository
+	hub  *Hub
+}
+
+func NewInboxNotifier(repo *repository.ChatRepository, hub *Hub) *InboxNotifier {
+	return &InboxNotifier{repo: repo, hub: hub}
+}
+
+// Run delivers new items until ctx is cancelled
+func (n *InboxNotifier) Run(ctx context.Context) {
+	ids := make(chan int64, 4096)
+	go n.listen(ctx, ids)
+
+	var pending []int64
+	flush := time.NewTimer(inboxFlushDelay)
+	flush.Stop()
+	defer flush.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-ids:
+			pending = append(pending, id)
+			if len(pending) == 1 {
+				flush.Reset(inboxFlushDelay)
+			}
+			if len(pending) >= inboxFlushMax {
+				flush.Stop()
+				n.deliver(ctx, pending)
+				pending = nil
+			}
+		case <-flush.C:
+			n.deliver(ctx, pending)
+			pending = nil
+		}
+	}
+}
+
+func (n *InboxNotifier) deliver(ctx context.Context, ids []int64) {
+	if len(ids) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	items, err := n.repo.GetInboxItems(ctx, ids)
+	if err != nil {
+		log.Printf("Inbox: loading %d new notifications failed: %v", len(ids), err)
+		return
+	}
+	for _, item := range items {
+		msg, err := json.Marshal(InboxEvent{Type: "notification", Notification: item})
+		if err != nil {
+			continue
+		}
+		n.hub.SendToUser(strconv.FormatInt(item.UserID, 10), msg)
+	}
+}
+
+// listen forwards the IDs of new items for locally connected users,
+// reconnecting on failure
+func (n *InboxNotifier) listen(ctx context.Context, ids chan<- int64) {
+	for ctx.Err() == nil {
+		if err := n.listenOnce(ctx, ids); err != nil && ctx.Err() == nil {
+			log.Printf("Inbox: listening for notifications failed: %v", err)
+			time.Sleep(10 * time.Second)
+		}
+	}
+}
+
+func (n *InboxNotifier) listenOnce(ctx context.Context, ids chan<- int64) error {
+	pooled, err := n.repo.DB.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// LISTEN state belongs to the session, so take the connection out of the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+inboxChannel); err != nil {
+		return err
+	}
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		userID, rawID, ok := strings.Cut(notification.Payload, ":")
+		if !ok || !n.hub.HasLocalUser(userID) {
+			continue
+		}
+		if id, err := strconv.ParseInt(rawID, 10, 64); err == nil {
+			ids <- id
+		}
+	}
+}
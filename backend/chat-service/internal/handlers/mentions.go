@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/repository"
+)
+
+// mentionPattern matches the @<register number> tokens the chat input inserts
+var mentionPattern = regexp.MustCompile(`@([A-Za-z0-9]+)`)
+
+// mentionPreviewRunes caps how much of the message a mention notification quotes
+const mentionPreviewRunes = 120
+
+// mentionedRegisterNumbers returns the distinct register numbers @mentioned in content
+func mentionedRegisterNumbers(content string) []string {
+	seen := map[string]bool{}
+	var numbers []string
+	for _, m := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			numbers = append(numbers, m[1])
+		}
+	}
+	return numbers
+}
+
+// notifyMentions sends a chat_mention notification to every group member
+// @mentioned in msg. Members who turned the category off for a channel are
+// skipped by the inbox and the notification workers.
+func (h *Hub) notifyMentions(msg repository.ChatMessage) {
+	if msg.Type != "text" {
+		return
+	}
+	numbers := mentionedRegisterNumbers(msg.Content)
+	if len(numbers) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	userIDs, err := h.Repo.MentionedMembers(ctx, msg.GroupID, msg.SenderID, numbers)
+	if err != nil {
+		log.Printf("Mentions: resolving mentions in message %d failed: %v", msg.ID, err)
+		return
+	}
+
+	body := []rune(msg.Content)
+	if len(body) > mentionPreviewRunes {
+		body = append(body[:mentionPreviewRunes], '…')
+	}
+	err = h.Repo.NotifyUsers(ctx, userIDs, repository.UserNotification{
+		Category: repository.InboxChatMention,
+		Type:     "chat_mention",
+		Title:    fmt.Sprintf("%s mentioned you", msg.SenderName),
+		Body:     string(body),
+		Data: map[string]string{
+			"group_id":   strconv.FormatInt(msg.GroupID, 10),
+			"message_id": strconv.FormatInt(msg.ID, 10),
+		},
+		DedupeKey: fmt.Sprintf("chat_mention:%d", msg.ID),
+	})
+	if err != nil {
+		log.Printf("Mentions: notifying mentions in message %d failed: %v", msg.ID, err)
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+var errEmptyEdit = errors.New("message content cannot be empty")
+
+// MessageEditWindow is how long after sending a message its sender may still
+// edit it. Configured with CHAT_EDIT_WINDOW_MINUTES, default 15.
+func MessageEditWindow() time.Duration {
+	if v, err := strconv.Atoi(os.Getenv("CHAT_EDIT_WINDOW_MINUTES")); err == nil && v > 0 {
+		return time.Duration(v) * time.Minute
+	}
+	return 15 * time.Minute
+}
+
+// Editing or deleting a message gives it the group's next seq, so a client
+// resyncing from its last seen seq picks up the change. Seq is therefore an
+// update cursor, not an ordering key: order messages by created_at and id,
+// and advance the last seen seq from the events below as from new messages.
+
+// MessageEditedEvent tells group members that a message's content changed
+type MessageEditedEvent struct {
+	Type      string    `json:"type"` // "message_edited"
+	GroupID   int64     `json:"group_id"`
+	MessageID int64     `json:"message_id"`
+	Seq       int64     `json:"seq"`
+	Content   string    `json:"content"`
+	EditedAt  time.Time `json:"edited_at"`
+	EditorID  int64     `json:"editor_id"`
+}
+
+// MessageDeletedEvent tells group members that a message was deleted for
+// everyone
+type MessageDeletedEvent struct {
+	Type      string `json:"type"` // "message_deleted"
+	GroupID   int64  `json:"group_id"`
+	MessageID int64  `json:"message_id"`
+	Seq       int64  `json:"seq"`
+	SenderID  int64  `json:"sender_id"`
+}
+
+// editMessage applies an edit and broadcasts it to the group. It is shared by
+// the REST endpoint and the WebSocket "edit" frame.
+func editMessage(ctx context.Context, hub *Hub, msgID, editorID int64, content string) (*MessageEditedEvent, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, errEmptyEdit
+	}
+
+	groupID, seq, editedAt, err := hub.Repo.EditMessage(ctx, msgID, editorID, content, MessageEditWindow())
+	if err != nil {
+		return nil, err
+	}
+
+	event := &MessageEditedEvent{
+		Type:      "message_edited",
+		GroupID:   groupID,
+		MessageID: msgID,
+		Seq:       seq,
+		Content:   content,
+		EditedAt:  editedAt,
+		EditorID:  editorID,
+	}
+	if payload, err := json.Marshal(event); err == nil {
+		// Membership was verified while editing, so send it as a server event
+		hub.SendToGroup(groupID, "", payload)
+	}
+	return event, nil
+}
+
+// editErrorStatus maps edit failures to an HTTP status
+func editErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrMessageNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, repository.ErrNotMessageSender),
+		errors.Is(err, repository.ErrNotGroupMember),
+		errors.Is(err, repository.ErrEditWindowExpired):
+		return fiber.StatusForbidden
+	case errors.Is(err, repository.ErrMessageNotEditable), errors.Is(err, errEmptyEdit):
+		return fiber.StatusBadRequest
+	default:
+		return fiber.StatusInternalServerError
+	}
+}
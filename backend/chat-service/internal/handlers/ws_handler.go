@@ -3,24 +3,30 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"os"
 	"strconv"
 	"time"
 
 	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/repository"
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/utils"
 	"github.com/gofiber/fiber/v2"
 	websocket "github.com/gofiber/websocket/v2"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// resyncPageSize caps the messages returned by one resync request
+const resyncPageSize = 200
+
 // Client represents a connected user
 type Client struct {
 	Hub  *Hub
 	Conn *websocket.Conn
 	Send chan []byte
 	ID   string // User ID
+	// Groups the user belonged to when connecting; later changes arrive as membership events
+	Groups []int64
 }
 
 // PresenceEvent defines the structure for online/offline events
@@ -30,98 +36,6 @@ type PresenceEvent struct {
 	Status string `json:"status"` // "online" or "offline"
 }
 
-// Hub maintains the set of active clients and broadcasts messages
-type Hub struct {
-	Clients    map[*Client]bool
-	UserCounts map[string]int // UserID -> Connection Count
-	Broadcast  chan []byte
-	Register   chan *Client
-	Unregister chan *Client
-	Repo       *repository.ChatRepository
-}
-
-func NewHub(repo *repository.ChatRepository) *Hub {
-	return &Hub{
-		Broadcast:  make(chan []byte),
-		Register:   make(chan *Client),
-		Unregister: make(chan *Client),
-		Clients:    make(map[*Client]bool),
-		UserCounts: make(map[string]int),
-		Repo:       repo,
-	}
-}
-
-func (h *Hub) Run() {
-	for {
-		select {
-		case client := <-h.Register:
-			h.Clients[client] = true
-			h.UserCounts[client.ID]++
-			if h.UserCounts[client.ID] == 1 {
-				// Broadcast User Online
-				h.broadcastPresence(client.ID, "online")
-			}
-
-			// Send list of currently online users to the new client
-			var onlineUsers []string
-			for userID := range h.UserCounts {
-				onlineUsers = append(onlineUsers, userID)
-			}
-			if len(onlineUsers) > 0 {
-				syncMsg, _ := json.Marshal(map[string]interface{}{
-					"type":  "presence_sync",
-					"users": onlineUsers,
-				})
-				client.Send <- syncMsg
-			}
-
-		case client := <-h.Unregister:
-			if _, ok := h.Clients[client]; ok {
-				delete(h.Clients, client)
-				close(client.Send)
-				h.UserCounts[client.ID]--
-				if h.UserCounts[client.ID] <= 0 {
-					delete(h.UserCounts, client.ID)
-
-					// Update Last Seen in DB
-					userIDInt, _ := strconv.ParseInt(client.ID, 10, 64)
-					ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-					_, _ = h.Repo.DB.Exec(ctx, "UPDATE users SET last_seen = NOW() WHERE id = $1", userIDInt)
-					cancel()
-
-					// Broadcast User Offline
-					h.broadcastPresence(client.ID, "offline")
-				}
-			}
-		case message := <-h.Broadcast:
-			for client := range h.Clients {
-				select {
-				case client.Send <- message:
-				default:
-					close(client.Send)
-					delete(h.Clients, client)
-				}
-			}
-		}
-	}
-}
-
-func (h *Hub) broadcastPresence(userID, status string) {
-	msg, _ := json.Marshal(PresenceEvent{
-		Type:   "presence",
-		UserID: userID,
-		Status: status,
-	})
-	for client := range h.Clients {
-		select {
-		case client.Send <- msg:
-		default:
-			close(client.Send)
-			delete(h.Clients, client)
-		}
-	}
-}
-
 func ServeWs(hub *Hub) fiber.Handler {
 	return websocket.New(func(c *websocket.Conn) {
 		// Extract token
@@ -139,19 +53,13 @@ func ServeWs(hub *Hub) fiber.Handler {
 		}
 
 		// Validate Token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
+		token, err := utils.ParseToken(tokenString)
 
 		if err != nil || !token.Valid {
 			log.Printf("Invalid WebSocket token: %v", err)
 			if token != nil {
 				log.Printf("Token valid state: %v", token.Valid)
 			}
-			log.Printf("JWT_SECRET length: %d", len(os.Getenv("JWT_SECRET")))
 			c.Close()
 			return
 		}
@@ -162,6 +70,11 @@ func ServeWs(hub *Hub) fiber.Handler {
 			c.Close()
 			return
 		}
+		if err := utils.CheckSessionClaims(claims); err != nil {
+			log.Printf("WS: Rejected token: %s", err.Message)
+			c.Close()
+			return
+		}
 
 		// Adjust this based on your JWT claim structure
 		// Assuming "user_id" is float64 (default for JSON numbers)
@@ -179,7 +92,32 @@ func ServeWs(hub *Hub) fiber.Handler {
 
 		log.Printf("WS: Auth success for UserID: %s", userID)
 
-		client := &Client{Hub: hub, Conn: c, Send: make(chan []byte, 256), ID: userID}
+		// Load memberships so the hub only routes this user's groups to them
+		userIDInt, _ := strconv.ParseInt(userID, 10, 64)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		groups, err := hub.Repo.GetUserGroupIDs(ctx, userIDInt)
+		cancel()
+		if err != nil {
+			log.Printf("WS: Failed to load groups for UserID %s: %v", userID, err)
+			c.Close()
+			return
+		}
+
+		client := &Client{Hub: hub, Conn: c, Send: make(chan []byte, 256), ID: userID, Groups: groups}
+
+		// Send list of users online on any instance to the new client
+		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+		onlineUsers, err := hub.OnlineUsers(ctx)
+		cancel()
+		if err != nil {
+			log.Printf("WS: Failed to load presence: %v", err)
+		}
+		onlineUsers = append(onlineUsers, userID)
+		syncMsg, _ := json.Marshal(map[string]interface{}{
+			"type":  "presence_sync",
+			"users": onlineUsers,
+		})
+		client.Send <- syncMsg
 		client.Hub.Register <- client
 
 		defer func() {
@@ -217,6 +155,10 @@ func (c *Client) readPump() {
 			Metadata  any    `json:"metadata"`
 			ReplyToID *int64 `json:"reply_to_id"` // [NEW]
 			Forwarded bool   `json:"forwarded"`   // [NEW]
+			// ClientMsgID makes retries idempotent; it is echoed back in the ack
+			ClientMsgID string `json:"client_msg_id"`
+			AfterSeq    int64  `json:"after_seq"`
+			MessageID   int64  `json:"message_id"`
 		}
 		if err := json.Unmarshal(message, &msgData); err == nil {
 			// Handle Typing Events
@@ -240,7 +182,7 @@ func (c *Client) readPump() {
 					"sender_id":   senderID,
 					"sender_name": senderName,
 				})
-				c.Hub.Broadcast <- broadcastMsg
+				c.Hub.SendToGroup(msgData.GroupID, c.ID, broadcastMsg)
 				continue
 			}
 
@@ -253,6 +195,10 @@ func (c *Client) readPump() {
 
 				// Update DB
 				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				if isMember, _ := c.Hub.Repo.IsGroupMember(ctx, msgData.GroupID, senderID); !isMember {
+					cancel()
+					continue
+				}
 				// Mark messages as 'seen'
 				err := c.Hub.Repo.UpdateMessagesStatus(ctx, msgData.GroupID, senderID, "seen")
 				// Also update Last Read At
@@ -270,19 +216,55 @@ func (c *Client) readPump() {
 					"group_id":  msgData.GroupID,
 					"reader_id": c.ID, // Notify who read it
 				})
-				c.Hub.Broadcast <- broadcastMsg
+				c.Hub.SendToGroup(msgData.GroupID, c.ID, broadcastMsg)
 				continue
 			}
 
-			// Handle Message Deleted (broadcast to other clients)
+			// Deletions are announced by DELETE /messages/:msgId with their seq;
+			// older clients still relay them, so drop the frame
 			if msgData.Type == "message_deleted" {
-				broadcastMsg, _ := json.Marshal(map[string]interface{}{
-					"type":       "message_deleted",
-					"group_id":   msgData.GroupID,
-					"message_id": msgData.Content, // We reuse Content field for message ID
-					"sender_id":  c.ID,
+				continue
+			}
+
+			// Handle Edit (sender only, within the edit window)
+			if msgData.Type == "edit" {
+				editorID, _ := strconv.ParseInt(c.ID, 10, 64)
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				_, err := editMessage(ctx, c.Hub, msgData.MessageID, editorID, msgData.Content)
+				cancel()
+				if err != nil {
+					reply, _ := json.Marshal(map[string]interface{}{
+						"type":       "edit_failed",
+						"message_id": msgData.MessageID,
+						"error":      err.Error(),
+					})
+					c.Hub.SendToClient(c, reply)
+				}
+				continue
+			}
+
+			// Handle Resync (messages missed while disconnected or dropped)
+			if msgData.Type == "resync" {
+				userID, _ := strconv.ParseInt(c.ID, 10, 64)
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				if isMember, _ := c.Hub.Repo.IsGroupMember(ctx, msgData.GroupID, userID); !isMember {
+					cancel()
+					continue
+				}
+				messages, hasMore, err := c.Hub.Repo.GetMessagesAfterSeq(ctx, msgData.GroupID, msgData.AfterSeq, userID, resyncPageSize)
+				cancel()
+				if err != nil {
+					log.Printf("Failed to resync group %d: %v", msgData.GroupID, err)
+					continue
+				}
+
+				reply, _ := json.Marshal(map[string]interface{}{
+					"type":     "resync",
+					"group_id": msgData.GroupID,
+					"messages": messages,
+					"has_more": hasMore,
 				})
-				c.Hub.Broadcast <- broadcastMsg
+				c.Hub.SendToClient(c, reply)
 				continue
 			}
 
@@ -294,11 +276,46 @@ func (c *Client) readPump() {
 
 			// We need context.Background() or a timeout context
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
-
-			savedMsg, err := c.Hub.Repo.SaveMessage(ctx, msgData.GroupID, senderID, msgData.Content, msgData.Type, msgData.Metadata, msgData.ReplyToID, msgData.Forwarded)
+			savedMsg, duplicate, err := c.Hub.Repo.SaveMessage(ctx, msgData.GroupID, senderID, msgData.Content, msgData.Type, msgData.Metadata, msgData.ReplyToID, msgData.Forwarded, msgData.ClientMsgID)
+			cancel()
 			if err != nil {
-				log.Printf("Failed to save message: %v", err)
+				reason := "save_failed"
+				switch {
+				case errors.Is(err, repository.ErrNotGroupMember):
+					log.Printf("WS: UserID %s tried to post to group %d without membership", c.ID, msgData.GroupID)
+					reason = "not_group_member"
+				case errors.Is(err, repository.ErrMemberMuted):
+					reason = "muted"
+				case errors.Is(err, repository.ErrAnnouncementOnly):
+					reason = "announcement_only"
+				case errors.Is(err, repository.ErrGroupArchived):
+					reason = "archived"
+				default:
+					log.Printf("Failed to save message: %v", err)
+				}
+				nack, _ := json.Marshal(map[string]interface{}{
+					"type":          "nack",
+					"client_msg_id": msgData.ClientMsgID,
+					"group_id":      msgData.GroupID,
+					"error":         reason,
+				})
+				c.Hub.SendToClient(c, nack)
+				continue
+			}
+
+			// Ack the sender with the server id and seq so the client can
+			// reconcile its optimistic copy and stop retrying
+			ack, _ := json.Marshal(map[string]interface{}{
+				"type":          "ack",
+				"client_msg_id": msgData.ClientMsgID,
+				"id":            savedMsg.ID,
+				"group_id":      savedMsg.GroupID,
+				"seq":           savedMsg.Seq,
+				"created_at":    savedMsg.CreatedAt,
+			})
+			c.Hub.SendToClient(c, ack)
+			if duplicate {
+				// A retry of a message that was already stored and broadcast
 				continue
 			}
 
@@ -313,10 +330,11 @@ func (c *Client) readPump() {
 			// Frontend: ChatMessage interface in chat.service.ts
 
 			if broadcastBytes, err := json.Marshal(savedMsg); err == nil {
-				c.Hub.Broadcast <- broadcastBytes
+				c.Hub.SendToGroup(savedMsg.GroupID, c.ID, broadcastBytes)
 			} else {
 				log.Printf("Failed to marshal saved message: %v", err)
 			}
+			go c.Hub.notifyMentions(*savedMsg)
 		} else {
 			log.Printf("Received invalid JSON message: %s", string(message))
 		}
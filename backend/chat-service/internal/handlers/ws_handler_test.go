@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/repository"
+	fastws "github.com/fasthttp/websocket"
+	websocket "github.com/gofiber/websocket/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testRepo connects to TEST_DATABASE_URL, a database migrated with
+// scripts/run_schemas.sh, with chat-service's search_path. Tests using it
+// are skipped without one.
+func testRepo(t *testing.T) *repository.ChatRepository {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		_, err := conn.Exec(ctx, "SET search_path TO chat, public")
+		return err
+	}
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return repository.NewChatRepository(pool)
+}
+
+// testUser adds a student removed when the test ends
+func testUser(t *testing.T, repo *repository.ChatRepository, name string) int64 {
+	ctx := context.Background()
+	var id int64
+	email := fmt.Sprintf("%s.%d@ws.test", name, time.Now().UnixNano())
+	err := repo.DB.QueryRow(ctx, `
+		INSERT INTO users (email, password_hash, role, name)
+		VALUES ($1, 'x', 'student', $2) RETURNING id`, email, name).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.DB.Exec(ctx, `DELETE FROM users WHERE id = $1`, id) })
+	return id
+}
+
+// testGroup creates a custom group owned by owner with members
+func testGroup(t *testing.T, repo *repository.ChatRepository, owner int64, members ...int64) int64 {
+	ctx := context.Background()
+	id, err := repo.CreateGroupWithMembers(ctx, "ws test", "custom", owner, members)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.DB.Exec(ctx, `DELETE FROM chat_groups WHERE id = $1`, id) })
+	return id
+}
+
+// connect serves a socket for userID the way ServeWs does once the token is
+// accepted, and returns the client end after the hub has registered it
+func connect(t *testing.T, hub *Hub, userID int64) *fastws.Conn {
+	groups, err := hub.Repo.GetUserGroupIDs(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := strconv.FormatInt(userID, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := fastws.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		client := &Client{Hub: hub, Conn: &websocket.Conn{Conn: conn}, Send: make(chan []byte, 256), ID: id, Groups: groups}
+		hub.Register <- client
+		go client.writePump()
+		client.readPump()
+	}))
+	t.Cleanup(srv.Close)
+
+	conn, _, err := fastws.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	readUntil(t, conn, func(m map[string]any) bool {
+		return m["type"] == "presence" && m["user_id"] == id
+	})
+	return conn
+}
+
+// readUntil returns the first frame on conn that matches
+func readUntil(t *testing.T, conn *fastws.Conn, match func(map[string]any) bool) map[string]any {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var m map[string]any
+		if err := conn.ReadJSON(&m); err != nil {
+			t.Fatal(err)
+		}
+		if match(m) {
+			return m
+		}
+	}
+}
+
+func ofType(kind string) func(map[string]any) bool {
+	return func(m map[string]any) bool { return m["type"] == kind }
+}
+
+func TestRetriedSendIsAckedWithTheOriginalMessage(t *testing.T) {
+	repo := testRepo(t)
+	alice := testUser(t, repo, "alice")
+	bob := testUser(t, repo, "bob")
+	group := testGroup(t, repo, alice, bob)
+
+	hub := startHub(repo)
+	a := connect(t, hub, alice)
+	b := connect(t, hub, bob)
+
+	send := map[string]any{"group_id": group, "type": "text", "content": "hello", "client_msg_id": "retry-1"}
+	if err := a.WriteJSON(send); err != nil {
+		t.Fatal(err)
+	}
+	first := readUntil(t, a, ofType("ack"))
+	if err := a.WriteJSON(send); err != nil {
+		t.Fatal(err)
+	}
+	second := readUntil(t, a, ofType("ack"))
+	if first["id"] != second["id"] || first["seq"] != second["seq"] {
+		t.Fatalf("retry acked as %v/%v, want original %v/%v", second["id"], second["seq"], first["id"], first["seq"])
+	}
+	if second["client_msg_id"] != "retry-1" {
+		t.Errorf("ack carries client_msg_id %v", second["client_msg_id"])
+	}
+
+	var stored int
+	err := repo.DB.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM chat_messages WHERE group_id = $1 AND client_msg_id = 'retry-1'`, group).Scan(&stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != 1 {
+		t.Errorf("retry stored %d messages, want 1", stored)
+	}
+
+	// The retry is not broadcast again
+	if err := a.WriteJSON(map[string]any{"group_id": group, "type": "text", "content": "next", "client_msg_id": "retry-2"}); err != nil {
+		t.Fatal(err)
+	}
+	copies := 0
+	readUntil(t, b, func(m map[string]any) bool {
+		if m["id"] == first["id"] {
+			copies++
+		}
+		return m["content"] == "next"
+	})
+	if copies != 1 {
+		t.Errorf("other member received the message %d times, want 1", copies)
+	}
+}
+
+func TestResyncReturnsMessagesAfterSeq(t *testing.T) {
+	repo := testRepo(t)
+	alice := testUser(t, repo, "alice")
+	group := testGroup(t, repo, alice)
+
+	ctx := context.Background()
+	var saved []*repository.ChatMessage
+	for _, content := range []string{"one", "two", "three"} {
+		msg, _, err := repo.SaveMessage(ctx, group, alice, content, "text", nil, nil, false, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved = append(saved, msg)
+	}
+
+	hub := startHub(repo)
+	a := connect(t, hub, alice)
+
+	resync := func(afterSeq int64) []string {
+		t.Helper()
+		if err := a.WriteJSON(map[string]any{"type": "resync", "group_id": group, "after_seq": afterSeq}); err != nil {
+			t.Fatal(err)
+		}
+		reply := readUntil(t, a, ofType("resync"))
+		if reply["has_more"] != false {
+			t.Errorf("has_more = %v for a short page", reply["has_more"])
+		}
+		var contents []string
+		for _, m := range reply["messages"].([]any) {
+			contents = append(contents, m.(map[string]any)["content"].(string))
+		}
+		return contents
+	}
+
+	if got := resync(saved[0].Seq); strings.Join(got, ",") != "two,three" {
+		t.Errorf("resync after %d = %v, want [two three]", saved[0].Seq, got)
+	}
+
+	// An edit moves the message past the client's cursor so it is resent
+	if _, _, _, err := repo.EditMessage(ctx, saved[0].ID, alice, "one, edited", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if got := resync(saved[2].Seq); strings.Join(got, ",") != "one, edited" {
+		t.Errorf("resync after %d = %v, want the edited message", saved[2].Seq, got)
+	}
+}
@@ -1,10 +1,9 @@
 package middleware
 
 import (
-	"fmt"
-	"os"
 	"strings"
 
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -21,17 +20,22 @@ func Protected() fiber.Handler {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid Authorization Header Format"})
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
+		token, err := utils.ParseToken(tokenString)
 
 		if err != nil || !token.Valid {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or Expired Token"})
 		}
 
+		if claims, ok := token.Claims.(jwt.MapClaims); ok {
+			if err := utils.CheckSessionClaims(claims); err != nil {
+				body := fiber.Map{"error": err.Message}
+				if err.Code != "" {
+					body["code"] = err.Code
+				}
+				return c.Status(err.Status).JSON(body)
+			}
+		}
+
 		c.Locals("user", token)
 		return c.Next()
 	}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// permissionsCacheTTL bounds how stale a grant can be if an invalidation is missed.
+const permissionsCacheTTL = 10 * time.Minute
+
+// PermissionStore loads the permission keys granted to a user.
+type PermissionStore interface {
+	GetGrantedPermissions(ctx context.Context, userID int64) ([]string, error)
+}
+
+// RequirePermission allows the request only if the caller has been granted
+// permissionKey in admin.role_permissions. Super admins bypass the check.
+// Granted keys are cached in Redis under utils.PermissionsCacheKey, which
+// admin-service invalidates whenever a user's grants change.
+func RequirePermission(store PermissionStore, permissionKey string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if utils.GetUserRole(c) == "super_admin" {
+			return c.Next()
+		}
+		userID := utils.GetUserID(c)
+		if userID == 0 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		keys, err := grantedPermissions(c.Context(), store, userID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify permissions"})
+		}
+		for _, k := range keys {
+			if k == permissionKey {
+				return c.Next()
+			}
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":      "Forbidden: You don't have permission for this action",
+			"permission": permissionKey,
+		})
+	}
+}
+
+func grantedPermissions(ctx context.Context, store PermissionStore, userID int64) ([]string, error) {
+	key := utils.PermissionsCacheKey(userID)
+	if utils.RedisClient != nil {
+		if raw, err := utils.RedisClient.Get(ctx, key).Bytes(); err == nil {
+			var keys []string
+			if json.Unmarshal(raw, &keys) == nil {
+				return keys, nil
+			}
+		}
+	}
+
+	keys, err := store.GetGrantedPermissions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load permissions: %w", err)
+	}
+	if utils.RedisClient != nil {
+		if raw, err := json.Marshal(keys); err == nil {
+			utils.RedisClient.Set(ctx, key, raw, permissionsCacheTTL)
+		}
+	}
+	return keys, nil
+}
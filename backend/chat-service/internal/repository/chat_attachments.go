@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/utils"
+	"github.com/jackc/pgx/v5"
+)
+
+// attachmentMessageTypes are message types whose content is an uploaded object
+var attachmentMessageTypes = map[string]bool{"image": true, "file": true, "audio": true}
+
+// Attachment is an uploaded object referenced by a chat message
+type Attachment struct {
+	ID        int64     `json:"id"`
+	MessageID int64     `json:"message_id"`
+	Bucket    string    `json:"-"`
+	ObjectKey string    `json:"-"`
+	FileName  *string   `json:"name"`
+	FileType  *string   `json:"type"`
+	FileSize  *int64    `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// attachmentObject returns the bucket and object key an attachment message
+// points to. Clients send the key returned by the upload endpoint; older
+// clients sent a presigned URL, which is reduced to its key so that no signed
+// link is stored or handed back out. The bucket of a bare key is resolved by
+// recordAttachment.
+func attachmentObject(msgType, content string) (string, string) {
+	if !attachmentMessageTypes[msgType] || content == "" {
+		return "", ""
+	}
+	if !strings.HasPrefix(content, "http://") && !strings.HasPrefix(content, "https://") {
+		return "", content
+	}
+	bucket, key := utils.ExtractBucketAndKey(content)
+	if idx := strings.Index(key, "?"); idx != -1 {
+		key = key[:idx]
+	}
+	if key == "" || (bucket != utils.GetChatBucket() && bucket != utils.GetDefaultBucket()) {
+		return "", ""
+	}
+	return bucket, key
+}
+
+// recordAttachment links an attachment message to its object. Only objects in
+// the chat buckets under the prefix of a group the sender belongs to (which
+// covers forwarding) or of the sender's own DM uploads are recorded, so a
+// message cannot be used to reach another group's files.
+func recordAttachment(ctx context.Context, tx pgx.Tx, msg *ChatMessage, bucket, key string, metadata any) error {
+	if key == "" {
+		return nil
+	}
+	if bucket == "" {
+		// A forwarded key keeps the bucket it was first recorded in
+		err := tx.QueryRow(ctx, `
+			SELECT COALESCE((SELECT bucket FROM chat_attachments WHERE object_key = $1 AND bucket IS NOT NULL ORDER BY id LIMIT 1), $2)`,
+			key, utils.GetChatBucket()).Scan(&bucket)
+		if err != nil {
+			return err
+		}
+	}
+	if !strings.HasPrefix(key, fmt.Sprintf("chat_attachments/%d/", msg.SenderID)) {
+		var sourceGroup int64
+		if _, err := fmt.Sscanf(key, "chat_groups/%d/", &sourceGroup); err != nil {
+			return nil
+		}
+		if sourceGroup != msg.GroupID {
+			var isMember bool
+			err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM chat_group_members WHERE group_id = $1 AND user_id = $2)`, sourceGroup, msg.SenderID).Scan(&isMember)
+			if err != nil || !isMember {
+				return err
+			}
+		}
+	}
+
+	var name, mimeType *string
+	var size *int64
+	if meta, ok := metadata.(map[string]any); ok {
+		if v, ok := meta["name"].(string); ok {
+			name = &v
+		}
+		if v, ok := meta["mimeType"].(string); ok {
+			mimeType = &v
+		}
+		if v, ok := meta["size"].(float64); ok {
+			n := int64(v)
+			size = &n
+		}
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO chat_attachments (message_id, file_url, file_type, file_size, bucket, object_key, file_name)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		msg.ID, key, mimeType, size, bucket, key, name)
+	return err
+}
+
+// GetVisibleAttachments returns a message's attachments if userID belongs to
+// its group and has not deleted it
+func (r *ChatRepository) GetVisibleAttachments(ctx context.Context, msgID, userID int64) ([]Attachment, error) {
+	var visible bool
+	err := r.DB.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM chat_messages m
+			JOIN chat_group_members gm ON gm.group_id = m.group_id AND gm.user_id = $2
+			WHERE m.id = $1
+			AND (m.deleted_for IS NULL OR NOT (m.deleted_for @> jsonb_build_array($2::bigint)))
+		)`, msgID, userID).Scan(&visible)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, ErrMessageNotFound
+	}
+
+	rows, err := r.DB.Query(ctx, `
+		SELECT id, message_id, COALESCE(bucket, ''), COALESCE(object_key, ''), file_name, file_type, file_size, created_at
+		FROM chat_attachments
+		WHERE message_id = $1 AND object_key IS NOT NULL
+		ORDER BY id`, msgID)
+	if err != nil {
+		return nil, err
+	}
+	return scanAttachments(rows)
+}
+
+// deleteAttachments removes a message's attachment rows and returns those
+// whose objects no other message (e.g. a forward) still points to, so the
+// objects can be deleted
+func deleteAttachments(ctx context.Context, tx pgx.Tx, msgID int64) ([]Attachment, error) {
+	rows, err := tx.Query(ctx, `
+		WITH gone AS (
+			DELETE FROM chat_attachments WHERE message_id = $1
+			RETURNING id, message_id, bucket, object_key, file_name, file_type, file_size, created_at
+		)
+		SELECT id, message_id, COALESCE(bucket, ''), COALESCE(object_key, ''), file_name, file_type, file_size, created_at
+		FROM gone
+		WHERE object_key IS NOT NULL
+		AND NOT EXISTS (
+			SELECT 1 FROM chat_attachments a
+			WHERE a.bucket = gone.bucket AND a.object_key = gone.object_key AND a.message_id <> gone.message_id
+		)`, msgID)
+	if err != nil {
+		return nil, err
+	}
+	return scanAttachments(rows)
+}
+
+func scanAttachments(rows pgx.Rows) ([]Attachment, error) {
+	defer rows.Close()
+	attachments := []Attachment{}
+	for rows.Next() {
+		var a Attachment
+		if err := rows.Scan(&a.ID, &a.MessageID, &a.Bucket, &a.ObjectKey, &a.FileName, &a.FileType, &a.FileSize, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+// ReferencedObjectKeys returns the keys in bucket that messages still point to
+func (r *ChatRepository) ReferencedObjectKeys(ctx context.Context, bucket string) (map[string]bool, error) {
+	rows, err := r.DB.Query(ctx, `SELECT object_key FROM chat_attachments WHERE bucket = $1 AND object_key IS NOT NULL`, bucket)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make(map[string]bool)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys[key] = true
+	}
+	return keys, rows.Err()
+}
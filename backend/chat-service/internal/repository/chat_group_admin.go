@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/utils"
+	"github.com/jackc/pgx/v5"
+)
+
+// Group member roles stored in chat_group_members.role
+const (
+	GroupRoleAdmin  = "admin"
+	GroupRoleMember = "member"
+)
+
+// Errors returned by group administration and posting
+var (
+	ErrNotGroupAdmin    = errors.New("only group admins can do this")
+	ErrLastGroupAdmin   = errors.New("a group needs at least one admin")
+	ErrDirectGroup      = errors.New("direct chats have no members to manage")
+	ErrMemberMuted      = errors.New("you are muted in this group")
+	ErrAnnouncementOnly = errors.New("only admins can post in this group")
+	ErrGroupArchived    = errors.New("this group is archived")
+)
+
+// GroupMember is a member of a chat group with their group role
+type GroupMember struct {
+	UserID     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Image      *string    `json:"image,omitempty"`
+	UserRole   string     `json:"user_role"`
+	Role       string     `json:"role"`
+	JoinedAt   *time.Time `json:"joined_at"`
+	MutedUntil *time.Time `json:"muted_until"`
+}
+
+// GetMemberRole returns userID's role in groupID, or ErrNotGroupMember
+func (r *ChatRepository) GetMemberRole(ctx context.Context, groupID, userID int64) (string, error) {
+	var role string
+	err := r.DB.QueryRow(ctx, `SELECT COALESCE(role, 'member') FROM chat_group_members WHERE group_id = $1 AND user_id = $2`, groupID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotGroupMember
+	}
+	return role, err
+}
+
+// GetGroupType returns the type of a group
+func (r *ChatRepository) GetGroupType(ctx context.Context, groupID int64) (string, error) {
+	var groupType string
+	err := r.DB.QueryRow(ctx, `SELECT type FROM chat_groups WHERE id = $1`, groupID).Scan(&groupType)
+	return groupType, err
+}
+
+// GetGroupMembers lists the members of a group, admins first
+func (r *ChatRepository) GetGroupMembers(ctx context.Context, groupID int64) ([]GroupMember, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT u.id, u.name, u.profile_photo_url, u.role, COALESCE(mem.role, 'member'), mem.joined_at, mem.muted_until
+		FROM chat_group_members mem
+		JOIN users u ON u.id = mem.user_id
+		WHERE mem.group_id = $1
+		ORDER BY mem.role = 'admin' DESC, u.name ASC`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []GroupMember{}
+	for rows.Next() {
+		var m GroupMember
+		if err := rows.Scan(&m.UserID, &m.Name, &m.Image, &m.UserRole, &m.Role, &m.JoinedAt, &m.MutedUntil); err != nil {
+			return nil, err
+		}
+		if m.Image != nil && *m.Image != "" {
+			signed := utils.GenerateSignedProfileURL(*m.Image)
+			m.Image = &signed
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// AddGroupMembers adds existing users to a group and returns the IDs that
+// were not already members
+func (r *ChatRepository) AddGroupMembers(ctx context.Context, groupID int64, userIDs []int64) ([]int64, error) {
+	rows, err := r.DB.Query(ctx, `
+		INSERT INTO chat_group_members (group_id, user_id, role)
+		SELECT $1, id, 'member' FROM users WHERE id = ANY($2)
+		ON CONFLICT (group_id, user_id) DO NOTHING
+		RETURNING user_id`, groupID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	added := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		added = append(added, id)
+	}
+	return added, rows.Err()
+}
+
+// RemoveGroupMember removes userID from a group. The last admin cannot be
+// removed while other members remain.
+func (r *ChatRepository) RemoveGroupMember(ctx context.Context, groupID, userID int64) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockLastAdmin(ctx, tx, groupID, userID, true); err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM chat_group_members WHERE group_id = $1 AND user_id = $2`, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotGroupMember
+	}
+	return tx.Commit(ctx)
+}
+
+// SetMemberRole promotes or demotes a member. The last admin cannot be demoted.
+func (r *ChatRepository) SetMemberRole(ctx context.Context, groupID, userID int64, role string) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if role != GroupRoleAdmin {
+		if err := lockLastAdmin(ctx, tx, groupID, userID, false); err != nil {
+			return err
+		}
+	}
+	// Admins are never muted
+	tag, err := tx.Exec(ctx, `
+		UPDATE chat_group_members
+		SET role = $3, muted_until = CASE WHEN $3 = 'admin' THEN NULL ELSE muted_until END
+		WHERE group_id = $1 AND user_id = $2`, groupID, userID, role)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotGroupMember
+	}
+	return tx.Commit(ctx)
+}
+
+// lockLastAdmin locks the group's admin rows and fails with ErrLastGroupAdmin
+// when userID is the only admin. With leaving set, the last admin may still
+// go if they are also the last member.
+func lockLastAdmin(ctx context.Context, tx pgx.Tx, groupID, userID int64, leaving bool) error {
+	rows, err := tx.Query(ctx, `SELECT user_id FROM chat_group_members WHERE group_id = $1 AND role = 'admin' FOR UPDATE`, groupID)
+	if err != nil {
+		return err
+	}
+	admins, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return err
+	}
+	if len(admins) != 1 || admins[0] != userID {
+		return nil
+	}
+	if leaving {
+		var members int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM chat_group_members WHERE group_id = $1`, groupID).Scan(&members); err != nil {
+			return err
+		}
+		if members == 1 {
+			return nil
+		}
+	}
+	return ErrLastGroupAdmin
+}
+
+// MuteMember stops a member from posting until the given time; nil unmutes
+func (r *ChatRepository) MuteMember(ctx context.Context, groupID, userID int64, until *time.Time) error {
+	tag, err := r.DB.Exec(ctx, `UPDATE chat_group_members SET muted_until = $3 WHERE group_id = $1 AND user_id = $2`, groupID, userID, until)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotGroupMember
+	}
+	return nil
+}
+
+// SetAnnouncementOnly switches whether only group admins may post
+func (r *ChatRepository) SetAnnouncementOnly(ctx context.Context, groupID int64, enabled bool) error {
+	_, err := r.DB.Exec(ctx, `UPDATE chat_groups SET announcement_only = $2, updated_at = NOW() WHERE id = $1`, groupID, enabled)
+	return err
+}
+
+// SaveSystemMessage records a group event (member added, muted, ...) as a
+// 'system' message attributed to actorID. Posting rules do not apply.
+func (r *ChatRepository) SaveSystemMessage(ctx context.Context, groupID, actorID int64, content string, metadata map[string]any) (*ChatMessage, error) {
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		metadataJSON = []byte("{}")
+	}
+
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	seq, err := nextSeq(ctx, tx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &ChatMessage{
+		GroupID:  groupID,
+		SenderID: actorID,
+		Content:  content,
+		Type:     "system",
+		Seq:      seq,
+		Metadata: metadata,
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO chat_messages (group_id, sender_id, content, type, status, metadata, seq)
+		VALUES ($1, $2, $3, 'system', 'sent', $4, $5)
+		RETURNING id, created_at, status`, groupID, actorID, content, metadataJSON, seq).Scan(&msg.ID, &msg.CreatedAt, &msg.Status)
+	if err != nil {
+		return nil, err
+	}
+	return msg, tx.Commit(ctx)
+}
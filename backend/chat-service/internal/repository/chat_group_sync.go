@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MembershipChange is a member added to or removed from a group by the sync
+type MembershipChange struct {
+	GroupID int64
+	UserID  int64
+	Joined  bool
+}
+
+// directoryMembersCTE lists who should belong to each synced group: students
+// by department or batch year, and department coordinators as admins.
+// Archived groups keep their members as they were. $1 optionally restricts
+// the result to some users.
+const directoryMembersCTE = `
+	WITH desired AS (
+		SELECT g.id AS group_id, sp.user_id, 'member' AS role
+		FROM chat_groups g
+		JOIN student.student_personal sp ON sp.department = g.department_code
+		WHERE g.type = 'department' AND g.archived_at IS NULL
+		UNION ALL
+		SELECT g.id, sp.user_id, 'member'
+		FROM chat_groups g
+		JOIN student.student_personal sp ON sp.batch_year = g.batch_year
+		WHERE g.type = 'batch' AND g.archived_at IS NULL
+		UNION ALL
+		SELECT g.id, u.id, 'admin'
+		FROM chat_groups g
+		JOIN users u ON u.role = 'coordinator' AND u.department_code = g.department_code
+		WHERE g.type = 'department' AND g.archived_at IS NULL
+	),
+	scoped AS (
+		SELECT DISTINCT ON (group_id, user_id) group_id, user_id, role
+		FROM desired
+		WHERE $1::bigint[] IS NULL OR user_id = ANY($1)
+		ORDER BY group_id, user_id, role = 'admin' DESC
+	)`
+
+// SyncDirectoryGroups creates a group per active department and per batch,
+// archives batches marked inactive (graduated), and reconciles synced
+// members against student.student_personal. With userIDs set only those
+// users are reconciled. ran is false when another instance holds the sync
+// lock, in which case that instance applies the changes.
+func (r *ChatRepository) SyncDirectoryGroups(ctx context.Context, userIDs []int64) (changes []MembershipChange, ran bool, err error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock(hashtext('chat_directory_group_sync'))`).Scan(&ran); err != nil || !ran {
+		return nil, false, err
+	}
+
+	setup := []string{
+		`INSERT INTO chat_groups (name, type, department_code)
+		 SELECT d.name, 'department', d.code FROM departments d WHERE d.is_active
+		 ON CONFLICT (department_code) WHERE type = 'department' AND department_code IS NOT NULL DO NOTHING`,
+		`INSERT INTO chat_groups (name, type, batch_year)
+		 SELECT 'Batch ' || b.year, 'batch', b.year FROM batches b WHERE b.is_active
+		 ON CONFLICT (batch_year) WHERE type = 'batch' AND batch_year IS NOT NULL DO NOTHING`,
+		// Graduated batches become read-only history; reactivating restores them
+		`UPDATE chat_groups g SET archived_at = NOW(), announcement_only = TRUE, updated_at = NOW()
+		 FROM batches b
+		 WHERE g.type = 'batch' AND g.batch_year = b.year AND NOT b.is_active AND g.archived_at IS NULL`,
+		`UPDATE chat_groups g SET archived_at = NULL, announcement_only = FALSE, updated_at = NOW()
+		 FROM batches b
+		 WHERE g.type = 'batch' AND g.batch_year = b.year AND b.is_active AND g.archived_at IS NOT NULL`,
+	}
+	for _, query := range setup {
+		if _, err := tx.Exec(ctx, query); err != nil {
+			return nil, true, err
+		}
+	}
+
+	changes, err = reconcileMembers(ctx, tx, directoryMembersCTE,
+		`(g.type = 'department' AND g.department_code IS NOT NULL) OR (g.type = 'batch' AND g.batch_year IS NOT NULL)`,
+		`$1::bigint[] IS NULL OR m.user_id = ANY($1)`,
+		userIDs)
+	if err != nil {
+		return nil, true, err
+	}
+
+	return changes, true, tx.Commit(ctx)
+}
+
+// driveMembersCTE lists who should belong to each drive room: applicants
+// still in the running for the main room, shortlisted and placed students for
+// the shortlist room, and the drive's poster and SPOCs as admins of both.
+// $1 optionally restricts the result to some drives.
+const driveMembersCTE = `
+	WITH desired AS (
+		SELECT g.id AS group_id, da.student_id AS user_id, 'member' AS role
+		FROM chat_groups g
+		JOIN drive.drive_applications da ON da.drive_id = g.drive_id
+		WHERE g.type = 'drive' AND g.archived_at IS NULL
+		AND (
+			(g.drive_room = 'applicants' AND da.status IN ('opted_in', 'request_to_attend', 'shortlisted', 'placed'))
+			OR (g.drive_room = 'shortlisted' AND da.status IN ('shortlisted', 'placed'))
+		)
+		UNION ALL
+		SELECT g.id, staff.user_id, 'admin'
+		FROM chat_groups g
+		JOIN (
+			SELECT id AS drive_id, posted_by AS user_id FROM drive.placement_drives
+			UNION SELECT id, spoc_id FROM drive.placement_drives
+			UNION SELECT drive_id, spoc_id FROM drive.drive_spocs
+		) staff ON staff.drive_id = g.drive_id
+		JOIN users u ON u.id = staff.user_id
+		WHERE g.type = 'drive' AND g.archived_at IS NULL
+	),
+	scoped AS (
+		SELECT DISTINCT ON (d.group_id, d.user_id) d.group_id, d.user_id, d.role
+		FROM desired d
+		JOIN chat_groups g ON g.id = d.group_id
+		WHERE $1::bigint[] IS NULL OR g.drive_id = ANY($1)
+		ORDER BY d.group_id, d.user_id, d.role = 'admin' DESC
+	)`
+
+// SyncDriveRooms opens an applicants room for every open drive and a
+// shortlist room once a drive has shortlisted students, archives rooms of
+// completed or cancelled drives, and reconciles members against
+// drive.drive_applications. With driveIDs set only those drives are synced.
+// ran is false when another instance holds the sync lock.
+func (r *ChatRepository) SyncDriveRooms(ctx context.Context, driveIDs []int64) (changes []MembershipChange, ran bool, err error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock(hashtext('chat_drive_room_sync'))`).Scan(&ran); err != nil || !ran {
+		return nil, false, err
+	}
+
+	setup := []string{
+		`INSERT INTO chat_groups (name, type, drive_id, drive_room)
+		 SELECT pd.company_name || ' (' || to_char(pd.drive_date, 'DD Mon YYYY') || ')', 'drive', pd.id, 'applicants'
+		 FROM drive.placement_drives pd
+		 WHERE pd.status = 'open' AND ($1::bigint[] IS NULL OR pd.id = ANY($1))
+		 ON CONFLICT (drive_id, drive_room) WHERE type = 'drive' DO NOTHING`,
+		`INSERT INTO chat_groups (name, type, drive_id, drive_room)
+		 SELECT pd.company_name || ' - Shortlisted', 'drive', pd.id, 'shortlisted'
+		 FROM drive.placement_drives pd
+		 WHERE pd.status NOT IN ('draft', 'completed', 'cancelled')
+		 AND ($1::bigint[] IS NULL OR pd.id = ANY($1))
+		 AND EXISTS (
+			SELECT 1 FROM drive.drive_applications da
+			WHERE da.drive_id = pd.id AND da.status IN ('shortlisted', 'placed')
+		 )
+		 ON CONFLICT (drive_id, drive_room) WHERE type = 'drive' DO NOTHING`,
+		// Rooms stay readable once the drive is over; deleted drives count as over
+		`UPDATE chat_groups g SET archived_at = NOW(), announcement_only = TRUE, updated_at = NOW()
+		 WHERE g.type = 'drive' AND g.archived_at IS NULL
+		 AND ($1::bigint[] IS NULL OR g.drive_id = ANY($1))
+		 AND NOT EXISTS (
+			SELECT 1 FROM drive.placement_drives pd
+			WHERE pd.id = g.drive_id AND pd.status NOT IN ('completed', 'cancelled')
+		 )`,
+		`UPDATE chat_groups g SET archived_at = NULL, announcement_only = FALSE, updated_at = NOW()
+		 FROM drive.placement_drives pd
+		 WHERE g.type = 'drive' AND g.drive_id = pd.id AND g.archived_at IS NOT NULL
+		 AND pd.status NOT IN ('completed', 'cancelled')
+		 AND ($1::bigint[] IS NULL OR g.drive_id = ANY($1))`,
+	}
+	for _, query := range setup {
+		if _, err := tx.Exec(ctx, query, driveIDs); err != nil {
+			return nil, true, err
+		}
+	}
+
+	changes, err = reconcileMembers(ctx, tx, driveMembersCTE,
+		`g.type = 'drive' AND ($1::bigint[] IS NULL OR g.drive_id = ANY($1))`,
+		`TRUE`,
+		driveIDs)
+	if err != nil {
+		return nil, true, err
+	}
+
+	return changes, true, tx.Commit(ctx)
+}
+
+// reconcileMembers brings synced groups in line with the "scoped" set built
+// by membersCTE: missing members are added (flagged as synced), expected
+// admins are promoted, and synced members no longer expected are removed.
+// managedGroups and memberFilter are SQL conditions on g and m for the
+// removal; scope is passed to all statements as $1.
+func reconcileMembers(ctx context.Context, tx pgx.Tx, membersCTE, managedGroups, memberFilter string, scope []int64) ([]MembershipChange, error) {
+	rows, err := tx.Query(ctx, membersCTE+`
+		INSERT INTO chat_group_members (group_id, user_id, role, synced)
+		SELECT group_id, user_id, role, TRUE FROM scoped
+		ON CONFLICT (group_id, user_id) DO NOTHING
+		RETURNING group_id, user_id`, scope)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := collectChanges(rows, true, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expected admins who were already members (e.g. added by hand) are promoted
+	_, err = tx.Exec(ctx, membersCTE+`
+		UPDATE chat_group_members m SET role = 'admin'
+		FROM scoped s
+		WHERE s.role = 'admin' AND m.group_id = s.group_id AND m.user_id = s.user_id AND m.role IS DISTINCT FROM 'admin'`, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(ctx, membersCTE+`
+		DELETE FROM chat_group_members m
+		USING chat_groups g
+		WHERE m.group_id = g.id AND m.synced AND g.archived_at IS NULL
+		AND (`+managedGroups+`)
+		AND (`+memberFilter+`)
+		AND NOT EXISTS (SELECT 1 FROM scoped s WHERE s.group_id = m.group_id AND s.user_id = m.user_id)
+		RETURNING m.group_id, m.user_id`, scope)
+	if err != nil {
+		return nil, err
+	}
+	return collectChanges(rows, false, changes)
+}
+
+func collectChanges(rows pgx.Rows, joined bool, changes []MembershipChange) ([]MembershipChange, error) {
+	defer rows.Close()
+	for rows.Next() {
+		change := MembershipChange{Joined: joined}
+		if err := rows.Scan(&change.GroupID, &change.UserID); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
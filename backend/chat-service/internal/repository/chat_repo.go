@@ -3,13 +3,30 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/utils"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrNotGroupMember is returned when a user acts on a group they do not belong to
+var ErrNotGroupMember = errors.New("not a member of this group")
+
+// Errors returned by EditMessage
+var (
+	ErrNotMessageSender   = errors.New("only the sender can edit this message")
+	ErrMessageNotEditable = errors.New("this message cannot be edited")
+	ErrEditWindowExpired  = errors.New("edit window has expired")
+)
+
+// ErrMessageNotFound is returned when a message does not exist in the group or
+// was deleted for the requesting user
+var ErrMessageNotFound = errors.New("message not found")
+
 type ChatRepository struct {
 	DB *pgxpool.Pool
 }
@@ -27,21 +44,24 @@ type ChatGroup struct {
 }
 
 type ChatMessage struct {
-	ID          int64     `json:"id"`
-	GroupID     int64     `json:"group_id"`
-	SenderID    int64     `json:"sender_id"`
-	Content     string    `json:"content"`
-	Type        string    `json:"type"`
-	Status      string    `json:"status"` // sent, delivered, seen
-	CreatedAt   time.Time `json:"created_at"`
-	SenderName  string    `json:"sender_name"`
-	SenderImage *string   `json:"sender_image,omitempty"`
-	SenderRole  *string   `json:"sender_role,omitempty"`
-	Metadata    any       `json:"metadata,omitempty"`
-	ReplyToID   *int64    `json:"reply_to_id"` // [NEW]
-	IsPinned    bool      `json:"is_pinned"`   // [NEW]
-	DeletedFor  []int64   `json:"deleted_for"` // [NEW] JSONB array of user IDs
-	Forwarded   bool      `json:"forwarded"`   // [NEW]
+	ID          int64      `json:"id"`
+	GroupID     int64      `json:"group_id"`
+	SenderID    int64      `json:"sender_id"`
+	Content     string     `json:"content"`
+	Type        string     `json:"type"`
+	Status      string     `json:"status"` // sent, delivered, seen
+	CreatedAt   time.Time  `json:"created_at"`
+	SenderName  string     `json:"sender_name"`
+	SenderImage *string    `json:"sender_image,omitempty"`
+	SenderRole  *string    `json:"sender_role,omitempty"`
+	Metadata    any        `json:"metadata,omitempty"`
+	ReplyToID   *int64     `json:"reply_to_id"`             // [NEW]
+	IsPinned    bool       `json:"is_pinned"`               // [NEW]
+	DeletedFor  []int64    `json:"deleted_for"`             // [NEW] JSONB array of user IDs
+	Forwarded   bool       `json:"forwarded"`               // [NEW]
+	Seq         int64      `json:"seq"`                     // Per-group sequence number, reassigned on edit and delete
+	ClientMsgID *string    `json:"client_msg_id,omitempty"` // Sender-generated ID for idempotent saves
+	EditedAt    *time.Time `json:"edited_at,omitempty"`
 }
 
 // Migrate ensures the database schema is up to date
@@ -101,10 +121,7 @@ func (r *ChatRepository) FindDirectMessageGroup(ctx context.Context, user1, user
 func (r *ChatRepository) GetMessages(ctx context.Context, groupID int64, since time.Time, userID int64) ([]ChatMessage, bool, error) {
 	// Updated query to include metadata and time filter
 	query := `
-		SELECT 
-			m.id, m.group_id, m.sender_id, m.content, m.type, m.status, m.created_at,
-			u.name as sender_name, u.profile_photo_url as sender_image, u.role as sender_role,
-			m.reply_to_id, m.is_pinned, m.forwarded, m.metadata
+		SELECT ` + messageColumns + `
 		FROM chat_messages m
 		LEFT JOIN users u ON m.sender_id = u.id
 		WHERE m.group_id = $1
@@ -118,37 +135,9 @@ func (r *ChatRepository) GetMessages(ctx context.Context, groupID int64, since t
 		fmt.Printf("GetMessages Query Error: %v\n", err)
 		return nil, false, err
 	}
-	defer rows.Close()
-
-	var messages []ChatMessage
-	for rows.Next() {
-		var msg ChatMessage
-		var metadataJSON []byte // Temp for scanning
-
-		err := rows.Scan(
-			&msg.ID, &msg.GroupID, &msg.SenderID, &msg.Content, &msg.Type, &msg.Status, &msg.CreatedAt,
-			&msg.SenderName, &msg.SenderImage, &msg.SenderRole,
-			&msg.ReplyToID, &msg.IsPinned, &msg.Forwarded, &metadataJSON,
-		)
-		if err != nil {
-			fmt.Printf("GetMessages Scan Error: %v\n", err)
-			return nil, false, err
-		}
-
-		// Sign profile photo URL
-		if msg.SenderImage != nil && *msg.SenderImage != "" {
-			signed := utils.GenerateSignedProfileURL(*msg.SenderImage)
-			msg.SenderImage = &signed
-		}
-
-		// Parse metadata if present
-		if len(metadataJSON) > 0 {
-			_ = json.Unmarshal(metadataJSON, &msg.Metadata)
-		}
-
-		messages = append(messages, msg)
-	}
-	if err := rows.Err(); err != nil {
+	messages, err := scanMessages(rows)
+	if err != nil {
+		fmt.Printf("GetMessages Scan Error: %v\n", err)
 		return nil, false, err
 	}
 
@@ -165,39 +154,225 @@ func (r *ChatRepository) GetMessages(ctx context.Context, groupID int64, since t
 	return messages, hasOlder, nil
 }
 
-func (r *ChatRepository) SaveMessage(ctx context.Context, groupID, senderID int64, content, msgType string, metadata any, replyToID *int64, forwarded bool) (*ChatMessage, error) {
+// HistoryCursor selects one page of a group's history. At most one of Before,
+// After and Around is set; with none the newest page is returned.
+type HistoryCursor struct {
+	Before int64 // messages older than this id
+	After  int64 // messages newer than this id
+	Around int64 // a page centred on this id, for jumping to replies and pins
+	Limit  int
+}
+
+// GetMessagesPage returns a page of messages in ascending id order using
+// keyset pagination on (group_id, id). hasOlder and hasNewer report whether
+// more messages exist on either side of the page.
+func (r *ChatRepository) GetMessagesPage(ctx context.Context, groupID, userID int64, cursor HistoryCursor) (messages []ChatMessage, hasOlder, hasNewer bool, err error) {
+	switch {
+	case cursor.Around > 0:
+		var visible bool
+		err = r.DB.QueryRow(ctx, `
+			SELECT EXISTS(
+				SELECT 1 FROM chat_messages m
+				WHERE m.id = $1 AND m.group_id = $2
+				AND (m.deleted_for IS NULL OR NOT (m.deleted_for @> jsonb_build_array($3::bigint)))
+			)`, cursor.Around, groupID, userID).Scan(&visible)
+		if err != nil {
+			return nil, false, false, err
+		}
+		if !visible {
+			return nil, false, false, ErrMessageNotFound
+		}
+
+		olderLimit := cursor.Limit / 2
+		older, moreOlder, err := r.messagesBeyond(ctx, groupID, userID, "<", cursor.Around, olderLimit)
+		if err != nil {
+			return nil, false, false, err
+		}
+		newer, moreNewer, err := r.messagesBeyond(ctx, groupID, userID, ">=", cursor.Around, cursor.Limit-olderLimit)
+		if err != nil {
+			return nil, false, false, err
+		}
+		return append(older, newer...), moreOlder, moreNewer, nil
+
+	case cursor.After > 0:
+		messages, hasNewer, err = r.messagesBeyond(ctx, groupID, userID, ">", cursor.After, cursor.Limit)
+		return messages, true, hasNewer, err
+
+	case cursor.Before > 0:
+		messages, hasOlder, err = r.messagesBeyond(ctx, groupID, userID, "<", cursor.Before, cursor.Limit)
+		return messages, hasOlder, true, err
+
+	default:
+		messages, hasOlder, err = r.messagesBeyond(ctx, groupID, userID, "<", 0, cursor.Limit)
+		return messages, hasOlder, false, err
+	}
+}
+
+// messagesBeyond reads up to limit visible messages on one side of pivot
+// (op is "<", ">" or ">="; a zero pivot with "<" means the newest messages)
+// and returns them in ascending order along with whether more remain.
+func (r *ChatRepository) messagesBeyond(ctx context.Context, groupID, userID int64, op string, pivot int64, limit int) ([]ChatMessage, bool, error) {
+	if limit <= 0 {
+		return []ChatMessage{}, false, nil
+	}
+
+	order := "ASC"
+	bound := "m.id " + op + " $3"
+	if op == "<" {
+		order = "DESC"
+		if pivot == 0 {
+			bound = "$3::bigint = 0"
+		}
+	}
+
+	query := `
+		SELECT ` + messageColumns + `
+		FROM chat_messages m
+		LEFT JOIN users u ON m.sender_id = u.id
+		WHERE m.group_id = $1
+		AND ` + bound + `
+		AND (m.deleted_for IS NULL OR NOT (m.deleted_for @> jsonb_build_array($2::bigint)))
+		ORDER BY m.id ` + order + `
+		LIMIT $4
+	`
+	rows, err := r.DB.Query(ctx, query, groupID, userID, pivot, limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+	if order == "DESC" {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+	return messages, hasMore, nil
+}
+
+// SaveMessage stores a message under the group's next sequence number. When
+// clientMsgID was already used by the sender in this group, the stored message
+// is returned with duplicate set instead of inserting it again, so clients can
+// safely retry sends.
+func (r *ChatRepository) SaveMessage(ctx context.Context, groupID, senderID int64, content, msgType string, metadata any, replyToID *int64, forwarded bool, clientMsgID string) (msg *ChatMessage, duplicate bool, err error) {
 	// metadata can be map[string]interface{} or nil
 	metadataJSON, err := json.Marshal(metadata)
 	if err != nil {
 		// If marshalling fails, just use empty JSON object
 		metadataJSON = []byte("{}")
 	}
+	var clientID *string
+	if clientMsgID != "" {
+		clientID = &clientMsgID
+	}
+	bucket, key := attachmentObject(msgType, content)
+	if key != "" {
+		content = key
+	}
+
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Only members may post. Checked inside the transaction so it cannot race
+	// with a member being removed or muted.
+	var role string
+	var mutedUntil *time.Time
+	var announcementOnly, archived bool
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(mem.role, 'member'), mem.muted_until, g.announcement_only, g.archived_at IS NOT NULL
+		FROM chat_group_members mem
+		JOIN chat_groups g ON g.id = mem.group_id
+		WHERE mem.group_id = $1 AND mem.user_id = $2`, groupID, senderID).Scan(&role, &mutedUntil, &announcementOnly, &archived)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, ErrNotGroupMember
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Taking the next sequence locks the group row, which also serialises the
+	// duplicate check below for this group
+	seq, err := nextSeq(ctx, tx, groupID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if clientID != nil {
+		rows, err := tx.Query(ctx, `
+			SELECT `+messageColumns+`
+			FROM chat_messages m
+			LEFT JOIN users u ON m.sender_id = u.id
+			WHERE m.group_id = $1 AND m.sender_id = $2 AND m.client_msg_id = $3
+		`, groupID, senderID, clientMsgID)
+		if err != nil {
+			return nil, false, err
+		}
+		existing, err := scanMessages(rows)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(existing) > 0 {
+			// Rolling back releases the unused sequence number
+			return &existing[0], true, nil
+		}
+	}
+
+	// Retries of stored messages are acked above even if posting rights
+	// changed in between
+	if archived {
+		return nil, false, ErrGroupArchived
+	}
+	if mutedUntil != nil && mutedUntil.After(time.Now()) {
+		return nil, false, ErrMemberMuted
+	}
+	if announcementOnly && role != GroupRoleAdmin {
+		return nil, false, ErrAnnouncementOnly
+	}
 
 	query := `
-		INSERT INTO chat_messages (group_id, sender_id, content, type, status, metadata, reply_to_id, forwarded)
-		VALUES ($1, $2, $3, $4, 'sent', $5, $6, $7)
+		INSERT INTO chat_messages (group_id, sender_id, content, type, status, metadata, reply_to_id, forwarded, seq, client_msg_id)
+		VALUES ($1, $2, $3, $4, 'sent', $5, $6, $7, $8, $9)
 		RETURNING id, created_at, status
 	`
-	var msg ChatMessage
-	msg.GroupID = groupID
-	msg.SenderID = senderID
-	msg.Content = content
-	msg.Type = msgType
-	msg.ReplyToID = replyToID
-	msg.Forwarded = forwarded
-	// We need to unmarshal later if we want it in struct, but for now we just return what we passed
-	msg.Metadata = metadata
-
-	err = r.DB.QueryRow(ctx, query, groupID, senderID, content, msgType, metadataJSON, replyToID, forwarded).Scan(&msg.ID, &msg.CreatedAt, &msg.Status)
+	msg = &ChatMessage{
+		GroupID:     groupID,
+		SenderID:    senderID,
+		Content:     content,
+		Type:        msgType,
+		ReplyToID:   replyToID,
+		Forwarded:   forwarded,
+		Seq:         seq,
+		ClientMsgID: clientID,
+		// We need to unmarshal later if we want it in struct, but for now we just return what we passed
+		Metadata: metadata,
+	}
+	err = tx.QueryRow(ctx, query, groupID, senderID, content, msgType, metadataJSON, replyToID, forwarded, seq, clientID).Scan(&msg.ID, &msg.CreatedAt, &msg.Status)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+
+	if err := recordAttachment(ctx, tx, msg, bucket, key, metadata); err != nil {
+		return nil, false, err
 	}
 
 	// Fetch sender details to return full object for broadcast
 	userQuery := `SELECT name, profile_photo_url, role FROM users WHERE id = $1`
-	err = r.DB.QueryRow(ctx, userQuery, senderID).Scan(&msg.SenderName, &msg.SenderImage, &msg.SenderRole)
+	err = tx.QueryRow(ctx, userQuery, senderID).Scan(&msg.SenderName, &msg.SenderImage, &msg.SenderRole)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, err
 	}
 
 	// Sign profile photo URL
@@ -206,7 +381,14 @@ func (r *ChatRepository) SaveMessage(ctx context.Context, groupID, senderID int6
 		msg.SenderImage = &signed
 	}
 
-	return &msg, nil
+	return msg, false, nil
+}
+
+// nextSeq allocates the group's next message sequence number
+func nextSeq(ctx context.Context, tx pgx.Tx, groupID int64) (int64, error) {
+	var seq int64
+	err := tx.QueryRow(ctx, `UPDATE chat_groups SET last_seq = last_seq + 1 WHERE id = $1 RETURNING last_seq`, groupID).Scan(&seq)
+	return seq, err
 }
 
 // PinMessage toggles the pinned status of a message
@@ -222,6 +404,84 @@ func (r *ChatRepository) PinMessage(ctx context.Context, msgID int64) (bool, err
 	return isPinned, err
 }
 
+// messageColumns is the select list read by scanMessages
+const messageColumns = `
+			m.id, m.group_id, m.sender_id, m.content, m.type, m.status, m.created_at,
+			u.name as sender_name, u.profile_photo_url as sender_image, u.role as sender_role,
+			m.reply_to_id, m.is_pinned, m.forwarded, m.metadata, COALESCE(m.seq, 0), m.client_msg_id,
+			m.edited_at`
+
+// scanMessages reads rows selected with messageColumns
+func scanMessages(rows pgx.Rows) ([]ChatMessage, error) {
+	defer rows.Close()
+
+	messages := []ChatMessage{}
+	for rows.Next() {
+		var msg ChatMessage
+		var metadataJSON []byte // Temp for scanning
+
+		err := rows.Scan(
+			&msg.ID, &msg.GroupID, &msg.SenderID, &msg.Content, &msg.Type, &msg.Status, &msg.CreatedAt,
+			&msg.SenderName, &msg.SenderImage, &msg.SenderRole,
+			&msg.ReplyToID, &msg.IsPinned, &msg.Forwarded, &metadataJSON, &msg.Seq, &msg.ClientMsgID,
+			&msg.EditedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// Sign profile photo URL
+		if msg.SenderImage != nil && *msg.SenderImage != "" {
+			signed := utils.GenerateSignedProfileURL(*msg.SenderImage)
+			msg.SenderImage = &signed
+		}
+
+		// Parse metadata if present
+		if len(metadataJSON) > 0 {
+			_ = json.Unmarshal(metadataJSON, &msg.Metadata)
+		}
+
+		// Attachments are fetched through GetVisibleAttachments; never hand
+		// out a signed URL an older client stored as content
+		if _, key := attachmentObject(msg.Type, msg.Content); key != "" {
+			msg.Content = key
+		}
+
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// GetMessagesAfterSeq returns up to limit messages with seq > afterSeq in
+// order, for clients resyncing after a disconnect. hasMore reports whether
+// another page follows.
+func (r *ChatRepository) GetMessagesAfterSeq(ctx context.Context, groupID, afterSeq, userID int64, limit int) ([]ChatMessage, bool, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM chat_messages m
+		LEFT JOIN users u ON m.sender_id = u.id
+		WHERE m.group_id = $1
+		AND m.seq > $3
+		AND (m.deleted_for IS NULL OR NOT (m.deleted_for @> jsonb_build_array($2::bigint)))
+		ORDER BY m.seq ASC
+		LIMIT $4
+	`
+	rows, err := r.DB.Query(ctx, query, groupID, userID, afterSeq, limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+	return messages, hasMore, nil
+}
+
 // MessageInfo holds basic info about a message for delete cleanup
 type MessageInfo struct {
 	ID       int64  `json:"id"`
@@ -242,13 +502,56 @@ func (r *ChatRepository) GetMessageByID(ctx context.Context, msgID int64) (*Mess
 	return &msg, nil
 }
 
-// DeleteMessage performs soft delete (for everyone or for user)
-func (r *ChatRepository) DeleteMessage(ctx context.Context, msgID, userID int64, forEveryone bool) error {
+// DeletedMessage is a message deleted for everyone: its group and new seq,
+// for the delete event, and the attachments whose objects should be removed
+type DeletedMessage struct {
+	GroupID     int64
+	Seq         int64
+	Attachments []Attachment
+}
+
+// DeleteMessage performs soft delete (for everyone or for user). Deleting for
+// everyone also drops the message's attachment records and returns them so
+// the caller can remove the objects; deleting for the user returns nil.
+func (r *ChatRepository) DeleteMessage(ctx context.Context, msgID, userID int64, forEveryone bool) (*DeletedMessage, error) {
 	if forEveryone {
+		tx, err := r.DB.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback(ctx)
+
+		var groupID int64
+		err = tx.QueryRow(ctx, `SELECT group_id FROM chat_messages WHERE id = $1 AND sender_id = $2 FOR UPDATE`, msgID, userID).Scan(&groupID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMessageNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		// A new sequence number lets resyncing clients pick up the deletion
+		seq, err := nextSeq(ctx, tx, groupID)
+		if err != nil {
+			return nil, err
+		}
+
 		// Mark as deleted, clear content, metadata, and unpin
-		query := `UPDATE chat_messages SET type = 'deleted', content = '', metadata = '{}'::jsonb, is_pinned = false WHERE id = $1 AND sender_id = $2`
-		_, err := r.DB.Exec(ctx, query, msgID, userID)
-		return err
+		query := `UPDATE chat_messages SET type = 'deleted', content = '', metadata = '{}'::jsonb, is_pinned = false, seq = $2 WHERE id = $1`
+		if _, err := tx.Exec(ctx, query, msgID, seq); err != nil {
+			return nil, err
+		}
+		// Earlier versions of the content go with it
+		if _, err := tx.Exec(ctx, `DELETE FROM chat_message_edits WHERE message_id = $1`, msgID); err != nil {
+			return nil, err
+		}
+		attachments, err := deleteAttachments(ctx, tx, msgID)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+		return &DeletedMessage{GroupID: groupID, Seq: seq, Attachments: attachments}, nil
 	} else {
 		// Add userID to deleted_for array
 		query := `
@@ -257,8 +560,118 @@ func (r *ChatRepository) DeleteMessage(ctx context.Context, msgID, userID int64,
 			WHERE id = $1 AND NOT (deleted_for @> to_jsonb($2::bigint))
 		`
 		_, err := r.DB.Exec(ctx, query, msgID, userID)
-		return err
+		return nil, err
+	}
+}
+
+// MessageEdit is a previous version of an edited message
+type MessageEdit struct {
+	ID              int64     `json:"id"`
+	MessageID       int64     `json:"message_id"`
+	EditorID        *int64    `json:"editor_id"`
+	PreviousContent string    `json:"previous_content"`
+	EditedAt        time.Time `json:"edited_at"`
+}
+
+// EditMessage replaces the content of a text message sent by editorID no
+// longer than window ago, keeping the previous content in
+// chat_message_edits. It returns the group, new seq and new edited_at for
+// broadcasting.
+func (r *ChatRepository) EditMessage(ctx context.Context, msgID, editorID int64, content string, window time.Duration) (groupID, seq int64, editedAt time.Time, err error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return 0, 0, time.Time{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var senderID *int64
+	var previous, msgType string
+	var createdAt time.Time
+	var deletedFor []int64
+	err = tx.QueryRow(ctx, `
+		SELECT group_id, sender_id, COALESCE(content, ''), COALESCE(type, 'text'), created_at, COALESCE(deleted_for, '[]'::jsonb)
+		FROM chat_messages WHERE id = $1
+		FOR UPDATE`, msgID).Scan(&groupID, &senderID, &previous, &msgType, &createdAt, &deletedFor)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, 0, time.Time{}, ErrMessageNotFound
+	}
+	if err != nil {
+		return 0, 0, time.Time{}, err
+	}
+	if slices.Contains(deletedFor, editorID) {
+		return 0, 0, time.Time{}, ErrMessageNotFound
+	}
+	if senderID == nil || *senderID != editorID {
+		return 0, 0, time.Time{}, ErrNotMessageSender
+	}
+	if msgType != "text" && msgType != "" {
+		return 0, 0, time.Time{}, ErrMessageNotEditable
+	}
+	if time.Since(createdAt) > window {
+		return 0, 0, time.Time{}, ErrEditWindowExpired
+	}
+
+	var isMember bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM chat_group_members WHERE group_id = $1 AND user_id = $2)`, groupID, editorID).Scan(&isMember)
+	if err != nil {
+		return 0, 0, time.Time{}, err
+	}
+	if !isMember {
+		return 0, 0, time.Time{}, ErrNotGroupMember
+	}
+
+	if _, err = tx.Exec(ctx, `INSERT INTO chat_message_edits (message_id, editor_id, previous_content) VALUES ($1, $2, $3)`, msgID, editorID, previous); err != nil {
+		return 0, 0, time.Time{}, err
+	}
+	// A new sequence number lets resyncing clients pick up the edit
+	seq, err = nextSeq(ctx, tx, groupID)
+	if err != nil {
+		return 0, 0, time.Time{}, err
+	}
+	err = tx.QueryRow(ctx, `UPDATE chat_messages SET content = $2, edited_at = NOW(), seq = $3 WHERE id = $1 RETURNING edited_at`, msgID, content, seq).Scan(&editedAt)
+	if err != nil {
+		return 0, 0, time.Time{}, err
+	}
+
+	return groupID, seq, editedAt, tx.Commit(ctx)
+}
+
+// GetMessageEdits lists the previous versions of a message, oldest first
+func (r *ChatRepository) GetMessageEdits(ctx context.Context, msgID int64) ([]MessageEdit, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT id, message_id, editor_id, COALESCE(previous_content, ''), edited_at
+		FROM chat_message_edits
+		WHERE message_id = $1
+		ORDER BY edited_at ASC, id ASC`, msgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := []MessageEdit{}
+	for rows.Next() {
+		var e MessageEdit
+		if err := rows.Scan(&e.ID, &e.MessageID, &e.EditorID, &e.PreviousContent, &e.EditedAt); err != nil {
+			return nil, err
+		}
+		edits = append(edits, e)
 	}
+	return edits, rows.Err()
+}
+
+// GetVisibleMessageGroupID returns the group a message belongs to. Messages
+// deleted for everyone, or hidden by userID, are reported as not found.
+func (r *ChatRepository) GetVisibleMessageGroupID(ctx context.Context, msgID, userID int64) (int64, error) {
+	var groupID int64
+	err := r.DB.QueryRow(ctx, `
+		SELECT group_id FROM chat_messages
+		WHERE id = $1 AND COALESCE(type, 'text') <> 'deleted'
+		AND (deleted_for IS NULL OR NOT (deleted_for @> jsonb_build_array($2::bigint)))`,
+		msgID, userID).Scan(&groupID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrMessageNotFound
+	}
+	return groupID, err
 }
 
 func (r *ChatRepository) UpdateMessagesStatus(ctx context.Context, groupID, readerID int64, status string) error {
@@ -276,7 +689,7 @@ func (r *ChatRepository) GetGroupsByUser(ctx context.Context, userID int64) ([]m
 	// 1. Get Groups (Direct & Group) with efficient Last Message fetch
 	query := `
         SELECT 
-            g.id, g.name, g.type, 
+            g.id, g.name, g.type, g.announcement_only, mem.role, mem.muted_until, g.archived_at,
             lm.content as last_message,
             lm.type as last_message_type,
             lm.metadata as last_message_metadata,
@@ -293,7 +706,7 @@ func (r *ChatRepository) GetGroupsByUser(ctx context.Context, userID int64) ([]m
             LIMIT 1
         ) lm ON true
         WHERE mem.user_id = $1
-        GROUP BY g.id, g.name, g.type, lm.content, lm.type, lm.metadata, lm.created_at
+        GROUP BY g.id, g.name, g.type, g.announcement_only, mem.role, mem.muted_until, g.archived_at, lm.content, lm.type, lm.metadata, lm.created_at
         ORDER BY lm.created_at DESC NULLS LAST
     `
 	rows, err := r.DB.Query(ctx, query, userID)
@@ -311,8 +724,11 @@ func (r *ChatRepository) GetGroupsByUser(ctx context.Context, userID int64) ([]m
 		var lastMessageMetadata []byte
 		var lastMessageTime *time.Time
 		var unreadCount int
+		var announcementOnly bool
+		var myRole *string
+		var mutedUntil, archivedAt *time.Time
 
-		if err := rows.Scan(&id, &name, &groupType, &lastMessage, &lastMessageType, &lastMessageMetadata, &lastMessageTime, &unreadCount); err != nil {
+		if err := rows.Scan(&id, &name, &groupType, &announcementOnly, &myRole, &mutedUntil, &archivedAt, &lastMessage, &lastMessageType, &lastMessageMetadata, &lastMessageTime, &unreadCount); err != nil {
 			return nil, err
 		}
 
@@ -323,8 +739,12 @@ func (r *ChatRepository) GetGroupsByUser(ctx context.Context, userID int64) ([]m
 			"last_message":      lastMessage,
 			"last_message_type": lastMessageType,
 			// "last_message_metadata": parsed below
-			"last_message_at": lastMessageTime,
-			"unread_count":    unreadCount,
+			"last_message_at":   lastMessageTime,
+			"unread_count":      unreadCount,
+			"announcement_only": announcementOnly,
+			"my_role":           myRole,
+			"muted_until":       mutedUntil,
+			"archived_at":       archivedAt,
 		}
 
 		if len(lastMessageMetadata) > 0 {
@@ -365,13 +785,16 @@ func (r *ChatRepository) GetGroupsByUser(ctx context.Context, userID int64) ([]m
 	return groups, nil
 }
 
-func (r *ChatRepository) GetPotentialUsers(ctx context.Context, currentUserID int64) ([]map[string]interface{}, error) {
+// GetPotentialUsers lists admins and coordinators that can be added to a chat.
+// With deptScope set, coordinators from other departments are left out.
+func (r *ChatRepository) GetPotentialUsers(ctx context.Context, currentUserID int64, deptScope *string) ([]map[string]interface{}, error) {
 	query := `
         SELECT id, name, email, role, profile_photo_url, department_code 
         FROM users 
         WHERE role IN ('admin', 'coordinator') AND id != $1 AND is_active = TRUE
+          AND ($2::text IS NULL OR role = 'admin' OR department_code = $2)
     `
-	rows, err := r.DB.Query(ctx, query, currentUserID)
+	rows, err := r.DB.Query(ctx, query, currentUserID, deptScope)
 	if err != nil {
 		return nil, err
 	}
@@ -414,7 +837,8 @@ func (r *ChatRepository) CreateGroupWithMembers(ctx context.Context, name, group
 	defer tx.Rollback(ctx)
 
 	var groupID int64
-	err = tx.QueryRow(ctx, "INSERT INTO chat_groups (name, type, created_by) VALUES ($1, $2, $3) RETURNING id", name, groupType, createdBy).Scan(&groupID)
+	// Broadcast groups start out announcement-only
+	err = tx.QueryRow(ctx, "INSERT INTO chat_groups (name, type, created_by, announcement_only) VALUES ($1, $2, $3, $2 = 'broadcast') RETURNING id", name, groupType, createdBy).Scan(&groupID)
 	if err != nil {
 		return 0, err
 	}
@@ -450,3 +874,30 @@ func (r *ChatRepository) GetUserSimple(ctx context.Context, userID int64) (strin
 	}
 	return name, nil
 }
+
+// IsGroupMember reports whether userID belongs to groupID
+func (r *ChatRepository) IsGroupMember(ctx context.Context, groupID, userID int64) (bool, error) {
+	var isMember bool
+	query := `SELECT EXISTS(SELECT 1 FROM chat_group_members WHERE group_id = $1 AND user_id = $2)`
+	err := r.DB.QueryRow(ctx, query, groupID, userID).Scan(&isMember)
+	return isMember, err
+}
+
+// GetUserGroupIDs lists the groups a user belongs to, used to seed the hub's routing index
+func (r *ChatRepository) GetUserGroupIDs(ctx context.Context, userID int64) ([]int64, error) {
+	rows, err := r.DB.Query(ctx, `SELECT group_id FROM chat_group_members WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groupIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		groupIDs = append(groupIDs, id)
+	}
+	return groupIDs, rows.Err()
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testRepo connects to TEST_DATABASE_URL, a database migrated with
+// scripts/run_schemas.sh, with chat-service's search_path. Tests using it
+// are skipped without one.
+func testRepo(t *testing.T) *ChatRepository {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		_, err := conn.Exec(ctx, "SET search_path TO chat, public")
+		return err
+	}
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return NewChatRepository(pool)
+}
+
+// staffUser adds an admin or coordinator removed when the test ends
+func staffUser(t *testing.T, repo *ChatRepository, role, dept string) int64 {
+	ctx := context.Background()
+	var id int64
+	email := fmt.Sprintf("%s.%s.%d@scope.test", role, dept, time.Now().UnixNano())
+	err := repo.DB.QueryRow(ctx, `
+		INSERT INTO users (email, password_hash, role, name, department_code)
+		VALUES ($1, 'x', $2, $3, NULLIF($4, '')) RETURNING id`, email, role, role+" "+dept, dept).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.DB.Exec(ctx, `DELETE FROM users WHERE id = $1`, id) })
+	return id
+}
+
+func TestGetPotentialUsersHidesOtherDepartmentCoordinators(t *testing.T) {
+	repo := testRepo(t)
+	admin := staffUser(t, repo, "admin", "")
+	cse := staffUser(t, repo, "coordinator", "CSE")
+	ece := staffUser(t, repo, "coordinator", "ECE")
+
+	dept := "CSE"
+	users, err := repo.GetPotentialUsers(context.Background(), 0, &dept)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[int64]bool{}
+	for _, u := range users {
+		seen[u["id"].(int64)] = true
+	}
+	if !seen[admin] || !seen[cse] {
+		t.Errorf("CSE coordinator cannot add the admin or their own department's coordinator")
+	}
+	if seen[ece] {
+		t.Errorf("CSE coordinator can add an ECE coordinator")
+	}
+
+	all, err := repo.GetPotentialUsers(context.Background(), 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen = map[int64]bool{}
+	for _, u := range all {
+		seen[u["id"].(int64)] = true
+	}
+	if !seen[ece] {
+		t.Errorf("admins cannot add an ECE coordinator")
+	}
+}
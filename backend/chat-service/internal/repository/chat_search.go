@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SysSyncer/placement-portal-kec/chat-service/internal/utils"
+)
+
+// searchContextSize is how many neighbouring message IDs are returned on each
+// side of a hit so clients can open the conversation around it
+const searchContextSize = 2
+
+// MessageSearchFilter narrows a message search. Zero values are ignored.
+type MessageSearchFilter struct {
+	Query    string
+	GroupID  int64
+	SenderID int64
+	Type     string
+	From     time.Time
+	To       time.Time
+	Limit    int
+	Offset   int
+}
+
+// MessageSearchResult is a matching message with a highlighted snippet.
+// ContextBefore/ContextAfter hold the neighbouring message IDs in order.
+type MessageSearchResult struct {
+	ID            int64     `json:"id"`
+	GroupID       int64     `json:"group_id"`
+	GroupName     *string   `json:"group_name"`
+	SenderID      int64     `json:"sender_id"`
+	SenderName    *string   `json:"sender_name"`
+	SenderImage   *string   `json:"sender_image,omitempty"`
+	Type          string    `json:"type"`
+	CreatedAt     time.Time `json:"created_at"`
+	FileName      *string   `json:"file_name,omitempty"`
+	Snippet       string    `json:"snippet"`
+	Rank          float32   `json:"rank"`
+	ContextBefore []int64   `json:"context_before"`
+	ContextAfter  []int64   `json:"context_after"`
+}
+
+// SearchMessages runs a full-text search over the messages of groups userID
+// belongs to, skipping messages deleted for them. Matches are wrapped in
+// <mark></mark> in the snippet. total counts all matches for paging.
+func (r *ChatRepository) SearchMessages(ctx context.Context, userID int64, f MessageSearchFilter) (results []MessageSearchResult, total int, err error) {
+	args := []any{f.Query, userID}
+	where := []string{
+		"m.search_vector @@ q.query",
+		"(m.deleted_for IS NULL OR NOT (m.deleted_for @> jsonb_build_array($2::bigint)))",
+	}
+	addFilter := func(cond string, val any) {
+		args = append(args, val)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.GroupID > 0 {
+		addFilter("m.group_id = $%d", f.GroupID)
+	}
+	if f.SenderID > 0 {
+		addFilter("m.sender_id = $%d", f.SenderID)
+	}
+	if f.Type != "" {
+		addFilter("m.type = $%d", f.Type)
+	}
+	if !f.From.IsZero() {
+		addFilter("m.created_at >= $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		addFilter("m.created_at < $%d", f.To)
+	}
+
+	from := `
+		FROM chat_messages m
+		CROSS JOIN (
+			SELECT websearch_to_tsquery('english', $1) || websearch_to_tsquery('simple', $1) AS query
+		) q
+		JOIN chat_group_members gm ON gm.group_id = m.group_id AND gm.user_id = $2`
+	whereSQL := `
+		WHERE ` + strings.Join(where, " AND ")
+
+	if err := r.DB.QueryRow(ctx, `SELECT COUNT(*) `+from+whereSQL, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, searchContextSize, f.Limit, f.Offset)
+	n := len(args)
+	visible := `(c.deleted_for IS NULL OR NOT (c.deleted_for @> jsonb_build_array($2::bigint)))`
+	query := fmt.Sprintf(`
+		SELECT m.id, m.group_id, g.name, COALESCE(m.sender_id, 0), u.name, u.profile_photo_url, m.type, m.created_at,
+			m.metadata->>'name',
+			ts_headline('english', COALESCE(m.content, '') || ' ' || COALESCE(m.metadata->>'name', ''), q.query,
+				'StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, MaxFragments=2'),
+			ts_rank(m.search_vector, q.query) AS rank,
+			ARRAY(
+				SELECT id FROM (
+					SELECT c.id FROM chat_messages c
+					WHERE c.group_id = m.group_id AND c.id < m.id AND %[1]s
+					ORDER BY c.id DESC LIMIT $%[2]d
+				) prev ORDER BY id
+			),
+			ARRAY(
+				SELECT c.id FROM chat_messages c
+				WHERE c.group_id = m.group_id AND c.id > m.id AND %[1]s
+				ORDER BY c.id ASC LIMIT $%[2]d
+			)`, visible, n-2) + from + `
+		JOIN chat_groups g ON g.id = m.group_id
+		LEFT JOIN users u ON u.id = m.sender_id` + whereSQL + fmt.Sprintf(`
+		ORDER BY rank DESC, m.id DESC
+		LIMIT $%d OFFSET $%d
+	`, n-1, n)
+
+	rows, err := r.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	results = []MessageSearchResult{}
+	for rows.Next() {
+		var res MessageSearchResult
+		if err := rows.Scan(
+			&res.ID, &res.GroupID, &res.GroupName, &res.SenderID, &res.SenderName, &res.SenderImage, &res.Type, &res.CreatedAt,
+			&res.FileName, &res.Snippet, &res.Rank, &res.ContextBefore, &res.ContextAfter,
+		); err != nil {
+			return nil, 0, err
+		}
+		if res.SenderImage != nil && *res.SenderImage != "" {
+			signed := utils.GenerateSignedProfileURL(*res.SenderImage)
+			res.SenderImage = &signed
+		}
+		results = append(results, res)
+	}
+	return results, total, rows.Err()
+}
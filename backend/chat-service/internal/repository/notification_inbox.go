@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// InboxItem is one in-app notification from notify.inbox. Admin and drive
+// services write them alongside every push they send.
+type InboxItem struct {
+	ID        int64           `json:"id"`
+	UserID    int64           `json:"user_id"`
+	Category  string          `json:"category"`
+	Type      string          `json:"type"`
+	Title     string          `json:"title"`
+	Body      string          `json:"body"`
+	Data      json.RawMessage `json:"data"`
+	ReadAt    *time.Time      `json:"read_at"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// InboxFilter selects a page of a user's inbox, newest first.
+type InboxFilter struct {
+	UnreadOnly bool
+	Category   string
+	Before     int64 // only items with a smaller ID
+	Limit      int
+}
+
+const inboxColumns = `id, user_id, category, type, title, body, data, read_at, created_at`
+
+func scanInboxItems(rows pgx.Rows) ([]InboxItem, error) {
+	defer rows.Close()
+	items := []InboxItem{}
+	for rows.Next() {
+		var it InboxItem
+		if err := rows.Scan(&it.ID, &it.UserID, &it.Category, &it.Type, &it.Title, &it.Body, &it.Data,
+			&it.ReadAt, &it.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// ListInbox returns a page of userID's notifications, newest first, and
+// whether older ones remain.
+func (r *ChatRepository) ListInbox(ctx context.Context, userID int64, f InboxFilter) ([]InboxItem, bool, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT `+inboxColumns+`
+		FROM notify.inbox
+		WHERE user_id = $1
+		  AND (NOT $2 OR read_at IS NULL)
+		  AND ($3 = '' OR category = $3)
+		  AND ($4 = 0 OR id < $4)
+		ORDER BY id DESC
+		LIMIT $5`,
+		userID, f.UnreadOnly, f.Category, f.Before, f.Limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	items, err := scanInboxItems(rows)
+	if err != nil {
+		return nil, false, err
+	}
+	hasMore := len(items) > f.Limit
+	if hasMore {
+		items = items[:f.Limit]
+	}
+	return items, hasMore, nil
+}
+
+// GetInboxItems loads notifications by ID, for live delivery.
+func (r *ChatRepository) GetInboxItems(ctx context.Context, ids []int64) ([]InboxItem, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT `+inboxColumns+`
+		FROM notify.inbox
+		WHERE id = ANY($1)
+		ORDER BY id`, ids)
+	if err != nil {
+		return nil, err
+	}
+	return scanInboxItems(rows)
+}
+
+// CountUnreadInbox returns how many of userID's notifications are unread.
+func (r *ChatRepository) CountUnreadInbox(ctx context.Context, userID int64) (int, error) {
+	var n int
+	err := r.DB.QueryRow(ctx,
+		`SELECT COUNT(*) FROM notify.inbox WHERE user_id = $1 AND read_at IS NULL`, userID).Scan(&n)
+	return n, err
+}
+
+// MarkInboxRead marks one of userID's notifications read. It reports false
+// when the notification does not exist or belongs to someone else; marking an
+// already read notification is not an error.
+func (r *ChatRepository) MarkInboxRead(ctx context.Context, userID, id int64) (bool, error) {
+	tag, err := r.DB.Exec(ctx, `
+		UPDATE notify.inbox SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// MarkAllInboxRead marks all of userID's unread notifications read, optionally
+// only those in category, and returns how many changed.
+func (r *ChatRepository) MarkAllInboxRead(ctx context.Context, userID int64, category string) (int64, error) {
+	tag, err := r.DB.Exec(ctx, `
+		UPDATE notify.inbox SET read_at = NOW()
+		WHERE user_id = $1 AND read_at IS NULL AND ($2 = '' OR category = $2)`, userID, category)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
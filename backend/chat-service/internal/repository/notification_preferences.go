@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Notification categories and channels users can set preferences for. They
+// match the notify.preferences CHECK constraints.
+var (
+	NotificationCategories = []string{"drive_announcement", "application_status", "change_request", "chat_mention", "broadcast", "digest"}
+	NotificationChannels   = []string{"PUSH", "EMAIL", "WHATSAPP", "IN_APP"}
+	// DigestFrequencies are how often digest emails can be sent; weekly is
+	// the default
+	DigestFrequencies = []string{"weekly", "daily"}
+)
+
+// NotificationPreference turns one category on or off for one channel
+type NotificationPreference struct {
+	Category string `json:"category"`
+	Channel  string `json:"channel"`
+	Enabled  bool   `json:"enabled"`
+}
+
+// QuietHours is a daily window, in Timezone, during which non-critical
+// notifications are held until End. Times are "HH:MM".
+type QuietHours struct {
+	Enabled  bool   `json:"enabled"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Timezone string `json:"timezone"`
+}
+
+// GetNotificationPreferences returns the channels userID has switched and
+// their quiet hours (nil when never set). Unlisted channels are enabled.
+func (r *ChatRepository) GetNotificationPreferences(ctx context.Context, userID int64) ([]NotificationPreference, *QuietHours, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT category, channel, enabled FROM notify.preferences WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	var prefs []NotificationPreference
+	for rows.Next() {
+		var p NotificationPreference
+		if err := rows.Scan(&p.Category, &p.Channel, &p.Enabled); err != nil {
+			return nil, nil, err
+		}
+		prefs = append(prefs, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var q QuietHours
+	err = r.DB.QueryRow(ctx, `
+		SELECT enabled, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI'), timezone
+		FROM notify.quiet_hours WHERE user_id = $1`, userID).Scan(&q.Enabled, &q.Start, &q.End, &q.Timezone)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return prefs, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return prefs, &q, nil
+}
+
+// SetNotificationPreferences saves the given switches for userID, leaving
+// other categories and channels as they were.
+func (r *ChatRepository) SetNotificationPreferences(ctx context.Context, userID int64, prefs []NotificationPreference) error {
+	if len(prefs) == 0 {
+		return nil
+	}
+	categories := make([]string, len(prefs))
+	channels := make([]string, len(prefs))
+	enabled := make([]bool, len(prefs))
+	for i, p := range prefs {
+		categories[i], channels[i], enabled[i] = p.Category, p.Channel, p.Enabled
+	}
+	_, err := r.DB.Exec(ctx, `
+		INSERT INTO notify.preferences (user_id, category, channel, enabled)
+		SELECT $1, p.category, p.channel, p.enabled
+		FROM unnest($2::text[], $3::text[], $4::boolean[]) AS p(category, channel, enabled)
+		ON CONFLICT (user_id, category, channel)
+		DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = NOW()`,
+		userID, categories, channels, enabled)
+	return err
+}
+
+// SetQuietHours saves userID's quiet hours
+func (r *ChatRepository) SetQuietHours(ctx context.Context, userID int64, q QuietHours) error {
+	_, err := r.DB.Exec(ctx, `
+		INSERT INTO notify.quiet_hours (user_id, enabled, start_time, end_time, timezone)
+		VALUES ($1, $2, $3::time, $4::time, $5)
+		ON CONFLICT (user_id)
+		DO UPDATE SET enabled = EXCLUDED.enabled, start_time = EXCLUDED.start_time,
+		              end_time = EXCLUDED.end_time, timezone = EXCLUDED.timezone, updated_at = NOW()`,
+		userID, q.Enabled, q.Start, q.End, q.Timezone)
+	return err
+}
+
+// GetDigestFrequency returns how often userID gets digest emails
+func (r *ChatRepository) GetDigestFrequency(ctx context.Context, userID int64) (string, error) {
+	var frequency string
+	err := r.DB.QueryRow(ctx, `
+		SELECT COALESCE((SELECT frequency FROM notify.digest_settings WHERE user_id = $1), 'weekly')`,
+		userID).Scan(&frequency)
+	return frequency, err
+}
+
+// SetDigestFrequency saves how often userID gets digest emails
+func (r *ChatRepository) SetDigestFrequency(ctx context.Context, userID int64, frequency string) error {
+	_, err := r.DB.Exec(ctx, `
+		INSERT INTO notify.digest_settings (user_id, frequency)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET frequency = EXCLUDED.frequency, updated_at = NOW()`,
+		userID, frequency)
+	return err
+}
+
+// IsKnownTimezone reports whether Postgres, which evaluates quiet hours,
+// recognises the IANA timezone name
+func (r *ChatRepository) IsKnownTimezone(ctx context.Context, name string) (bool, error) {
+	var ok bool
+	err := r.DB.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM pg_timezone_names WHERE name = $1)`, name).Scan(&ok)
+	return ok, err
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// InboxChatMention is the notify.inbox category of @mentions in chat
+const InboxChatMention = "chat_mention"
+
+// OutboundNotification is a message for the shared notify.jobs queue, which
+// admin-service's notification workers drain with retries. When Category is
+// set and the address belongs to a user, the job follows that user's
+// preferences and quiet hours.
+type OutboundNotification struct {
+	Provider       string // EMAIL, WHATSAPP or PUSH
+	Kind           string
+	Address        string
+	Subject        string
+	Body           string
+	IdempotencyKey string // optional; a repeated key is not queued again
+	Category       string // optional notify.preferences category
+}
+
+// EnqueueNotifications queues the notifications and returns how many were
+// added (duplicates by idempotency key are skipped).
+func (r *ChatRepository) EnqueueNotifications(ctx context.Context, notes []OutboundNotification) (int64, error) {
+	if len(notes) == 0 {
+		return 0, nil
+	}
+	providers := make([]string, len(notes))
+	kinds := make([]string, len(notes))
+	addresses := make([]string, len(notes))
+	subjects := make([]string, len(notes))
+	bodies := make([]string, len(notes))
+	keys := make([]string, len(notes))
+	categories := make([]string, len(notes))
+	for i, n := range notes {
+		providers[i], kinds[i], addresses[i] = n.Provider, n.Kind, n.Address
+		subjects[i], bodies[i], keys[i], categories[i] = n.Subject, n.Body, n.IdempotencyKey, n.Category
+	}
+
+	tag, err := r.DB.Exec(ctx, `
+		INSERT INTO notify.jobs (provider, kind, source, payload, idempotency_key, user_id, category)
+		SELECT n.provider, n.kind, 'chat-service',
+		       jsonb_build_object('address', n.address, 'subject', n.subject, 'body', n.body),
+		       NULLIF(n.key, ''),
+		       CASE WHEN n.category = '' THEN NULL
+		            WHEN n.provider = 'EMAIL' THEN
+		                (SELECT u.id FROM public.users u WHERE lower(u.email) = lower(n.address) LIMIT 1)
+		            WHEN n.provider = 'WHATSAPP' THEN
+		                (SELECT sp.user_id FROM student.student_personal sp
+		                 WHERE right(regexp_replace(sp.mobile_number, '\D', '', 'g'), 10) = right(regexp_replace(n.address, '\D', '', 'g'), 10)
+		                 LIMIT 1)
+		       END,
+		       NULLIF(n.category, '')
+		FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[], $6::text[], $7::text[])
+		     AS n(provider, kind, address, subject, body, key, category)
+		ON CONFLICT (idempotency_key) DO NOTHING`,
+		providers, kinds, addresses, subjects, bodies, keys, categories)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// UserNotification is an in-app notification for a set of users. Each user
+// gets a notify.inbox row unless they turned in-app notifications for the
+// category off, and users with a registered device also get a PUSH job that
+// the workers check against their preferences and quiet hours.
+type UserNotification struct {
+	Category string // Inbox*
+	Type     string // event type, also the job kind and data["type"]
+	Title    string
+	Body     string
+	Data     map[string]string
+	// DedupeKey, when set, is suffixed with each user ID so notifying the same
+	// event twice leaves one inbox item (and one push) per user.
+	DedupeKey string
+}
+
+// NotifyUsers writes n to each user's inbox and queues the matching pushes on
+// the shared notify.jobs queue.
+func (r *ChatRepository) NotifyUsers(ctx context.Context, userIDs []int64, n UserNotification) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	data := n.Data
+	if data == nil {
+		data = map[string]string{}
+	}
+	if _, ok := data["type"]; !ok {
+		data["type"] = n.Type
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.DB.Exec(ctx, `
+		WITH recipients AS (
+			SELECT DISTINCT unnest($1::bigint[]) AS user_id
+		), inboxed AS (
+			INSERT INTO notify.inbox (user_id, category, type, title, body, data, source, dedupe_key)
+			SELECT r.user_id, $2, $3, $4, $5, $6::jsonb, 'chat-service',
+			       CASE WHEN $7 = '' THEN NULL ELSE $7 || ':' || r.user_id END
+			FROM recipients r
+			WHERE notify.channel_enabled(r.user_id, $2, 'IN_APP')
+			ON CONFLICT (dedupe_key) DO NOTHING
+			RETURNING id, user_id
+		)
+		INSERT INTO notify.jobs (provider, kind, source, payload, idempotency_key, user_id, category)
+		SELECT 'PUSH', $3, 'chat-service',
+		       jsonb_build_object('user_id', r.user_id, 'address', d.token, 'subject', $4::text, 'body', $5::text,
+		                          'data', $6::jsonb || jsonb_strip_nulls(jsonb_build_object('notification_id', i.id::text))),
+		       CASE WHEN $7 = '' THEN NULL ELSE 'push:' || $7 || ':' || r.user_id END,
+		       r.user_id, $2
+		FROM recipients r
+		JOIN LATERAL (
+			SELECT token FROM notify.device_tokens
+			WHERE user_id = r.user_id ORDER BY last_seen_at DESC LIMIT 1
+		) d ON TRUE
+		LEFT JOIN inboxed i ON i.user_id = r.user_id
+		ON CONFLICT (idempotency_key) DO NOTHING`,
+		userIDs, n.Category, n.Type, n.Title, n.Body, encoded, n.DedupeKey)
+	return err
+}
+
+// MentionedMembers returns the members of groupID, other than senderID, whose
+// register numbers are among the @mentions
+func (r *ChatRepository) MentionedMembers(ctx context.Context, groupID, senderID int64, registerNumbers []string) ([]int64, error) {
+	if len(registerNumbers) == 0 {
+		return nil, nil
+	}
+	lowered := make([]string, len(registerNumbers))
+	for i, n := range registerNumbers {
+		lowered[i] = strings.ToLower(n)
+	}
+	rows, err := r.DB.Query(ctx, `
+		SELECT DISTINCT gm.user_id
+		FROM chat_group_members gm
+		JOIN student.student_personal sp ON sp.user_id = gm.user_id
+		WHERE gm.group_id = $1 AND gm.user_id <> $2
+		AND lower(sp.register_number) = ANY($3::text[])`, groupID, senderID, lowered)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
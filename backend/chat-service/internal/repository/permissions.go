@@ -0,0 +1,24 @@
+package repository
+
+import "context"
+
+// GetGrantedPermissions returns the permission keys granted to userID in
+// admin.role_permissions.
+func (r *ChatRepository) GetGrantedPermissions(ctx context.Context, userID int64) ([]string, error) {
+	rows, err := r.DB.Query(ctx,
+		`SELECT permission_key FROM admin.role_permissions WHERE user_id = $1 AND is_granted = TRUE`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
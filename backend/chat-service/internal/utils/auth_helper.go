@@ -60,3 +60,60 @@ func GetUserID(c *fiber.Ctx) int64 {
 
 	return userID
 }
+
+// GetUserRole returns the role claim of the current user
+func GetUserRole(c *fiber.Ctx) string {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return ""
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+	role, _ := claims["role"].(string)
+	return role
+}
+
+// DepartmentScope returns the department a coordinator is restricted to, or nil
+// for every other role
+func DepartmentScope(c *fiber.Ctx) *string {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return nil
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["role"] != "coordinator" {
+		return nil
+	}
+	dept, _ := claims["department_code"].(string)
+	return &dept
+}
+
+// SessionError is why a valid token may not be used for chat, with the HTTP
+// status and machine-readable code the REST API answers with
+type SessionError struct {
+	Status  int
+	Message string
+	Code    string
+}
+
+func (e *SessionError) Error() string { return e.Message }
+
+// CheckSessionClaims applies the session rules shared by the REST middleware
+// and the WebSocket handshake
+func CheckSessionClaims(claims jwt.MapClaims) *SessionError {
+	// Accounts on an admin-assigned password may only change it (PUT /api/v1/auth/password)
+	if mustChange, _ := claims["pwd_change_required"].(bool); mustChange {
+		return &SessionError{Status: fiber.StatusForbidden, Message: "Password change required", Code: "PASSWORD_CHANGE_REQUIRED"}
+	}
+	// "View as student" sessions are read-only and audited; chat is neither
+	if _, impersonating := claims["impersonator_id"]; impersonating {
+		return &SessionError{Status: fiber.StatusForbidden, Message: "Chat is not available while impersonating", Code: "IMPERSONATION_READ_ONLY"}
+	}
+	// Coordinators are scoped to the department carried in their token
+	if dept, _ := claims["department_code"].(string); claims["role"] == "coordinator" && dept == "" {
+		return &SessionError{Status: fiber.StatusUnauthorized, Message: "Session expired, please log in again"}
+	}
+	return nil
+}
@@ -1,29 +1,113 @@
 package utils
 
 import (
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/smtp"
 	"os"
+	"time"
 )
 
+// SMTPConfig is the outgoing mail server, configured by the same variables as
+// admin-service: Gmail on port 587 with STARTTLS unless overridden.
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	TLS      string // "starttls", "tls" (implicit, usually port 465) or "none"
+	Username string
+	Password string
+	From     string
+}
+
+// LoadSMTPConfig reads SMTP_HOST, SMTP_PORT, SMTP_TLS, SMTP_EMAIL,
+// SMTP_PASSWORD and SMTP_FROM (defaults to SMTP_EMAIL).
+func LoadSMTPConfig() SMTPConfig {
+	cfg := SMTPConfig{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     os.Getenv("SMTP_PORT"),
+		TLS:      os.Getenv("SMTP_TLS"),
+		Username: os.Getenv("SMTP_EMAIL"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	}
+	if cfg.Host == "" {
+		cfg.Host = "smtp.gmail.com"
+	}
+	if cfg.Port == "" {
+		cfg.Port = "587"
+	}
+	if cfg.TLS == "" {
+		cfg.TLS = "starttls"
+	}
+	if cfg.From == "" {
+		cfg.From = cfg.Username
+	}
+	return cfg
+}
+
 func SendEmail(toEmail, subject, body string) error {
-	from := os.Getenv("SMTP_EMAIL")
-	password := os.Getenv("SMTP_PASSWORD")
-	host := "smtp.gmail.com"
-	port := "587"
+	cfg := LoadSMTPConfig()
 
 	mime := "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
-	msg := []byte("Subject: " + subject + "\n" + mime + body)
+	msg := []byte("From: " + cfg.From + "\nTo: " + toEmail + "\nSubject: " + subject + "\n" + mime + body)
+
+	if err := sendSMTP(cfg, toEmail, msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
 
-	// Authentication
-	auth := smtp.PlainAuth("", from, password, host)
+func sendSMTP(cfg SMTPConfig, to string, msg []byte) error {
+	addr := net.JoinHostPort(cfg.Host, cfg.Port)
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
 
-	// Send
-	addr := host + ":" + port
-	err := smtp.SendMail(addr, auth, from, []string{toEmail}, msg)
+	var conn net.Conn
+	var err error
+	if cfg.TLS == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+		return err
+	}
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
 	}
+	defer client.Close()
 
-	return nil
+	if cfg.TLS == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS (set SMTP_TLS=none for a local sink)", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if cfg.Password != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(cfg.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
 }
@@ -0,0 +1,10 @@
+package utils
+
+import "fmt"
+
+// PermissionsCacheKey is the Redis key holding a user's granted permission keys.
+// Shared by every service's RequirePermission middleware; admin-service deletes
+// it whenever the user's grants, role or account change.
+func PermissionsCacheKey(userID int64) string {
+	return fmt.Sprintf("permissions:user:%d", userID)
+}
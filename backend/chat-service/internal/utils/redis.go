@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var RedisClient *redis.Client
+
+// InitRedis connects to the Redis server and makes the client globally available.
+// RedisClient stays nil when Redis is unreachable so callers can fall back to
+// single-instance behaviour.
+func InitRedis() {
+	redisAddr := os.Getenv("REDIS_ADDR")
+	if redisAddr == "" {
+		redisAddr = "127.0.0.1:6379" // Fallback for local dev avoiding IPv6 dial tcp [::1]:6379 error
+	}
+
+	RedisClient = redis.NewClient(&redis.Options{
+		Addr:     redisAddr,
+		Password: "", // No password set by default
+		DB:       0,  // Use default DB
+	})
+
+	// Test connection
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := RedisClient.Ping(ctx).Err(); err != nil {
+		log.Printf("Warning: Could not connect to Redis at %s: %v. Chat will run as a single instance.", redisAddr, err)
+		RedisClient = nil // Keep it nil so we know it failed
+	} else {
+		log.Printf("Connected to Redis at %s", redisAddr)
+	}
+}
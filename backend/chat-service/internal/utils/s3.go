@@ -14,6 +14,13 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
+// S3Object is an entry of a bucket listing
+type S3Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
 // GetS3Client creates an S3-compatible client for Garage
 func GetS3Client() (*s3.Client, error) {
 	useSSL := os.Getenv("GARAGE_USE_SSL") == "true"
@@ -51,6 +58,11 @@ func GetChatBucket() string {
 	return bucket
 }
 
+// GetDefaultBucket returns the shared bucket older chat uploads fell back to
+func GetDefaultBucket() string {
+	return os.Getenv("GARAGE_BUCKET")
+}
+
 // DeleteS3Object deletes an object from the given bucket by key
 func DeleteS3Object(ctx context.Context, bucket, key string) error {
 	client, err := GetS3Client()
@@ -69,6 +81,35 @@ func DeleteS3Object(ctx context.Context, bucket, key string) error {
 	return nil
 }
 
+// ListS3Objects lists every object in bucket under prefix
+func ListS3Objects(ctx context.Context, bucket, prefix string) ([]S3Object, error) {
+	client, err := GetS3Client()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	var objects []S3Object
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+		for _, obj := range page.Contents {
+			objects = append(objects, S3Object{
+				Key:          aws.ToString(obj.Key),
+				Size:         aws.ToInt64(obj.Size),
+				LastModified: aws.ToTime(obj.LastModified),
+			})
+		}
+	}
+	return objects, nil
+}
+
 // ExtractS3KeyFromURL extracts the S3 object key from a Garage/S3 URL.
 // URLs typically look like: http://host:port/bucket/key or a presigned version.
 func ExtractS3KeyFromURL(rawURL string) (string, error) {
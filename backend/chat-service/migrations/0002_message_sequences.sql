@@ -0,0 +1,30 @@
+-- ==========================================
+-- CHAT SERVICE — Migration 0002
+-- Reliable delivery: client-generated message IDs make saves idempotent and
+-- per-group sequence numbers let clients resync everything after the last
+-- message they saw.
+-- ==========================================
+
+SET search_path TO chat, public;
+
+ALTER TABLE chat_groups ADD COLUMN IF NOT EXISTS last_seq BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS client_msg_id VARCHAR(64);
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS seq BIGINT;
+
+-- Backfill sequences in message order
+WITH numbered AS (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY group_id ORDER BY id) AS rn
+    FROM chat_messages
+    WHERE seq IS NULL
+)
+UPDATE chat_messages m SET seq = n.rn
+FROM numbered n
+WHERE m.id = n.id;
+
+UPDATE chat_groups g SET last_seq = COALESCE((SELECT MAX(seq) FROM chat_messages WHERE group_id = g.id), 0);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chat_messages_group_seq ON chat_messages(group_id, seq);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chat_messages_client_id
+    ON chat_messages(group_id, sender_id, client_msg_id)
+    WHERE client_msg_id IS NOT NULL;
@@ -0,0 +1,8 @@
+-- ==========================================
+-- CHAT SERVICE — Migration 0003
+-- Keyset pagination walks a group's history by message id.
+-- ==========================================
+
+SET search_path TO chat, public;
+
+CREATE INDEX IF NOT EXISTS idx_chat_messages_group_id_id ON chat_messages(group_id, id);
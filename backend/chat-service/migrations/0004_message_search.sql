@@ -0,0 +1,15 @@
+-- ==========================================
+-- CHAT SERVICE — Migration 0004
+-- Full-text search over message text and attachment file names
+-- (metadata->>'name' for file messages).
+-- ==========================================
+
+SET search_path TO chat, public;
+
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(content, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(metadata->>'name', '')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_chat_messages_search ON chat_messages USING GIN (search_vector);
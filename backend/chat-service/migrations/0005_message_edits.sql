@@ -0,0 +1,19 @@
+-- ==========================================
+-- CHAT SERVICE — Migration 0005
+-- Message editing: edited_at on the message, previous versions in
+-- chat_message_edits.
+-- ==========================================
+
+SET search_path TO chat, public;
+
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS chat_message_edits (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    message_id BIGINT NOT NULL REFERENCES chat_messages(id) ON DELETE CASCADE,
+    editor_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    previous_content TEXT,
+    edited_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_message_edits_message ON chat_message_edits(message_id, edited_at);
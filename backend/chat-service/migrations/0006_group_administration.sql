@@ -0,0 +1,13 @@
+-- ==========================================
+-- CHAT SERVICE — Migration 0006
+-- Group administration: muted members and announcement-only groups where
+-- only group admins may post.
+-- ==========================================
+
+SET search_path TO chat, public;
+
+ALTER TABLE chat_groups ADD COLUMN IF NOT EXISTS announcement_only BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE chat_group_members ADD COLUMN IF NOT EXISTS muted_until TIMESTAMPTZ;
+
+-- Existing broadcast groups were meant to be one-way
+UPDATE chat_groups SET announcement_only = TRUE WHERE type = 'broadcast';
@@ -0,0 +1,43 @@
+-- ==========================================
+-- CHAT SERVICE — Migration 0007
+-- Department and batch groups kept in sync with student.student_personal.
+-- Synced groups carry the department code / batch year they mirror, and
+-- members added by the sync are flagged so manual additions are left alone.
+-- ==========================================
+
+SET search_path TO chat, public;
+
+ALTER TABLE chat_groups ADD COLUMN IF NOT EXISTS department_code VARCHAR(20);
+ALTER TABLE chat_groups ADD COLUMN IF NOT EXISTS batch_year INTEGER;
+ALTER TABLE chat_groups ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+ALTER TABLE chat_group_members ADD COLUMN IF NOT EXISTS synced BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chat_groups_department
+    ON chat_groups(department_code) WHERE type = 'department' AND department_code IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chat_groups_batch
+    ON chat_groups(batch_year) WHERE type = 'batch' AND batch_year IS NOT NULL;
+
+-- Tell the chat service which students changed so it can resync them
+-- without waiting for the periodic reconcile
+CREATE OR REPLACE FUNCTION chat.notify_student_change() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('chat_student_changes', OLD.user_id::text);
+    ELSE
+        PERFORM pg_notify('chat_student_changes', NEW.user_id::text);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DO $$
+BEGIN
+    IF to_regclass('student.student_personal') IS NOT NULL THEN
+        DROP TRIGGER IF EXISTS chat_student_change ON student.student_personal;
+        CREATE TRIGGER chat_student_change
+            AFTER INSERT OR DELETE OR UPDATE OF department, batch_year ON student.student_personal
+            FOR EACH ROW EXECUTE FUNCTION chat.notify_student_change();
+    END IF;
+END;
+$$;
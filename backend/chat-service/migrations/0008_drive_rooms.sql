@@ -0,0 +1,58 @@
+-- ==========================================
+-- CHAT SERVICE — Migration 0008
+-- Drive rooms: one room per open placement drive with its applicants, plus
+-- a separate room for shortlisted students. Kept in sync with
+-- drive.drive_applications and archived when the drive completes.
+-- Reads: drive.placement_drives, drive.drive_applications, drive.drive_spocs
+-- ==========================================
+
+SET search_path TO chat, public;
+
+-- 'direct' and 'group' are created by the chat UI as well
+ALTER TABLE chat_groups DROP CONSTRAINT IF EXISTS chat_groups_type_check;
+ALTER TABLE chat_groups ADD CONSTRAINT chat_groups_type_check
+    CHECK (type IN ('department', 'batch', 'custom', 'broadcast', 'direct', 'group', 'drive'));
+
+ALTER TABLE chat_groups ADD COLUMN IF NOT EXISTS drive_id BIGINT;
+ALTER TABLE chat_groups ADD COLUMN IF NOT EXISTS drive_room VARCHAR(20)
+    CHECK (drive_room IN ('applicants', 'shortlisted'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chat_groups_drive_room
+    ON chat_groups(drive_id, drive_room) WHERE type = 'drive';
+
+CREATE OR REPLACE FUNCTION chat.notify_drive_change() RETURNS trigger AS $$
+DECLARE
+    drive BIGINT;
+BEGIN
+    IF TG_TABLE_NAME = 'placement_drives' THEN
+        drive := NEW.id;
+    ELSIF TG_OP = 'DELETE' THEN
+        drive := OLD.drive_id;
+    ELSE
+        drive := NEW.drive_id;
+    END IF;
+    PERFORM pg_notify('chat_drive_changes', drive::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DO $$
+BEGIN
+    IF to_regclass('drive.placement_drives') IS NOT NULL THEN
+        DROP TRIGGER IF EXISTS chat_drive_change ON drive.placement_drives;
+        CREATE TRIGGER chat_drive_change
+            AFTER INSERT OR UPDATE OF status, spoc_id, posted_by ON drive.placement_drives
+            FOR EACH ROW EXECUTE FUNCTION chat.notify_drive_change();
+
+        DROP TRIGGER IF EXISTS chat_drive_change ON drive.drive_applications;
+        CREATE TRIGGER chat_drive_change
+            AFTER INSERT OR DELETE OR UPDATE OF status ON drive.drive_applications
+            FOR EACH ROW EXECUTE FUNCTION chat.notify_drive_change();
+
+        DROP TRIGGER IF EXISTS chat_drive_change ON drive.drive_spocs;
+        CREATE TRIGGER chat_drive_change
+            AFTER INSERT OR DELETE ON drive.drive_spocs
+            FOR EACH ROW EXECUTE FUNCTION chat.notify_drive_change();
+    END IF;
+END;
+$$;
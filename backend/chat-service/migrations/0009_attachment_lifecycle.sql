@@ -0,0 +1,43 @@
+-- ==========================================
+-- CHAT SERVICE — Migration 0009
+-- Attachment lifecycle: every attachment message records the object it
+-- points to so the service can presign it for members only, delete it with
+-- the message, and spot orphaned objects in the chat bucket.
+-- ==========================================
+
+SET search_path TO chat, public;
+
+ALTER TABLE chat_attachments ADD COLUMN IF NOT EXISTS bucket VARCHAR(100);
+ALTER TABLE chat_attachments ADD COLUMN IF NOT EXISTS object_key TEXT;
+ALTER TABLE chat_attachments ADD COLUMN IF NOT EXISTS file_name TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_chat_attachments_message ON chat_attachments(message_id);
+CREATE INDEX IF NOT EXISTS idx_chat_attachments_object ON chat_attachments(bucket, object_key);
+
+-- Backfill from existing attachment messages, whose content is a (presigned)
+-- URL of the form http(s)://host/bucket/key?...
+INSERT INTO chat_attachments (message_id, file_url, file_type, file_size, bucket, object_key, file_name)
+SELECT m.id,
+       m.content,
+       m.metadata->>'mimeType',
+       CASE WHEN m.metadata->>'size' ~ '^[0-9]+$' THEN (m.metadata->>'size')::bigint END,
+       substring(m.content from '^https?://[^/]+/([^/?]+)/'),
+       substring(m.content from '^https?://[^/]+/[^/?]+/([^?]+)'),
+       m.metadata->>'name'
+FROM chat_messages m
+WHERE m.type IN ('image', 'file', 'audio')
+  AND m.content ~ '^https?://[^/]+/[^/?]+/[^?]+'
+  AND NOT EXISTS (SELECT 1 FROM chat_attachments a WHERE a.message_id = m.id);
+
+-- Messages now store the object key and links are presigned on request, so
+-- replace the stored signed URLs with their keys
+UPDATE chat_attachments
+SET file_url = object_key
+WHERE object_key IS NOT NULL AND file_url ~ '^https?://';
+
+UPDATE chat_messages m
+SET content = a.object_key
+FROM chat_attachments a
+WHERE a.message_id = m.id
+  AND a.object_key IS NOT NULL
+  AND m.content ~ '^https?://';
@@ -11,10 +11,12 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/placement-portal-kec/drive-service/internal/database"
 	"github.com/placement-portal-kec/drive-service/internal/handlers"
 	"github.com/placement-portal-kec/drive-service/internal/repository"
 	"github.com/placement-portal-kec/drive-service/internal/routes"
 	"github.com/placement-portal-kec/drive-service/internal/services"
+	"github.com/placement-portal-kec/drive-service/internal/utils"
 )
 
 func main() {
@@ -52,12 +54,21 @@ func main() {
 
 	log.Println("Connected to Database for Drive Service (with explicit search_path)")
 
+	// Middleware (permissions, department scope) reads through the package-level pool
+	database.DB = db
+
 	// Initialize Redis Cache
 	services.InitRedis()
 
+	// Load auth-service public keys for JWT verification
+	utils.InitJWKS()
+
 	driveRepo := repository.NewDriveRepository(db)
 	driveHandler := handlers.NewDriveHandler(driveRepo)
 
+	// Remind eligible students who have not responded as deadlines approach
+	go driveHandler.RunDeadlineReminders(context.Background(), handlers.DeadlineReminderOffsets())
+
 	// 3. Initialize Fiber App
 	app := fiber.New(fiber.Config{
 		AppName: "Placement Portal - Drive Service",
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/placement-portal-kec/drive-service/internal/repository"
+	"github.com/placement-portal-kec/drive-service/internal/utils"
+)
+
+// defaultDeadlineReminderOffsets are used when DEADLINE_REMINDER_OFFSETS is unset
+var defaultDeadlineReminderOffsets = []int{48, 24, 6}
+
+// istZone formats deadlines in reminders; India has no daylight saving
+var istZone = time.FixedZone("IST", 5*60*60+30*60)
+
+// DeadlineReminderOffsets reads DEADLINE_REMINDER_OFFSETS, a comma-separated
+// list of whole hours before a drive's deadline ("48h,24h,6h" or "48,24,6"), and
+// returns them largest first. "off" disables deadline reminders.
+func DeadlineReminderOffsets() []int {
+	value := strings.TrimSpace(os.Getenv("DEADLINE_REMINDER_OFFSETS"))
+	if value == "" {
+		return defaultDeadlineReminderOffsets
+	}
+	if strings.EqualFold(value, "off") {
+		return nil
+	}
+
+	var offsets []int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSuffix(strings.TrimSpace(part), "h")
+		hours, err := strconv.Atoi(part)
+		if err != nil || hours <= 0 {
+			log.Printf("DeadlineReminders: ignoring invalid offset %q in DEADLINE_REMINDER_OFFSETS", part)
+			continue
+		}
+		if !slices.Contains(offsets, hours) {
+			offsets = append(offsets, hours)
+		}
+	}
+	slices.Sort(offsets)
+	slices.Reverse(offsets)
+	return offsets
+}
+
+// RunDeadlineReminders reminds eligible students who have neither opted in
+// nor out of an open drive as its deadline approaches, once at each offset,
+// checking every minute until ctx is cancelled. These are the only
+// registration deadline reminders; admin-service's scheduler only sends
+// drive-day reminders.
+func (h *DriveHandler) RunDeadlineReminders(ctx context.Context, offsets []int) {
+	if len(offsets) == 0 {
+		log.Println("DeadlineReminders: disabled")
+		return
+	}
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		h.sendDeadlineReminders(ctx, offsets)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDeadlineReminders sends the reminders due at each offset (largest
+// first). A drive already inside the next smaller offset only gets that
+// reminder, so a late start never sends two at once.
+func (h *DriveHandler) sendDeadlineReminders(ctx context.Context, offsets []int) {
+	for i, offset := range offsets {
+		after := 0
+		if i+1 < len(offsets) {
+			after = offsets[i+1]
+		}
+		drives, err := h.repo.GetDeadlineReminderDrives(ctx, offset, after)
+		if err != nil {
+			log.Printf("DeadlineReminders: finding drives due at %dh failed: %v", offset, err)
+			continue
+		}
+		for _, d := range drives {
+			h.sendDeadlineReminder(ctx, d, offset)
+		}
+	}
+}
+
+func (h *DriveHandler) sendDeadlineReminder(ctx context.Context, d repository.DeadlineReminderDrive, offset int) {
+	studentIDs, err := h.repo.ClaimDeadlineReminders(ctx, d.ID, offset)
+	if err != nil {
+		log.Printf("DeadlineReminders: recording %dh reminders for drive %d failed: %v", offset, d.ID, err)
+		return
+	}
+	if len(studentIDs) == 0 {
+		return
+	}
+
+	left := time.Until(d.DeadlineDate).Round(time.Hour)
+	when := fmt.Sprintf("%d hours", int(left.Hours()))
+	if left < 2*time.Hour {
+		when = "an hour"
+	}
+	err = h.repo.NotifyUsers(ctx, studentIDs, repository.UserNotification{
+		Category: repository.InboxDriveAnnouncement,
+		Type:     "deadline_reminder",
+		Title:    fmt.Sprintf("%s registration closes soon", d.CompanyName),
+		Body: fmt.Sprintf("Registration closes in about %s (%s). Opt in or opt out so the placement cell knows.",
+			when, d.DeadlineDate.In(istZone).Format("Mon, 2 Jan 3:04 PM")),
+		Data: map[string]string{
+			"drive_id":     strconv.FormatInt(d.ID, 10),
+			"offset_hours": strconv.Itoa(offset),
+		},
+		DedupeKey: fmt.Sprintf("drive:%d:deadline_reminder:%dh", d.ID, offset),
+	})
+	if err != nil {
+		log.Printf("DeadlineReminders: queueing %dh reminders for drive %d failed: %v", offset, d.ID, err)
+		if err := h.repo.ReleaseDeadlineReminders(ctx, d.ID, offset, studentIDs); err != nil {
+			log.Printf("DeadlineReminders: releasing %dh reminders for drive %d failed: %v", offset, d.ID, err)
+		}
+		return
+	}
+	log.Printf("DeadlineReminders: reminded %d students about drive %d (%dh before deadline)", len(studentIDs), d.ID, offset)
+}
+
+// GetDriveResponseReport - GET /v1/admin/drives/:id/response-report
+// Eligible students who opted in, opted out or have not responded, per
+// department, and how many responded after each deadline reminder.
+// Coordinators see their own department only.
+func (h *DriveHandler) GetDriveResponseReport(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Drive ID"})
+	}
+
+	report, err := h.repo.GetDriveResponseReport(c.Context(), id, utils.DepartmentScope(c))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(404).JSON(fiber.Map{"error": "Drive not found"})
+	}
+	if err != nil {
+		fmt.Printf("Response Report Error: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to build response report"})
+	}
+	return c.JSON(report)
+}
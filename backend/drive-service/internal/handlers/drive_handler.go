@@ -1,10 +1,10 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -155,43 +155,21 @@ func (h *DriveHandler) CreateDrive(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to create drive", "details": err.Error()})
 	}
 
-	// E. Send Notification to Eligible Students (Async)
-	go func(d models.PlacementDrive) {
-		// 1. Fetch Tokens
-		tokens, err := repo.GetEligibleStudentTokens(context.Background(), d)
-		if err != nil {
-			fmt.Printf("Notification Error: Failed to fetch eligible tokens: %v\n", err)
-			return
-		}
-
-		if len(tokens) == 0 {
-			fmt.Println("Notification: No eligible students with tokens found.")
-			return
-		}
-
-		// 2. Initialize Service
-		// Assuming config is in root
-		ns, err := services.NewNotificationService("firebase-service-account.json")
-		if err != nil {
-			fmt.Printf("Notification Error: Failed to init service: %v. Make sure firebase-service-account.json is present.\n", err)
-			return
-		}
-
-		// 3. Send
-		title := "New Placement Drive!"
-		body := fmt.Sprintf("%s is hiring. Check eligibility now!", d.CompanyName)
-		data := map[string]string{
-			"drive_id": strconv.FormatInt(d.ID, 10),
-			"type":     "new_drive",
-		}
-
-		successCount, err := ns.SendMulticastNotification(context.Background(), tokens, title, body, data)
-		if err != nil {
-			fmt.Printf("Notification Error: Send failed: %v\n", err)
-		} else {
-			fmt.Printf("Notification Sent: Successfully sent to %d/%d devices.\n", successCount, len(tokens))
-		}
-	}(drive)
+	// E. Notify eligible students (inbox + push)
+	if studentIDs, err := repo.GetEligibleStudentIDs(c.Context(), drive); err != nil {
+		fmt.Printf("Notification Error: Failed to fetch eligible students: %v\n", err)
+	} else if err := repo.NotifyUsers(c.Context(), studentIDs, repository.UserNotification{
+		Category: repository.InboxDriveAnnouncement,
+		Type:     "new_drive",
+		Title:    "New Placement Drive!",
+		Body:     fmt.Sprintf("%s is hiring. Check eligibility now!", drive.CompanyName),
+		Data: map[string]string{
+			"drive_id": strconv.FormatInt(drive.ID, 10),
+		},
+		DedupeKey: fmt.Sprintf("drive:%d:new_drive", drive.ID),
+	}); err != nil {
+		fmt.Printf("Notification Error: Failed to queue notifications: %v\n", err)
+	}
 
 	// F. Send WhatsApp Broadcast (Async) - DISABLED (Unimplemented/Token errors)
 	/*
@@ -436,8 +414,9 @@ func (h *DriveHandler) UpdateDrive(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Drive not found"})
 	}
 
-	// Capture old status for notification logic
+	// Capture old status and rounds for notification logic
 	oldStatus := drive.Status
+	oldRounds := drive.Rounds
 
 	// If Multipart with Files, upload them now that we have drive info
 	if isMultipart {
@@ -629,78 +608,47 @@ func (h *DriveHandler) UpdateDrive(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update drive"})
 	}
 
-	// F. Send Notification to Eligible Students (Async)
-	go func(d models.PlacementDrive) {
-		// 1. Fetch Tokens
-		tokens, err := repo.GetEligibleStudentTokens(context.Background(), d)
-		if err != nil {
-			fmt.Printf("Notification Error: Failed to fetch eligible tokens for update: %v\n", err)
-			return
-		}
-
-		if len(tokens) == 0 {
-			fmt.Println("Notification: No eligible students with tokens found for update.")
-			return
-		}
-
-		// 2. Initialize Service
-		ns, err := services.NewNotificationService("firebase-service-account.json")
-		if err != nil {
-			fmt.Printf("Notification Error: Failed to init service: %v.\n", err)
-			return
-		}
-
-		// 3. Send
-		// 3. Send
-		// Logic: If status changed from !open to open, send "New Drive" notification (Republish)
-		// If status changed from open to cancelled/on_hold, notify as well.
-		var title, body, notifType string
-		shouldNotify := false
-
-		if oldStatus != "open" && d.Status == "open" {
-			shouldNotify = true
-			title = "New Placement Drive!" // Treated as new for students
-			body = fmt.Sprintf("%s is hiring. Apply now!", d.CompanyName)
-			notifType = "new_drive"
-		} else if oldStatus == "open" && (d.Status == "cancelled" || d.Status == "on_hold") {
-			shouldNotify = true
-			if d.Status == "cancelled" {
-				title = "Placement Drive Cancelled"
-				body = fmt.Sprintf("%s is cancelled", d.CompanyName)
-				notifType = "drive_cancelled"
-			} else {
-				title = "Placement Drive On Hold"
-				body = fmt.Sprintf("%s is on hold now!", d.CompanyName)
-				notifType = "drive_on_hold"
-			}
-		} else if oldStatus != d.Status {
-			// Other status changes
-			title = "Placement Drive Updated"
-			body = fmt.Sprintf("Updates have been made to %s. Check for changes.", d.CompanyName)
-			notifType = "drive_update"
-			shouldNotify = true
-		} else {
-			// Fallback for non-status updates
-			title = "Placement Drive Updated"
-			body = fmt.Sprintf("Updates have been made to %s. Check for changes.", d.CompanyName)
-			notifType = "drive_update"
-			shouldNotify = true
-		}
-
-		if shouldNotify {
-			data := map[string]string{
-				"drive_id": strconv.FormatInt(d.ID, 10),
-				"type":     notifType,
-			}
-
-			successCount, err := ns.SendMulticastNotification(context.Background(), tokens, title, body, data)
-			if err != nil {
-				fmt.Printf("Notification Error: Send failed: %v\n", err)
-			} else {
-				fmt.Printf("Notification Sent: Successfully sent to %d/%d devices.\n", successCount, len(tokens))
-			}
-		}
-	}(*drive)
+	// F. Queue a push notification for eligible students
+	// If status changed from !open to open, send "New Drive" notification (Republish)
+	// If status changed from open to cancelled/on_hold, notify as well.
+	title := "Placement Drive Updated"
+	body := fmt.Sprintf("Updates have been made to %s. Check for changes.", drive.CompanyName)
+	notifType := "drive_update"
+	critical := false
+	if oldStatus != "open" && drive.Status == "open" {
+		title = "New Placement Drive!" // Treated as new for students
+		body = fmt.Sprintf("%s is hiring. Apply now!", drive.CompanyName)
+		notifType = "new_drive"
+	} else if oldStatus == "open" && drive.Status == "cancelled" {
+		title = "Placement Drive Cancelled"
+		body = fmt.Sprintf("%s is cancelled", drive.CompanyName)
+		notifType = "drive_cancelled"
+		critical = true
+	} else if oldStatus == "open" && drive.Status == "on_hold" {
+		title = "Placement Drive On Hold"
+		body = fmt.Sprintf("%s is on hold now!", drive.CompanyName)
+		notifType = "drive_on_hold"
+	} else if moved := rescheduledRounds(oldRounds, drive.Rounds); len(moved) > 0 {
+		title = "Round Rescheduled"
+		body = roundRescheduleBody(drive.CompanyName, moved)
+		notifType = "round_rescheduled"
+		critical = true
+	}
+
+	if studentIDs, err := repo.GetEligibleStudentIDs(c.Context(), *drive); err != nil {
+		fmt.Printf("Notification Error: Failed to fetch eligible students for update: %v\n", err)
+	} else if err := repo.NotifyUsers(c.Context(), studentIDs, repository.UserNotification{
+		Category: repository.InboxDriveAnnouncement,
+		Type:     notifType,
+		Title:    title,
+		Body:     body,
+		Data: map[string]string{
+			"drive_id": strconv.FormatInt(drive.ID, 10),
+		},
+		Critical: critical,
+	}); err != nil {
+		fmt.Printf("Notification Error: Failed to queue notifications: %v\n", err)
+	}
 
 	// Invalidate Cache
 	services.InvalidateCacheByPrefix(c.Context(), "api:student:drives:")
@@ -738,6 +686,7 @@ func (h *DriveHandler) PatchDriveStatus(c *fiber.Ctx) error {
 	}
 
 	oldStatus := drive.Status
+	oldRounds := drive.Rounds
 
 	// Selectively Update
 	if input.CompanyName != nil {
@@ -835,6 +784,7 @@ func (h *DriveHandler) PatchDriveStatus(c *fiber.Ctx) error {
 	if input.Status != nil && oldStatus != *input.Status {
 		newStatus := *input.Status
 		shouldNotify := false
+		critical := false
 		title, body, notifType := "", "", ""
 
 		if newStatus == "open" && oldStatus != "open" {
@@ -848,6 +798,7 @@ func (h *DriveHandler) PatchDriveStatus(c *fiber.Ctx) error {
 				title = "Placement Drive Cancelled"
 				body = fmt.Sprintf("%s is cancelled", drive.CompanyName)
 				notifType = "drive_cancelled"
+				critical = true
 			} else {
 				title = "Placement Drive On Hold"
 				body = fmt.Sprintf("%s is on hold now!", drive.CompanyName)
@@ -856,21 +807,38 @@ func (h *DriveHandler) PatchDriveStatus(c *fiber.Ctx) error {
 		}
 
 		if shouldNotify {
-			go func(d models.PlacementDrive, t, b, nt string) {
-				// Use the repository method to get tokens
-				tokens, err := repo.GetEligibleStudentTokens(context.Background(), d)
-				if err != nil || len(tokens) == 0 {
-					return
-				}
-				ns, _ := services.NewNotificationService("firebase-service-account.json")
-				if ns != nil {
-					data := map[string]string{
-						"drive_id": strconv.FormatInt(d.ID, 10),
-						"type":     nt,
-					}
-					ns.SendMulticastNotification(context.Background(), tokens, t, b, data)
-				}
-			}(*drive, title, body, notifType)
+			if studentIDs, err := repo.GetEligibleStudentIDs(c.Context(), *drive); err != nil {
+				fmt.Printf("Notification Error: Failed to fetch eligible students: %v\n", err)
+			} else if err := repo.NotifyUsers(c.Context(), studentIDs, repository.UserNotification{
+				Category: repository.InboxDriveAnnouncement,
+				Type:     notifType,
+				Title:    title,
+				Body:     body,
+				Data: map[string]string{
+					"drive_id": strconv.FormatInt(drive.ID, 10),
+				},
+				Critical: critical,
+			}); err != nil {
+				fmt.Printf("Notification Error: Failed to queue notifications: %v\n", err)
+			}
+		}
+	}
+
+	// Round dates moved: critical, so it reaches students during quiet hours
+	if moved := rescheduledRounds(oldRounds, drive.Rounds); len(moved) > 0 && drive.Status != "cancelled" {
+		if studentIDs, err := repo.GetEligibleStudentIDs(c.Context(), *drive); err != nil {
+			fmt.Printf("Notification Error: Failed to fetch eligible students: %v\n", err)
+		} else if err := repo.NotifyUsers(c.Context(), studentIDs, repository.UserNotification{
+			Category: repository.InboxDriveAnnouncement,
+			Type:     "round_rescheduled",
+			Title:    "Round Rescheduled",
+			Body:     roundRescheduleBody(drive.CompanyName, moved),
+			Data: map[string]string{
+				"drive_id": strconv.FormatInt(drive.ID, 10),
+			},
+			Critical: true,
+		}); err != nil {
+			fmt.Printf("Notification Error: Failed to queue notifications: %v\n", err)
 		}
 	}
 
@@ -881,6 +849,30 @@ func (h *DriveHandler) PatchDriveStatus(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"message": "Drive patched successfully", "drive": drive})
 }
 
+// rescheduledRounds returns the rounds in updated whose date differs from the
+// round of the same name in old. New and removed rounds are not reschedules.
+func rescheduledRounds(old, updated []models.Round) []models.Round {
+	oldDates := make(map[string]string, len(old))
+	for _, r := range old {
+		oldDates[strings.ToLower(strings.TrimSpace(r.Name))] = r.Date
+	}
+	var moved []models.Round
+	for _, r := range updated {
+		if date, ok := oldDates[strings.ToLower(strings.TrimSpace(r.Name))]; ok && date != r.Date {
+			moved = append(moved, r)
+		}
+	}
+	return moved
+}
+
+func roundRescheduleBody(company string, moved []models.Round) string {
+	parts := make([]string, len(moved))
+	for i, r := range moved {
+		parts[i] = fmt.Sprintf("%s is now on %s", r.Name, r.Date)
+	}
+	return fmt.Sprintf("%s: %s.", company, strings.Join(parts, ", "))
+}
+
 // DeleteDrive - Handles DELETE requests
 // @Summary Delete a placement drive
 // @Description Delete a drive by ID (Admin only)
@@ -1021,7 +1013,8 @@ func (h *DriveHandler) AdminAddApplication(c *fiber.Ctx) error {
 	}
 
 	studentRepo := repository.NewStudentRepository(database.DB)
-	studentID, err := studentRepo.GetStudentIDByRegisterNumber(c.Context(), input.RegisterNumber)
+	// Coordinators can only add students of their own department
+	studentID, err := studentRepo.GetStudentIDByRegisterNumber(c.Context(), input.RegisterNumber, utils.DepartmentScope(c))
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Student not found", "details": err.Error()})
 	}
@@ -1031,39 +1024,22 @@ func (h *DriveHandler) AdminAddApplication(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "Manual registration failed", "details": err.Error()})
 	}
 
-	// Send Notification Async
-	go func() {
-		// 1. Get Token
-		token, err := repo.GetStudentFCMToken(context.Background(), studentID)
-		if err != nil || token == "" {
-			fmt.Println("Manual Register Notification: No token found or error")
-			return
-		}
-
-		// 2. Get Drive Info for Message
-		drive, err := repo.GetDriveByID(context.Background(), driveID)
-		if err != nil {
-			return
-		}
-
-		// 3. Send
-		ns, err := services.NewNotificationService("firebase-service-account.json")
+	// Notify the student (inbox + push)
+	if drive, err := repo.GetDriveByID(c.Context(), driveID); err == nil {
+		err := repo.NotifyUsers(c.Context(), []int64{studentID}, repository.UserNotification{
+			Category: repository.InboxApplicationStatus,
+			Type:     "manual_add",
+			Title:    "Added to Drive",
+			Body:     fmt.Sprintf("You have been manually added to the placement drive for %s.", drive.CompanyName),
+			Data: map[string]string{
+				"drive_id": strconv.FormatInt(driveID, 10),
+			},
+			DedupeKey: fmt.Sprintf("drive:%d:manual_add", driveID),
+		})
 		if err != nil {
-			fmt.Printf("Notification Error: Failed to init service: %v\n", err)
-			return
-		}
-
-		title := "Added to Drive"
-		body := fmt.Sprintf("You have been manually added to the placement drive for %s.", drive.CompanyName)
-		data := map[string]string{
-			"drive_id": strconv.FormatInt(driveID, 10),
-			"type":     "manual_add",
+			fmt.Printf("Notification Error: Failed to queue notification: %v\n", err)
 		}
-
-		// Helper to send single? We have SendMulticast.
-		// We can wrap single token in list.
-		ns.SendMulticastNotification(context.Background(), []string{token}, title, body, data)
-	}()
+	}
 
 	return c.JSON(fiber.Map{"message": "Student manually added and notified"})
 }
@@ -1091,20 +1067,8 @@ func (h *DriveHandler) ExportApplicants(c *fiber.Ctx) error {
 	// Body is optional (if empty, we export all)
 	c.BodyParser(&input)
 
-	// Role Check for Coordinator
-	role := c.Locals("role").(string)
-	var deptFilter *string
-	if role == "coordinator" {
-		if deptCode, ok := c.Locals("department_code").(string); ok && deptCode != "" {
-			deptFilter = &deptCode
-		} else {
-			// Should not happen if middleware ensures it, but safe fallback
-			return c.Status(403).JSON(fiber.Map{"error": "Coordinator department not found"})
-		}
-	}
-
 	repo := h.repo
-	applicants, err := repo.GetDriveApplicantsDetailed(c.Context(), driveID, input.StudentIDs, deptFilter)
+	applicants, err := repo.GetDriveApplicantsDetailed(c.Context(), driveID, input.StudentIDs, utils.DepartmentScope(c))
 	if err != nil {
 		fmt.Printf("Export Error: %v\n", err)
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch applicants"})
@@ -1129,20 +1093,8 @@ func (h *DriveHandler) GetApplicants(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid Drive ID"})
 	}
 
-	// Role Check for Coordinator
-	role := c.Locals("role").(string)
-	var deptFilter *string
-	if role == "coordinator" {
-		if deptCode, ok := c.Locals("department_code").(string); ok && deptCode != "" {
-			deptFilter = &deptCode
-		} else {
-			// Should not happen if middleware ensures it, but safe fallback
-			return c.Status(403).JSON(fiber.Map{"error": "Coordinator department not found"})
-		}
-	}
-
 	repo := h.repo
-	applicants, err := repo.GetDriveApplicantsDetailed(c.Context(), driveID, nil, deptFilter)
+	applicants, err := repo.GetDriveApplicantsDetailed(c.Context(), driveID, nil, utils.DepartmentScope(c))
 	if err != nil {
 		fmt.Printf("Get Detailed Applicants Error: %v\n", err)
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch applicants"})
@@ -1289,6 +1241,14 @@ func (h *DriveHandler) AdminRemoveApplication(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Student ID is required"})
 	}
 
+	inScope, err := repository.NewStudentRepository(database.DB).StudentInScope(c.Context(), input.StudentID, utils.DepartmentScope(c))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to verify student department"})
+	}
+	if !inScope {
+		return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You can only manage students of your department"})
+	}
+
 	repo := h.repo
 	if err := repo.WithdrawApplication(c.Context(), input.StudentID, driveID, "Removed by Admin"); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to remove application: " + err.Error()})
@@ -1320,7 +1280,7 @@ func (h *DriveHandler) GetDriveApplicants(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid Drive ID"})
 	}
 
-	applicants, err := h.repo.GetDriveApplicants(c.Context(), id)
+	applicants, err := h.repo.GetDriveApplicants(c.Context(), id, utils.DepartmentScope(c))
 	if err != nil {
 		fmt.Printf("Get Applicants Error: %v\n", err)
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch applicants"})
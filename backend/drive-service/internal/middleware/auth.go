@@ -5,7 +5,6 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/placement-portal-kec/drive-service/internal/database"
 	"github.com/placement-portal-kec/drive-service/internal/utils"
 )
 
@@ -24,17 +23,33 @@ func JWTMiddleware() fiber.Handler {
 
 		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return utils.SecretKey, nil
-		})
+		token, err := utils.ParseToken(tokenString)
 
 		if err != nil || !token.Valid {
 			return c.Status(401).JSON(fiber.Map{"error": "Unauthorized: Invalid token"})
 		}
 
 		claims := token.Claims.(jwt.MapClaims)
+
+		// Accounts on an admin-assigned password may only change it (PUT /api/v1/auth/password)
+		if mustChange, _ := claims["pwd_change_required"].(bool); mustChange {
+			return c.Status(403).JSON(fiber.Map{"error": "Password change required", "code": "PASSWORD_CHANGE_REQUIRED"})
+		}
+
+		if handled, err := guardImpersonation(c, claims); handled {
+			return err
+		}
+
+		// Coordinators are scoped to the department carried in their token; tokens
+		// issued before the claim existed must be refreshed rather than see everything
+		deptCode, _ := claims["department_code"].(string)
+		if claims["role"] == "coordinator" && deptCode == "" {
+			return c.Status(401).JSON(fiber.Map{"error": "Unauthorized: Session expired, please log in again"})
+		}
+
 		c.Locals("user_id", claims["user_id"])
 		c.Locals("role", claims["role"])
+		c.Locals("department_code", deptCode)
 
 		return c.Next()
 	}
@@ -74,58 +89,3 @@ func SuperAdminOnly(c *fiber.Ctx) error {
 	}
 	return c.Next()
 }
-
-// HasPermission checks if the user has a specific permission granted
-func HasPermission(permissionKey string) fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		role := c.Locals("role").(string)
-
-		// Super admin bypasses all permission checks
-		if role == "super_admin" {
-			return c.Next()
-		}
-
-		userID := int64(c.Locals("user_id").(float64))
-
-		var isGranted bool
-		err := database.DB.QueryRow(c.Context(),
-			"SELECT is_granted FROM role_permissions WHERE user_id = $1 AND permission_key = $2",
-			userID, permissionKey,
-		).Scan(&isGranted)
-
-		if err != nil || !isGranted {
-			return c.Status(403).JSON(fiber.Map{
-				"error": "Forbidden: You don't have permission for this action",
-			})
-		}
-
-		return c.Next()
-	}
-}
-
-// DepartmentScoped injects department filtering for coordinator users
-// It sets c.Locals("department_scope") which handlers can use to filter queries
-func DepartmentScoped(c *fiber.Ctx) error {
-	role := c.Locals("role").(string)
-
-	if role == "coordinator" {
-		userID := int64(c.Locals("user_id").(float64))
-
-		var deptCode *string
-		err := database.DB.QueryRow(c.Context(),
-			"SELECT department_code FROM users WHERE id = $1",
-			userID,
-		).Scan(&deptCode)
-
-		if err != nil || deptCode == nil {
-			return c.Status(403).JSON(fiber.Map{
-				"error": "Coordinator has no department assigned",
-			})
-		}
-
-		c.Locals("department_scope", *deptCode)
-	}
-	// For admin and super_admin, no department scope is set (they see everything)
-
-	return c.Next()
-}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/placement-portal-kec/drive-service/internal/database"
+)
+
+// guardImpersonation enforces the rules for "view as student" tokens minted by
+// auth-service: the session must still be open (not ended early, student not
+// since blocked), every request is written to activity_logs under the super
+// admin who started the session, and anything other than a read is refused. When
+// handled is true a response has already been written and the caller must
+// return err without calling the next handler.
+func guardImpersonation(c *fiber.Ctx, claims jwt.MapClaims) (handled bool, err error) {
+	actor, ok := claims["impersonator_id"].(float64)
+	if !ok {
+		return false, nil
+	}
+
+	sessionID, _ := claims["impersonation_session"].(float64)
+	var open bool
+	err = database.DB.QueryRow(c.Context(),
+		`SELECT EXISTS(
+			SELECT 1 FROM auth.impersonation_sessions s
+			JOIN public.users u ON u.id = s.user_id
+			WHERE s.id = $1 AND s.ended_at IS NULL
+			  AND COALESCE(u.is_active, TRUE) AND NOT COALESCE(u.is_blocked, FALSE))`,
+		int64(sessionID),
+	).Scan(&open)
+	if err != nil {
+		return true, c.Status(500).JSON(fiber.Map{"error": "Failed to verify impersonation session"})
+	}
+	if !open {
+		return true, c.Status(401).JSON(fiber.Map{"error": "Impersonation session has ended", "code": "IMPERSONATION_ENDED"})
+	}
+
+	readOnly := c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead || c.Method() == fiber.MethodOptions
+	studentID, _ := claims["user_id"].(float64)
+	details := map[string]interface{}{
+		"impersonated_user_id": int64(studentID),
+		"method":               c.Method(),
+		"path":                 c.Path(),
+		"refused":              !readOnly,
+	}
+	_, err = database.DB.Exec(c.Context(),
+		`INSERT INTO admin.activity_logs (user_id, action, entity_type, entity_id, details, ip_address, created_at)
+		 VALUES ($1, 'IMPERSONATED_REQUEST', 'USER', $2, $3, $4, NOW())`,
+		int64(actor), strconv.FormatInt(int64(studentID), 10), details, c.IP(),
+	)
+	if err != nil {
+		return true, c.Status(500).JSON(fiber.Map{"error": "Failed to audit impersonated request"})
+	}
+
+	if !readOnly {
+		return true, c.Status(403).JSON(fiber.Map{"error": "Impersonation sessions are read-only", "code": "IMPERSONATION_READ_ONLY"})
+	}
+	c.Locals("impersonator_id", actor)
+	return false, nil
+}
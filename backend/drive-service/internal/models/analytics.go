@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // YearWiseAnalytics holds placement metrics grouped by batch year
 type YearWiseAnalytics struct {
 	BatchYear           int     `json:"batch_year"`
@@ -59,3 +61,39 @@ type DashboardAnalytics struct {
 	SalaryWise     []SalaryBracketAnalytics  `json:"salary_wise"`
 	TopRecruiters  []TopRecruitersAnalytics  `json:"top_recruiters"`
 }
+
+// ResponseCounts is how eligible students answered a drive. Pending
+// students have neither opted in nor opted out.
+type ResponseCounts struct {
+	Eligible     int     `json:"eligible"`
+	OptedIn      int     `json:"opted_in"`
+	OptedOut     int     `json:"opted_out"`
+	Pending      int     `json:"pending"`
+	ResponseRate float64 `json:"response_rate"` // percent of eligible who responded
+}
+
+// DepartmentResponse is a drive's response counts for one department
+type DepartmentResponse struct {
+	DepartmentCode string `json:"department_code"`
+	ResponseCounts
+}
+
+// ReminderEffect is one deadline reminder offset: how many students it
+// reached and how many of them responded afterwards
+type ReminderEffect struct {
+	OffsetHours    int        `json:"offset_hours"`
+	Reminded       int        `json:"reminded"`
+	RespondedAfter int        `json:"responded_after"`
+	FirstSentAt    *time.Time `json:"first_sent_at"`
+	ConversionRate float64    `json:"conversion_rate"` // percent of reminded who responded after
+}
+
+// DriveResponseReport is the per-drive response-rate report for admins
+type DriveResponseReport struct {
+	DriveID      int64                `json:"drive_id"`
+	CompanyName  string               `json:"company_name"`
+	DeadlineDate time.Time            `json:"deadline_date"`
+	Total        ResponseCounts       `json:"total"`
+	Departments  []DepartmentResponse `json:"departments"`
+	Reminders    []ReminderEffect     `json:"reminders"`
+}
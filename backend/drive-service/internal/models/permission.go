@@ -0,0 +1,18 @@
+package models
+
+// Permission keys checked by RequirePermission. The catalog (labels, role
+// defaults) lives in admin-service/internal/models/permission.go; keep these
+// keys in step with it.
+const (
+	PermManageDrives   = "manage_drives"
+	PermManageStudents = "manage_students"
+	PermApproveChanges = "approve_changes"
+	PermManageSpocs    = "manage_spocs"
+	PermManageStorage  = "manage_storage"
+	PermExportData     = "export_data"
+	PermManualDriveOps = "manual_drive_ops"
+	PermViewAnalytics  = "view_analytics"
+	PermManageConfig   = "manage_config"
+	PermSendBroadcasts = "send_broadcasts"
+	PermManageSupport  = "manage_support"
+)
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/placement-portal-kec/drive-service/internal/models"
+)
+
+// activeStudentSQL matches users (u) who are students that can be reached
+// about drives: active and not blocked. Every drive audience query uses it.
+const activeStudentSQL = `u.role = 'student' AND COALESCE(u.is_active, TRUE) AND NOT COALESCE(u.is_blocked, FALSE)`
+
+// eligibleForDriveSQL matches the students (u, sp) a drive (pd) is announced
+// to, as GetEligibleStudentIDs does: batch and department, willingness,
+// placed candidates (placed by another drive) and exclusions. CGPA and
+// other criteria are checked when the student opts in.
+const eligibleForDriveSQL = activeStudentSQL + `
+	AND (sp.placement_willingness IS NULL OR sp.placement_willingness = 'Interested')
+	AND (NOT EXISTS (SELECT 1 FROM drive_eligible_departments ded WHERE ded.drive_id = pd.id)
+	     OR sp.department IN (SELECT ded.department_code FROM drive_eligible_departments ded WHERE ded.drive_id = pd.id))
+	AND (NOT EXISTS (SELECT 1 FROM drive_eligible_batches deb WHERE deb.drive_id = pd.id)
+	     OR sp.batch_year IN (SELECT deb.batch_year FROM drive_eligible_batches deb WHERE deb.drive_id = pd.id))
+	AND (COALESCE(pd.allow_placed_candidates, FALSE)
+	     OR NOT EXISTS (SELECT 1 FROM drive_applications da
+	                    WHERE da.student_id = u.id AND da.status = 'placed' AND da.drive_id <> pd.id))
+	AND NOT COALESCE(pd.excluded_student_ids, '[]'::jsonb) @> to_jsonb(u.id)`
+
+// DeadlineReminderDrive is an open drive due for a deadline reminder
+type DeadlineReminderDrive struct {
+	ID           int64
+	CompanyName  string
+	DeadlineDate time.Time
+}
+
+// GetDeadlineReminderDrives returns the open drives whose deadline is more
+// than afterHours but at most offsetHours away. afterHours is the next
+// smaller offset, so a drive whose deadline is already that close gets
+// only the later reminder. Drives created inside the window are skipped;
+// their announcement went out recently enough.
+func (r *DriveRepository) GetDeadlineReminderDrives(ctx context.Context, offsetHours, afterHours int) ([]DeadlineReminderDrive, error) {
+	rows, err := r.DB.Query(ctx, `
+		SELECT id, company_name, deadline_date
+		FROM placement_drives
+		WHERE status = 'open'
+		  AND deadline_date > NOW() + make_interval(hours => $2)
+		  AND deadline_date <= NOW() + make_interval(hours => $1)
+		  AND created_at <= deadline_date - make_interval(hours => $1)
+		ORDER BY deadline_date`, offsetHours, afterHours)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var drives []DeadlineReminderDrive
+	for rows.Next() {
+		var d DeadlineReminderDrive
+		if err := rows.Scan(&d.ID, &d.CompanyName, &d.DeadlineDate); err != nil {
+			return nil, err
+		}
+		drives = append(drives, d)
+	}
+	return drives, rows.Err()
+}
+
+// ClaimDeadlineReminders records a reminder at offsetHours for every
+// eligible student who has not yet opted in or out of the drive and returns
+// those students. Students already reminded at this offset are left out, so
+// concurrent runs never remind anyone twice.
+func (r *DriveRepository) ClaimDeadlineReminders(ctx context.Context, driveID int64, offsetHours int) ([]int64, error) {
+	rows, err := r.DB.Query(ctx, `
+		INSERT INTO drive_deadline_reminders (drive_id, student_id, offset_hours)
+		SELECT pd.id, u.id, $2
+		FROM placement_drives pd
+		JOIN public.users u ON TRUE
+		JOIN student.student_personal sp ON u.id = sp.user_id
+		WHERE pd.id = $1 AND `+eligibleForDriveSQL+`
+		  AND NOT EXISTS (SELECT 1 FROM drive_applications da WHERE da.drive_id = pd.id AND da.student_id = u.id)
+		ON CONFLICT (drive_id, student_id, offset_hours) DO NOTHING
+		RETURNING student_id`, driveID, offsetHours)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ReleaseDeadlineReminders forgets claimed reminders that could not be
+// queued, so the next run tries them again.
+func (r *DriveRepository) ReleaseDeadlineReminders(ctx context.Context, driveID int64, offsetHours int, studentIDs []int64) error {
+	_, err := r.DB.Exec(ctx, `
+		DELETE FROM drive_deadline_reminders
+		WHERE drive_id = $1 AND offset_hours = $2 AND student_id = ANY($3)`,
+		driveID, offsetHours, studentIDs)
+	return err
+}
+
+// GetDriveResponseReport returns how a drive's eligible students responded,
+// per department, and how many responded after each deadline reminder.
+// deptScope limits both to one department.
+func (r *DriveRepository) GetDriveResponseReport(ctx context.Context, driveID int64, deptScope *string) (*models.DriveResponseReport, error) {
+	report := models.DriveResponseReport{
+		DriveID:     driveID,
+		Departments: []models.DepartmentResponse{},
+		Reminders:   []models.ReminderEffect{},
+	}
+	err := r.DB.QueryRow(ctx, `SELECT company_name, deadline_date FROM placement_drives WHERE id = $1`, driveID).
+		Scan(&report.CompanyName, &report.DeadlineDate)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.DB.Query(ctx, `
+		SELECT COALESCE(sp.department, ''),
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE app.status <> 'opted_out'),
+		       COUNT(*) FILTER (WHERE app.status = 'opted_out'),
+		       COUNT(*) FILTER (WHERE app.student_id IS NULL)
+		FROM placement_drives pd
+		JOIN public.users u ON TRUE
+		JOIN student.student_personal sp ON u.id = sp.user_id
+		LEFT JOIN drive_applications app ON app.drive_id = pd.id AND app.student_id = u.id
+		WHERE pd.id = $1 AND `+eligibleForDriveSQL+`
+		  AND ($2::text IS NULL OR sp.department = $2)
+		GROUP BY 1
+		ORDER BY 1`, driveID, deptScope)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d models.DepartmentResponse
+		if err := rows.Scan(&d.DepartmentCode, &d.Eligible, &d.OptedIn, &d.OptedOut, &d.Pending); err != nil {
+			return nil, err
+		}
+		d.ResponseRate = percentOf(d.OptedIn+d.OptedOut, d.Eligible)
+		report.Total.Eligible += d.Eligible
+		report.Total.OptedIn += d.OptedIn
+		report.Total.OptedOut += d.OptedOut
+		report.Total.Pending += d.Pending
+		report.Departments = append(report.Departments, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	report.Total.ResponseRate = percentOf(report.Total.OptedIn+report.Total.OptedOut, report.Total.Eligible)
+
+	rows, err = r.DB.Query(ctx, `
+		SELECT dr.offset_hours, COUNT(*),
+		       COUNT(*) FILTER (WHERE app.applied_at >= dr.sent_at),
+		       MIN(dr.sent_at)
+		FROM drive_deadline_reminders dr
+		JOIN student.student_personal sp ON sp.user_id = dr.student_id
+		LEFT JOIN drive_applications app ON app.drive_id = dr.drive_id AND app.student_id = dr.student_id
+		WHERE dr.drive_id = $1 AND ($2::text IS NULL OR sp.department = $2)
+		GROUP BY dr.offset_hours
+		ORDER BY dr.offset_hours DESC`, driveID, deptScope)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e models.ReminderEffect
+		if err := rows.Scan(&e.OffsetHours, &e.Reminded, &e.RespondedAfter, &e.FirstSentAt); err != nil {
+			return nil, err
+		}
+		e.ConversionRate = percentOf(e.RespondedAfter, e.Reminded)
+		report.Reminders = append(report.Reminders, e)
+	}
+	return &report, rows.Err()
+}
+
+// percentOf returns n as a percentage of total, to one decimal place
+func percentOf(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(n)*1000/float64(total)) / 10
+}
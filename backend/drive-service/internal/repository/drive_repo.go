@@ -912,7 +912,7 @@ func (r *DriveRepository) BulkUpdateApplicationStatus(ctx context.Context, reque
 }
 
 // GetDriveApplicants fetches all students applied to a drive
-func (r *DriveRepository) GetDriveApplicants(ctx context.Context, driveID int64) ([]models.DriveApplicant, error) {
+func (r *DriveRepository) GetDriveApplicants(ctx context.Context, driveID int64, deptScope *string) ([]models.DriveApplicant, error) {
 	query := `
         SELECT 
             u.id, COALESCE(u.name, ''), COALESCE(sp.register_number, ''), u.email, COALESCE(sp.department, ''), 
@@ -922,11 +922,11 @@ func (r *DriveRepository) GetDriveApplicants(ctx context.Context, driveID int64)
         JOIN student.student_personal sp ON u.id = sp.user_id
         LEFT JOIN student.student_degrees d_ug ON u.id = d_ug.user_id AND d_ug.degree_level = 'UG'
         LEFT JOIN student.student_documents sd ON u.id = sd.user_id
-        WHERE da.drive_id = $1
+        WHERE da.drive_id = $1 AND ($2::text IS NULL OR sp.department = $2)
         ORDER BY da.applied_at DESC
     `
 
-	rows, err := r.DB.Query(ctx, query, driveID)
+	rows, err := r.DB.Query(ctx, query, driveID, deptScope)
 	if err != nil {
 		return nil, err
 	}
@@ -956,8 +956,7 @@ func (r *DriveRepository) GetEligibleStudentTokens(ctx context.Context, drive mo
 		SELECT u.fcm_token 
 		FROM public.users u
 		JOIN student.student_personal sp ON u.id = sp.user_id
-		WHERE u.role = 'student' 
-		AND u.is_active = true 
+		WHERE ` + activeStudentSQL + `
 		AND u.fcm_token IS NOT NULL 
 		AND u.fcm_token != ''
 		AND (sp.placement_willingness IS NULL OR sp.placement_willingness = 'Interested')
@@ -988,14 +987,48 @@ func (r *DriveRepository) GetEligibleStudentTokens(ctx context.Context, drive mo
 	return tokens, nil
 }
 
+// GetEligibleStudentIDs fetches the user IDs of students matching drive's batch + department
+func (r *DriveRepository) GetEligibleStudentIDs(ctx context.Context, drive models.PlacementDrive) ([]int64, error) {
+	query := `
+		SELECT u.id
+		FROM public.users u
+		JOIN student.student_personal sp ON u.id = sp.user_id
+		WHERE ` + activeStudentSQL + `
+		AND (sp.placement_willingness IS NULL OR sp.placement_willingness = 'Interested')
+		AND ($1::text[] IS NULL OR cardinality($1::text[]) = 0 OR sp.department = ANY($1::text[]))
+		AND ($2::int[] IS NULL OR cardinality($2::int[]) = 0 OR sp.batch_year = ANY($2::int[]))
+		AND ($3::boolean = TRUE OR NOT EXISTS (SELECT 1 FROM drive_applications da WHERE da.student_id = u.id AND da.status = 'placed'))
+		AND NOT u.id = ANY($4::bigint[])
+	`
+
+	rows, err := r.DB.Query(ctx, query,
+		drive.EligibleDepartments,
+		drive.EligibleBatches,
+		drive.AllowPlacedCandidates,
+		drive.ExcludedStudentIDs,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
 // GetEligibleStudentPhoneNumbers fetches mobile numbers of students matching drive's batch + department
 func (r *DriveRepository) GetEligibleStudentPhoneNumbers(ctx context.Context, drive models.PlacementDrive) ([]string, error) {
 	query := `
 		SELECT sp.mobile_number 
 		FROM public.users u
 		JOIN student.student_personal sp ON u.id = sp.user_id
-		WHERE u.role = 'student' 
-		AND u.is_active = true 
+		WHERE ` + activeStudentSQL + `
 		AND sp.mobile_number IS NOT NULL 
 		AND sp.mobile_number != '' 
 		AND sp.mobile_number != 'NA'
@@ -1055,8 +1088,7 @@ func (r *DriveRepository) GetEligibleStudentsPreview(ctx context.Context, input
         LEFT JOIN student.student_schooling sch ON u.id = sch.user_id
         LEFT JOIN student.student_degrees d_ug ON u.id = d_ug.user_id AND d_ug.degree_level = 'UG'
         LEFT JOIN student.student_degrees d_pg ON u.id = d_pg.user_id AND d_pg.degree_level = 'PG'
-        WHERE u.role = 'student' 
-        AND u.is_active = true 
+        WHERE ` + activeStudentSQL + `
         AND (sp.placement_willingness IS NULL OR sp.placement_willingness = 'Interested')
         AND ($1::text[] IS NULL OR cardinality($1::text[]) = 0 OR sp.department = ANY($1::text[]))
         AND ($2::int[] IS NULL OR cardinality($2::int[]) = 0 OR sp.batch_year = ANY($2::int[]))
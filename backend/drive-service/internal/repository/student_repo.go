@@ -391,18 +391,32 @@ func (r *StudentRepository) GetStudentFullProfile(ctx context.Context, userID in
 	return &s, nil
 }
 
-// GetStudentIDByRegisterNumber finds the user ID for a student by their register number
-// GetStudentIDByRegisterNumber finds the user ID for a student by their register number
-func (r *StudentRepository) GetStudentIDByRegisterNumber(ctx context.Context, regNo string) (int64, error) {
+// GetStudentIDByRegisterNumber finds the user ID for a student by their
+// register number. When deptScope is set, students outside that department
+// are reported as not found.
+func (r *StudentRepository) GetStudentIDByRegisterNumber(ctx context.Context, regNo string, deptScope *string) (int64, error) {
 	var userID int64
-	query := `SELECT user_id FROM student_personal WHERE register_number = $1`
-	err := r.DB.QueryRow(ctx, query, regNo).Scan(&userID)
+	query := `SELECT user_id FROM student_personal WHERE register_number = $1 AND ($2::text IS NULL OR department = $2)`
+	err := r.DB.QueryRow(ctx, query, regNo, deptScope).Scan(&userID)
 	if err != nil {
 		return 0, err
 	}
 	return userID, nil
 }
 
+// StudentInScope reports whether studentID may be managed under deptScope:
+// always when deptScope is nil, otherwise only for students of that department.
+func (r *StudentRepository) StudentInScope(ctx context.Context, studentID int64, deptScope *string) (bool, error) {
+	if deptScope == nil {
+		return true, nil
+	}
+	var ok bool
+	err := r.DB.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM student_personal WHERE user_id = $1 AND department = $2)`,
+		studentID, *deptScope).Scan(&ok)
+	return ok, err
+}
+
 // ApplyFieldUpdate applies a specific field update from a change request
 func (r *StudentRepository) ApplyFieldUpdate(studentID int64, fieldName string, newValue string) error {
 	ctx := context.Background()
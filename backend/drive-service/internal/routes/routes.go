@@ -4,6 +4,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/placement-portal-kec/drive-service/internal/handlers"
 	"github.com/placement-portal-kec/drive-service/internal/middleware"
+	"github.com/placement-portal-kec/drive-service/internal/models"
 )
 
 func SetupDriveRoutes(router fiber.Router, driveHandler *handlers.DriveHandler) {
@@ -23,20 +24,21 @@ func SetupDriveRoutes(router fiber.Router, driveHandler *handlers.DriveHandler)
 	adminGroup.Use(middleware.JWTMiddleware())
 	adminGroup.Use(middleware.RoleMiddleware("admin", "coordinator"))
 
-	adminGroup.Post("/", driveHandler.CreateDrive)
-	adminGroup.Get("/", driveHandler.GetAllDrives) // Change from /all to /
-	adminGroup.Get("/:id", driveHandler.GetDriveByID)
-	adminGroup.Put("/:id", driveHandler.UpdateDrive)        // Full update
-	adminGroup.Patch("/:id", driveHandler.PatchDriveStatus) // Status only
-	adminGroup.Delete("/:id", driveHandler.DeleteDrive)     // Soft delete
+	adminGroup.Post("/", middleware.RequirePermission(models.PermManageDrives), driveHandler.CreateDrive)
+	adminGroup.Get("/", middleware.RequirePermission(models.PermManageDrives), driveHandler.GetAllDrives) // Change from /all to /
+	adminGroup.Get("/:id", middleware.RequirePermission(models.PermManageDrives), driveHandler.GetDriveByID)
+	adminGroup.Put("/:id", middleware.RequirePermission(models.PermManageDrives), driveHandler.UpdateDrive)        // Full update
+	adminGroup.Patch("/:id", middleware.RequirePermission(models.PermManageDrives), driveHandler.PatchDriveStatus) // Status only
+	adminGroup.Delete("/:id", middleware.RequirePermission(models.PermManageDrives), driveHandler.DeleteDrive)     // Soft delete
 
 	// Applicant Management
-	adminGroup.Get("/:id/applicants", driveHandler.GetDriveApplicants)
-	adminGroup.Get("/:id/applicants/detailed", driveHandler.GetApplicants)
-	adminGroup.Post("/:id/export", driveHandler.ExportApplicants)         // Change to /export to match frontend
-	adminGroup.Post("/:id/add-student", driveHandler.AdminAddApplication) // Change to /add-student to match frontend
-	adminGroup.Delete("/:id/applications/:studentId", driveHandler.AdminRemoveApplication)
+	adminGroup.Get("/:id/applicants", middleware.RequirePermission(models.PermManageDrives), driveHandler.GetDriveApplicants)
+	adminGroup.Get("/:id/applicants/detailed", middleware.RequirePermission(models.PermManageDrives), driveHandler.GetApplicants)
+	adminGroup.Get("/:id/response-report", middleware.RequirePermission(models.PermManageDrives), driveHandler.GetDriveResponseReport)
+	adminGroup.Post("/:id/export", middleware.RequirePermission(models.PermExportData), driveHandler.ExportApplicants)         // Change to /export to match frontend
+	adminGroup.Post("/:id/add-student", middleware.RequirePermission(models.PermManualDriveOps), driveHandler.AdminAddApplication) // Change to /add-student to match frontend
+	adminGroup.Delete("/:id/applications/:studentId", middleware.RequirePermission(models.PermManualDriveOps), driveHandler.AdminRemoveApplication)
 
 	// Eligibility Preview
-	adminGroup.Post("/eligibility-preview", driveHandler.EligibilityPreview)
+	adminGroup.Post("/eligibility-preview", middleware.RequirePermission(models.PermManageDrives), driveHandler.EligibilityPreview)
 }
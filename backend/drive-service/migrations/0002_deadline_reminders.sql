@@ -0,0 +1,23 @@
+-- ==========================================
+-- DRIVE SERVICE — Migration 0002
+-- Deadline reminders for eligible students who have not responded.
+-- Owns: drive_deadline_reminders
+-- The drive-service scheduler reminds eligible students with no
+-- drive_applications row at each configured offset before deadline_date
+-- (DEADLINE_REMINDER_OFFSETS, default 48h, 24h and 6h). A row per student and
+-- offset is written before the reminder is queued, so nobody is reminded
+-- twice at the same offset.
+-- ==========================================
+
+SET search_path TO drive, public;
+
+CREATE TABLE IF NOT EXISTS drive_deadline_reminders (
+    drive_id BIGINT NOT NULL REFERENCES placement_drives(id) ON DELETE CASCADE,
+    student_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    offset_hours INTEGER NOT NULL CHECK (offset_hours > 0),
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (drive_id, student_id, offset_hours)
+);
+
+CREATE INDEX IF NOT EXISTS idx_drive_deadline_reminders_drive
+    ON drive_deadline_reminders(drive_id, offset_hours);
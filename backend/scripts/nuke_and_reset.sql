@@ -10,6 +10,7 @@ DROP SCHEMA IF EXISTS drive CASCADE;
 DROP SCHEMA IF EXISTS admin CASCADE;
 DROP SCHEMA IF EXISTS chat CASCADE;
 DROP SCHEMA IF EXISTS analytics CASCADE;
+DROP SCHEMA IF EXISTS notify CASCADE;
 
 -- 2. Clean up shared tables in public schema
 DROP TABLE IF EXISTS public.users CASCADE;
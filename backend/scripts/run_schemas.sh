@@ -48,6 +48,7 @@ if [ "$FORCE_REINIT" = "true" ]; then
         DROP SCHEMA IF EXISTS admin CASCADE;
         DROP SCHEMA IF EXISTS chat CASCADE;
         DROP SCHEMA IF EXISTS analytics CASCADE;
+        DROP SCHEMA IF EXISTS notify CASCADE;
         DROP TABLE IF EXISTS public.users CASCADE;
         DROP TABLE IF EXISTS public.departments CASCADE;
         DROP TABLE IF EXISTS public.batches CASCADE;
@@ -0,0 +1,48 @@
+#!/bin/bash
+# ============================================
+# Password Policy Sync
+# auth-service/internal/password is the one source of the password policy
+# and breached-password list. admin-service and student-service build on
+# their own, so this copies the package into each of them.
+#
+# Usage:
+#   bash scripts/sync_password_policy.sh          # write the copies
+#   bash scripts/sync_password_policy.sh --check  # fail if a copy is stale
+#
+# Also run by `go generate ./internal/password` in auth-service.
+# ============================================
+
+set -e
+
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+BACKEND_DIR="$(dirname "$SCRIPT_DIR")"
+SOURCE_DIR="$BACKEND_DIR/auth-service/internal/password"
+TARGETS="admin-service student-service"
+
+render_policy() {
+    echo "// Code generated by scripts/sync_password_policy.sh from auth-service/internal/password; DO NOT EDIT."
+    echo ""
+    cat "$SOURCE_DIR/policy.go"
+}
+
+render_breached() {
+    echo "# Generated by scripts/sync_password_policy.sh from auth-service/internal/password; do not edit."
+    cat "$SOURCE_DIR/breached_passwords.txt"
+}
+
+stale=0
+for service in $TARGETS; do
+    dir="$BACKEND_DIR/$service/internal/password"
+    if [ "$1" = "--check" ]; then
+        if ! render_policy | cmp -s - "$dir/policy.go" || ! render_breached | cmp -s - "$dir/breached_passwords.txt"; then
+            echo "$service/internal/password is out of date; run scripts/sync_password_policy.sh"
+            stale=1
+        fi
+    else
+        mkdir -p "$dir"
+        render_policy > "$dir/policy.go"
+        render_breached > "$dir/breached_passwords.txt"
+        echo "Synced $service/internal/password"
+    fi
+done
+exit $stale
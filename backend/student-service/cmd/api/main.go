@@ -18,8 +18,8 @@ import (
 )
 
 func main() {
-	// Initialize JWT Secret
-	utils.SecretKey = []byte(os.Getenv("JWT_SECRET"))
+	// Load auth-service public keys for JWT verification
+	utils.InitJWKS()
 
 	// Connect to Database
 	pool, err := database.Connect()
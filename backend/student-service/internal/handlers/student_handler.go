@@ -12,6 +12,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/placement-portal-kec/student-service/internal/database"
 	"github.com/placement-portal-kec/student-service/internal/models"
+	"github.com/placement-portal-kec/student-service/internal/password"
 	"github.com/placement-portal-kec/student-service/internal/repository"
 	"github.com/placement-portal-kec/student-service/internal/services"
 	"github.com/placement-portal-kec/student-service/internal/utils"
@@ -194,34 +195,40 @@ func (h *StudentHandler) ChangePassword(c *fiber.Ctx) error {
 	if err := c.BodyParser(&input); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
 	}
-	if input.NewPassword == "" || len(input.NewPassword) < 6 {
-		return c.Status(400).JSON(fiber.Map{"error": "New password must be at least 6 characters long"})
-	}
 	if input.NewPassword != input.ConfirmPassword {
 		return c.Status(400).JSON(fiber.Map{"error": "New passwords do not match"})
 	}
 
-	currentHash, err := h.userRepo.GetPasswordHash(c.Context(), userID)
+	policy := password.LoadPolicy()
+	creds, err := h.userRepo.GetPasswordCredentials(c.Context(), userID, policy.HistorySize)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch user data"})
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(currentHash), []byte(input.OldPassword)); err != nil {
+	if err := bcrypt.CompareHashAndPassword([]byte(creds.PasswordHash), []byte(input.OldPassword)); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Incorrect old password"})
 	}
+	if err := policy.CheckNew(input.NewPassword, creds.Email, creds.PasswordHash, creds.History); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	newHash, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to process new password"})
 	}
-	if err := h.userRepo.UpdatePassword(c.Context(), userID, string(newHash)); err != nil {
+	if err := h.userRepo.UpdatePassword(c.Context(), userID, string(newHash), policy.HistorySize); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to update password"})
 	}
 	return c.JSON(fiber.Map{"message": "Password updated successfully"})
 }
 
+// UpdateFCMToken registers the calling device for push notifications. Body:
+// {"token": "...", "platform": "android" | "ios" | "web"}; a user may have
+// several devices registered at once.
 func (h *StudentHandler) UpdateFCMToken(c *fiber.Ctx) error {
 	userID := int64(c.Locals("user_id").(float64))
 	var input struct {
-		Token string `json:"token"`
+		Token    string `json:"token"`
+		Platform string `json:"platform"`
 	}
 	if err := c.BodyParser(&input); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
@@ -229,12 +236,39 @@ func (h *StudentHandler) UpdateFCMToken(c *fiber.Ctx) error {
 	if input.Token == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "Token is required"})
 	}
-	if err := h.userRepo.UpdateFCMToken(c.Context(), userID, input.Token); err != nil {
+	platform := strings.ToLower(input.Platform)
+	switch platform {
+	case "android", "ios", "web":
+	case "":
+		platform = "unknown"
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "Platform must be android, ios or web"})
+	}
+	if err := h.userRepo.UpdateFCMToken(c.Context(), userID, input.Token, platform); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to update token", "details": err.Error()})
 	}
 	return c.JSON(fiber.Map{"message": "FCM Token updated successfully"})
 }
 
+// DeleteFCMToken unregisters the calling device, so a logged-out device stops
+// receiving the user's pushes. Body: {"token": "..."}
+func (h *StudentHandler) DeleteFCMToken(c *fiber.Ctx) error {
+	userID := int64(c.Locals("user_id").(float64))
+	var input struct {
+		Token string `json:"token"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	if input.Token == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Token is required"})
+	}
+	if err := h.userRepo.DeleteFCMToken(c.Context(), userID, input.Token); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to remove token"})
+	}
+	return c.JSON(fiber.Map{"message": "FCM Token removed"})
+}
+
 func (h *StudentHandler) CreateStudent(c *fiber.Ctx) error {
 	var input models.CreateStudentInput
 	if err := c.BodyParser(&input); err != nil {
@@ -244,10 +278,22 @@ func (h *StudentHandler) CreateStudent(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Name, Email, and Register Number are required"})
 	}
 	input.RegisterNumber = strings.ToUpper(input.RegisterNumber)
+	if scope := utils.DepartmentScope(c); scope != nil && input.Department != *scope {
+		return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You can only manage students of your department"})
+	}
 
+	// No shared default: the student sets their own password with the OTP in the
+	// welcome email, via auth-service's reset-password
+	policy := password.LoadPolicy()
 	plainPassword := input.Password
 	if plainPassword == "" {
-		plainPassword = "Student@123"
+		generated, err := policy.GenerateTemporary()
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Could not generate a temporary password"})
+		}
+		plainPassword = generated
+	} else if err := policy.Validate(plainPassword, input.Email); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(plainPassword), bcrypt.DefaultCost)
 	if err != nil {
@@ -259,15 +305,19 @@ func (h *StudentHandler) CreateStudent(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to create student.", "details": err.Error()})
 	}
 
+	// Queue the welcome email; the notification worker retries it if SMTP fails
 	otp := utils.GenerateOTP()
-	if err := h.userRepo.SaveOTP(c.Context(), input.Email, otp); err != nil {
+	if err := h.userRepo.SaveOTP(c.Context(), input.Email, otp, repository.WelcomeOTPValidity); err != nil {
 		fmt.Printf("Failed to save OTP for %s: %v\n", input.Email, err)
+	} else if err := h.userRepo.QueueEmail(c.Context(), repository.QueuedEmail{
+		Kind:           repository.JobKindWelcomeEmail,
+		Address:        input.Email,
+		Template:       "welcome",
+		Vars:           map[string]string{"name": input.FullName, "otp": otp},
+		IdempotencyKey: fmt.Sprintf("welcome:%d", user.ID),
+	}); err != nil {
+		fmt.Printf("Failed to queue welcome email for %s: %v\n", input.Email, err)
 	}
-	go func() {
-		if err := utils.SendWelcomeEmail(input.Email, input.FullName, otp); err != nil {
-			fmt.Printf("Failed to send welcome email to %s: %v\n", input.Email, err)
-		}
-	}()
 
 	// Invalidate student list cache
 	services.InvalidateCacheByPrefix(c.Context(), "students:list:")
@@ -347,6 +397,10 @@ func (h *StudentHandler) GetStudentDocumentURL(c *fiber.Ctx) error {
 		}
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch student profile"})
 	}
+	// Coordinators only see documents of their own department's students
+	if scope := utils.DepartmentScope(c); scope != nil && profile.Department != *scope {
+		return c.Status(404).JSON(fiber.Map{"error": "Student not found"})
+	}
 
 	var documentURL string
 	switch dbField {
@@ -519,29 +573,39 @@ func (h *StudentHandler) UploadChatAttachment(c *fiber.Ctx) error {
 	}
 
 	randomName := fmt.Sprintf("%d_%s%s", time.Now().UnixNano(), utils.GenerateRandomString(8), ext)
-	groupID := c.FormValue("group_id")
 	var path string
-	if groupID != "" {
+	if groupValue := c.FormValue("group_id"); groupValue != "" {
+		groupID, err := strconv.ParseInt(groupValue, 10, 64)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid group_id"})
+		}
+		isMember, err := h.userRepo.IsChatGroupMember(c.Context(), groupID, userID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to check group membership"})
+		}
+		if !isMember {
+			return c.Status(403).JSON(fiber.Map{"error": "Not a member of this group"})
+		}
 		now := time.Now()
-		path = fmt.Sprintf("chat_groups/%s/%d/%02d/%s", groupID, now.Year(), now.Month(), randomName)
+		path = fmt.Sprintf("chat_groups/%d/%d/%02d/%s", groupID, now.Year(), now.Month(), randomName)
 	} else {
 		path = fmt.Sprintf("chat_attachments/%d/%s", userID, randomName)
 	}
 
 	chatBucket := os.Getenv("GARAGE_CHAT_BUCKET")
-	var url string
 	if chatBucket != "" {
-		url, err = utils.UploadToS3Bucket(file, fileHeader, path, chatBucket)
+		_, err = utils.UploadToS3Bucket(file, fileHeader, path, chatBucket)
 	} else {
-		url, err = utils.UploadToS3(file, fileHeader, path)
+		_, err = utils.UploadToS3(file, fileHeader, path)
 	}
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf("Upload failed: %v", err)})
 	}
 
-	signedURL := utils.GenerateSignedProfileURL(url)
+	// The object key is sent as the message content; chat-service presigns
+	// it for group members on request
 	return c.JSON(fiber.Map{
-		"message": "Upload successful", "url": signedURL, "path": path,
+		"message": "Upload successful", "path": path,
 		"name": fileHeader.Filename, "size": fileHeader.Size, "type": fileHeader.Header.Get("Content-Type"),
 	})
 }
@@ -609,7 +673,7 @@ func (h *StudentHandler) DeleteMyRequest(c *fiber.Ctx) error {
 // ---- Admin Request Management ----
 
 func (h *StudentHandler) GetPendingRequests(c *fiber.Ctx) error {
-	reqs, err := h.requestRepo.GetPendingRequests()
+	reqs, err := h.requestRepo.GetPendingRequests(utils.DepartmentScope(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch requests"})
 	}
@@ -628,12 +692,14 @@ func (h *StudentHandler) ReviewRequest(c *fiber.Ctx) error {
 
 	adminID := int64(c.Locals("user_id").(float64))
 
+	// Coordinators may only review requests from their own department
+	req, err := h.requestRepo.GetRequestByID(id, utils.DepartmentScope(c))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Request not found"})
+	}
+
 	switch input.Action {
 	case "approve":
-		req, err := h.requestRepo.GetRequestByID(id)
-		if err != nil {
-			return c.Status(404).JSON(fiber.Map{"error": "Request not found"})
-		}
 		if err := h.requestRepo.UpdateRequestStatus(id, "approved", adminID, nil); err != nil {
 			if len(err.Error()) > 8 && err.Error()[:8] == "CONFLICT" {
 				return c.Status(409).JSON(fiber.Map{"error": err.Error(), "code": "CONFLICT"})
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Email job kinds queued by student-service, matching admin-service's
+const (
+	JobKindWelcomeEmail = "welcome_email"
+)
+
+// QueuedEmail is a templated email for the shared notify.jobs queue, which
+// admin-service's notification workers render and send with retries.
+type QueuedEmail struct {
+	Kind           string
+	Address        string
+	Template       string // admin-service emails template name
+	Vars           map[string]string
+	IdempotencyKey string // a repeated key is not queued again
+}
+
+// QueueEmail puts e on the notify.jobs queue. Account emails like the
+// welcome email carry no notification category, so no preference stops them.
+func (r *UserRepository) QueueEmail(ctx context.Context, e QueuedEmail) error {
+	payload, err := json.Marshal(map[string]any{
+		"address":        e.Address,
+		"email_template": e.Template,
+		"vars":           e.Vars,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.DB.Exec(ctx, `
+		INSERT INTO notify.jobs (provider, kind, source, payload, idempotency_key)
+		VALUES ('EMAIL', $1, 'student-service', $2::jsonb, NULLIF($3, ''))
+		ON CONFLICT (idempotency_key) DO NOTHING`,
+		e.Kind, payload, e.IdempotencyKey)
+	return err
+}
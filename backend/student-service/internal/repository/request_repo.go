@@ -24,17 +24,19 @@ func (r *RequestRepository) CreateRequest(req *models.StudentChangeRequest) erro
 	return err
 }
 
-func (r *RequestRepository) GetPendingRequests() ([]models.StudentChangeRequest, error) {
+// GetPendingRequests lists pending change requests, limited to students of
+// deptScope when set
+func (r *RequestRepository) GetPendingRequests(deptScope *string) ([]models.StudentChangeRequest, error) {
 	query := `
         SELECT r.id, r.student_id, r.field_name, r.old_value, r.new_value, r.reason, r.status, r.created_at,
                COALESCE(u.name, 'Unknown'), sp.register_number 
         FROM student_change_requests r
         JOIN users u ON r.student_id = u.id
         LEFT JOIN student_personal sp ON u.id = sp.user_id
-        WHERE r.status = 'pending'
+        WHERE r.status = 'pending' AND ($1::text IS NULL OR sp.department = $1)
         ORDER BY r.created_at ASC
     `
-	rows, err := r.DB.Query(context.Background(), query)
+	rows, err := r.DB.Query(context.Background(), query, deptScope)
 	if err != nil {
 		return nil, err
 	}
@@ -93,10 +95,15 @@ func (r *RequestRepository) UpdateRequestStatus(id int64, status string, handled
 	return nil
 }
 
-func (r *RequestRepository) GetRequestByID(id int64) (*models.StudentChangeRequest, error) {
+// GetRequestByID fetches a change request. Requests from students outside
+// deptScope (when set) are reported as not found.
+func (r *RequestRepository) GetRequestByID(id int64, deptScope *string) (*models.StudentChangeRequest, error) {
 	var req models.StudentChangeRequest
-	query := `SELECT id, student_id, field_name, old_value, new_value, reason, status FROM student_change_requests WHERE id = $1`
-	err := r.DB.QueryRow(context.Background(), query, id).Scan(
+	query := `SELECT r.id, r.student_id, r.field_name, r.old_value, r.new_value, r.reason, r.status
+              FROM student_change_requests r
+              LEFT JOIN student_personal sp ON r.student_id = sp.user_id
+              WHERE r.id = $1 AND ($2::text IS NULL OR sp.department = $2)`
+	err := r.DB.QueryRow(context.Background(), query, id, deptScope).Scan(
 		&req.ID, &req.StudentID, &req.FieldName, &req.OldValue, &req.NewValue, &req.Reason, &req.Status,
 	)
 	if err != nil {
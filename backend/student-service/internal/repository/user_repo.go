@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/placement-portal-kec/student-service/internal/models"
@@ -47,20 +48,105 @@ func (r *UserRepository) GetRegisterNumber(ctx context.Context, userID int64) (s
 	return regNo, nil
 }
 
-func (r *UserRepository) UpdateFCMToken(ctx context.Context, userID int64, token string) error {
-	_, err := r.DB.Exec(ctx, `UPDATE users SET fcm_token = $1 WHERE id = $2`, token, userID)
-	return err
+// IsChatGroupMember reports whether userID belongs to the chat group, so
+// uploads can only be written under groups the user can post to
+func (r *UserRepository) IsChatGroupMember(ctx context.Context, groupID, userID int64) (bool, error) {
+	var isMember bool
+	err := r.DB.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM chat.chat_group_members WHERE group_id = $1 AND user_id = $2)`, groupID, userID).Scan(&isMember)
+	return isMember, err
 }
 
-func (r *UserRepository) GetPasswordHash(ctx context.Context, userID int64) (string, error) {
-	var hash string
-	err := r.DB.QueryRow(ctx, `SELECT password_hash FROM users WHERE id = $1`, userID).Scan(&hash)
-	return hash, err
+// UpdateFCMToken registers one of the user's push devices. A token already
+// registered (even to another user, after a shared device's logout/login)
+// moves to this user; users.fcm_token keeps the latest for older readers.
+func (r *UserRepository) UpdateFCMToken(ctx context.Context, userID int64, token, platform string) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO notify.device_tokens (user_id, token, platform)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (token) DO UPDATE
+		SET user_id = EXCLUDED.user_id, platform = EXCLUDED.platform, last_seen_at = NOW()`,
+		userID, token, platform); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE users SET fcm_token = $1 WHERE id = $2`, token, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-func (r *UserRepository) UpdatePassword(ctx context.Context, userID int64, passwordHash string) error {
-	_, err := r.DB.Exec(ctx, `UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2`, passwordHash, userID)
-	return err
+// DeleteFCMToken unregisters one of the user's push devices, e.g. on logout
+func (r *UserRepository) DeleteFCMToken(ctx context.Context, userID int64, token string) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM notify.device_tokens WHERE user_id = $1 AND token = $2`, userID, token); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE users SET fcm_token = (
+			SELECT token FROM notify.device_tokens WHERE user_id = $1 ORDER BY last_seen_at DESC LIMIT 1)
+		WHERE id = $1 AND fcm_token = $2`, userID, token); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// PasswordCredentials is what a password change is checked against
+type PasswordCredentials struct {
+	Email        string
+	PasswordHash string
+	History      []string // previous hashes, newest first
+}
+
+// GetPasswordCredentials fetches a user's email, current hash and newest
+// historySize previous hashes from auth.password_history.
+func (r *UserRepository) GetPasswordCredentials(ctx context.Context, userID int64, historySize int) (*PasswordCredentials, error) {
+	var creds PasswordCredentials
+	err := r.DB.QueryRow(ctx, `
+		SELECT email, password_hash,
+		       COALESCE(ARRAY(SELECT h.password_hash FROM auth.password_history h
+		                      WHERE h.user_id = u.id ORDER BY h.created_at DESC, h.id DESC LIMIT $2), '{}')
+		FROM users u WHERE u.id = $1`, userID, max(historySize, 0)).Scan(&creds.Email, &creds.PasswordHash, &creds.History)
+	if err != nil {
+		return nil, err
+	}
+	return &creds, nil
+}
+
+// UpdatePassword sets a new password hash, clears the forced-change flag and
+// records the hash in auth.password_history (keeping the newest historySize).
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID int64, passwordHash string, historySize int) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `UPDATE users SET password_hash = $1, must_change_password = FALSE, password_changed_at = NOW(), updated_at = NOW() WHERE id = $2`, passwordHash, userID); err != nil {
+		return err
+	}
+	if historySize > 0 {
+		if _, err := tx.Exec(ctx, `INSERT INTO auth.password_history (user_id, password_hash) VALUES ($1, $2)`, userID, passwordHash); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM auth.password_history
+			WHERE user_id = $1 AND id NOT IN (
+				SELECT id FROM auth.password_history WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2
+			)`, userID, historySize); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
 }
 
 func (r *UserRepository) UpdateUserProfile(ctx context.Context, userID int64, name, photoURL string) error {
@@ -82,10 +168,18 @@ func (r *UserRepository) GetUserByID(ctx context.Context, userID int64) (*models
 	return &user, nil
 }
 
-func (r *UserRepository) SaveOTP(ctx context.Context, email, otp string) error {
-	query := `INSERT INTO password_resets (email, otp, created_at, expires_at) VALUES ($1, $2, NOW(), NOW() + INTERVAL '10 minutes')
-	           ON CONFLICT (email) DO UPDATE SET otp = $2, created_at = NOW(), expires_at = NOW() + INTERVAL '10 minutes'`
-	_, err := r.DB.Exec(ctx, query, email, otp)
+// WelcomeOTPValidity is how long the code in a new student's welcome email
+// can be used to set their password
+const WelcomeOTPValidity = 72 * time.Hour
+
+// SaveOTP stores a code in auth.password_resets, replacing any earlier one.
+// auth-service's POST /api/v1/auth/reset-password redeems it.
+func (r *UserRepository) SaveOTP(ctx context.Context, email, otp string, validFor time.Duration) error {
+	_, err := r.DB.Exec(ctx, `
+		INSERT INTO auth.password_resets (email, otp_code, expires_at, attempts)
+		VALUES (LOWER($1), $2, NOW() + make_interval(secs => $3), 0)
+		ON CONFLICT (email) DO UPDATE SET otp_code = EXCLUDED.otp_code, expires_at = EXCLUDED.expires_at, attempts = 0`,
+		email, otp, validFor.Seconds())
 	return err
 }
 
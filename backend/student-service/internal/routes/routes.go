@@ -4,6 +4,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/placement-portal-kec/student-service/internal/handlers"
 	"github.com/placement-portal-kec/student-service/internal/middleware"
+	"github.com/placement-portal-kec/student-service/internal/models"
 )
 
 func SetupRoutes(app *fiber.App, h *handlers.StudentHandler) {
@@ -25,6 +26,7 @@ func SetupRoutes(app *fiber.App, h *handlers.StudentHandler) {
 	// Generic User Actions
 	api.Post("/user/upload/profile-photo", h.UploadProfilePicture)
 	api.Post("/user/fcm-token", h.UpdateFCMToken)
+	api.Delete("/user/fcm-token", h.DeleteFCMToken)
 	api.Post("/chat/upload", h.UploadChatAttachment)
 
 	// Student Change Requests
@@ -34,10 +36,10 @@ func SetupRoutes(app *fiber.App, h *handlers.StudentHandler) {
 
 	// Admin-only routes
 	admin := api.Group("/admin", middleware.AdminOnly)
-	admin.Post("/students", h.CreateStudent)
-	admin.Get("/students/:student_id/documents/:type", h.GetStudentDocumentURL)
+	admin.Post("/students", middleware.RequirePermission(models.PermManageStudents), h.CreateStudent)
+	admin.Get("/students/:student_id/documents/:type", middleware.RequirePermission(models.PermManageStudents), h.GetStudentDocumentURL)
 
 	// Admin: Request Management
-	admin.Get("/requests", h.GetPendingRequests)
-	admin.Put("/requests/:id", h.ReviewRequest)
+	admin.Get("/requests", middleware.RequirePermission(models.PermApproveChanges), h.GetPendingRequests)
+	admin.Put("/requests/:id", middleware.RequirePermission(models.PermApproveChanges), h.ReviewRequest)
 }
@@ -4,12 +4,9 @@ import (
 	"crypto/rand"
 	"fmt"
 	"math/big"
-	"os"
 	"strings"
 )
 
-var SecretKey = []byte(os.Getenv("JWT_SECRET"))
-
 func GenerateOTP() string {
 	n, _ := rand.Int(rand.Reader, big.NewInt(900000))
 	return fmt.Sprintf("%06d", n.Int64()+100000)
@@ -25,12 +22,6 @@ func GenerateRandomString(length int) string {
 	return string(b)
 }
 
-// SendWelcomeEmail is a stub — the full implementation would use SMTP
-func SendWelcomeEmail(email, name, otp string) error {
-	fmt.Printf("[StudentService] Welcome email to %s (%s) with OTP: %s\n", email, name, otp)
-	return nil
-}
-
 // SanitizeFileName is imported from s3.go, but redeclared here as a fallback
 // It replaces non-alphanumeric characters with underscores
 func SanitizeInput(name string) string {
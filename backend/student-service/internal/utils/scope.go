@@ -0,0 +1,14 @@
+package utils
+
+import "github.com/gofiber/fiber/v2"
+
+// DepartmentScope returns the department a coordinator is restricted to, or nil
+// for admins and super admins who see every department. Repositories take the
+// result as their deptScope argument so scoping is applied in SQL.
+func DepartmentScope(c *fiber.Ctx) *string {
+	if role, _ := c.Locals("role").(string); role != "coordinator" {
+		return nil
+	}
+	dept, _ := c.Locals("department_code").(string)
+	return &dept
+}
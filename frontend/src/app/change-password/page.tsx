@@ -0,0 +1,99 @@
+'use client';
+
+import { useState } from 'react';
+import { useRouter } from 'next/navigation';
+import { authService } from '@/services/auth.service';
+import { useAuth } from '@/context/auth-context';
+import { APP_ROUTES } from '@/constants/routes';
+import { Button } from '@/components/ui/button';
+import { Input } from '@/components/ui/input';
+import { Card, CardContent, CardDescription, CardFooter, CardHeader, CardTitle } from '@/components/ui/card';
+import { Label } from '@/components/ui/label';
+import { toast } from 'sonner';
+import { Loader2, ArrowLeft } from 'lucide-react';
+
+// Shown after logging in with an admin-assigned password. The login token only
+// allows this one call; the token it returns replaces it.
+export default function ChangePasswordPage() {
+  const router = useRouter();
+  const { logout } = useAuth();
+  const [passwords, setPasswords] = useState({ old_password: '', new_password: '', confirm_password: '' });
+  const [loading, setLoading] = useState(false);
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    if (passwords.new_password !== passwords.confirm_password) {
+      toast.error('New passwords do not match');
+      return;
+    }
+    setLoading(true);
+    try {
+      const { token } = await authService.changePassword(passwords);
+      localStorage.setItem('token', token);
+      toast.success('Password changed successfully');
+      router.push(APP_ROUTES.DASHBOARD);
+    } catch (error: any) {
+      // The api interceptor has already shown the server's message
+      console.warn('[ChangePasswordPage] Change failed:', error?.message);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  return (
+    <div className="flex items-center justify-center min-h-screen bg-[#f0f4f8] p-4">
+      <Card className="w-full max-w-[400px] shadow-lg">
+        <CardHeader className="text-center">
+          <CardTitle className="text-xl text-[#002147]">Change Password</CardTitle>
+          <CardDescription>Your password was set by an administrator. Choose a new one to continue.</CardDescription>
+        </CardHeader>
+        <CardContent>
+          <form onSubmit={handleSubmit} className="space-y-4">
+            <div className="space-y-2">
+              <Label htmlFor="oldPassword">Current Password</Label>
+              <Input
+                id="oldPassword"
+                type="password"
+                value={passwords.old_password}
+                onChange={(e) => setPasswords({ ...passwords, old_password: e.target.value })}
+                required
+              />
+            </div>
+            <div className="space-y-2">
+              <Label htmlFor="newPassword">New Password</Label>
+              <Input
+                id="newPassword"
+                type="password"
+                placeholder="••••••••"
+                value={passwords.new_password}
+                onChange={(e) => setPasswords({ ...passwords, new_password: e.target.value })}
+                required
+                minLength={8}
+              />
+            </div>
+            <div className="space-y-2">
+              <Label htmlFor="confirmPassword">Confirm New Password</Label>
+              <Input
+                id="confirmPassword"
+                type="password"
+                placeholder="••••••••"
+                value={passwords.confirm_password}
+                onChange={(e) => setPasswords({ ...passwords, confirm_password: e.target.value })}
+                required
+                minLength={8}
+              />
+            </div>
+            <Button className="w-full bg-[#002147]" type="submit" disabled={loading}>
+              {loading ? <Loader2 className="animate-spin h-4 w-4" /> : 'Change Password'}
+            </Button>
+          </form>
+        </CardContent>
+        <CardFooter className="flex justify-center border-t py-4">
+          <Button variant="ghost" size="sm" className="text-muted-foreground" onClick={logout}>
+            <ArrowLeft className="mr-2 h-4 w-4" /> Back to Login
+          </Button>
+        </CardFooter>
+      </Card>
+    </div>
+  );
+}
@@ -219,12 +219,8 @@ export default function ChatArea({ groupId, groupName, groupImage, groupType, se
               await ChatService.pinMessage(msgId); // toggles pin off
           }
           await ChatService.deleteMessage(msgId, true);
+          // The server announces the deletion to the other members
           setMessages(prev => prev.map(m => m.id === msgId ? { ...m, type: 'text', content: '', metadata: { isDeleted: true }, is_pinned: false } : m));
-          
-          // Broadcast deletion to other connected clients via WebSocket
-          if (groupId) {
-              ChatService.broadcastDeleteForEveryone(groupId, msgId);
-          }
           toast.success('Message deleted for everyone');
       } catch(e) { console.error('Delete failed', e); toast.error('Delete failed'); }
   }
@@ -758,18 +754,39 @@ const getFileIconProps = (filename: string) => {
     return { ext, styles };
 };
 
+// Messages carry the object key; the link is presigned for group members on
+// request and only stays valid for a few minutes
+function useAttachmentUrl(msg: ChatMessage) {
+    const [url, setUrl] = useState<string | null>(null);
+    const isOptimistic = (msg as any).isOptimistic;
+
+    useEffect(() => {
+        if (isOptimistic) return;
+        let cancelled = false;
+        ChatService.getAttachmentUrls(msg.id)
+            .then((attachments: { url: string }[]) => {
+                if (!cancelled) setUrl(attachments?.[0]?.url ?? null);
+            })
+            .catch((err) => console.error('Failed to load attachment', err));
+        return () => { cancelled = true; };
+    }, [msg.id, isOptimistic]);
+
+    return url;
+}
+
 function FilePreviewCard({ msg, isMe }: { msg: ChatMessage, isMe: boolean }) {
+    const attachmentUrl = useAttachmentUrl(msg);
     const isImage = msg.type === 'image';
     const isAudio = msg.metadata?.mimeType?.startsWith('audio/') || msg.metadata?.isVoice;
     
     if (isImage) {
         return (
-            <div className={`relative aspect-video w-48 bg-gray-100 rounded-lg overflow-hidden cursor-pointer border ${isMe ? 'border-primary/20' : 'border-gray-200'}`} onClick={() => window.open(msg.content, '_blank')}>
-                <img 
-                    src={msg.content} 
+            <div className={`relative aspect-video w-48 bg-gray-100 rounded-lg overflow-hidden cursor-pointer border ${isMe ? 'border-primary/20' : 'border-gray-200'}`} onClick={() => attachmentUrl && window.open(attachmentUrl, '_blank')}>
+                {attachmentUrl && <img 
+                    src={attachmentUrl} 
                     alt="Attached image" 
                     className="object-cover w-full h-full hover:scale-105 transition-transform duration-300"
-                />
+                />}
             </div>
         );
     }
@@ -782,7 +799,7 @@ function FilePreviewCard({ msg, isMe }: { msg: ChatMessage, isMe: boolean }) {
               : 'bg-white text-gray-900 border border-gray-200'
             }`}>
                 <Mic className={`h-4 w-4 shrink-0 ${isMe ? 'text-primary-foreground/70' : 'text-gray-400'}`} />
-                <audio controls src={msg.content} className="h-8 w-full [&::-webkit-media-controls-panel]:bg-transparent" style={{ minWidth: 0 }} />
+                <audio controls src={attachmentUrl ?? undefined} className="h-8 w-full [&::-webkit-media-controls-panel]:bg-transparent" style={{ minWidth: 0 }} />
             </div>
         );
     }
@@ -813,7 +830,8 @@ function FilePreviewCard({ msg, isMe }: { msg: ChatMessage, isMe: boolean }) {
         if (isDownloading) return;
         setIsDownloading(true);
         try {
-            const proxyUrl = `/api/proxy/download?url=${encodeURIComponent(msg.content)}&filename=${encodeURIComponent(displayFileName)}`;
+            if (!attachmentUrl) throw new Error('Attachment link not loaded');
+            const proxyUrl = `/api/proxy/download?url=${encodeURIComponent(attachmentUrl)}&filename=${encodeURIComponent(displayFileName)}`;
             const response = await fetch(proxyUrl);
             const blob = await response.blob();
             const blobUrl = window.URL.createObjectURL(blob);
@@ -851,7 +869,8 @@ function FilePreviewCard({ msg, isMe }: { msg: ChatMessage, isMe: boolean }) {
         // Fetch and cache, then open
         setIsOpening(true);
         try {
-            const proxyUrl = `/api/proxy/download?url=${encodeURIComponent(msg.content)}&filename=${encodeURIComponent(displayFileName)}`;
+            if (!attachmentUrl) throw new Error('Attachment link not loaded');
+            const proxyUrl = `/api/proxy/download?url=${encodeURIComponent(attachmentUrl)}&filename=${encodeURIComponent(displayFileName)}`;
             const response = await fetch(proxyUrl);
             const blob = await response.blob();
             const blobUrl = window.URL.createObjectURL(blob);
@@ -211,7 +211,7 @@ export default function ChatInput({ onSend, onTyping, groupId }: ChatInputProps)
       try {
         const result = await ChatService.uploadAttachment(file, groupId ? Number(groupId) : undefined);
         const type = file.type.startsWith('image/') ? 'image' : 'file';
-        onSend(result.url, type, { 
+        onSend(result.path, type, { 
           name: file.name, 
           size: file.size, 
           mimeType: file.type 
@@ -417,7 +417,7 @@ export default function ChatInput({ onSend, onTyping, groupId }: ChatInputProps)
     setIsUploading(true);
     try {
       const result = await ChatService.uploadAttachment(audioFile, groupId ? Number(groupId) : undefined);
-      onSend(result.url, 'file', { 
+      onSend(result.path, 'file', { 
         name: "Voice Message", 
         size: audioFile.size,
         mimeType: 'audio/webm',
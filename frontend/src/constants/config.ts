@@ -8,8 +8,9 @@ export const API_ROUTES = {
   ADMIN_AUTH: {
     LOGIN: '/v1/auth/login',
     REGISTER: '/v1/auth/register',
-    FORGOT_PASSWORD: '/v1/admin/auth/forgot-password',
-    RESET_PASSWORD: '/v1/admin/auth/reset-password',
+    FORGOT_PASSWORD: '/v1/auth/forgot-password',
+    RESET_PASSWORD: '/v1/auth/reset-password',
+    CHANGE_PASSWORD: '/v1/auth/password',
   },
   STUDENT_AUTH: {
     LOGIN: '/v1/auth/login',
@@ -30,7 +31,8 @@ export const API_ROUTES = {
   },
   SETTINGS: {
     FIELDS: '/v1/admin/settings/fields',
-    REQUESTS: '/v1/admin/requests'
+    REQUESTS: '/v1/admin/requests',
+    EMAIL_TEMPLATES: '/v1/admin/email-templates'
   },
   SUPER_ADMIN: {
     USERS: '/v1/super-admin/users',
@@ -17,6 +17,7 @@ import { UserRole } from '@/types/auth';
 export const APP_ROUTES = {
   DASHBOARD: '/dashboard',
   LOGIN: '/login',
+  CHANGE_PASSWORD: '/change-password',
   SIGNUP: '/signup', // Kept for reference, though unused
 };
 
@@ -150,7 +150,9 @@ export const AuthProvider = ({ children }: { children: React.ReactNode }) => {
         localStorage.setItem('user', JSON.stringify(userData));
         setUser(userData);
         setIsAuthenticated(true);
-        router.push(PAGE_ROUTES.DASHBOARD);
+        // An admin-assigned password must be replaced before anything else;
+        // the token only works for the change-password call until then.
+        router.push(responseData.must_change_password ? PAGE_ROUTES.CHANGE_PASSWORD : PAGE_ROUTES.DASHBOARD);
         return true;
       } else {
         toast.error("Login failed. No token received.");
@@ -66,7 +66,12 @@ api.interceptors.response.use(
         : typeof error.response?.data?.message === 'string' ? error.response?.data?.message 
         : 'An error occurred';
 
-    if (status === 401 || status === 403) {
+    // Restricted token: the user has to change their password first
+    if (status === 403 && error.response?.data?.code === 'PASSWORD_CHANGE_REQUIRED') {
+      if (typeof window !== 'undefined' && window.location.pathname !== '/change-password') {
+        window.location.href = '/change-password';
+      }
+    } else if (status === 401 || status === 403) {
       const isLoginUrl = error.config?.url?.includes('/login');
       if (isLoginUrl) {
         toast.error('Invalid credentials');
@@ -11,4 +11,11 @@ export const authService = {
     const response = await api.post(API_ROUTES.ADMIN_AUTH.RESET_PASSWORD, data);
     return response.data;
   },
+
+  // Works with the restricted token issued when a password change is required;
+  // the response carries a full token that replaces it.
+  changePassword: async (data: { old_password: string; new_password: string; confirm_password: string }) => {
+    const response = await api.put(API_ROUTES.ADMIN_AUTH.CHANGE_PASSWORD, data);
+    return response.data as { message: string; token: string };
+  },
 };
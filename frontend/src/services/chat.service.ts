@@ -38,6 +38,31 @@ export interface ChatMessage {
   forwarded?: boolean;
 }
 
+// In-app notification; new ones also arrive over the socket as
+// { type: 'notification', notification }
+export interface InboxNotification {
+  id: number;
+  user_id: number;
+  category: 'drive_announcement' | 'application_status' | 'change_request' | 'broadcast' | string;
+  type: string;
+  title: string;
+  body: string;
+  data: Record<string, string>;
+  read_at: string | null;
+  created_at: string;
+}
+
+// Daily window ("HH:MM", in timezone) during which non-critical
+// notifications are held back
+export interface QuietHours {
+  enabled: boolean;
+  start: string;
+  end: string;
+  timezone: string;
+}
+
+export type DigestFrequency = 'weekly' | 'daily';
+
 class ChatServiceClass {
   private ws: WebSocket | null = null;
   private messageHandlers: ((msg: any) => void)[] = [];
@@ -78,6 +103,73 @@ class ChatServiceClass {
       return response.data;
   }
 
+  async getNotifications(params: { unread?: boolean, category?: string, before?: number, limit?: number } = {}): Promise<{ notifications: InboxNotification[], has_more: boolean, unread_count: number }> {
+      const token = getAuthToken();
+      const response = await axios.get(`${CHAT_API_URL}/notifications`, {
+          params,
+          headers: { Authorization: `Bearer ${token}` }
+      });
+      return response.data;
+  }
+
+  async getUnreadNotificationCount(): Promise<number> {
+      const token = getAuthToken();
+      const response = await axios.get(`${CHAT_API_URL}/notifications/unread-count`, {
+          headers: { Authorization: `Bearer ${token}` }
+      });
+      return response.data.unread_count;
+  }
+
+  async markNotificationRead(id: number) {
+      const token = getAuthToken();
+      const response = await axios.post(`${CHAT_API_URL}/notifications/${id}/read`, null, {
+          headers: { Authorization: `Bearer ${token}` }
+      });
+      return response.data;
+  }
+
+  async getNotificationPreferences(): Promise<{ preferences: Record<string, Record<string, boolean>>, quiet_hours: QuietHours | null, digest_frequency: DigestFrequency }> {
+      const token = getAuthToken();
+      const response = await axios.get(`${CHAT_API_URL}/notifications/preferences`, {
+          headers: { Authorization: `Bearer ${token}` }
+      });
+      return response.data;
+  }
+
+  async updateNotificationPreferences(preferences: Record<string, Record<string, boolean>>) {
+      const token = getAuthToken();
+      const response = await axios.put(`${CHAT_API_URL}/notifications/preferences`, { preferences }, {
+          headers: { Authorization: `Bearer ${token}` }
+      });
+      return response.data;
+  }
+
+  async updateQuietHours(quietHours: QuietHours) {
+      const token = getAuthToken();
+      const response = await axios.put(`${CHAT_API_URL}/notifications/quiet-hours`, quietHours, {
+          headers: { Authorization: `Bearer ${token}` }
+      });
+      return response.data;
+  }
+
+  // Turning digests off is the "digest" EMAIL preference
+  async updateDigestFrequency(frequency: DigestFrequency) {
+      const token = getAuthToken();
+      const response = await axios.put(`${CHAT_API_URL}/notifications/digest`, { frequency }, {
+          headers: { Authorization: `Bearer ${token}` }
+      });
+      return response.data;
+  }
+
+  async markAllNotificationsRead(category?: string) {
+      const token = getAuthToken();
+      const response = await axios.post(`${CHAT_API_URL}/notifications/read-all`, null, {
+          params: category ? { category } : undefined,
+          headers: { Authorization: `Bearer ${token}` }
+      });
+      return response.data;
+  }
+
   async createGroup(name: string, type: 'direct' | 'group', memberIds: number[], creatorId: number) {
     const token = getAuthToken();
     const response = await axios.post(`${CHAT_API_URL}/groups?user_id=${creatorId}`, { name, type, member_ids: memberIds }, {
@@ -86,12 +178,13 @@ class ChatServiceClass {
     return response.data;
   }
 
-  async getHistory(groupId: number, days: number = 30) {
+  async getHistory(groupId: number, cursor: { before?: number; after?: number; around?: number; limit?: number } = {}) {
     const token = getAuthToken();
-    const response = await axios.get(`${CHAT_API_URL}/groups/${groupId}/messages?days=${days}`, {
+    const response = await axios.get(`${CHAT_API_URL}/groups/${groupId}/messages`, {
+      params: cursor,
       headers: { Authorization: `Bearer ${token}` }
     });
-    // Backend now returns { messages: [], has_older: bool, start_date: string }
+    // Backend returns { messages: [], has_older: bool, has_newer: bool }
     return response.data;
   }
 
@@ -115,6 +208,15 @@ class ChatServiceClass {
     return response.data;
   }
 
+  async getAttachmentUrls(messageId: number) {
+    const token = getAuthToken();
+    const response = await axios.get(`${CHAT_API_URL}/messages/${messageId}/attachments`, {
+      headers: { Authorization: `Bearer ${token}` }
+    });
+    // { attachments: [{ id, name, type, size, url, expires_at }] }
+    return response.data.attachments;
+  }
+
   // WebSocket Methods
   connect() {
     if (this.ws?.readyState === WebSocket.OPEN || this.ws?.readyState === WebSocket.CONNECTING) return;
@@ -243,16 +345,6 @@ class ChatServiceClass {
       });
   }
 
-  broadcastDeleteForEveryone(groupId: number, messageId: number) {
-      if (this.ws && this.ws.readyState === WebSocket.OPEN) {
-          this.ws.send(JSON.stringify({
-              type: 'message_deleted',
-              group_id: groupId,
-              content: String(messageId), // reuse content field for message ID
-          }));
-      }
-  }
-
   async forwardMessage(groupId: number, messageId: number) {
       // For now, we can reuse sendMessage logic in the UI or backend.
       // Ideally backend handles "forward" to copy content.
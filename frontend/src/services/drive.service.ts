@@ -32,6 +32,29 @@ export interface DriveApplicant {
   applied_at: string;
 }
 
+export interface ResponseCounts {
+  eligible: number;
+  opted_in: number;
+  opted_out: number;
+  pending: number;
+  response_rate: number;
+}
+
+export interface DriveResponseReport {
+  drive_id: number;
+  company_name: string;
+  deadline_date: string;
+  total: ResponseCounts;
+  departments: (ResponseCounts & { department_code: string })[];
+  reminders: {
+    offset_hours: number;
+    reminded: number;
+    responded_after: number;
+    first_sent_at: string | null;
+    conversion_rate: number;
+  }[];
+}
+
 export interface DriveApplicantDetailed {
   // User Info
   id: number;
@@ -311,6 +334,11 @@ export const driveService = {
     return response.data || [];
   },
 
+  getDriveResponseReport: async (driveId: number) => {
+    const response = await api.get<DriveResponseReport>(`${API_ROUTES.ADMIN_DRIVES}/${driveId}/response-report`);
+    return response.data;
+  },
+
   exportDriveApplicants: async (driveId: number, studentIds?: number[]) => {
     const response = await api.post<DriveApplicantDetailed[]>(`${API_ROUTES.ADMIN_DRIVES}/${driveId}/export`, { student_ids: studentIds });
     return response.data;
@@ -21,6 +21,22 @@ export interface StudentChangeRequest {
   field_label?: string;
 }
 
+export interface EmailTemplate {
+  name: string;
+  description: string;
+  variables: string[];
+  sample: Record<string, string>;
+  languages: string[];
+}
+
+export interface EmailPreview {
+  template: string;
+  language: string;
+  subject: string;
+  html: string;
+  text: string;
+}
+
 export const settingsService = {
   // Get all field permissions
   getPermissions: async () => {
@@ -46,5 +62,19 @@ export const settingsService = {
   handleRequest: async (id: number, action: 'approve' | 'reject') => {
     const response = await api.post(`${API_ROUTES.SETTINGS.REQUESTS}/${id}?action=${action}`);
     return response.data;
+  },
+
+  // List email templates and the languages they are translated into
+  getEmailTemplates: async () => {
+    const response = await api.get<{ templates: EmailTemplate[]; languages: string[] }>(API_ROUTES.SETTINGS.EMAIL_TEMPLATES);
+    return response.data;
+  },
+
+  // Render a template with the current branding; vars override its sample values
+  previewEmailTemplate: async (name: string, lang = 'en', vars: Record<string, string> = {}) => {
+    const response = await api.get<EmailPreview>(`${API_ROUTES.SETTINGS.EMAIL_TEMPLATES}/${name}/preview`, {
+      params: { ...vars, lang },
+    });
+    return response.data;
   }
 };
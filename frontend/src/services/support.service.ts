@@ -0,0 +1,46 @@
+import api from "@/lib/api";
+
+export type SupportStatus = 'open' | 'replied' | 'closed';
+
+// A message sent to the college's WhatsApp number. Student fields are set
+// when the sender's number matches a student's mobile number.
+export interface SupportMessage {
+    id: number;
+    channel: string;
+    from_address: string;
+    contact_name: string | null;
+    user_id: number | null;
+    student_name?: string;
+    register_number?: string;
+    department?: string;
+    message_type: string;
+    body: string;
+    received_at: string;
+    status: SupportStatus;
+    reply: string | null;
+    replied_by: number | null;
+    replied_at: string | null;
+}
+
+export interface SupportMessageList {
+    messages: SupportMessage[];
+    total: number;
+}
+
+export const supportService = {
+  listMessages: async (params: { status?: SupportStatus; limit?: number; offset?: number } = {}) => {
+    const response = await api.get<SupportMessageList>('/v1/admin/support/messages', { params });
+    return response.data;
+  },
+
+  // Replies go out on WhatsApp, which only accepts free text within 24 hours
+  // of the sender's last message
+  reply: async (id: number, message: string) => {
+    const response = await api.post<{ message: string; job_id: number }>(`/v1/admin/support/messages/${id}/reply`, { message });
+    return response.data;
+  },
+
+  setStatus: async (id: number, status: 'open' | 'closed') => {
+    await api.patch(`/v1/admin/support/messages/${id}`, { status });
+  }
+};
@@ -21,6 +21,7 @@ export interface AuthResponse {
   department_code?: string;
   permissions?: string[];
   profile_photo_url?: string;
+  must_change_password?: boolean;
 }
 
 export interface LoginCredentials {
@@ -13,9 +13,14 @@ AuthService authService(Ref ref) {
 /// State class to hold login result
 class AuthState {
   final bool isProfileComplete;
+  final bool mustChangePassword;
   final String? error;
 
-  const AuthState({this.isProfileComplete = false, this.error});
+  const AuthState({
+    this.isProfileComplete = false,
+    this.mustChangePassword = false,
+    this.error,
+  });
 }
 
 @riverpod
@@ -27,9 +32,15 @@ class AuthController extends _$AuthController {
     final isProfileComplete = await ref
         .read(authServiceProvider)
         .isProfileComplete();
+    final mustChangePassword = await ref
+        .read(authServiceProvider)
+        .mustChangePassword();
 
     if (token != null && token.isNotEmpty) {
-      return AuthState(isProfileComplete: isProfileComplete);
+      return AuthState(
+        isProfileComplete: isProfileComplete,
+        mustChangePassword: mustChangePassword,
+      );
     }
     return null;
   }
@@ -44,10 +55,32 @@ class AuthController extends _$AuthController {
       // CRITICAL: Invalidate self to trigger rebuild and router redirect
       ref.invalidateSelf();
 
-      return AuthState(isProfileComplete: response.isProfileComplete);
+      return AuthState(
+        isProfileComplete: response.isProfileComplete,
+        mustChangePassword: response.mustChangePassword,
+      );
     });
   }
 
+  /// Called when the API refuses the token with PASSWORD_CHANGE_REQUIRED
+  Future<void> requirePasswordChange() async {
+    await ref.read(authServiceProvider).setMustChangePassword(true);
+    state = AsyncValue.data(
+      AuthState(
+        isProfileComplete: state.value?.isProfileComplete ?? false,
+        mustChangePassword: true,
+      ),
+    );
+  }
+
+  /// Called after the forced change; the new token is already stored
+  Future<void> passwordChanged() async {
+    await ref.read(authServiceProvider).setMustChangePassword(false);
+    state = AsyncValue.data(
+      AuthState(isProfileComplete: state.value?.isProfileComplete ?? false),
+    );
+  }
+
   Future<void> logout() async {
     state = const AsyncValue.loading();
     state = await AsyncValue.guard(() async {
@@ -60,6 +93,11 @@ class AuthController extends _$AuthController {
     // Optimistic update or wait?
     // Let's rely on service + state update.
     await ref.read(authServiceProvider).setProfileComplete(true);
-    state = const AsyncValue.data(AuthState(isProfileComplete: true));
+    state = AsyncValue.data(
+      AuthState(
+        isProfileComplete: true,
+        mustChangePassword: state.value?.mustChangePassword ?? false,
+      ),
+    );
   }
 }
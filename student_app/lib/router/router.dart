@@ -14,6 +14,7 @@ import '../screens/onboarding/address_screen.dart';
 import '../screens/onboarding/profile_pic_screen.dart';
 import '../screens/onboarding/documents_screen.dart';
 import '../screens/loading_screen.dart'; // [NEW]
+import '../screens/change_password_screen.dart';
 
 final _rootNavigatorKey = GlobalKey<NavigatorState>();
 // final _shellNavigatorKey = GlobalKey<NavigatorState>();
@@ -47,12 +48,16 @@ final routerProvider = Provider<GoRouter>((ref) {
       final isLoginRoute = state.matchedLocation == '/login';
       final isOnboardingRoute = state.matchedLocation.startsWith('/onboarding');
       final isLoadingRoute = state.matchedLocation == '/loading';
+      final isChangePasswordRoute = state.matchedLocation == '/change-password';
 
       // If loading, show loading screen
       if (isLoading) return '/loading';
 
       // If finished loading and still on loading screen, redirect based on auth
       if (!isLoading && isLoadingRoute) {
+        if (isAuthenticated && authState.value!.mustChangePassword) {
+          return '/change-password';
+        }
         return isAuthenticated
             ? (authState.value!.isProfileComplete
                   ? '/drives'
@@ -64,6 +69,16 @@ final routerProvider = Provider<GoRouter>((ref) {
         return isLoginRoute ? null : '/login';
       }
 
+      // An admin-assigned password has to be changed before anything else
+      if (authState.value!.mustChangePassword) {
+        return isChangePasswordRoute ? null : '/change-password';
+      }
+      if (isChangePasswordRoute) {
+        return authState.value!.isProfileComplete
+            ? '/drives'
+            : '/onboarding/welcome';
+      }
+
       // Check profile completion if authenticated
       if (isAuthenticated) {
         final isProfileComplete = authState.value!.isProfileComplete;
@@ -88,6 +103,12 @@ final routerProvider = Provider<GoRouter>((ref) {
       // Login
       GoRoute(path: '/login', builder: (context, state) => const LoginScreen()),
 
+      // Forced password change after logging in with an assigned password
+      GoRoute(
+        path: '/change-password',
+        builder: (context, state) => const ChangePasswordScreen(forced: true),
+      ),
+
       // Onboarding Flow
       GoRoute(
         path: '/onboarding/welcome',
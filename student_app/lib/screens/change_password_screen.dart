@@ -1,14 +1,21 @@
 import 'package:flutter/material.dart';
+import 'package:flutter_riverpod/flutter_riverpod.dart';
+import '../providers/auth_provider.dart';
 import '../services/student_service.dart';
 
-class ChangePasswordScreen extends StatefulWidget {
-  const ChangePasswordScreen({super.key});
+class ChangePasswordScreen extends ConsumerStatefulWidget {
+  /// True when shown by the router because the password was set by an admin;
+  /// there is nothing to go back to until it is changed.
+  final bool forced;
+
+  const ChangePasswordScreen({super.key, this.forced = false});
 
   @override
-  State<ChangePasswordScreen> createState() => _ChangePasswordScreenState();
+  ConsumerState<ChangePasswordScreen> createState() =>
+      _ChangePasswordScreenState();
 }
 
-class _ChangePasswordScreenState extends State<ChangePasswordScreen> {
+class _ChangePasswordScreenState extends ConsumerState<ChangePasswordScreen> {
   final _formKey = GlobalKey<FormState>();
   final _oldPasswordController = TextEditingController();
   final _newPasswordController = TextEditingController();
@@ -48,7 +55,12 @@ class _ChangePasswordScreenState extends State<ChangePasswordScreen> {
             backgroundColor: Colors.green,
           ),
         );
-        Navigator.of(context).pop();
+        if (widget.forced) {
+          // The router moves on once the flag is cleared
+          await ref.read(authControllerProvider.notifier).passwordChanged();
+        } else {
+          Navigator.of(context).pop();
+        }
       }
     } catch (e) {
       if (mounted) {
@@ -76,6 +88,15 @@ class _ChangePasswordScreenState extends State<ChangePasswordScreen> {
         backgroundColor: Theme.of(context).scaffoldBackgroundColor,
         elevation: 0,
         scrolledUnderElevation: 0,
+        automaticallyImplyLeading: !widget.forced,
+        actions: [
+          if (widget.forced)
+            TextButton(
+              onPressed: () =>
+                  ref.read(authControllerProvider.notifier).logout(),
+              child: const Text('Log out'),
+            ),
+        ],
       ),
       body: SingleChildScrollView(
         padding: const EdgeInsets.all(24),
@@ -99,7 +120,7 @@ class _ChangePasswordScreenState extends State<ChangePasswordScreen> {
                     setState(() => _obscureNew = !_obscureNew),
                 validator: (val) {
                   if (val == null || val.isEmpty) return 'Required';
-                  if (val.length < 6) return 'Must be at least 6 chars';
+                  if (val.length < 8) return 'Must be at least 8 chars';
                   return null;
                 },
               ),
@@ -1,8 +1,10 @@
 import 'dart:async';
+import 'dart:convert';
 import 'dart:io';
 import 'package:http/http.dart' as http;
 import 'package:flutter_riverpod/flutter_riverpod.dart';
 import 'package:connectivity_plus/connectivity_plus.dart';
+import '../providers/auth_provider.dart';
 import '../providers/server_status_provider.dart';
 
 final apiClientProvider = Provider<ApiClient>((ref) => ApiClient(ref));
@@ -42,10 +44,25 @@ class ApiClient {
     }
   }
 
+  /// Sends the user to the forced password change when the token is refused
+  /// with PASSWORD_CHANGE_REQUIRED.
+  Future<void> _checkPasswordChange(http.Response response) async {
+    if (response.statusCode != 403) return;
+    try {
+      final decoded = jsonDecode(response.body);
+      if (decoded is Map && decoded['code'] == 'PASSWORD_CHANGE_REQUIRED') {
+        await _ref.read(authControllerProvider.notifier).requirePasswordChange();
+      }
+    } catch (_) {
+      // Not a JSON error body
+    }
+  }
+
   Future<http.Response> get(Uri url, {Map<String, String>? headers}) async {
     try {
       final response = await _client.get(url, headers: headers);
       if (response.statusCode >= 500) await _handleError(null, response);
+      await _checkPasswordChange(response);
       return response;
     } catch (e) {
       await _handleError(e);
@@ -66,6 +83,7 @@ class ApiClient {
     try {
       final response = await _client.post(url, headers: headers, body: body);
       if (response.statusCode >= 500) await _handleError(null, response);
+      await _checkPasswordChange(response);
       return response;
     } catch (e) {
       await _handleError(e);
@@ -11,12 +11,14 @@ class LoginResponse {
   final String email;
   final String role;
   final bool isProfileComplete;
+  final bool mustChangePassword;
 
   LoginResponse({
     required this.token,
     required this.email,
     required this.role,
     required this.isProfileComplete,
+    this.mustChangePassword = false,
   });
 
   factory LoginResponse.fromJson(Map<String, dynamic> json) {
@@ -25,6 +27,7 @@ class LoginResponse {
       email: json['email'] ?? '',
       role: json['role'] ?? 'student',
       isProfileComplete: json['is_profile_complete'] ?? false,
+      mustChangePassword: json['must_change_password'] ?? false,
     );
   }
 }
@@ -63,6 +66,11 @@ class AuthService {
             'is_profile_complete',
             data['is_profile_complete'] ?? false,
           );
+          // The token only allows a password change until one is made
+          await prefs.setBool(
+            'must_change_password',
+            data['must_change_password'] ?? false,
+          );
 
           // [NEW] Sync FCM Token immediately after login
           NotificationService.syncToken();
@@ -105,9 +113,21 @@ class AuthService {
     await prefs.setBool('is_profile_complete', value);
   }
 
+  Future<bool> mustChangePassword() async {
+    final prefs = await SharedPreferences.getInstance();
+    return prefs.getBool('must_change_password') ?? false;
+  }
+
+  Future<void> setMustChangePassword(bool value) async {
+    final prefs = await SharedPreferences.getInstance();
+    await prefs.setBool('must_change_password', value);
+  }
+
   Future<void> logout() async {
+    await NotificationService.unregisterToken();
     final prefs = await SharedPreferences.getInstance();
     await prefs.remove('token');
     await prefs.remove('is_profile_complete');
+    await prefs.remove('must_change_password');
   }
 }
@@ -1,5 +1,7 @@
 import 'dart:convert';
 import 'dart:developer';
+import 'package:flutter/foundation.dart'
+    show TargetPlatform, defaultTargetPlatform, kIsWeb;
 import 'package:flutter/material.dart'; // [NEW] Required for ValueNotifier
 import 'package:firebase_messaging/firebase_messaging.dart';
 import 'package:flutter_local_notifications/flutter_local_notifications.dart';
@@ -123,6 +125,42 @@ class NotificationService {
     }
   }
 
+  // Platform reported with the token; the backend keeps one token per device
+  static String _platform() {
+    if (kIsWeb) return 'web';
+    switch (defaultTargetPlatform) {
+      case TargetPlatform.android:
+        return 'android';
+      case TargetPlatform.iOS:
+        return 'ios';
+      default:
+        return '';
+    }
+  }
+
+  // Unregister this device so pushes stop after logout. Call before the
+  // auth token is cleared.
+  static Future<void> unregisterToken() async {
+    final prefs = await SharedPreferences.getInstance();
+    final String? authToken = prefs.getString('token');
+    if (authToken == null) return;
+
+    try {
+      final String? token = await FirebaseMessaging.instance.getToken();
+      if (token == null) return;
+      await http.delete(
+        Uri.parse('${AppConstants.apiBaseUrl}/v1/user/fcm-token'),
+        headers: {
+          'Content-Type': 'application/json',
+          'Authorization': 'Bearer $authToken',
+        },
+        body: jsonEncode({'token': token}),
+      );
+    } catch (e) {
+      log('Error unregistering FCM token: $e');
+    }
+  }
+
   // Send Token to Backend
   static Future<void> _sendTokenToBackend(String fcmToken) async {
     final prefs = await SharedPreferences.getInstance();
@@ -140,7 +178,7 @@ class NotificationService {
           'Content-Type': 'application/json',
           'Authorization': 'Bearer $authToken',
         },
-        body: jsonEncode({'token': fcmToken}),
+        body: jsonEncode({'token': fcmToken, 'platform': _platform()}),
       );
 
       log('Backend response code: ${response.statusCode}');
@@ -148,6 +148,8 @@ class StudentService {
   }
 
   // Change Password
+  // Goes to auth-service, which also accepts the restricted token issued
+  // when a change is required, and returns a full token to use from now on.
   Future<void> changePassword(
     String oldPassword,
     String newPassword,
@@ -155,7 +157,7 @@ class StudentService {
   ) async {
     final token = await _getToken();
     final response = await http.put(
-      Uri.parse('$baseUrl/v1/student/password'),
+      Uri.parse('$baseUrl/v1/auth/password'),
       headers: {
         'Authorization': 'Bearer $token',
         'Content-Type': 'application/json',
@@ -171,6 +173,11 @@ class StudentService {
       final error = jsonDecode(response.body);
       throw Exception(error['error'] ?? 'Failed to change password');
     }
+    final data = jsonDecode(response.body);
+    if (data['token'] != null) {
+      final prefs = await SharedPreferences.getInstance();
+      await prefs.setString('token', data['token']);
+    }
   }
 
   // Get My Requests